@@ -0,0 +1,19 @@
+// Package api holds the OpenAPI description of the HTTP API as YAML,
+// compiled into the binary so the server can hand it out at
+// GET /docs/openapi.yaml.
+//
+// openapi.yaml is generated from internal/http/handlers/openapi/openapi.json;
+// don't edit it by hand. After changing openapi.json, regenerate it with:
+//
+//	make openapi-yaml
+package api
+
+import _ "embed"
+
+// OpenAPIYAML is openapi.yaml, embedded at compile time.
+//
+// go:embed can only reach files in this directory or below it, which is
+// why the embedding lives here rather than in the handler that serves it.
+//
+//go:embed openapi.yaml
+var OpenAPIYAML []byte
@@ -0,0 +1,110 @@
+// gen-handler scaffolds a new REST resource that follows the same
+// closure/factory handler pattern as internal/http/handlers/student.
+//
+// Given a resource name it writes three files:
+//
+//	internal/types/<resource>.go                        → the model struct
+//	internal/storage/<resource>.go                      → the storage interface
+//	internal/http/handlers/<resource>/<resource>.go     → New, GetByID, GetList,
+//	                                                      Update, Delete handlers
+//
+// and prints the route registration lines to paste into main.go.
+//
+// USAGE:
+//
+//	go run ./cmd/gen-handler --resource=course
+//
+// Existing files are never overwritten unless --force is given, because
+// the generated code is a starting point meant to be edited by hand.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// modulePath is the import path prefix used in generated imports.
+const modulePath = "github.com/aanand-mishra/students-api"
+
+// resourcePattern restricts names to something that is valid as a Go
+// package name, file name and URL segment all at once.
+var resourcePattern = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+
+// Resource holds the name variants the templates need.
+type Resource struct {
+	Module string // e.g. github.com/aanand-mishra/students-api
+	Lower  string // e.g. course   — package, file and variable names
+	Title  string // e.g. Course   — exported type names
+	Plural string // e.g. courses  — URL path segment
+}
+
+func main() {
+	resource := flag.String("resource", "", "Name of the resource to generate, e.g. course")
+	root := flag.String("out", ".", "Repository root to write the generated files into")
+	force := flag.Bool("force", false, "Overwrite files that already exist")
+	flag.Parse()
+
+	if !resourcePattern.MatchString(*resource) {
+		log.Fatal("--resource must be a lowercase identifier, e.g. --resource=course")
+	}
+
+	res := Resource{
+		Module: modulePath,
+		Lower:  *resource,
+		Title:  strings.ToUpper((*resource)[:1]) + (*resource)[1:],
+		Plural: *resource + "s",
+	}
+
+	files := map[string]*template.Template{
+		filepath.Join("internal", "types", res.Lower+".go"):                       typesTmpl,
+		filepath.Join("internal", "storage", res.Lower+".go"):                     storageTmpl,
+		filepath.Join("internal", "http", "handlers", res.Lower, res.Lower+".go"): handlerTmpl,
+	}
+
+	for path, tmpl := range files {
+		if err := generate(filepath.Join(*root, path), tmpl, res, *force); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("generated", path)
+	}
+
+	fmt.Println()
+	fmt.Println("Add these routes to cmd/students-api/main.go:")
+	fmt.Println()
+	if err := routesTmpl.Execute(os.Stdout, res); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generate renders tmpl with res, gofmt's the output and writes it to path.
+func generate(path string, tmpl *template.Template, res Resource, force bool) error {
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, res); err != nil {
+		return fmt.Errorf("render %s: %w", path, err)
+	}
+
+	// format.Source both tidies the output and catches template mistakes
+	// that would produce invalid Go.
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("format %s: %w", path, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create dir for %s: %w", path, err)
+	}
+
+	return os.WriteFile(path, src, 0o644)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+// TestGeneratedCodeCompiles generates a resource and builds it as part
+// of this module.
+//
+// The files are written to a temporary directory and laid over the
+// module with go build -overlay, so the test never touches the source
+// tree.
+func TestGeneratedCodeCompiles(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+
+	repo, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := t.TempDir()
+
+	res := Resource{Module: modulePath, Lower: "gentest", Title: "Gentest", Plural: "gentests"}
+	files := map[string]string{}
+	for path, tmpl := range map[string]*template.Template{
+		filepath.Join("internal", "types", "gentest.go"):                       typesTmpl,
+		filepath.Join("internal", "storage", "gentest.go"):                     storageTmpl,
+		filepath.Join("internal", "http", "handlers", "gentest", "gentest.go"): handlerTmpl,
+	} {
+		if err := generate(filepath.Join(out, path), tmpl, res, false); err != nil {
+			t.Fatalf("generate %s: %v", path, err)
+		}
+		files[filepath.Join(repo, path)] = filepath.Join(out, path)
+	}
+
+	overlay, err := json.Marshal(map[string]any{"Replace": files})
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlayPath := filepath.Join(out, "overlay.json")
+	if err := os.WriteFile(overlayPath, overlay, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(goBin, "build", "-overlay="+overlayPath,
+		"./internal/types", "./internal/storage", "./internal/http/handlers/gentest")
+	cmd.Dir = repo
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("go build of the generated code failed: %v\n%s", err, output)
+	}
+}
+
+func TestGenerateRefusesToOverwrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "course.go")
+	if err := os.WriteFile(path, []byte("package types\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	res := Resource{Module: modulePath, Lower: "course", Title: "Course", Plural: "courses"}
+
+	err := generate(path, typesTmpl, res, false)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("generate over an existing file: error = %v, want \"already exists\"", err)
+	}
+	if src, _ := os.ReadFile(path); string(src) != "package types\n" {
+		t.Error("existing file was changed")
+	}
+
+	if err := generate(path, typesTmpl, res, true); err != nil {
+		t.Fatalf("generate with force: %v", err)
+	}
+	if src, _ := os.ReadFile(path); !strings.Contains(string(src), "type Course struct") {
+		t.Errorf("forced generate did not write the type:\n%s", src)
+	}
+}
+
+func TestGeneratedHandlerUsesSharedValidator(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "course.go")
+	res := Resource{Module: modulePath, Lower: "course", Title: "Course", Plural: "courses"}
+	if err := generate(path, handlerTmpl, res, false); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(src), "validator.New()") || !strings.Contains(string(src), "validation.Validator().Struct(") {
+		t.Errorf("the generated handler must validate with the shared validation.Validator():\n%s", src)
+	}
+}
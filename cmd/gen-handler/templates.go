@@ -0,0 +1,203 @@
+package main
+
+import "text/template"
+
+var typesTmpl = template.Must(template.New("types").Parse(`package types
+
+// {{.Title}} represents a {{.Lower}} record.
+// Add fields here, with json:"..." and validate:"..." tags like Student.
+type {{.Title}} struct {
+	ID   int    ` + "`json:\"id\"`" + `
+	Name string ` + "`json:\"name\" validate:\"required\"`" + `
+}
+`))
+
+var storageTmpl = template.Must(template.New("storage").Parse(`package storage
+
+import "{{.Module}}/internal/types"
+
+// {{.Title}}Storage is the database contract for {{.Lower}} records.
+// Implement it next to the Storage implementation (e.g. in sqlite.go).
+type {{.Title}}Storage interface {
+	// Create{{.Title}} inserts a new {{.Lower}} and returns its generated ID.
+	Create{{.Title}}({{.Lower}} types.{{.Title}}) (int64, error)
+
+	// Get{{.Title}}ByID fetches a single {{.Lower}} by primary key.
+	Get{{.Title}}ByID(id int64) (types.{{.Title}}, error)
+
+	// Get{{.Title}}s returns every {{.Lower}}, or an empty slice.
+	Get{{.Title}}s() ([]types.{{.Title}}, error)
+
+	// Update{{.Title}}ByID replaces the fields of an existing {{.Lower}}.
+	Update{{.Title}}ByID(id int64, {{.Lower}} types.{{.Title}}) (types.{{.Title}}, error)
+
+	// Delete{{.Title}}ByID removes a {{.Lower}} permanently.
+	Delete{{.Title}}ByID(id int64) error
+}
+`))
+
+var handlerTmpl = template.Must(template.New("handler").Parse(`// Package {{.Lower}} contains all HTTP handlers related to the {{.Title}}
+// resource. It follows the same closure/factory pattern as the student
+// package: each exported function receives its dependencies once at
+// startup and returns the http.HandlerFunc the router calls per request.
+package {{.Lower}}
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"{{.Module}}/internal/storage"
+	"{{.Module}}/internal/types"
+	"{{.Module}}/internal/utils/response"
+	"{{.Module}}/internal/validation"
+	"github.com/go-playground/validator/v10"
+)
+
+// New handles POST /api/{{.Plural}}
+func New(storage storage.{{.Title}}Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slog.Info("creating a {{.Lower}}")
+
+		{{.Lower}}, ok := decode(w, r)
+		if !ok {
+			return
+		}
+
+		lastID, err := storage.Create{{.Title}}({{.Lower}})
+		if err != nil {
+			response.WriteJSON(w, http.StatusInternalServerError,
+				response.GeneralError(err))
+			return
+		}
+
+		response.WriteJSON(w, http.StatusCreated, map[string]int64{"id": lastID})
+	}
+}
+
+// GetByID handles GET /api/{{.Plural}}/{id}
+func GetByID(storage storage.{{.Title}}Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		intID, ok := parseID(w, r)
+		if !ok {
+			return
+		}
+
+		{{.Lower}}, err := storage.Get{{.Title}}ByID(intID)
+		if err != nil {
+			response.WriteJSON(w, http.StatusInternalServerError,
+				response.GeneralError(err))
+			return
+		}
+
+		response.WriteJSON(w, http.StatusOK, {{.Lower}})
+	}
+}
+
+// GetList handles GET /api/{{.Plural}}
+func GetList(storage storage.{{.Title}}Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		{{.Plural}}, err := storage.Get{{.Title}}s()
+		if err != nil {
+			response.WriteJSON(w, http.StatusInternalServerError,
+				response.GeneralError(err))
+			return
+		}
+
+		response.WriteJSON(w, http.StatusOK, {{.Plural}})
+	}
+}
+
+// Update handles PUT /api/{{.Plural}}/{id}
+func Update(storage storage.{{.Title}}Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		intID, ok := parseID(w, r)
+		if !ok {
+			return
+		}
+
+		{{.Lower}}, ok := decode(w, r)
+		if !ok {
+			return
+		}
+
+		updated, err := storage.Update{{.Title}}ByID(intID, {{.Lower}})
+		if err != nil {
+			response.WriteJSON(w, http.StatusInternalServerError,
+				response.GeneralError(err))
+			return
+		}
+
+		response.WriteJSON(w, http.StatusOK, updated)
+	}
+}
+
+// Delete handles DELETE /api/{{.Plural}}/{id}
+func Delete(storage storage.{{.Title}}Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		intID, ok := parseID(w, r)
+		if !ok {
+			return
+		}
+
+		if err := storage.Delete{{.Title}}ByID(intID); err != nil {
+			response.WriteJSON(w, http.StatusInternalServerError,
+				response.GeneralError(err))
+			return
+		}
+
+		response.WriteJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+	}
+}
+
+// parseID reads the {id} path segment, writing a 400 if it is not an integer.
+func parseID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	intID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		response.WriteJSON(w, http.StatusBadRequest,
+			response.BadRequestError(errors.New("invalid id: must be an integer")))
+		return 0, false
+	}
+	return intID, true
+}
+
+// decode reads and validates the JSON body, writing a 400 if it is
+// malformed or a 422 if it fails validation.
+func decode(w http.ResponseWriter, r *http.Request) (types.{{.Title}}, bool) {
+	var {{.Lower}} types.{{.Title}}
+
+	err := json.NewDecoder(r.Body).Decode(&{{.Lower}})
+	if errors.Is(err, io.EOF) {
+		response.WriteJSON(w, http.StatusBadRequest,
+			response.BadRequestError(errors.New("request body is empty")))
+		return {{.Lower}}, false
+	}
+	if err != nil {
+		response.WriteJSON(w, http.StatusBadRequest, response.BadRequestError(err))
+		return {{.Lower}}, false
+	}
+
+	// The shared validator caches what it learns about each struct type.
+	if err := validation.Validator().Struct({{.Lower}}); err != nil {
+		var validateErrs validator.ValidationErrors
+		if !errors.As(err, &validateErrs) {
+			response.WriteJSON(w, http.StatusBadRequest, response.BadRequestError(err))
+			return {{.Lower}}, false
+		}
+		response.WriteJSON(w, http.StatusUnprocessableEntity, response.ValidationError(validateErrs))
+		return {{.Lower}}, false
+	}
+
+	return {{.Lower}}, true
+}
+`))
+
+var routesTmpl = template.Must(template.New("routes").Parse(`	router.HandleFunc("POST /api/{{.Plural}}", {{.Lower}}.New({{.Lower}}Storage))
+	router.HandleFunc("GET /api/{{.Plural}}", {{.Lower}}.GetList({{.Lower}}Storage))
+	router.HandleFunc("GET /api/{{.Plural}}/{id}", {{.Lower}}.GetByID({{.Lower}}Storage))
+	router.HandleFunc("PUT /api/{{.Plural}}/{id}", {{.Lower}}.Update({{.Lower}}Storage))
+	router.HandleFunc("DELETE /api/{{.Plural}}/{id}", {{.Lower}}.Delete({{.Lower}}Storage))
+`))
@@ -2,11 +2,12 @@
 //
 // STARTUP SEQUENCE:
 //  1. Load configuration from a YAML file
-//  2. Initialise the logger
-//  3. Connect to (and set up) the SQLite database
+//  2. Initialise the logger and tracing
+//  3. Connect to (and set up) the SQLite database, optionally self-test it
 //  4. Register all HTTP routes
 //  5. Start the HTTP server in a separate goroutine
-//  6. Block the main goroutine until an OS signal (Ctrl+C / kill) arrives
+//  6. Block the main goroutine until an OS signal (Ctrl+C / kill) arrives;
+//     meanwhile SIGHUP reloads the config and SIGUSR1 toggles draining
 //  7. Gracefully shut down: finish in-flight requests, then exit
 //
 // RUNNING THE SERVER:
@@ -16,22 +17,76 @@
 // or (with the environment variable):
 //
 //	CONFIG_PATH=config/local.yaml go run ./cmd/students-api
+//
+// To only bring the database schema up to date and exit (e.g. as a
+// deploy step before starting servers with auto_migrate off):
+//
+//	go run ./cmd/students-api --config=config/local.yaml --migrate-only
 package main
 
 import (
 	"context"
+	"crypto/tls"
+	"flag"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/aanand-mishra/students-api/internal/audit"
+	"github.com/aanand-mishra/students-api/internal/build"
 	"github.com/aanand-mishra/students-api/internal/config"
+	"github.com/aanand-mishra/students-api/internal/crypto"
+	"github.com/aanand-mishra/students-api/internal/events"
+	grpcserver "github.com/aanand-mishra/students-api/internal/grpc/server"
+	"github.com/aanand-mishra/students-api/internal/http/certmanager"
+	"github.com/aanand-mishra/students-api/internal/http/handlers/admin"
+	"github.com/aanand-mishra/students-api/internal/http/handlers/health"
+	"github.com/aanand-mishra/students-api/internal/http/handlers/history"
+	"github.com/aanand-mishra/students-api/internal/http/handlers/openapi"
+	"github.com/aanand-mishra/students-api/internal/http/handlers/redirect"
+	"github.com/aanand-mishra/students-api/internal/http/handlers/schema"
 	"github.com/aanand-mishra/students-api/internal/http/handlers/student"
+	"github.com/aanand-mishra/students-api/internal/http/handlers/webhooks"
+	"github.com/aanand-mishra/students-api/internal/http/limit"
+	"github.com/aanand-mishra/students-api/internal/http/middleware"
+	"github.com/aanand-mishra/students-api/internal/http/middleware/auth"
+	"github.com/aanand-mishra/students-api/internal/http/middleware/bodylimit"
+	"github.com/aanand-mishra/students-api/internal/http/middleware/compress"
+	"github.com/aanand-mishra/students-api/internal/http/middleware/logger"
+	"github.com/aanand-mishra/students-api/internal/http/middleware/ratelimit"
+	"github.com/aanand-mishra/students-api/internal/http/middleware/rbac"
+	"github.com/aanand-mishra/students-api/internal/http/middleware/recovery"
+	"github.com/aanand-mishra/students-api/internal/http/middleware/requestid"
+	"github.com/aanand-mishra/students-api/internal/http/router"
+	"github.com/aanand-mishra/students-api/internal/metrics"
+	"github.com/aanand-mishra/students-api/internal/selftest"
+	storagepkg "github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/storage/cache"
+	"github.com/aanand-mishra/students-api/internal/storage/capacity"
+	"github.com/aanand-mishra/students-api/internal/storage/encrypt"
+	"github.com/aanand-mishra/students-api/internal/storage/postgres"
 	"github.com/aanand-mishra/students-api/internal/storage/sqlite"
+	"github.com/aanand-mishra/students-api/internal/tracing"
+	"github.com/aanand-mishra/students-api/internal/utils/response"
+	"github.com/aanand-mishra/students-api/internal/validation"
+	"github.com/aanand-mishra/students-api/internal/webhook"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"google.golang.org/grpc"
 )
 
+// configDriftInterval is how often the config file is re-read to detect
+// changes made on disk after startup.
+const configDriftInterval = 60 * time.Second
+
+// migrateOnly is registered here and parsed by config.MustLoad together
+// with --config.
+var migrateOnly = flag.Bool("migrate-only", false,
+	"Apply pending database migrations, then exit without starting the server")
+
 func main() {
 	// ── 1. Load Config ────────────────────────────────────────────────────
 	// MustLoad reads the YAML config and panics if anything is wrong.
@@ -43,106 +98,635 @@ func main() {
 	// Structured logging writes key=value pairs rather than plain strings,
 	// making logs easy to filter/search in tools like Loki or Datadog.
 	log := setupLogger(cfg.Env)
+	// Handlers and middleware log through the package-level slog
+	// functions, so make them use the same format and level.
+	slog.SetDefault(log)
+
+	// Indented JSON is easier to read while developing.
+	response.SetPrettyDefault(cfg.Env == "dev")
+	// In dev, internal error responses include a stack trace.
+	response.SetDebug(cfg.Env == "dev")
+
+	// Which student fields are required is configured per deployment.
+	if err := validation.SetRequiredFields(cfg.Validation.RequiredFields); err != nil {
+		log.Error("invalid validation config", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	validation.SetDepartmentEmailDomains(cfg.Validation.DepartmentEmailDomains)
+	response.SetJoinValidationErrors(!cfg.Validation.ErrorsOnly)
 
 	log.Info("starting students-api",
 		slog.String("env", cfg.Env),
-		slog.String("version", "1.0.0"),
+		slog.String("version", build.Version),
 	)
 
-	// ── 3. Initialise Storage (Database) ──────────────────────────────────
-	// sqlite.New opens the SQLite file and creates the students table.
-	// We store the result as the storage.Storage INTERFACE, not *sqlite.SQLite.
-	// This means the rest of the code only knows about the interface —
-	// swapping to PostgreSQL later only requires changing this one line.
-	storage, err := sqlite.New(cfg)
+	// Tracing: spans for every request and the storage calls it makes,
+	// exported to otlp_endpoint if set. Shutdown flushes the spans still
+	// buffered, so it runs last, after the servers have stopped.
+	tp, err := tracing.Setup(cfg)
 	if err != nil {
-		log.Error("failed to initialise storage",
+		log.Error("failed to set up tracing", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tp.Shutdown(ctx); err != nil {
+			log.Error("failed to flush traces", slog.String("error", err.Error()))
+		}
+	}()
+	if cfg.OTLPEndpoint != "" {
+		log.Info("tracing enabled", slog.String("otlp_endpoint", cfg.OTLPEndpoint))
+	}
+
+	if *migrateOnly {
+		if err := runMigrations(cfg); err != nil {
+			log.Error("migration failed",
+				slog.String("backend", cfg.StorageBackend),
+				slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		log.Info("database schema is up to date; exiting (--migrate-only)",
+			slog.String("backend", cfg.StorageBackend))
+		return
+	}
+
+	// ── 3. Initialise Storage (Database) ──────────────────────────────────
+	// storage_backend picks the database. Handlers only ever see the
+	// storage.Storage INTERFACE (via `storage` below), never the concrete
+	// type, so nothing past this block cares which one it is.
+	//
+	// sqliteDB stays nil on PostgreSQL; it gates the SQLite-only extras
+	// (connection warm-up, planner statistics, export and admin routes).
+	// countAll counts the students of every tenant, which storage.Storage
+	// deliberately can't; only the students_total metric needs it.
+	var (
+		db          storagepkg.Storage
+		auditWriter audit.Writer
+		idemStore   middleware.IdempotencyStore
+		hookStore   webhook.Store
+		sqliteDB    *sqlite.SQLite
+		countAll    func(context.Context) (int64, error)
+	)
+
+	// Background work that should stop when the server does.
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	switch cfg.StorageBackend {
+	case config.StorageBackendPostgres:
+		// postgres.New connects to the server and creates the schema.
+		pg, err := postgres.New(cfg)
+		if err != nil {
+			log.Error("failed to initialise storage",
+				slog.String("backend", cfg.StorageBackend),
+				slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		db, auditWriter, idemStore, hookStore = pg, pg, pg, pg
+		countAll = pg.CountAllStudents
+
+		// Never log the DSN itself — it usually contains a password.
+		log.Info("storage initialised",
+			slog.String("backend", cfg.StorageBackend))
+
+	default:
+		// sqlite.NewWithRetry opens the SQLite file and brings its schema
+		// up to date, retrying for a while if it can't (a volume that
+		// isn't mounted yet, say). Ctrl+C or SIGTERM stops the retrying.
+		startCtx, stopStart := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		var err error
+		sqliteDB, err = sqlite.NewWithRetry(startCtx, cfg, cfg.Database.RetryAttempts, cfg.Database.RetryDelay)
+		stopStart()
+		if err != nil {
+			log.Error("failed to initialise storage",
+				slog.String("backend", cfg.StorageBackend),
+				slog.String("error", err.Error()))
+			os.Exit(1) // non-zero exit code signals failure to the OS / CI system
+		}
+		db, auditWriter, idemStore, hookStore = sqliteDB, sqliteDB, sqliteDB, sqliteDB
+		countAll = sqliteDB.CountAllStudents
+
+		log.Info("storage initialised",
+			slog.String("backend", cfg.StorageBackend),
+			slog.String("path", cfg.StoragePath),
+			slog.String("journal_mode", sqliteDB.JournalMode()))
+
+		// Open a few connections now rather than on the first requests.
+		warmStart := time.Now()
+		warmCtx, cancelWarm := context.WithTimeout(context.Background(), 10*time.Second)
+		err = sqliteDB.WarmUp(warmCtx, cfg.Database.WarmUpConns)
+		cancelWarm()
+		if err != nil {
+			log.Error("failed to warm up database connections",
+				slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		log.Info("database connections warmed up",
+			slog.Int("connections", sqliteDB.Db.Stats().OpenConnections),
+			slog.Duration("duration", time.Since(warmStart)))
+
+		// Keep the query planner's statistics current as the table grows.
+		sqlite.NewOptimizer(sqliteDB).Start(bgCtx, cfg.Database.OptimizeInterval)
+	}
+
+	// Photos are written here by the upload handler; create it up front so
+	// the first upload doesn't fail on a missing directory.
+	if err := os.MkdirAll(cfg.PhotoStoragePath, 0o755); err != nil {
+		log.Error("failed to create photo directory",
+			slog.String("path", cfg.PhotoStoragePath),
 			slog.String("error", err.Error()))
-		os.Exit(1) // non-zero exit code signals failure to the OS / CI system
+		os.Exit(1)
+	}
+
+	// Optionally prove the database actually works (writable, schema
+	// matches) before accepting traffic, rather than at the first request.
+	if cfg.SelfTestEnabled() {
+		if err := selftest.Run(context.Background(), db); err != nil {
+			log.Error("startup self-test failed",
+				slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		log.Info("startup self-test passed")
 	}
 
-	log.Info("storage initialised",
-		slog.String("path", cfg.StoragePath))
+	// The handlers talk to a stack of decorators around the database:
+	//   cache → (optional) student cap → (optional) field encryption →
+	//   SQLite or PostgreSQL
+	backend := db
+
+	// With ENCRYPTION_KEY set, names and emails are encrypted at rest.
+	if cfg.Security.EncryptionKey != "" {
+		key, err := crypto.ParseKey(cfg.Security.EncryptionKey)
+		if err != nil {
+			log.Error("invalid ENCRYPTION_KEY", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		backend = encrypt.New(backend, key)
+		// Otherwise the plaintext would still reach the audit log via
+		// the recorded request bodies.
+		audit.AddRedactedKeys("name", "email")
+		log.Info("field encryption enabled")
+	}
+
+	// With max_students set, creating students beyond it is refused.
+	if cfg.MaxStudents > 0 {
+		backend = capacity.New(backend, cfg.MaxStudents)
+		log.Info("student cap enabled", slog.Int("max_students", cfg.MaxStudents))
+	}
+
+	// Wrap the database in an in-memory read cache. It also lets reads
+	// fall back to stale data while the database is failing, if enabled.
+	storage := cache.New(backend, cfg.Database)
+
+	// Record every change to a student in the audit_log table.
+	db.RegisterHook(audit.New(auditWriter))
+
+	// POST every change to the URLs registered at /api/webhooks. Unlike
+	// the audit log, this hook goes through the decorators, so with
+	// encryption on subscribers are sent plaintext students.
+	dispatcher := webhook.New(hookStore, cfg.Webhooks.QueueSize, cfg.Webhooks.Timeout)
+	storage.RegisterHook(dispatcher)
+	dispatcher.Start(bgCtx)
+
+	// Broadcast every change to the clients of GET /api/students/events,
+	// also through the decorators.
+	bus := events.NewEventBus()
+	storage.RegisterHook(bus)
 
 	// ── 4. Register HTTP Routes ───────────────────────────────────────────
-	// http.NewServeMux() creates an empty router.
+	// router.New() creates an empty router — an http.ServeMux that can
+	// also tell which methods a path allows, to answer OPTIONS requests.
 	// HandleFunc maps a METHOD+PATTERN to a handler function.
 	//
 	// The handler functions (student.New, student.GetByID, etc.) are
 	// FACTORIES — they receive `storage` and return the actual handler.
 	// This is the dependency injection / closure pattern.
 	//
-	// Route table:
-	//   POST   /api/students        → create a new student
-	//   GET    /api/students        → list all students
-	//   GET    /api/students/{id}   → get one student by ID
-	//   PUT    /api/students/{id}   → update a student
-	//   DELETE /api/students/{id}   → delete a student
-	router := http.NewServeMux()
-
-	router.HandleFunc("POST /api/students", student.New(storage))
-	router.HandleFunc("GET /api/students", student.GetList(storage))
-	router.HandleFunc("GET /api/students/{id}", student.GetByID(storage))
-	router.HandleFunc("PUT /api/students/{id}", student.Update(storage))
-	router.HandleFunc("DELETE /api/students/{id}", student.Delete(storage))
+	// Route table (every /api/students and /api/webhooks route needs a
+	// bearer token). Every
+	// /api route is served under the current version too, e.g.
+	// /v1/api/students; the unversioned paths answer with a
+	// "Deprecation: true" header:
+	//   POST   /api/students                        → create a new student (Idempotency-Key honoured)
+	//   GET    /api/students                        → list students, a page at a time
+	//   GET    /api/students/random                 → get a random student
+	//   GET    /api/students/search?q=              → find students by part of name or email
+	//   GET    /api/students/stats                  → count and average/min/max age
+	//   GET    /api/students/events                 → stream every change as Server-Sent Events
+	//   GET    /api/students/export                 → download students as a CSV file (filters honoured)
+	//   GET    /api/students/export.tar.gz          → download all students as CSV in a tar.gz (SQLite)
+	//   POST   /api/students/import                 → create/update students from a CSV file (or a form upload)
+	//   GET    /api/students/{id}                   → get one student by ID
+	//   GET    /api/students/external/{external_id} → get one by external ID
+	//   GET    /api/students/by-email?email=        → get one by email
+	//   PUT    /api/students/{id}                   → update a student
+	//   PATCH  /api/students/{id}                   → partially update (JSON Merge Patch)
+	//   DELETE /api/students/{id}                   → delete a student (soft delete)
+	//   PATCH  /api/students/{id}/status            → set status: active, inactive or suspended
+	//   POST   /api/students/{id}/restore           → undo a delete
+	//   POST   /api/students/{id}/anonymize         → erase a student's personal data (admin role)
+	//   GET    /api/students/{id}/history           → the student's audit log, oldest first
+	//   POST   /api/students/batch                  → create many students at once
+	//   DELETE /api/students/batch                  → delete many students, all or nothing
+	//   PUT    /api/students/batch/upsert           → create or update many by email
+	//   POST   /api/webhooks                        → register a URL to be sent every change
+	//   GET    /api/webhooks                        → list the registered webhooks
+	//   DELETE /api/webhooks/{id}                   → unregister a webhook
+	//   GET    /api/schemas/student                 → JSON Schema of a student payload
+	//   GET    /openapi.json                        → OpenAPI 3.0 description of the API (not in prod by default)
+	//   GET    /docs/openapi.yaml                   → the same description, as YAML
+	//   GET    /docs                                → Swagger UI for /openapi.json
+	//   POST   /api/students/{id}/photo             → upload a JPEG/PNG photo
+	//   GET    /photos/{filename}                   → serve an uploaded photo
+	//   GET    /admin/db/download                   → download a DB snapshot (API key, SQLite)
+	//   GET    /admin/db/stats                      → database size and row counts (API key, SQLite)
+	//   GET    /health                              → liveness + config drift status (probe)
+	//   GET    /ready                               → readiness: can the database be reached (probe)
+	//   GET    /metrics                             → Prometheus metrics (on metrics_addr if set)
+	//   OPTIONS /api/..., /v1/api/...               → allowed methods + CORS preflight
+	router := router.New()
+
+	// Every /api/students and /api/webhooks route needs a valid bearer
+	// token (JWT). /api/schemas, the API docs, /metrics and the photos
+	// stay public, and the admin routes have their own API key. OPTIONS preflights
+	// can't carry the header, so registerAPIRoutes registers them
+	// unwrapped.
+	//
+	// On a server shared by several schools, every token must say whose
+	// students it works on.
+	var authOpts []auth.Option
+	if cfg.Security.MultiTenant {
+		authOpts = append(authOpts, auth.RequireTenant())
+	}
+	requireToken := auth.JWTMiddleware(cfg.Security.JWTSecret, authOpts...)
+	if cfg.Security.JWTSecret == "" {
+		log.Warn("security.jwt_secret is not set: every /api/students request will be refused")
+	}
+
+	// The API routes, keyed by their unversioned pattern. Each handler is
+	// built once and served under two paths (see registerAPIRoutes).
+	apiRoutes := map[string]http.Handler{
+		// Creating a student is the one call a client can't safely retry
+		// on its own; with an Idempotency-Key header it can.
+		"POST /api/students":                       middleware.Idempotency(idemStore, cfg.Idempotency.TTL, student.New(storage)),
+		"GET /api/students":                        student.GetList(storage, cfg.Pagination.MaxPerPage),
+		"GET /api/students/random":                 student.GetRandom(storage),
+		"GET /api/students/search":                 student.Search(storage),
+		"GET /api/students/stats":                  student.Stats(storage),
+		"GET /api/students/events":                 student.StreamEvents(bus),
+		"GET /api/students/export":                 student.ExportCSV(storage),
+		"POST /api/students/import":                student.Import(storage, cfg.Import.MaxBytes),
+		"GET /api/students/{id}":                   student.GetByID(storage),
+		"GET /api/students/external/{external_id}": student.GetByExternalID(storage),
+		"GET /api/students/by-email":               student.GetByEmail(storage),
+		"PUT /api/students/{id}":                   student.Update(storage),
+		"PATCH /api/students/{id}":                 student.Patch(storage),
+		"DELETE /api/students/{id}":                student.Delete(storage),
+		"PATCH /api/students/{id}/status":          student.SetStatus(storage),
+		"POST /api/students/{id}/restore":          student.Restore(storage),
+		"POST /api/students/{id}/anonymize":        student.Anonymize(storage, cfg.PhotoStoragePath),
+		history.Pattern:                            history.Get(storage),
+		"POST /api/students/batch":                 student.BatchCreate(storage),
+		"DELETE /api/students/batch":               student.BatchDelete(storage),
+		"PUT /api/students/batch/upsert":           student.Upsert(storage),
+		"POST /api/students/{id}/photo":            student.UploadPhoto(storage, cfg.PhotoStoragePath),
+		"POST /api/webhooks":                       webhooks.Create(hookStore),
+		"GET /api/webhooks":                        webhooks.List(hookStore),
+		"DELETE /api/webhooks/{id}":                webhooks.Delete(hookStore),
+	}
+	// The tar.gz export streams from the database itself, past the cache.
+	if sqliteDB != nil {
+		apiRoutes["GET /api/students/export.tar.gz"] = student.Export(sqliteDB)
+	}
+
+	// Each of them needs a token whose role is at least the one
+	// routePermissions gives the route: viewers may only read.
+	for pattern, handler := range apiRoutes {
+		apiRoutes[pattern] = requireToken(rbac.Require(routePermissions[pattern])(handler))
+	}
+
+	// The JSON Schema is public, like the API docs.
+	apiRoutes["GET /api/schemas/student"] = schema.Student()
+
+	// The current version's routes (/v1/api/students), and the old
+	// unversioned ones (/api/students) for clients written before there
+	// were versions. A future /v2 can be registered next to them.
+	apiPrefix := "/" + cfg.API.CurrentVersion
+	registerAPIRoutes(router, apiPrefix, apiRoutes, nil)
+	registerAPIRoutes(router, "", apiRoutes, middleware.Deprecated)
+
+	// The OpenAPI description of the routes above, as JSON and as YAML,
+	// and Swagger UI for it. They are public, like the JSON Schema, so
+	// production leaves them out unless http_server.enable_docs asks for
+	// them.
+	if cfg.DocsEnabled() {
+		router.HandleFunc("GET /openapi.json", openapi.Spec(apiPrefix))
+		router.HandleFunc("GET /docs/openapi.yaml", openapi.SpecYAML())
+		router.HandleFunc("GET /docs", openapi.Docs())
+	}
+
+	// Uploaded photos are plain files on disk; http.FileServer serves them
+	// (with correct Content-Type, Range and caching headers) once the
+	// "/photos/" prefix is stripped from the URL path.
+	router.Handle("GET /photos/", http.StripPrefix("/photos/",
+		http.FileServer(http.Dir(cfg.PhotoStoragePath))))
+
+	// Admin endpoints — all require the X-API-Key header. Both work on
+	// the SQLite file; PostgreSQL has pg_dump and its own statistics.
+	if sqliteDB != nil {
+		router.Handle("GET /admin/db/download",
+			middleware.RequireAPIKey(cfg.AdminAPIKey, admin.DownloadDB(sqliteDB)))
+		router.Handle("GET /admin/db/stats",
+			middleware.RequireAPIKey(cfg.AdminAPIKey, admin.DBStats(sqliteDB)))
+	}
+
+	// Without a metrics_addr of its own, /metrics is served with the API.
+	if cfg.MetricsAddr == "" {
+		router.HandleFunc("GET /metrics", metrics.Handler())
+	}
+
+	// How many students there are, across all tenants, is read from the
+	// database on each scrape, rather than tracked by hand on every write.
+	metrics.RegisterGaugeFunc("students_total", "Number of students in all tenants, not counting deleted ones.",
+		func(ctx context.Context) (float64, error) {
+			n, err := countAll(ctx)
+			return float64(n), err
+		})
+
+	// Wrap the router in the middleware chain. Each middleware wraps the
+	// next, so the outermost one runs first on every request.
+	denyList := middleware.MultiDenyList{middleware.NewStaticDenyList(cfg.Security.DeniedIPs)}
+	if cfg.Security.DenyListFile != "" {
+		denyList = append(denyList, middleware.FileDenyListSource(cfg.Security.DenyListFile))
+	}
+
+	// Each client (by IP) gets rate_limit.rps across the API, and the
+	// stricter rate_limit.routes limits on the routes listed there.
+	rateLimit := ratelimit.RateLimiter(cfg.RateLimit.RPS, cfg.RateLimit.Burst,
+		ratelimit.WithRoutes(cfg.RateLimit.Routes, router.Pattern),
+		ratelimit.WithIdleTTL(cfg.RateLimit.IdleTTL),
+		ratelimit.WithContext(bgCtx))
+
+	// The server drops connections whose response takes longer than
+	// writeTimeout. Handlers get 80% of that, so a slow one is answered
+	// with a proper 504 before the connection is cut.
+	writeTimeout := cfg.HTTPServer.WriteTimeout
+
+	// Request bodies are capped at http_server.max_body_bytes, except on
+	// the upload routes, which allow bigger files and check their size
+	// themselves.
+	uploadRoutes := apiPatterns(apiPrefix, "POST /api/students/import", "POST /api/students/{id}/photo")
+
+	// The event stream stays open as long as its client does, so the
+	// handler timeout must not cut it off.
+	streamRoutes := apiPatterns(apiPrefix, "GET /api/students/events")
+
+	// The logging section is the part of the config a SIGHUP reloads;
+	// everything else below is fixed for the life of the process.
+	reloadable := config.NewReloadable(cfg)
+	sampleRate := func() float64 { return reloadable.Current().Logging.SampleRate }
+
+	// RequestID goes first so every log line after it, in middleware and
+	// handlers alike, carries the request's ID. Compress sits inside
+	// Logger, so the logged response sizes are the compressed ones. The
+	// JWT check runs per route inside the router, so Logger sees the
+	// 401s too.
+	handler := requestid.RequestID()(
+		middleware.Metrics(router.Pattern)(
+			logger.Logger(log,
+				logger.WithLargeResponse(cfg.HTTPServer.LargeResponseThreshold),
+				logger.WithSampleRate(sampleRate))(
+				compress.Compress(cfg.HTTPServer.GzipMinBytes)(
+					middleware.IPDenyList(denyList)(
+						rateLimit(
+							middleware.CORS(
+								bodylimit.BodyLimit(cfg.HTTPServer.MaxBodyBytes, router.Pattern, uploadRoutes...)(
+									middleware.Timeout(writeTimeout*8/10, router.Pattern, streamRoutes...)(
+										middleware.PrettyJSON(router))))))))))
+
+	// otelhttp starts a span per request (continuing the caller's trace
+	// if it sent a traceparent header) and puts it in the request context,
+	// where the storage spans pick it up. Spans are named after the route,
+	// e.g. "GET /api/students/{id}", not the URL.
+	handler = otelhttp.NewHandler(handler, tracing.ServiceName,
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			if pattern := router.Pattern(r); pattern != "" {
+				return pattern
+			}
+			return r.Method
+		}))
+
+	// The probes are served in front of the chain: no auth, deny list or
+	// rate limit can make a healthy instance look dead, and the frequent
+	// probe requests don't flood the request log.
+	//   /health — liveness, always 200 while the process runs (plus the
+	//             config drift status)
+	//   /ready  — readiness, 503 while the database can't be reached
+	drift := &config.DriftStatus{}
+	drain := &health.Drain{}
+	root := http.NewServeMux()
+	root.Handle("GET /health", health.Health(drift))
+	root.Handle("GET /ready", health.Readiness(storage, drain))
+	root.Handle("/", handler)
+
+	// Recovery wraps everything, probes included, so a panic in any
+	// middleware or handler is answered 500 and logged with its stack.
+	rootHandler := recovery.Recovery(log)(root)
 
 	// ── 5. Create the HTTP Server ─────────────────────────────────────────
 	// http.Server is a struct. We configure it here but don't start it yet.
 	server := &http.Server{
 		Addr:    cfg.HTTPServer.Addr, // e.g. "localhost:8082"
-		Handler: router,              // every request goes through our router
+		Handler: rootHandler,         // probes, then everything else through the chain
 
 		// Production hardening — set timeouts to prevent slow-client attacks.
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		// All come from http_server in the config; none can be zero.
+		ReadTimeout:       cfg.HTTPServer.ReadTimeout,
+		ReadHeaderTimeout: cfg.HTTPServer.ReadHeaderTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       cfg.HTTPServer.IdleTimeout,
+	}
+
+	// Shutdown waits for every request to finish, and an event stream
+	// never would on its own: end them all as it starts.
+	server.RegisterOnShutdown(bus.Close)
+
+	// With TLS, the certificate comes from a certmanager rather than being
+	// read once by ServeTLS, so a renewed certificate is picked up from
+	// disk without a restart.
+	if cfg.TLSConfig.TLSEnabled() {
+		certs, err := certmanager.New(cfg.TLSConfig.CertFile, cfg.TLSConfig.KeyFile)
+		if err != nil {
+			log.Error("failed to load TLS certificate", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		if err := certs.Watch(bgCtx); err != nil {
+			log.Error("failed to watch TLS certificate", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		server.TLSConfig = &tls.Config{GetCertificate: certs.GetCertificate}
 	}
 
 	// ── 6. Start Server in a Goroutine ────────────────────────────────────
-	// ListenAndServe blocks forever (it loops accepting connections).
+	// Serve blocks forever (it loops accepting connections).
 	// If we called it here in main(), the graceful-shutdown code below
 	// would never run. So we run it in a separate goroutine.
 	//
 	// go func() { ... }() is an immediately-invoked goroutine (anonymous
 	// function launched with the `go` keyword).
 	go func() {
-		log.Info("server started", slog.String("address", cfg.HTTPServer.Addr))
+		log.Info("server started",
+			slog.String("address", cfg.HTTPServer.Addr),
+			slog.Bool("tls", cfg.TLSConfig.TLSEnabled()))
 
-		// ListenAndServe returns http.ErrServerClosed when Shutdown() is
-		// called. That's expected — we don't want to log it as an error.
-		if err := server.ListenAndServe(); err != nil &&
-			err != http.ErrServerClosed {
+		// Listening ourselves (instead of ListenAndServe) lets us cap the
+		// number of open connections before http.Server ever sees them.
+		ln, err := net.Listen("tcp", cfg.HTTPServer.Addr)
+		if err != nil {
+			log.Error("cannot listen", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		// Serve returns http.ErrServerClosed when Shutdown() is called.
+		// That's expected — we don't want to log it as an error.
+		if cfg.TLSConfig.TLSEnabled() {
+			// No plain-text 503 over TLS: the client expects a handshake.
+			ln = limit.Listener(ln, cfg.HTTPServer.MaxConnections, nil)
+			// Empty file names: the certificate comes from TLSConfig.
+			err = server.ServeTLS(ln, "", "")
+		} else {
+			ln = limit.Listener(ln, cfg.HTTPServer.MaxConnections, limit.ServiceUnavailable)
+			err = server.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Error("server encountered an error",
 				slog.String("error", err.Error()))
 			os.Exit(1)
 		}
 	}()
 
+	// With TLS on, a second plain-HTTP server redirects clients that
+	// forgot the "https://" instead of leaving their connection hanging.
+	var redirectServer *http.Server
+	if cfg.TLSConfig.TLSEnabled() {
+		acme := http.NotFoundHandler()
+		if cfg.TLSConfig.ACMEChallengeDir != "" {
+			acme = http.StripPrefix(redirect.ACMEChallengePrefix,
+				http.FileServer(http.Dir(cfg.TLSConfig.ACMEChallengeDir)))
+		}
+
+		redirectServer = &http.Server{
+			Addr:         cfg.HTTPServer.HTTPRedirectAddr,
+			Handler:      redirect.ToHTTPS(cfg.HTTPServer.Addr, acme),
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 5 * time.Second,
+		}
+
+		go func() {
+			log.Info("http redirect server started",
+				slog.String("address", cfg.HTTPServer.HTTPRedirectAddr))
+
+			if err := redirectServer.ListenAndServe(); err != nil &&
+				err != http.ErrServerClosed {
+				log.Error("redirect server encountered an error",
+					slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+		}()
+	}
+
+	// With metrics_addr set, Prometheus scrapes a separate listener that
+	// serves nothing else.
+	var metricsServer *http.Server
+	if cfg.MetricsAddr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("GET /metrics", metrics.Handler())
+
+		metricsServer = &http.Server{
+			Addr:         cfg.MetricsAddr,
+			Handler:      metricsMux,
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 10 * time.Second,
+		}
+
+		go func() {
+			log.Info("metrics server started", slog.String("address", cfg.MetricsAddr))
+
+			if err := metricsServer.ListenAndServe(); err != nil &&
+				err != http.ErrServerClosed {
+				log.Error("metrics server encountered an error",
+					slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+		}()
+	}
+
+	// With grpc_server.address set, the gRPC API (proto/students.proto)
+	// is served there too, on the same storage — and so the same cache,
+	// capacity limit and encryption — as the HTTP API.
+	var grpcServer *grpc.Server
+	if cfg.GRPCServer.Addr != "" {
+		grpcServer = grpcserver.NewGRPCServer(
+			grpcserver.New(storage, cfg.Pagination.MaxPerPage), cfg.Security.JWTSecret, authOpts...)
+
+		// Listen here rather than in the goroutine, so a port that is
+		// already taken stops the startup instead of racing it.
+		listener, err := net.Listen("tcp", cfg.GRPCServer.Addr)
+		if err != nil {
+			log.Error("failed to listen for gRPC",
+				slog.String("address", cfg.GRPCServer.Addr),
+				slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		go func() {
+			log.Info("gRPC server started", slog.String("address", cfg.GRPCServer.Addr))
+
+			// Serve returns nil once GracefulStop or Stop is called.
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Error("gRPC server encountered an error",
+					slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+		}()
+	}
+
+	// Periodically re-read the config file and warn if it has changed on
+	// disk. Changes are NOT applied — the running server keeps using cfg.
+	go watchConfigDrift(log, cfg, drift, configDriftInterval)
+
 	// ── 7. Wait for Shutdown Signal ───────────────────────────────────────
 	// make(chan os.Signal, 1) creates a buffered channel of size 1.
 	// Buffered so we don't miss the signal if main is briefly busy.
-	done := make(chan os.Signal, 1)
+	signals := make(chan os.Signal, 1)
 
 	// signal.Notify registers our channel to receive specific OS signals:
 	//   os.Interrupt = Ctrl+C (SIGINT)
 	//   syscall.SIGTERM = sent by `kill <pid>` or container orchestrators
-	signal.Notify(done, os.Interrupt, syscall.SIGTERM)
+	//   syscall.SIGHUP  = `kill -HUP <pid>`: reload the config
+	//   syscall.SIGUSR1 = `kill -USR1 <pid>`: start or stop draining
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGUSR1)
 
-	// <-done blocks (pauses) the main goroutine here.
-	// The program stays alive because this goroutine is running.
-	// When a signal arrives, done receives it and we unblock.
-	<-done
+	// waitForShutdown blocks (pauses) the main goroutine here, handling
+	// reload and drain signals, until a signal to stop arrives.
+	sig := waitForShutdown(signals,
+		func() { reloadConfig(log, reloadable) },
+		func() { toggleDrain(log, server, drain) })
 
-	log.Info("shutdown signal received, stopping server...")
+	log.Info("shutdown signal received, stopping server...",
+		slog.String("signal", sig.String()))
+	stopBackground()
 
 	// ── 8. Graceful Shutdown ──────────────────────────────────────────────
-	// context.WithTimeout gives the shutdown a 5-second deadline.
-	// If in-flight requests don't finish within 5 seconds,
+	// context.WithTimeout gives the shutdown a deadline of
+	// http_server.shutdown_timeout (5 seconds by default).
+	// If in-flight requests don't finish within it,
 	// the context cancels and Shutdown returns an error.
 	//
 	// defer cancel() ensures the context's resources are freed
 	// when main() returns, even if Shutdown finishes early.
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTPServer.ShutdownTimeout)
 	defer cancel()
 
 	// server.Shutdown:
@@ -155,9 +739,194 @@ func main() {
 		os.Exit(1)
 	}
 
+	// The redirect server (if any) shares the same deadline.
+	if redirectServer != nil {
+		if err := redirectServer.Shutdown(ctx); err != nil {
+			log.Error("failed to shutdown redirect server gracefully",
+				slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(ctx); err != nil {
+			log.Error("failed to shutdown metrics server gracefully",
+				slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	// GracefulStop waits for calls in flight but, unlike Shutdown, takes
+	// no deadline; past the shared one, Stop cancels whatever is left.
+	if grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			log.Error("failed to shutdown gRPC server gracefully",
+				slog.String("error", ctx.Err().Error()))
+			grpcServer.Stop()
+		}
+	}
+
+	// No request can reach the database any more, so release its
+	// connections. A failure here is logged but changes nothing: every
+	// request has already been answered.
+	if err := storage.Close(); err != nil {
+		log.Error("failed to close database",
+			slog.String("error", err.Error()))
+	}
+
 	log.Info("server stopped gracefully")
 }
 
+// routePermissions is the least role each token-protected API route
+// needs (see the rbac package): reads are open to viewers, anything that
+// changes data needs an editor, and what can't be undone an admin. A
+// route missing here makes rbac.Require panic at startup, so a new route
+// can't go out without a decision.
+var routePermissions = map[string]string{
+	"GET /api/students":                        rbac.Viewer,
+	"GET /api/students/random":                 rbac.Viewer,
+	"GET /api/students/search":                 rbac.Viewer,
+	"GET /api/students/stats":                  rbac.Viewer,
+	"GET /api/students/events":                 rbac.Viewer,
+	"GET /api/students/export":                 rbac.Viewer,
+	"GET /api/students/export.tar.gz":          rbac.Viewer,
+	"GET /api/students/{id}":                   rbac.Viewer,
+	"GET /api/students/external/{external_id}": rbac.Viewer,
+	"GET /api/students/by-email":               rbac.Viewer,
+	history.Pattern:                            rbac.Viewer,
+	"GET /api/webhooks":                        rbac.Viewer,
+	"POST /api/students":                       rbac.Editor,
+	"POST /api/students/import":                rbac.Editor,
+	"PUT /api/students/{id}":                   rbac.Editor,
+	"PATCH /api/students/{id}":                 rbac.Editor,
+	"DELETE /api/students/{id}":                rbac.Editor,
+	"PATCH /api/students/{id}/status":          rbac.Editor,
+	"POST /api/students/{id}/restore":          rbac.Editor,
+	"POST /api/students/batch":                 rbac.Editor,
+	"DELETE /api/students/batch":               rbac.Editor,
+	"PUT /api/students/batch/upsert":           rbac.Editor,
+	"POST /api/students/{id}/photo":            rbac.Editor,
+	"POST /api/webhooks":                       rbac.Editor,
+	"DELETE /api/webhooks/{id}":                rbac.Editor,
+	"POST /api/students/{id}/anonymize":        rbac.Admin,
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// registerAPIRoutes registers routes (keyed by patterns such as
+// "GET /api/students/{id}") on rt with prefix in front of each path, e.g.
+// "GET /v1/api/students/{id}" for prefix "/v1", plus the OPTIONS
+// preflights for everything under prefix+"/api/". wrap, if not nil, wraps
+// every handler — the unversioned routes use it to add a Deprecation
+// header.
+// ─────────────────────────────────────────────────────────────────────────────
+func registerAPIRoutes(rt *router.Router, prefix string, routes map[string]http.Handler, wrap func(http.Handler) http.Handler) {
+	for pattern, handler := range routes {
+		if wrap != nil {
+			handler = wrap(handler)
+		}
+		rt.Handle(router.WithPrefix(pattern, prefix), handler)
+	}
+	rt.HandlePreflight(prefix + "/api/")
+}
+
+// apiPatterns returns each of patterns as it is and with prefix in front
+// of its path — the two patterns registerAPIRoutes serves a route under.
+func apiPatterns(prefix string, patterns ...string) []string {
+	var all []string
+	for _, pattern := range patterns {
+		all = append(all, pattern, router.WithPrefix(pattern, prefix))
+	}
+	return all
+}
+
+// waitForShutdown reads signals until one of them asks the server to stop
+// (SIGINT or SIGTERM), and returns it. Meanwhile SIGHUP calls onReload
+// and SIGUSR1 calls onDrain, each once per signal.
+//
+// It takes the channel, rather than calling signal.Notify itself, so the
+// signals can come from anywhere — a test can send its own.
+func waitForShutdown(signals <-chan os.Signal, onReload, onDrain func()) os.Signal {
+	for sig := range signals {
+		switch sig {
+		case syscall.SIGHUP:
+			onReload()
+		case syscall.SIGUSR1:
+			onDrain()
+		default:
+			return sig
+		}
+	}
+	// Closed channel: nothing more will arrive, so stop.
+	return syscall.SIGTERM
+}
+
+// reloadConfig handles SIGHUP: it re-reads the config file and applies
+// what can be applied without a restart (see config.Reloadable). A bad
+// file is logged and leaves the running config as it was.
+func reloadConfig(log *slog.Logger, reloadable *config.Reloadable) {
+	applied, needRestart, err := reloadable.Reload()
+	if err != nil {
+		log.Error("config reload failed; keeping the running config",
+			slog.String("error", err.Error()))
+		return
+	}
+
+	log.Info("config reloaded", slog.Any("applied", applied))
+	if len(needRestart) > 0 {
+		log.Warn("config changes need a restart to apply",
+			slog.Any("fields", needRestart))
+	}
+}
+
+// toggleDrain handles SIGUSR1: it starts draining, or stops if already
+// draining. A draining server keeps serving, but /ready fails so load
+// balancers stop sending it new traffic, and keep-alive connections are
+// closed after their current request so clients reconnect elsewhere.
+// Unlike shutdown, a second SIGUSR1 puts the instance back in rotation.
+func toggleDrain(log *slog.Logger, server *http.Server, drain *health.Drain) {
+	draining := !drain.Draining()
+	drain.Set(draining)
+	server.SetKeepAlivesEnabled(!draining)
+
+	if draining {
+		log.Info("draining: /ready now fails; send SIGUSR1 again to resume")
+	} else {
+		log.Info("drain ended: accepting traffic again")
+	}
+}
+
+// runMigrations brings the database schema up to date, for --migrate-only.
+// Opening the database is what migrates it (see sqlite.New and
+// postgres.New); the connection is closed again straight away.
+func runMigrations(cfg *config.Config) error {
+	switch cfg.StorageBackend {
+	case config.StorageBackendPostgres:
+		pg, err := postgres.New(cfg)
+		if err != nil {
+			return err
+		}
+		return pg.Close()
+
+	default:
+		// Asking for --migrate-only is asking to migrate, whatever
+		// database.auto_migrate says.
+		cfg.Database.AutoMigrate = true
+
+		db, err := sqlite.New(cfg)
+		if err != nil {
+			return err
+		}
+		return db.Close()
+	}
+}
+
 // setupLogger returns a *slog.Logger configured for the given environment.
 //
 // Development (dev): human-readable text output at DEBUG level.
@@ -186,3 +955,34 @@ func setupLogger(env string) *slog.Logger {
 		)
 	}
 }
+
+// watchConfigDrift re-reads the config file every interval and compares it
+// with the config the server started with. Any difference (or a file that
+// can no longer be parsed) is logged as a warning and recorded in drift so
+// the /health endpoint can report it.
+//
+// It runs forever and is meant to be launched with `go`.
+func watchConfigDrift(log *slog.Logger, original *config.Config, drift *config.DriftStatus, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		current, err := config.Load(original.Path)
+		if err != nil {
+			// The file is missing or invalid — that is drift too.
+			log.Warn("config file is no longer valid",
+				slog.String("path", original.Path),
+				slog.String("error", err.Error()))
+			drift.Set(time.Now(), []string{"file"})
+			continue
+		}
+
+		changed := config.CheckDrift(original, current)
+		if len(changed) > 0 {
+			log.Warn("config file has changed since startup; changes not applied",
+				slog.String("path", original.Path),
+				slog.Any("fields", changed))
+		}
+		drift.Set(time.Now(), changed)
+	}
+}
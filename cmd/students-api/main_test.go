@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/aanand-mishra/students-api/internal/http/handlers/health"
+)
+
+func TestWaitForShutdown(t *testing.T) {
+	tests := []struct {
+		name       string
+		signals    []os.Signal
+		close      bool
+		want       os.Signal
+		wantReload int
+		wantDrain  int
+	}{
+		{"SIGTERM", []os.Signal{syscall.SIGTERM}, false, syscall.SIGTERM, 0, 0},
+		{"SIGINT", []os.Signal{os.Interrupt}, false, os.Interrupt, 0, 0},
+		{"reloads and drains first", []os.Signal{syscall.SIGHUP, syscall.SIGUSR1, syscall.SIGHUP, syscall.SIGUSR1, syscall.SIGTERM}, false, syscall.SIGTERM, 2, 2},
+		{"stops at the first stop signal", []os.Signal{syscall.SIGINT, syscall.SIGHUP}, false, syscall.SIGINT, 0, 0},
+		{"closed channel", []os.Signal{syscall.SIGHUP}, true, syscall.SIGTERM, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signals := make(chan os.Signal, len(tt.signals))
+			for _, sig := range tt.signals {
+				signals <- sig
+			}
+			if tt.close {
+				close(signals)
+			}
+
+			var reloads, drains int
+			got := waitForShutdown(signals, func() { reloads++ }, func() { drains++ })
+
+			if got != tt.want || reloads != tt.wantReload || drains != tt.wantDrain {
+				t.Errorf("returned %v after %d reloads, %d drains; want %v after %d, %d",
+					got, reloads, drains, tt.want, tt.wantReload, tt.wantDrain)
+			}
+		})
+	}
+}
+
+func TestToggleDrain(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	server := &http.Server{}
+	var drain health.Drain
+
+	toggleDrain(log, server, &drain)
+	if !drain.Draining() {
+		t.Fatal("the first SIGUSR1 didn't start draining")
+	}
+	toggleDrain(log, server, &drain)
+	if drain.Draining() {
+		t.Error("the second SIGUSR1 didn't end draining")
+	}
+}
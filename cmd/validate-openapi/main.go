@@ -0,0 +1,131 @@
+// validate-openapi checks the OpenAPI document served at /openapi.json
+// (internal/http/handlers/openapi/openapi.json) against the OpenAPI 3.0
+// specification: the structure, that every $ref resolves, that examples
+// match their schemas, and so on. It also checks that api/openapi.yaml,
+// served at /docs/openapi.yaml, is the same document. It exits non-zero
+// on the first problem.
+//
+// USAGE:
+//
+//	go run ./cmd/validate-openapi              # check both documents
+//	go run ./cmd/validate-openapi -write-yaml  # regenerate api/openapi.yaml
+//
+// CI runs the check on every push (.github/workflows/openapi.yml), so a
+// broken or stale document never ships.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v3"
+
+	"github.com/aanand-mishra/students-api/api"
+	"github.com/aanand-mishra/students-api/internal/http/handlers/openapi"
+)
+
+// yamlPath is where -write-yaml puts the YAML copy, relative to the
+// repository root (make runs from there).
+const yamlPath = "api/openapi.yaml"
+
+func main() {
+	writeYAML := flag.Bool("write-yaml", false, "regenerate "+yamlPath+" from openapi.json and exit")
+	flag.Parse()
+
+	if *writeYAML {
+		out, err := toYAML(openapi.Document())
+		if err != nil {
+			log.Fatalf("openapi.json: %v", err)
+		}
+		if err := os.WriteFile(yamlPath, out, 0o644); err != nil {
+			log.Fatalf("write %s: %v", yamlPath, err)
+		}
+		fmt.Printf("wrote %s\n", yamlPath)
+		return
+	}
+
+	loader := openapi3.NewLoader()
+
+	doc, err := loader.LoadFromData(openapi.Document())
+	if err != nil {
+		log.Fatalf("openapi.json: %v", err)
+	}
+
+	// Examples are checked too, so they can't drift from their schemas.
+	if err := doc.Validate(context.Background(), openapi3.EnableExamplesValidation()); err != nil {
+		log.Fatalf("openapi.json is not a valid OpenAPI 3.0 document: %v", err)
+	}
+
+	if err := sameDocument(openapi.Document(), api.OpenAPIYAML); err != nil {
+		log.Fatalf("%s: %v (run `make openapi-yaml`)", yamlPath, err)
+	}
+
+	fmt.Printf("openapi.json is valid: %d paths; %s matches it\n", doc.Paths.Len(), yamlPath)
+}
+
+// toYAML converts a JSON document to YAML, keeping its key order.
+//
+// JSON is valid YAML, so decoding it into a yaml.Node keeps every key in
+// place; clearing the flow and quoting styles JSON arrived with makes the
+// encoder write plain block YAML instead.
+func toYAML(doc []byte) ([]byte, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(doc, &node); err != nil {
+		return nil, fmt.Errorf("toYAML: decode: %w", err)
+	}
+	clearStyle(&node)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return nil, fmt.Errorf("toYAML: encode: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("toYAML: encode: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// clearStyle resets n and everything under it to the default style. A
+// string that would read as another type ("true", "1.0") is still quoted
+// by the encoder, so no value changes type.
+func clearStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		clearStyle(c)
+	}
+}
+
+// sameDocument reports whether jsonDoc and yamlDoc decode to the same
+// value, ignoring formatting and key order.
+func sameDocument(jsonDoc, yamlDoc []byte) error {
+	var fromJSON, fromYAML any
+	if err := json.Unmarshal(jsonDoc, &fromJSON); err != nil {
+		return fmt.Errorf("sameDocument: decode JSON: %w", err)
+	}
+	if err := yaml.Unmarshal(yamlDoc, &fromYAML); err != nil {
+		return fmt.Errorf("sameDocument: decode YAML: %w", err)
+	}
+	// Round-trip the YAML through JSON so numbers and maps have the same
+	// Go types on both sides.
+	b, err := json.Marshal(fromYAML)
+	if err != nil {
+		return fmt.Errorf("sameDocument: %w", err)
+	}
+	fromYAML = nil
+	if err := json.Unmarshal(b, &fromYAML); err != nil {
+		return fmt.Errorf("sameDocument: %w", err)
+	}
+	if !reflect.DeepEqual(fromJSON, fromYAML) {
+		return fmt.Errorf("out of date with openapi.json")
+	}
+	return nil
+}
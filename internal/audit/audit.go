@@ -0,0 +1,153 @@
+// Package audit records every change made to students in an audit log.
+//
+// AuditingStorage implements storage.Hook, so it is registered on the
+// storage with RegisterHook rather than wrapping it. For each change it
+// builds a types.AuditEntry; if the change came from an HTTP request (the
+// context carries a types.AuditContext) the entry is completed with the
+// request details and written once the response status is known.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// Writer persists audit entries (implemented by *sqlite.SQLite).
+type Writer interface {
+	InsertAuditEntry(ctx context.Context, entry types.AuditEntry) error
+}
+
+// AuditingStorage is a storage.Hook that writes audit entries.
+type AuditingStorage struct {
+	writer Writer
+}
+
+// New returns an AuditingStorage writing to w.
+func New(w Writer) *AuditingStorage {
+	return &AuditingStorage{writer: w}
+}
+
+// OnCreate implements storage.Hook.
+func (a *AuditingStorage) OnCreate(ctx context.Context, student types.Student) {
+	a.record(ctx, types.AuditEntry{
+		Action:    types.AuditActionCreate,
+		StudentID: int64(student.ID),
+		After:     marshal(student),
+	})
+}
+
+// OnUpdate implements storage.Hook.
+func (a *AuditingStorage) OnUpdate(ctx context.Context, old, new types.Student) {
+	a.record(ctx, types.AuditEntry{
+		Action:    types.AuditActionUpdate,
+		StudentID: int64(new.ID),
+		Before:    marshal(old),
+		After:     marshal(new),
+	})
+}
+
+// OnDelete implements storage.Hook.
+func (a *AuditingStorage) OnDelete(ctx context.Context, tenantID string, studentID int64) {
+	a.record(ctx, types.AuditEntry{
+		Action:    types.AuditActionDelete,
+		StudentID: studentID,
+	})
+}
+
+// record writes entry now, or — inside an HTTP request — once the
+// response is complete so the status and duration can be included.
+func (a *AuditingStorage) record(ctx context.Context, entry types.AuditEntry) {
+	entry.Timestamp = time.Now().UTC()
+
+	ac := types.AuditContextFrom(ctx)
+	if ac == nil {
+		a.write(ctx, entry)
+		return
+	}
+
+	entry.RequestBody = ac.RequestBody
+	entry.ClientIP = ac.ClientIP
+	entry.UserAgent = ac.UserAgent
+	entry.RequestID = ac.RequestID
+
+	ac.OnFinish(func(status int, duration time.Duration) {
+		entry.ResponseStatus = status
+		entry.Duration = duration
+		// The request context may already be cancelled by now.
+		a.write(context.Background(), entry)
+	})
+}
+
+// write stores entry, logging (not failing) on error — the change itself
+// has already been committed.
+func (a *AuditingStorage) write(ctx context.Context, entry types.AuditEntry) {
+	if err := a.writer.InsertAuditEntry(ctx, entry); err != nil {
+		slog.Error("failed to write audit entry",
+			slog.String("action", entry.Action),
+			slog.Int64("student_id", entry.StudentID),
+			slog.String("error", err.Error()))
+	}
+}
+
+func marshal(v any) json.RawMessage {
+	b, _ := json.Marshal(v)
+	return b
+}
+
+// redactedKeys are JSON object keys whose values are never stored in the
+// audit log. Matching is case-insensitive.
+var redactedKeys = map[string]bool{
+	"password":      true,
+	"token":         true,
+	"secret":        true,
+	"api_key":       true,
+	"authorization": true,
+}
+
+// AddRedactedKeys extends the blocklist used by RedactBody. It is meant
+// to be called once at startup, before any request is served — e.g. to
+// keep encrypted student fields out of the audit log in plaintext.
+func AddRedactedKeys(keys ...string) {
+	for _, k := range keys {
+		redactedKeys[strings.ToLower(k)] = true
+	}
+}
+
+// RedactBody returns body with the values of any blocklisted keys
+// (at any depth) replaced by "[REDACTED]". A body that is not valid JSON
+// is stored as a JSON string so the column always holds valid JSON.
+func RedactBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return marshal(string(body))
+	}
+
+	return marshal(redact(v))
+}
+
+func redact(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if redactedKeys[strings.ToLower(k)] {
+				v[k] = "[REDACTED]"
+			} else {
+				v[k] = redact(child)
+			}
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = redact(child)
+		}
+	}
+	return v
+}
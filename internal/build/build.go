@@ -0,0 +1,11 @@
+// Package build holds information stamped into the binary at build time.
+//
+// The values are plain variables rather than constants so the linker can
+// overwrite them:
+//
+//	go build -ldflags="-X github.com/aanand-mishra/students-api/internal/build.Version=1.2.0" ./cmd/students-api
+package build
+
+// Version is the release the binary was built from. Builds that don't
+// set it (go run, plain go build) report "dev".
+var Version = "dev"
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSelfTestEnabled(t *testing.T) {
+	on, off := true, false
+
+	tests := []struct {
+		name string
+		env  string
+		run  *bool
+		want bool
+	}{
+		{"dev default", "dev", nil, true},
+		{"prod default", "prod", nil, false},
+		{"staging default", "staging", nil, false},
+		{"forced on in prod", "prod", &on, true},
+		{"forced off in dev", "dev", &off, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Config{Env: tt.env, RunSelfTest: tt.run}
+			if got := c.SelfTestEnabled(); got != tt.want {
+				t.Errorf("SelfTestEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// validConfig returns a Config that passes Validate, with its SQLite
+// file in a fresh temporary directory. Tests change one setting to see
+// it rejected.
+func validConfig(t *testing.T) *Config {
+	t.Helper()
+
+	return &Config{
+		StorageBackend: StorageBackendSQLite,
+		StoragePath:    filepath.Join(t.TempDir(), "storage.db"),
+		SQLite:         SQLite{JournalMode: JournalModeWAL, BusyTimeoutMs: 5000},
+		Logging:        Logging{SampleRate: 1},
+		HTTPServer: HTTPServer{
+			MaxBodyBytes:      1 << 20,
+			ReadTimeout:       10 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      10 * time.Second,
+			IdleTimeout:       60 * time.Second,
+		},
+		API:      API{CurrentVersion: "v1"},
+		Import:   Import{MaxBytes: 10 << 20},
+		Webhooks: Webhooks{QueueSize: 1000, Timeout: 10 * time.Second},
+	}
+}
+
+func TestValidateStoragePath(t *testing.T) {
+	if err := validConfig(t).Validate(); err != nil {
+		t.Fatalf("Validate() = %v on a valid config", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"missing directory", filepath.Join(t.TempDir(), "missing", "storage.db"), "does not exist"},
+		{"file as directory", filepath.Join(file, "storage.db"), "is not a directory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig(t)
+			c.StoragePath = tt.path
+			err := c.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want an error saying %q", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("read-only directory", func(t *testing.T) {
+		if os.Geteuid() == 0 {
+			t.Skip("root can write to read-only directories")
+		}
+		dir := t.TempDir()
+		if err := os.Chmod(dir, 0o555); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Chmod(dir, 0o755) })
+
+		c := validConfig(t)
+		c.StoragePath = filepath.Join(dir, "storage.db")
+		if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "is not writable") {
+			t.Errorf("Validate() = %v, want an error saying it is not writable", err)
+		}
+	})
+
+	t.Run("no probe file left behind", func(t *testing.T) {
+		c := validConfig(t)
+		if err := c.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		entries, err := os.ReadDir(filepath.Dir(c.StoragePath))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("Validate() left %d files in the storage directory", len(entries))
+		}
+	})
+
+	t.Run("postgres skips the check", func(t *testing.T) {
+		c := validConfig(t)
+		c.StorageBackend = StorageBackendPostgres
+		c.PostgresDSN = "postgres://localhost/students"
+		c.StoragePath = filepath.Join(t.TempDir(), "missing", "storage.db")
+		if err := c.Validate(); err != nil {
+			t.Errorf("Validate() = %v; the SQLite path doesn't matter with postgres", err)
+		}
+	})
+}
+
+func TestValidateSampleRate(t *testing.T) {
+	for _, rate := range []float64{0, 0.5, 1} {
+		c := validConfig(t)
+		c.Logging.SampleRate = rate
+		if err := c.Validate(); err != nil {
+			t.Errorf("sample_rate %g: %v", rate, err)
+		}
+	}
+	for _, rate := range []float64{-0.1, 1.5} {
+		c := validConfig(t)
+		c.Logging.SampleRate = rate
+		if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "logging.sample_rate") {
+			t.Errorf("sample_rate %g: Validate() = %v, want it rejected", rate, err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"sync"
+	"time"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// CheckDrift compares two configs and returns the YAML keys whose values
+// differ between them, e.g. ["storage_path", "http_server.address"].
+//
+// It is used to detect a config file that was overwritten on disk after
+// the server started (common in containers with mounted config volumes).
+// An empty (nil) result means the two configs are equivalent.
+// ─────────────────────────────────────────────────────────────────────────────
+func CheckDrift(original, current *Config) []string {
+	var changed []string
+
+	if original.Env != current.Env {
+		changed = append(changed, "env")
+	}
+	if original.StoragePath != current.StoragePath {
+		changed = append(changed, "storage_path")
+	}
+	if original.HTTPServer.Addr != current.HTTPServer.Addr {
+		changed = append(changed, "http_server.address")
+	}
+	if original.TLSConfig != current.TLSConfig {
+		changed = append(changed, "tls")
+	}
+
+	return changed
+}
+
+// DriftStatus records the result of the most recent config drift check.
+//
+// It is written by the background checker in main.go and read by the
+// /health handler, so every access goes through the mutex.
+type DriftStatus struct {
+	mu          sync.RWMutex
+	lastChecked time.Time
+	drifted     bool
+	fields      []string
+}
+
+// Set stores the outcome of a drift check performed at time t.
+func (d *DriftStatus) Set(t time.Time, fields []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.lastChecked = t
+	d.drifted = len(fields) > 0
+	d.fields = fields
+}
+
+// Snapshot returns a copy of the current drift state.
+func (d *DriftStatus) Snapshot() (lastChecked time.Time, drifted bool, fields []string) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.lastChecked, d.drifted, append([]string(nil), d.fields...)
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeConfig writes a minimal config file to path, listening on addr.
+func writeConfig(t *testing.T, path, addr string) {
+	t.Helper()
+
+	yaml := "env: dev\n" +
+		"storage_path: " + filepath.Join(filepath.Dir(path), "test.db") + "\n" +
+		"http_server:\n" +
+		"  address: " + addr + "\n"
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+}
+
+func TestCheckDriftDetectsChangedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	writeConfig(t, path, "localhost:8082")
+	original, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load original: %v", err)
+	}
+
+	// Unchanged file: no drift.
+	current, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load unchanged: %v", err)
+	}
+	if changed := CheckDrift(original, current); changed != nil {
+		t.Errorf("CheckDrift(unchanged) = %v, want none", changed)
+	}
+
+	// The file is overwritten behind the server's back.
+	writeConfig(t, path, "0.0.0.0:9090")
+	current, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load changed: %v", err)
+	}
+
+	want := []string{"http_server.address"}
+	if changed := CheckDrift(original, current); !reflect.DeepEqual(changed, want) {
+		t.Errorf("CheckDrift(changed) = %v, want %v", changed, want)
+	}
+}
+
+func TestCheckDriftFields(t *testing.T) {
+	base := Config{Env: "dev", StoragePath: "a.db", HTTPServer: HTTPServer{Addr: ":8082"}}
+
+	tests := []struct {
+		name   string
+		change func(*Config)
+		want   []string
+	}{
+		{"env", func(c *Config) { c.Env = "prod" }, []string{"env"}},
+		{"storage path", func(c *Config) { c.StoragePath = "b.db" }, []string{"storage_path"}},
+		{"tls", func(c *Config) { c.TLSConfig.CertFile = "cert.pem" }, []string{"tls"}},
+		{"several", func(c *Config) { c.Env = "prod"; c.HTTPServer.Addr = ":9090" }, []string{"env", "http_server.address"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := base
+			tt.change(&current)
+			if got := CheckDrift(&base, &current); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckDrift = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDriftStatus(t *testing.T) {
+	var d DriftStatus
+
+	if last, drifted, fields := d.Snapshot(); !last.IsZero() || drifted || fields != nil {
+		t.Errorf("zero DriftStatus = (%v, %v, %v), want nothing recorded", last, drifted, fields)
+	}
+
+	now := time.Now()
+	d.Set(now, []string{"env"})
+	last, drifted, fields := d.Snapshot()
+	if !last.Equal(now) || !drifted || !reflect.DeepEqual(fields, []string{"env"}) {
+		t.Errorf("after Set = (%v, %v, %v), want (%v, true, [env])", last, drifted, fields, now)
+	}
+
+	// The snapshot is a copy: changing it leaves the status alone.
+	fields[0] = "changed"
+	if _, _, again := d.Snapshot(); again[0] != "env" {
+		t.Errorf("Snapshot shares its slice with DriftStatus")
+	}
+
+	d.Set(now, nil)
+	if _, drifted, _ := d.Snapshot(); drifted {
+		t.Error("drifted after a clean check, want false")
+	}
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"sync/atomic"
+)
+
+// Reloadable holds the running config and lets part of it be replaced
+// while the server runs (on SIGHUP, see main.go).
+//
+// Most settings are wired into the server once, at startup — the
+// address, the database, the middleware chain — so changing them needs
+// a restart. Only the logging section is taken from a reloaded file.
+// Components that should see a reload read Current() each time rather
+// than keeping a copy.
+type Reloadable struct {
+	current atomic.Pointer[Config]
+}
+
+// NewReloadable wraps the config the server started with.
+func NewReloadable(cfg *Config) *Reloadable {
+	r := &Reloadable{}
+	r.current.Store(cfg)
+	return r
+}
+
+// Current returns the config in effect. Callers must not modify it.
+func (r *Reloadable) Current() *Config {
+	return r.current.Load()
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Reload re-reads the config file and applies its logging section. It
+// returns the YAML keys that changed and were applied, and separately
+// those that changed but need a restart (as reported by CheckDrift).
+//
+// A file that can't be read or fails validation is an error and changes
+// nothing. Secrets are not fetched again: the running config keeps the
+// ones it resolved at startup.
+// ─────────────────────────────────────────────────────────────────────────────
+func (r *Reloadable) Reload() (applied, needRestart []string, err error) {
+	old := r.Current()
+
+	loaded, err := Load(old.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := loaded.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	next := *old
+	if loaded.Logging != old.Logging {
+		next.Logging = loaded.Logging
+		applied = append(applied, "logging")
+	}
+	r.current.Store(&next)
+
+	return applied, CheckDrift(old, loaded), nil
+}
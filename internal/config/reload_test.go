@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeReloadConfig writes config.yaml into dir, with the given env,
+// sample rate and shutdown timeout, and returns its path.
+func writeReloadConfig(t *testing.T, dir, env string, sampleRate float64, shutdown string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, "config.yaml")
+	yaml := fmt.Sprintf(`env: %q
+storage_path: %q
+http_server:
+  address: "localhost:8082"
+  shutdown_timeout: %q
+logging:
+  sample_rate: %v
+`, env, filepath.Join(dir, "storage.db"), shutdown, sampleRate)
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// load reads the config at path, failing the test on error.
+func load(t *testing.T, path string) *Config {
+	t.Helper()
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return cfg
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	dir := t.TempDir()
+
+	if got := load(t, writeReloadConfig(t, dir, "dev", 1, "30s")).HTTPServer.ShutdownTimeout; got != 30*time.Second {
+		t.Errorf("ShutdownTimeout = %s, want 30s", got)
+	}
+
+	// Left out, it defaults to 5s.
+	path := filepath.Join(dir, "minimal.yaml")
+	yaml := fmt.Sprintf("env: dev\nstorage_path: %q\nhttp_server:\n  address: localhost:8082\n", filepath.Join(dir, "storage.db"))
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := load(t, path).HTTPServer.ShutdownTimeout; got != 5*time.Second {
+		t.Errorf("default ShutdownTimeout = %s, want 5s", got)
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	path := writeReloadConfig(t, dir, "dev", 1, "5s")
+	r := NewReloadable(load(t, path))
+
+	t.Run("nothing changed", func(t *testing.T) {
+		applied, needRestart, err := r.Reload()
+		if err != nil || applied != nil || needRestart != nil {
+			t.Errorf("Reload() = %v, %v, %v; want nothing to do", applied, needRestart, err)
+		}
+	})
+
+	t.Run("logging is applied, the rest needs a restart", func(t *testing.T) {
+		before := r.Current()
+		writeReloadConfig(t, dir, "prod", 0.25, "30s")
+
+		applied, needRestart, err := r.Reload()
+		if err != nil {
+			t.Fatalf("Reload: %v", err)
+		}
+		if !reflect.DeepEqual(applied, []string{"logging"}) || !reflect.DeepEqual(needRestart, []string{"env"}) {
+			t.Errorf("applied %v, need restart %v; want [logging], [env]", applied, needRestart)
+		}
+
+		now := r.Current()
+		if now.Logging.SampleRate != 0.25 {
+			t.Errorf("sample rate = %v, want the reloaded 0.25", now.Logging.SampleRate)
+		}
+		if now.Env != "dev" || now.HTTPServer.ShutdownTimeout != 5*time.Second {
+			t.Errorf("env %q, shutdown timeout %s; want the startup values kept", now.Env, now.HTTPServer.ShutdownTimeout)
+		}
+		if before.Logging.SampleRate != 1 {
+			t.Error("Reload modified the config it replaced")
+		}
+	})
+
+	t.Run("a bad file changes nothing", func(t *testing.T) {
+		before := r.Current()
+		writeReloadConfig(t, dir, "prod", 7, "5s") // sample rate out of range
+
+		if _, _, err := r.Reload(); err == nil {
+			t.Fatal("Reload accepted a sample rate of 7")
+		}
+		if r.Current() != before {
+			t.Error("a failed reload replaced the running config")
+		}
+
+		if err := os.Remove(path); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := r.Reload(); err == nil {
+			t.Error("Reload succeeded without a config file")
+		}
+		if r.Current() != before {
+			t.Error("a failed reload replaced the running config")
+		}
+	})
+}
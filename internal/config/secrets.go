@@ -0,0 +1,44 @@
+package config
+
+import (
+	"context"
+	"log"
+	"reflect"
+
+	"github.com/aanand-mishra/students-api/internal/secrets"
+)
+
+// ResolveSecrets replaces every string field tagged secret:"true" with
+// the value resolver returns for that field's name (e.g. "AdminAPIKey").
+//
+// Lookups that fail are logged and skipped, leaving the value that came
+// from the YAML file / environment in place as a fallback.
+func ResolveSecrets(ctx context.Context, cfg *Config, resolver secrets.SecretsResolver) {
+	resolveStruct(ctx, reflect.ValueOf(cfg).Elem(), resolver)
+}
+
+// resolveStruct walks v's fields, recursing into nested structs such as
+// HTTPServer and TLS.
+func resolveStruct(ctx context.Context, v reflect.Value, resolver secrets.SecretsResolver) {
+	t := v.Type()
+
+	for i := 0; i < v.NumField(); i++ {
+		field, fieldType := v.Field(i), t.Field(i)
+
+		if field.Kind() == reflect.Struct {
+			resolveStruct(ctx, field, resolver)
+			continue
+		}
+		if fieldType.Tag.Get("secret") != "true" || field.Kind() != reflect.String || !field.CanSet() {
+			continue
+		}
+
+		value, err := resolver.Resolve(ctx, fieldType.Name)
+		if err != nil {
+			log.Printf("secret %s not resolved, using config file value: %s",
+				fieldType.Name, err.Error())
+			continue
+		}
+		field.SetString(value)
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aanand-mishra/students-api/internal/secrets"
+)
+
+// mapResolver is a SecretsResolver backed by a map. Names it doesn't
+// have are reported as not found, and every lookup is recorded.
+type mapResolver struct {
+	values map[string]string
+	asked  []string
+}
+
+func (m *mapResolver) Resolve(_ context.Context, name string) (string, error) {
+	m.asked = append(m.asked, name)
+	value, ok := m.values[name]
+	if !ok {
+		return "", secrets.ErrNotFound
+	}
+	return value, nil
+}
+
+func TestResolveSecrets(t *testing.T) {
+	tests := []struct {
+		name       string
+		values     map[string]string
+		wantAPIKey string
+		wantJWT    string
+	}{
+		{"both overridden", map[string]string{"AdminAPIKey": "from-manager", "JWTSecret": "jwt-from-manager"}, "from-manager", "jwt-from-manager"},
+		{"nested field only", map[string]string{"JWTSecret": "jwt-from-manager"}, "from-yaml", "jwt-from-manager"},
+		{"backend has nothing", nil, "from-yaml", "jwt-from-yaml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Env:         "prod",
+				StoragePath: "storage/storage.db",
+				AdminAPIKey: "from-yaml",
+				Security:    Security{JWTSecret: "jwt-from-yaml"},
+			}
+			resolver := &mapResolver{values: tt.values}
+
+			ResolveSecrets(context.Background(), cfg, resolver)
+
+			if cfg.AdminAPIKey != tt.wantAPIKey {
+				t.Errorf("AdminAPIKey = %q, want %q", cfg.AdminAPIKey, tt.wantAPIKey)
+			}
+			if cfg.Security.JWTSecret != tt.wantJWT {
+				t.Errorf("Security.JWTSecret = %q, want %q", cfg.Security.JWTSecret, tt.wantJWT)
+			}
+			// Fields not tagged secret:"true" are never looked up or touched.
+			if cfg.Env != "prod" || cfg.StoragePath != "storage/storage.db" {
+				t.Errorf("untagged fields changed: env %q, storage_path %q", cfg.Env, cfg.StoragePath)
+			}
+			for _, name := range resolver.asked {
+				if name != "AdminAPIKey" && name != "JWTSecret" {
+					t.Errorf("resolver asked for untagged field %s", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+// Package crypto encrypts individual field values (names, emails) so that
+// personal data is not readable from a stolen database file.
+//
+// All functions use AES-256-GCM, which both encrypts and authenticates:
+// a tampered ciphertext fails to decrypt instead of yielding garbage.
+// The 12-byte GCM nonce is prepended to the ciphertext before encoding.
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeySize is the required key length in bytes (AES-256).
+const KeySize = 32
+
+// ErrInvalidCiphertext is returned when a value cannot be decrypted.
+var ErrInvalidCiphertext = errors.New("crypto: invalid ciphertext")
+
+// lowerBase32 is base32 with a lower-case alphabet and no padding, so
+// deterministic ciphertexts are unchanged by strings.ToLower.
+var lowerBase32 = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// ParseKey decodes a base64-encoded 32-byte key, e.g. one generated with
+//
+//	openssl rand -base64 32
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("crypto: key is not valid base64: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("crypto: key must be %d bytes, got %d", KeySize, len(key))
+	}
+	return key, nil
+}
+
+// Encrypt encrypts plaintext with a random nonce and returns
+// base64(nonce || ciphertext). Encrypting the same value twice gives
+// different results, so equal values can't be spotted in the database.
+func Encrypt(plaintext string, key []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("crypto: generate nonce: %w", err)
+	}
+
+	// Seal appends the ciphertext to its first argument — the nonce.
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt reverses Encrypt.
+func Decrypt(ciphertext string, key []byte) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	return open(sealed, key)
+}
+
+// EncryptDeterministic encrypts plaintext so that the same input always
+// produces the same output, returned as lower-case base32.
+//
+// The nonce is derived from an HMAC of the plaintext instead of being
+// random. This deliberately reveals which rows share a value — that is
+// what lets a UNIQUE index and exact-match lookups keep working on an
+// encrypted column — but nothing else about the value.
+func EncryptDeterministic(plaintext string, key []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(plaintext))
+	nonce := mac.Sum(nil)[:gcm.NonceSize()]
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return lowerBase32.EncodeToString(sealed), nil
+}
+
+// DecryptDeterministic reverses EncryptDeterministic.
+func DecryptDeterministic(ciphertext string, key []byte) (string, error) {
+	sealed, err := lowerBase32.DecodeString(ciphertext)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	return open(sealed, key)
+}
+
+// open splits nonce || ciphertext and decrypts it.
+func open(sealed, key []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("crypto: key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,115 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var (
+	key      = bytes.Repeat([]byte{7}, KeySize)
+	otherKey = bytes.Repeat([]byte{8}, KeySize)
+)
+
+func TestRoundTrip(t *testing.T) {
+	for _, plaintext := range []string{"rakesh@test.com", "Rakesh Kumar", "", "Zoë 🎓"} {
+		ciphertext, err := Encrypt(plaintext, key)
+		if err != nil {
+			t.Fatalf("Encrypt(%q): %v", plaintext, err)
+		}
+		if plaintext != "" && strings.Contains(ciphertext, plaintext) {
+			t.Errorf("Encrypt(%q) = %q, contains the plaintext", plaintext, ciphertext)
+		}
+		got, err := Decrypt(ciphertext, key)
+		if err != nil || got != plaintext {
+			t.Errorf("Decrypt(Encrypt(%q)) = %q, %v", plaintext, got, err)
+		}
+	}
+}
+
+func TestEncryptIsRandomized(t *testing.T) {
+	a, _ := Encrypt("rakesh@test.com", key)
+	b, _ := Encrypt("rakesh@test.com", key)
+	if a == b {
+		t.Error("Encrypt gave the same ciphertext twice; the nonce must be random")
+	}
+}
+
+func TestDeterministicRoundTrip(t *testing.T) {
+	a, err := EncryptDeterministic("rakesh@test.com", key)
+	if err != nil {
+		t.Fatalf("EncryptDeterministic: %v", err)
+	}
+	b, _ := EncryptDeterministic("rakesh@test.com", key)
+	if a != b {
+		t.Errorf("EncryptDeterministic gave %q then %q, want the same", a, b)
+	}
+	if a != strings.ToLower(a) {
+		t.Errorf("EncryptDeterministic = %q, want lower case", a)
+	}
+	if got, err := DecryptDeterministic(a, key); err != nil || got != "rakesh@test.com" {
+		t.Errorf("DecryptDeterministic = %q, %v", got, err)
+	}
+}
+
+func TestDecryptWrongKey(t *testing.T) {
+	ciphertext, err := Encrypt("rakesh@test.com", key)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if _, err := Decrypt(ciphertext, otherKey); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Errorf("Decrypt with the wrong key = %v, want ErrInvalidCiphertext", err)
+	}
+
+	deterministic, _ := EncryptDeterministic("rakesh@test.com", key)
+	if _, err := DecryptDeterministic(deterministic, otherKey); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Errorf("DecryptDeterministic with the wrong key = %v, want ErrInvalidCiphertext", err)
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	ciphertext, err := Encrypt("rakesh@test.com", key)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	sealed, _ := base64.StdEncoding.DecodeString(ciphertext)
+
+	flipped := bytes.Clone(sealed)
+	flipped[len(flipped)-1] ^= 1
+
+	tests := []struct {
+		name       string
+		ciphertext string
+	}{
+		{"flipped bit", base64.StdEncoding.EncodeToString(flipped)},
+		{"truncated", base64.StdEncoding.EncodeToString(sealed[:len(sealed)-1])},
+		{"shorter than a nonce", base64.StdEncoding.EncodeToString(sealed[:5])},
+		{"not base64", "!!not base64!!"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decrypt(tt.ciphertext, key); !errors.Is(err, ErrInvalidCiphertext) {
+				t.Errorf("Decrypt = %v, want ErrInvalidCiphertext", err)
+			}
+		})
+	}
+}
+
+func TestKeySize(t *testing.T) {
+	if _, err := Encrypt("x", key[:16]); err == nil {
+		t.Error("Encrypt with a 16-byte key = nil error, want one: AES-256 needs 32 bytes")
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(key)
+	if got, err := ParseKey(" " + encoded + "\n"); err != nil || !bytes.Equal(got, key) {
+		t.Errorf("ParseKey = %v, %v", got, err)
+	}
+	if _, err := ParseKey(base64.StdEncoding.EncodeToString(key[:16])); err == nil {
+		t.Error("ParseKey of a 16-byte key = nil error")
+	}
+	if _, err := ParseKey("not base64"); err == nil {
+		t.Error("ParseKey of invalid base64 = nil error")
+	}
+}
@@ -0,0 +1,76 @@
+// Package errors attaches call stacks to errors, for diagnosing internal
+// errors during development.
+//
+// The standard library's errors carry a message but not where they came
+// from. Wrap records the stack at the point it is called; StackTrace
+// reads it back from anywhere further up the chain, through any amount
+// of fmt.Errorf("...: %w") wrapping.
+//
+// The package name clashes with the standard library's, so import it
+// under an alias where both are needed:
+//
+//	import apperrors "github.com/aanand-mishra/students-api/internal/errors"
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// MaxFrames is how many stack frames Wrap records. Enough to see the
+// path through handler, decorators and storage, short enough to read.
+const MaxFrames = 10
+
+// stackError is an error with the program counters of the stack that
+// created it.
+type stackError struct {
+	err error
+	pcs []uintptr
+}
+
+func (e *stackError) Error() string { return e.err.Error() }
+
+// Unwrap keeps errors.Is / errors.As working on the wrapped error.
+func (e *stackError) Unwrap() error { return e.err }
+
+// Wrap returns err with the caller's stack attached. It returns nil for
+// nil, and err unchanged if it already carries a stack — the deepest
+// stack is the most useful one.
+func Wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	var existing *stackError
+	if errors.As(err, &existing) {
+		return err
+	}
+
+	pcs := make([]uintptr, MaxFrames)
+	// Skip runtime.Callers itself and Wrap.
+	n := runtime.Callers(2, pcs)
+	return &stackError{err: err, pcs: pcs[:n]}
+}
+
+// StackTrace returns the stack recorded by Wrap anywhere in err's chain,
+// one frame per line as "function (file.go:line)", innermost first. It
+// returns "" when err carries no stack.
+func StackTrace(err error) string {
+	var se *stackError
+	if !errors.As(err, &se) {
+		return ""
+	}
+
+	var b strings.Builder
+	frames := runtime.CallersFrames(se.pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s (%s:%d)\n", frame.Function, filepath.Base(frame.File), frame.Line)
+		if !more {
+			break
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,52 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func wrapHere(err error) error { return Wrap(err) }
+
+func TestWrap(t *testing.T) {
+	if Wrap(nil) != nil {
+		t.Error("Wrap(nil) != nil")
+	}
+
+	err := wrapHere(io.EOF)
+	if err.Error() != io.EOF.Error() {
+		t.Errorf("Error() = %q, want the wrapped message", err.Error())
+	}
+	if !errors.Is(err, io.EOF) {
+		t.Error("errors.Is doesn't see through Wrap")
+	}
+
+	stack := StackTrace(fmt.Errorf("GetStudentByID: %w", err))
+	lines := strings.Split(stack, "\n")
+	if !strings.Contains(lines[0], "wrapHere") || !strings.Contains(lines[0], "errors_test.go") {
+		t.Errorf("first frame = %q, want Wrap's caller", lines[0])
+	}
+	if !strings.Contains(stack, "TestWrap") {
+		t.Errorf("stack doesn't reach the test:\n%s", stack)
+	}
+	if len(lines) > MaxFrames {
+		t.Errorf("%d frames, want at most %d", len(lines), MaxFrames)
+	}
+}
+
+func TestWrapKeepsDeepestStack(t *testing.T) {
+	inner := wrapHere(io.EOF)
+	outer := Wrap(fmt.Errorf("outer: %w", inner))
+
+	if StackTrace(outer) != StackTrace(inner) {
+		t.Errorf("re-wrapping replaced the stack:\n%s\nwant\n%s", StackTrace(outer), StackTrace(inner))
+	}
+}
+
+func TestStackTraceWithoutStack(t *testing.T) {
+	if got := StackTrace(io.EOF); got != "" {
+		t.Errorf("StackTrace = %q for a plain error, want \"\"", got)
+	}
+}
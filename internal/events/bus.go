@@ -0,0 +1,140 @@
+// Package events broadcasts changes to students, as they happen, to
+// everyone listening in this process — the clients of
+// GET /api/students/events, for example.
+//
+// EventBus implements storage.Hook, so it is registered on the storage
+// with RegisterHook and sees every create, update and delete, however it
+// was made.
+package events
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// Values for Event.Type.
+const (
+	Created = "created"
+	Updated = "updated"
+	Deleted = "deleted"
+)
+
+// subscriberBuffer is how many events a subscriber can fall behind by
+// before new ones are dropped for it.
+const subscriberBuffer = 64
+
+// Event is one change to a student. Student is the record after the
+// change; for Deleted only StudentID is known. TenantID is the student's
+// tenant, which decides who receives it; it is not part of the JSON.
+type Event struct {
+	Type      string         `json:"type"`
+	StudentID int64          `json:"student_id"`
+	Student   *types.Student `json:"student,omitempty"`
+	TenantID  string         `json:"-"`
+}
+
+// EventBus hands every published Event to the subscribers of its tenant.
+//
+// Publish never blocks: a subscriber whose buffer is full misses the
+// event (a warning is logged) rather than holding up the write that
+// published it. Only the subscriber's own tenant's events take up room
+// in its buffer, so a busy tenant can't make another one miss events.
+type EventBus struct {
+	// mu guards subscribers and closed. Publish holds it for reading
+	// while it sends, so Unsubscribe can't close a channel under it.
+	mu          sync.RWMutex
+	subscribers map[<-chan Event]subscriber
+	closed      bool
+}
+
+// subscriber is one Subscribe call: its channel and the tenant it hears
+// about.
+type subscriber struct {
+	ch       chan Event
+	tenantID string
+}
+
+// NewEventBus returns an EventBus with no subscribers.
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[<-chan Event]subscriber)}
+}
+
+// Subscribe returns a channel that receives every event of tenantID's
+// students published from now on. Call Unsubscribe with it when done.
+// After Close, the channel returned is already closed.
+func (b *EventBus) Subscribe(tenantID string) <-chan Event {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(ch)
+		return ch
+	}
+	b.subscribers[ch] = subscriber{ch: ch, tenantID: tenantID}
+	return ch
+}
+
+// Unsubscribe stops sending to ch and closes it. It is safe to call more
+// than once, and after Close.
+func (b *EventBus) Unsubscribe(ch <-chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if sub, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(sub.ch)
+	}
+}
+
+// Publish sends e to every subscriber of e.TenantID that has room for it.
+func (b *EventBus) Publish(e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, sub := range b.subscribers {
+		if sub.tenantID != e.TenantID {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			slog.Warn("event subscriber is falling behind, event dropped",
+				slog.String("type", e.Type),
+				slog.Int64("student_id", e.StudentID),
+				slog.String("tenant_id", e.TenantID))
+		}
+	}
+}
+
+// Close closes every subscriber's channel, telling them no more events
+// will come, e.g. so streams end when the server shuts down.
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch, sub := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(sub.ch)
+	}
+	b.closed = true
+}
+
+// OnCreate implements storage.Hook.
+func (b *EventBus) OnCreate(ctx context.Context, student types.Student) {
+	b.Publish(Event{Type: Created, StudentID: int64(student.ID), Student: &student, TenantID: student.TenantID})
+}
+
+// OnUpdate implements storage.Hook.
+func (b *EventBus) OnUpdate(ctx context.Context, old, new types.Student) {
+	b.Publish(Event{Type: Updated, StudentID: int64(new.ID), Student: &new, TenantID: new.TenantID})
+}
+
+// OnDelete implements storage.Hook.
+func (b *EventBus) OnDelete(ctx context.Context, tenantID string, studentID int64) {
+	b.Publish(Event{Type: Deleted, StudentID: studentID, TenantID: tenantID})
+}
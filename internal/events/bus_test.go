@@ -0,0 +1,75 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+func TestPublishOnlyToTheTenant(t *testing.T) {
+	bus := NewEventBus()
+	acme, other := bus.Subscribe("acme"), bus.Subscribe("other")
+
+	bus.OnCreate(context.Background(), types.Student{ID: 1, TenantID: "acme"})
+	bus.OnDelete(context.Background(), "other", 2)
+
+	if got := len(acme); got != 1 {
+		t.Fatalf("acme has %d events waiting, want 1", got)
+	}
+	if e := <-acme; e.Type != Created || e.StudentID != 1 {
+		t.Errorf("acme got %+v, want student 1 created", e)
+	}
+	if got := len(other); got != 1 {
+		t.Fatalf("other has %d events waiting, want 1", got)
+	}
+	if e := <-other; e.Type != Deleted || e.StudentID != 2 {
+		t.Errorf("other got %+v, want student 2 deleted", e)
+	}
+}
+
+// TestBusyTenantDoesNotCrowdOutOthers fills one tenant's subscriber to
+// well past its buffer; a subscriber of another tenant that isn't
+// reading must still have room for its own events.
+func TestBusyTenantDoesNotCrowdOutOthers(t *testing.T) {
+	bus := NewEventBus()
+	busy, quiet := bus.Subscribe("busy"), bus.Subscribe("quiet")
+
+	for i := range 3 * subscriberBuffer {
+		bus.Publish(Event{Type: Updated, StudentID: int64(i), TenantID: "busy"})
+	}
+	bus.Publish(Event{Type: Updated, StudentID: 42, TenantID: "quiet"})
+
+	if got := len(busy); got != subscriberBuffer {
+		t.Errorf("busy has %d events waiting, want a full buffer of %d", got, subscriberBuffer)
+	}
+	if got := len(quiet); got != 1 {
+		t.Fatalf("quiet has %d events waiting, want its 1", got)
+	}
+	if e := <-quiet; e.StudentID != 42 {
+		t.Errorf("quiet got student %d, want 42", e.StudentID)
+	}
+}
+
+func TestUnsubscribeAndClose(t *testing.T) {
+	bus := NewEventBus()
+	a, b := bus.Subscribe("acme"), bus.Subscribe("acme")
+
+	bus.Unsubscribe(a)
+	bus.Unsubscribe(a) // a second call is harmless
+	if _, ok := <-a; ok {
+		t.Error("unsubscribed channel is still open")
+	}
+	bus.Publish(Event{Type: Created, TenantID: "acme"})
+
+	bus.Close()
+	if e, ok := <-b; !ok || e.Type != Created {
+		t.Errorf("b got %+v, %v; want the event published before Close", e, ok)
+	}
+	if _, ok := <-b; ok {
+		t.Error("channel still open after Close")
+	}
+	if _, ok := <-bus.Subscribe("acme"); ok {
+		t.Error("Subscribe after Close returned an open channel")
+	}
+}
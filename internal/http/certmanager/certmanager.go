@@ -0,0 +1,170 @@
+// Package certmanager serves the HTTPS certificate and swaps in a new one
+// when the files on disk change, so a renewed certificate (certbot runs
+// every couple of months) is picked up without restarting the server.
+//
+// http.Server.ServeTLS with file names reads them once at startup. Here
+// the server asks Manager.GetCertificate for the certificate on every
+// TLS handshake instead:
+//
+//	server.TLSConfig = &tls.Config{GetCertificate: mgr.GetCertificate}
+//	server.ServeTLS(ln, "", "")
+package certmanager
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ExpiryWarning is how close to expiry a certificate has to be before
+// New warns about it.
+const ExpiryWarning = 30 * 24 * time.Hour
+
+// Manager holds the current certificate, loaded from a PEM certificate
+// and key file pair.
+type Manager struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// New loads the certificate and key and returns a Manager serving them.
+// It logs a warning if the certificate expires within ExpiryWarning.
+func New(certFile, keyFile string) (*Manager, error) {
+	m := &Manager{certFile: certFile, keyFile: keyFile}
+	if err := m.reload(); err != nil {
+		return nil, fmt.Errorf("certmanager.New: %w", err)
+	}
+
+	if notAfter := m.NotAfter(); time.Until(notAfter) < ExpiryWarning {
+		slog.Warn("TLS certificate expires soon",
+			slog.String("cert_file", certFile),
+			slog.Time("not_after", notAfter))
+	}
+	return m, nil
+}
+
+// GetCertificate returns the current certificate. It has the signature
+// tls.Config.GetCertificate expects.
+func (m *Manager) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.cert, nil
+}
+
+// NotAfter returns when the current certificate expires.
+func (m *Manager) NotAfter() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.cert.Leaf.NotAfter
+}
+
+// reload reads both files and, only if they form a valid pair, replaces
+// the current certificate. A half-written renewal therefore never
+// replaces a working certificate.
+func (m *Manager) reload() error {
+	cert, err := tls.LoadX509KeyPair(m.certFile, m.keyFile)
+	if err != nil {
+		return fmt.Errorf("load key pair: %w", err)
+	}
+
+	// Parsed once here rather than on every handshake.
+	cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("parse certificate: %w", err)
+	}
+
+	m.mu.Lock()
+	m.cert = &cert
+	m.mu.Unlock()
+	return nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Watch reloads the certificate whenever the certificate or key file
+// changes, in a background goroutine, until ctx is cancelled.
+//
+// It watches the files' DIRECTORIES rather than the files: renewal tools
+// replace files by renaming a new one over the old (or, like certbot,
+// re-pointing a symlink), and a watch on the old file would be lost with
+// it.
+//
+// A renewal writes the two files one after the other, so the first event
+// usually finds a certificate that doesn't match the key yet. That
+// reload fails, is logged at DEBUG, and the old certificate stays until
+// the second file's event completes the pair.
+// ─────────────────────────────────────────────────────────────────────────────
+func (m *Manager) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("Watch: new watcher: %w", err)
+	}
+
+	dirs := map[string]bool{filepath.Dir(m.certFile): true, filepath.Dir(m.keyFile): true}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return fmt.Errorf("Watch: watch %s: %w", dir, err)
+		}
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !m.affects(event) {
+					continue
+				}
+				if err := m.reload(); err != nil {
+					slog.Debug("TLS certificate not reloaded",
+						slog.String("file", event.Name),
+						slog.String("error", err.Error()))
+					continue
+				}
+				slog.Info("TLS certificate reloaded",
+					slog.String("cert_file", m.certFile),
+					slog.Time("not_after", m.NotAfter()))
+
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("TLS certificate watcher error", slog.String("error", err.Error()))
+			}
+		}
+	}()
+	return nil
+}
+
+// affects reports whether event may have changed the certificate: a
+// write, create or rename of one of the two files. Kubernetes mounts
+// secrets as symlinks into a "..data" directory and renews them by
+// swapping that one symlink, so a change to it counts too.
+func (m *Manager) affects(event fsnotify.Event) bool {
+	if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+		return false
+	}
+
+	name := filepath.Clean(event.Name)
+	return name == filepath.Clean(m.certFile) || name == filepath.Clean(m.keyFile) ||
+		filepath.Base(name) == "..data"
+}
@@ -0,0 +1,144 @@
+// Package admin contains HTTP handlers for operational tasks that are not
+// part of the public students API. All of them must be registered behind
+// middleware.RequireAPIKey.
+package admin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/http/middleware/requestid"
+	"github.com/aanand-mishra/students-api/internal/storage/sqlite"
+	"github.com/aanand-mishra/students-api/internal/utils/response"
+)
+
+// Backupper is implemented by storage backends that can write a
+// consistent copy of their database to a file (e.g. *sqlite.SQLite).
+type Backupper interface {
+	Backup(ctx context.Context, destPath string) error
+}
+
+// Diagnostics is implemented by storage backends that can report
+// statistics about their database (e.g. *sqlite.SQLite). It is separate
+// from storage.Storage because only operators need it.
+type Diagnostics interface {
+	DBStats(ctx context.Context) (sqlite.SqliteStats, error)
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// DownloadDB handles GET /admin/db/download
+// Streams a consistent snapshot of the SQLite database as a file download.
+//
+//	curl -H "X-API-Key: $ADMIN_API_KEY" -o students.db \
+//	     http://localhost:8082/admin/db/download
+//
+// The snapshot is taken with Backupper.Backup into a temporary file, so
+// writes that happen during the download don't corrupt the copy. Every
+// attempt is logged with the client address for auditing.
+//
+// Error responses:
+//
+//	401 Unauthorized — missing or wrong X-API-Key (from the middleware)
+//	500 Internal     — snapshot could not be created
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func DownloadDB(db Backupper) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
+		log.Info("database download requested",
+			slog.String("remote_addr", r.RemoteAddr),
+			slog.Time("requested_at", time.Now()))
+
+		dir, err := os.MkdirTemp("", "students-backup-*")
+		if err != nil {
+			writeError(w, r, fmt.Errorf("create temp dir: %w", err))
+			return
+		}
+		defer os.RemoveAll(dir)
+
+		snapshot := filepath.Join(dir, "students.db")
+		if err := db.Backup(r.Context(), snapshot); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		file, err := os.Open(snapshot)
+		if err != nil {
+			writeError(w, r, fmt.Errorf("open snapshot: %w", err))
+			return
+		}
+		defer file.Close()
+
+		info, err := file.Stat()
+		if err != nil {
+			writeError(w, r, fmt.Errorf("stat snapshot: %w", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", `attachment; filename="students.db"`)
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+		w.WriteHeader(http.StatusOK)
+
+		// Headers are already sent, so a copy error can only be logged.
+		written, err := io.Copy(w, file)
+		if err != nil {
+			log.Error("database download interrupted",
+				slog.String("remote_addr", r.RemoteAddr),
+				slog.String("error", err.Error()))
+			return
+		}
+
+		log.Info("database download completed",
+			slog.String("remote_addr", r.RemoteAddr),
+			slog.Int64("bytes", written))
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// DBStats handles GET /admin/db/stats
+// Reports the database size and row statistics.
+//
+//	curl -H "X-API-Key: $ADMIN_API_KEY" http://localhost:8082/admin/db/stats
+//
+// Success response (200 OK):
+//
+//	{ "page_count": 8, "page_size": 4096, "freelist_count": 0,
+//	  "size_bytes": 32768, "student_count": 2, "student_data_bytes": 43 }
+//
+// Error responses:
+//
+//	401 Unauthorized — missing or wrong X-API-Key (from the middleware)
+//	500 Internal     — statistics could not be read
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func DBStats(db Diagnostics) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats, err := db.DBStats(r.Context())
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		response.WriteJSON(w, http.StatusOK, stats)
+	}
+}
+
+// writeError logs err and sends it as a 500 response.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	logFromContext(r.Context()).Error("admin request failed", slog.String("error", err.Error()))
+	response.WriteJSON(w, http.StatusInternalServerError, response.GeneralError(err))
+}
+
+// logFromContext returns the request's logger, which adds the request ID
+// to every line (see requestid.Logger).
+func logFromContext(ctx context.Context) *slog.Logger {
+	return requestid.Logger(ctx)
+}
@@ -0,0 +1,123 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/aanand-mishra/students-api/internal/config"
+	"github.com/aanand-mishra/students-api/internal/http/middleware"
+	"github.com/aanand-mishra/students-api/internal/storage/sqlite"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+const testAPIKey = "secret"
+
+func newStore(t *testing.T) *sqlite.SQLite {
+	t.Helper()
+
+	db, err := sqlite.New(&config.Config{
+		StoragePath: filepath.Join(t.TempDir(), "test.db"),
+		Database:    config.Database{AutoMigrate: true},
+		SQLite:      config.SQLite{JournalMode: config.JournalModeWAL, BusyTimeoutMs: 5000},
+	})
+	if err != nil {
+		t.Fatalf("sqlite.New: %v", err)
+	}
+	t.Cleanup(func() { db.Db.Close() })
+	return db
+}
+
+func TestDownloadDB(t *testing.T) {
+	db := newStore(t)
+	if _, err := db.CreateStudent(context.Background(), types.DefaultTenant, "Rakesh", "rakesh@test.com", 35, "", ""); err != nil {
+		t.Fatalf("CreateStudent: %v", err)
+	}
+
+	handler := middleware.RequireAPIKey(testAPIKey, DownloadDB(db))
+	req := httptest.NewRequest(http.MethodGet, "/admin/db/download", nil)
+	req.Header.Set(middleware.APIKeyHeader, testAPIKey)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/octet-stream" {
+		t.Errorf("Content-Type = %q, want application/octet-stream", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); cd != `attachment; filename="students.db"` {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	if cl := rec.Header().Get("Content-Length"); cl != strconv.Itoa(rec.Body.Len()) {
+		t.Errorf("Content-Length = %s, body is %d bytes", cl, rec.Body.Len())
+	}
+
+	// The download must open as a database of its own, with the data.
+	path := filepath.Join(t.TempDir(), "downloaded.db")
+	if err := os.WriteFile(path, rec.Body.Bytes(), 0o644); err != nil {
+		t.Fatalf("write download: %v", err)
+	}
+	copyDB, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("open download: %v", err)
+	}
+	defer copyDB.Close()
+
+	var table string
+	if err := copyDB.QueryRow("SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'students'").Scan(&table); err != nil {
+		t.Fatalf("students table missing from the download: %v", err)
+	}
+	var n int
+	if err := copyDB.QueryRow("SELECT COUNT(*) FROM students").Scan(&n); err != nil || n != 1 {
+		t.Errorf("download has %d students (%v), want 1", n, err)
+	}
+}
+
+func TestDownloadDBRequiresAPIKey(t *testing.T) {
+	handler := middleware.RequireAPIKey(testAPIKey, DownloadDB(newStore(t)))
+
+	for _, key := range []string{"", "wrong"} {
+		req := httptest.NewRequest(http.MethodGet, "/admin/db/download", nil)
+		if key != "" {
+			req.Header.Set(middleware.APIKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("key %q: status = %d, want %d", key, rec.Code, http.StatusUnauthorized)
+		}
+		if body, _ := io.ReadAll(rec.Body); len(body) > 0 && body[0] != '{' {
+			t.Errorf("key %q: body is not a JSON error: %.20q", key, body)
+		}
+	}
+}
+
+// failingBackupper can't take a snapshot.
+type failingBackupper struct{}
+
+func (failingBackupper) Backup(context.Context, string) error {
+	return errors.New("disk full")
+}
+
+func TestDownloadDBBackupFails(t *testing.T) {
+	rec := httptest.NewRecorder()
+	DownloadDB(failingBackupper{})(rec, httptest.NewRequest(http.MethodGet, "/admin/db/download", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+}
@@ -0,0 +1,129 @@
+// Package health contains HTTP handlers that report on the state of the
+// running service itself rather than on any business resource.
+package health
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/config"
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/utils/response"
+)
+
+// StatusUnavailable is the status GET /ready reports when the service
+// can't take traffic.
+const StatusUnavailable = "unavailable"
+
+// readinessTimeout bounds the database ping, so a hung database makes
+// the probe fail rather than hang until the prober gives up.
+const readinessTimeout = 2 * time.Second
+
+// Status is the JSON body returned by GET /health.
+type Status struct {
+	Status string `json:"status"`
+
+	// ConfigDrift is true when the config file on disk no longer matches
+	// the config the server was started with.
+	ConfigDrift bool `json:"config_drift"`
+
+	// ConfigDriftFields lists the YAML keys that changed, if any.
+	ConfigDriftFields []string `json:"config_drift_fields,omitempty"`
+
+	// ConfigCheckedAt is when the config file was last re-read.
+	// Omitted until the first check has run.
+	ConfigCheckedAt *time.Time `json:"config_checked_at,omitempty"`
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Health handles GET /health
+// Reports that the process is up, along with the latest config drift result.
+//
+// Success response (200 OK):
+//
+//	{ "status": "ok", "config_drift": false, "config_checked_at": "..." }
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func Health(drift *config.DriftStatus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lastChecked, drifted, fields := drift.Snapshot()
+
+		status := Status{
+			Status:            response.StatusOK,
+			ConfigDrift:       drifted,
+			ConfigDriftFields: fields,
+		}
+		if !lastChecked.IsZero() {
+			status.ConfigCheckedAt = &lastChecked
+		}
+
+		response.WriteJSON(w, http.StatusOK, status)
+	}
+}
+
+// Drain marks the service as draining: still serving, but asking load
+// balancers to send its traffic elsewhere (on SIGUSR1, see main.go).
+// The zero value is not draining.
+type Drain struct {
+	draining atomic.Bool
+}
+
+// Set starts (true) or ends (false) draining.
+func (d *Drain) Set(draining bool) {
+	d.draining.Store(draining)
+}
+
+// Draining reports whether the service is draining.
+func (d *Drain) Draining() bool {
+	return d.draining.Load()
+}
+
+// ReadyStatus is the JSON body returned by GET /ready.
+type ReadyStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Readiness handles GET /ready
+// Reports whether the service can serve requests right now, i.e. whether
+// its database answers. Load balancers and Kubernetes readiness probes
+// stop sending traffic while it fails, without restarting the process
+// the way a failing /health (liveness) probe would.
+//
+// While drain is set it fails without asking the database, so the
+// instance can be taken out of rotation and still finish its requests.
+//
+// Success response (200 OK):
+//
+//	{ "status": "ok" }
+//
+// Error responses:
+//
+//	503 Service Unavailable — { "status": "unavailable", "error": "..." }
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func Readiness(store storage.Storage, drain *Drain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if drain.Draining() {
+			response.WriteJSON(w, http.StatusServiceUnavailable,
+				ReadyStatus{Status: StatusUnavailable, Error: "draining"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+
+		if err := store.Ping(ctx); err != nil {
+			slog.Warn("readiness check failed", slog.String("error", err.Error()))
+			response.WriteJSON(w, http.StatusServiceUnavailable,
+				ReadyStatus{Status: StatusUnavailable, Error: err.Error()})
+			return
+		}
+
+		response.WriteJSON(w, http.StatusOK, ReadyStatus{Status: response.StatusOK})
+	}
+}
@@ -0,0 +1,115 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/config"
+	"github.com/aanand-mishra/students-api/internal/storage"
+)
+
+func TestHealthReportsConfigDrift(t *testing.T) {
+	checked := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		setup       func(*config.DriftStatus)
+		wantDrift   bool
+		wantFields  []string
+		wantChecked bool
+	}{
+		{"not checked yet", func(*config.DriftStatus) {}, false, nil, false},
+		{"no drift", func(d *config.DriftStatus) { d.Set(checked, nil) }, false, nil, true},
+		{"drifted", func(d *config.DriftStatus) { d.Set(checked, []string{"http_server.address"}) }, true, []string{"http_server.address"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drift := &config.DriftStatus{}
+			tt.setup(drift)
+
+			rec := httptest.NewRecorder()
+			Health(drift)(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+
+			var body Status
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if body.Status != "ok" {
+				t.Errorf("status = %q, want ok", body.Status)
+			}
+			if body.ConfigDrift != tt.wantDrift {
+				t.Errorf("config_drift = %v, want %v", body.ConfigDrift, tt.wantDrift)
+			}
+			if !reflect.DeepEqual(body.ConfigDriftFields, tt.wantFields) {
+				t.Errorf("config_drift_fields = %v, want %v", body.ConfigDriftFields, tt.wantFields)
+			}
+			if got := body.ConfigCheckedAt != nil; got != tt.wantChecked {
+				t.Errorf("config_checked_at present = %v, want %v", got, tt.wantChecked)
+			}
+		})
+	}
+}
+
+// pingStore is a storage.Storage whose Ping returns err. Readiness calls
+// nothing else.
+type pingStore struct {
+	storage.Storage
+	err   error
+	pings int
+}
+
+func (s *pingStore) Ping(context.Context) error {
+	s.pings++
+	return s.err
+}
+
+func TestReadinessWhileDraining(t *testing.T) {
+	tests := []struct {
+		name      string
+		draining  bool
+		pingErr   error
+		want      int
+		wantError string
+		wantPing  bool
+	}{
+		{"serving", false, nil, http.StatusOK, "", true},
+		{"database down", false, errors.New("connection refused"), http.StatusServiceUnavailable, "connection refused", true},
+		{"draining", true, nil, http.StatusServiceUnavailable, "draining", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &pingStore{err: tt.pingErr}
+			drain := &Drain{}
+			drain.Set(tt.draining)
+
+			rec := httptest.NewRecorder()
+			Readiness(store, drain)(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			var body ReadyStatus
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if body.Error != tt.wantError {
+				t.Errorf("error = %q, want %q", body.Error, tt.wantError)
+			}
+			if got := store.pings > 0; got != tt.wantPing {
+				t.Errorf("pinged the database = %v, want %v", got, tt.wantPing)
+			}
+		})
+	}
+}
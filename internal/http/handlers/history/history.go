@@ -0,0 +1,104 @@
+// Package history serves the audit log of a student: every create,
+// update and delete recorded for them (see internal/audit).
+package history
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/aanand-mishra/students-api/internal/http/middleware"
+	"github.com/aanand-mishra/students-api/internal/http/middleware/requestid"
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/utils/response"
+)
+
+// Pattern is the route Get is registered on.
+//
+// It can't be "GET /api/students/{id}/history": that and
+// "GET /api/students/external/{external_id}" both match
+// /api/students/external/history, and ServeMux refuses a pair of routes
+// when neither is more specific. With {view} the external route is the
+// more specific one and keeps that path; Get answers 404 for any view
+// other than "history".
+const Pattern = "GET /api/students/{id}/{view}"
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Get handles GET /api/students/{id}/history
+// Returns every change recorded for a student, oldest first.
+//
+// Path parameter: {id} — must be a valid integer
+//
+// Success response (200 OK):
+//
+//	[
+//	  { "id": 1, "action": "create", "student_id": 7,
+//	    "timestamp": "2024-06-01T10:00:00Z",
+//	    "after": { "id": 7, "name": "Rakesh", ... }, ... },
+//	  { "id": 9, "action": "update", "student_id": 7, ...,
+//	    "before": { ... }, "after": { ... } }
+//	]
+//
+// A deleted student's history is still returned, ending with its
+// "delete" entry.
+//
+// Error responses:
+//
+//	400 Bad Request  — id is not a valid integer
+//	404 Not Found    — no such student, and nothing recorded for the id
+//	500 Internal     — database error
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func Get(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("view") != "history" {
+			http.NotFound(w, r)
+			return
+		}
+
+		log := logFromContext(r.Context())
+		id := r.PathValue("id")
+		log.Info("getting a student's history", slog.String("id", id))
+
+		intID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New("invalid id: must be an integer")))
+			return
+		}
+
+		entries, err := storage.GetAuditLog(r.Context(), middleware.TenantFromContext(r.Context()), intID)
+		if err != nil {
+			log.Error("error getting audit log",
+				slog.String("id", id),
+				slog.String("error", err.Error()))
+			response.WriteJSON(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+
+		// An empty history is normal for a student created before the
+		// audit log existed; only an unknown id is a 404.
+		if len(entries) == 0 {
+			if _, err := storage.GetStudentByID(r.Context(), middleware.TenantFromContext(r.Context()), intID); isNotFound(err) {
+				response.WriteJSON(w, http.StatusNotFound, response.NotFoundError(err))
+				return
+			}
+		}
+
+		response.WriteJSON(w, http.StatusOK, entries)
+	}
+}
+
+// isNotFound reports whether err is storage.ErrNotFound. It is at package
+// level because inside Get the `storage` parameter shadows the package.
+func isNotFound(err error) bool {
+	return errors.Is(err, storage.ErrNotFound)
+}
+
+// logFromContext returns the request's logger, which adds the request ID
+// to every line (see requestid.Logger).
+func logFromContext(ctx context.Context) *slog.Logger {
+	return requestid.Logger(ctx)
+}
@@ -0,0 +1,129 @@
+// Package openapi serves the API's OpenAPI 3.0 description, and Swagger UI
+// to browse it.
+//
+// The document, openapi.json, is written by hand next to this file and
+// compiled into the binary. api/openapi.yaml is the same document as
+// YAML, generated from it. When a route, parameter or payload changes,
+// update openapi.json in the same commit, then regenerate the YAML and
+// check both with:
+//
+//	make openapi-yaml lint-openapi
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/aanand-mishra/students-api/api"
+	"github.com/aanand-mishra/students-api/internal/http/middleware/requestid"
+	"github.com/aanand-mishra/students-api/internal/utils/response"
+)
+
+// spec is openapi.json, embedded at compile time.
+//
+//go:embed openapi.json
+var spec []byte
+
+// Document returns the embedded OpenAPI document as written, for tools
+// that check it (see cmd/validate-openapi).
+func Document() []byte {
+	return spec
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Spec handles GET /openapi.json
+// Returns the OpenAPI 3.0 description of the API: every /api route with
+// its parameters, request and response bodies, and the error shape.
+//
+//	curl http://localhost:8082/openapi.json
+//
+// The document's paths are relative to its server URL, which is set to
+// apiPrefix (e.g. "/v1", from api.current_version), so clients generated
+// from it call the versioned routes.
+//
+// Error responses:
+//
+//	500 Internal — the embedded document could not be read
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func Spec(apiPrefix string) http.HandlerFunc {
+	var (
+		once sync.Once
+		doc  []byte
+		err  error
+	)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			doc, err = withServer(spec, apiPrefix)
+		})
+		if err != nil {
+			requestid.Logger(r.Context()).Error("error reading OpenAPI document", slog.String("error", err.Error()))
+			response.WriteJSON(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(doc)
+	}
+}
+
+// withServer returns the document with its top-level server URL set to
+// url. Path-level servers (the probes, which aren't versioned) are kept.
+func withServer(doc []byte, url string) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &fields); err != nil {
+		return nil, err
+	}
+
+	servers, err := json.Marshal([]map[string]string{
+		{"url": url, "description": "This server, current API version"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	fields["servers"] = servers
+
+	return json.MarshalIndent(fields, "", "  ")
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// SpecYAML handles GET /docs/openapi.yaml
+// Returns the same description as Spec, as YAML (api/openapi.yaml), for
+// tools that prefer it. Unlike Spec it is served exactly as committed:
+// its server URL is the default /v1, whatever api_prefix is set to.
+//
+//	curl http://localhost:8082/docs/openapi.yaml
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func SpecYAML() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(api.OpenAPIYAML)
+	}
+}
+
+// docsPage is Swagger UI, loaded from a CDN, pointed at /openapi.json.
+// It is a plain HTML file next to this one, embedded at compile time.
+//
+//go:embed docs.html
+var docsPage []byte
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Docs handles GET /docs
+// Serves Swagger UI for the document at /openapi.json: every route,
+// browsable, with "Try it out" to send requests (click "Authorize" to
+// add a bearer token first; it is kept across reloads).
+//
+// The page loads Swagger UI's script and styles from unpkg.com, so the
+// browser needs to reach it.
+// ─────────────────────────────────────────────────────────────────────────────
+func Docs() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(docsPage)
+	}
+}
@@ -0,0 +1,90 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestDocs(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Docs()(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Content-Type = %q, want text/html", ct)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		`<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>`,
+		`url: "/openapi.json"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page does not contain %s", want)
+		}
+	}
+}
+
+func TestSpec(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Spec("/v2")(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	var doc struct {
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("body is not valid JSON: %v", err)
+	}
+	if len(doc.Servers) != 1 || doc.Servers[0].URL != "/v2" {
+		t.Errorf("servers = %+v, want one with url /v2", doc.Servers)
+	}
+}
+
+func TestSpecYAML(t *testing.T) {
+	rec := httptest.NewRecorder()
+	SpecYAML()(rec, httptest.NewRequest(http.MethodGet, "/docs/openapi.yaml", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/yaml" {
+		t.Errorf("Content-Type = %q, want application/yaml", ct)
+	}
+
+	// The YAML must be the JSON document, not a hand-edited copy of it.
+	var fromJSON, fromYAML any
+	if err := json.Unmarshal(Document(), &fromJSON); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	if err := yaml.Unmarshal(rec.Body.Bytes(), &fromYAML); err != nil {
+		t.Fatalf("body is not valid YAML: %v", err)
+	}
+	b, err := json.Marshal(fromYAML)
+	if err != nil {
+		t.Fatalf("re-encode YAML: %v", err)
+	}
+	fromYAML = nil
+	if err := json.Unmarshal(b, &fromYAML); err != nil {
+		t.Fatalf("re-decode YAML: %v", err)
+	}
+	if !reflect.DeepEqual(fromJSON, fromYAML) {
+		t.Error("api/openapi.yaml is out of date with openapi.json; run `make openapi-yaml`")
+	}
+}
@@ -0,0 +1,59 @@
+// Package redirect contains the handler run by the plain-HTTP server
+// when the API itself is served over HTTPS.
+package redirect
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ACMEChallengePrefix is the path Let's Encrypt fetches over plain HTTP
+// to prove domain ownership. It must never be redirected.
+const ACMEChallengePrefix = "/.well-known/acme-challenge/"
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ToHTTPS returns a handler that answers every request with
+// 301 Moved Permanently to the same path and query on HTTPS.
+//
+// httpsAddr is the address the HTTPS server listens on (cfg.HTTPServer.Addr).
+// The redirect keeps the host the client asked for and swaps in the HTTPS
+// port; if the request carries no host, the host from httpsAddr is used.
+// The port is left out of the URL when it is the default 443.
+//
+// Requests under /.well-known/acme-challenge/ are passed to acme instead,
+// so certificate renewal keeps working.
+// ─────────────────────────────────────────────────────────────────────────────
+func ToHTTPS(httpsAddr string, acme http.Handler) http.HandlerFunc {
+	fallbackHost, httpsPort, err := net.SplitHostPort(httpsAddr)
+	if err != nil {
+		fallbackHost, httpsPort = httpsAddr, "443"
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, ACMEChallengePrefix) {
+			acme.ServeHTTP(w, r)
+			return
+		}
+
+		// r.URL.Host is only set for absolute-form request lines (proxies);
+		// normally the host comes from the Host header in r.Host.
+		host := r.URL.Host
+		if host == "" {
+			host = r.Host
+		}
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if host == "" {
+			host = fallbackHost
+		}
+
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	}
+}
@@ -0,0 +1,73 @@
+package redirect
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestToHTTPS(t *testing.T) {
+	tests := []struct {
+		name      string
+		httpsAddr string
+		target    string // request URL; its host becomes the Host header
+		want      string
+	}{
+		{"default port", ":443", "http://api.example.com/api/students?page=2", "https://api.example.com/api/students?page=2"},
+		{"custom port", ":8443", "http://api.example.com/api/students/1", "https://api.example.com:8443/api/students/1"},
+		{"client port dropped", ":443", "http://api.example.com:80/health", "https://api.example.com/health"},
+		{"address without port", "api.example.com", "http://other.example.com/", "https://other.example.com/"},
+		{"no host", "api.example.com:8443", "/api/students", "https://api.example.com:8443/api/students"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if r.URL.Host == "" {
+				r.Host = ""
+			}
+			rec := httptest.NewRecorder()
+			ToHTTPS(tt.httpsAddr, http.NotFoundHandler())(rec, r)
+
+			if rec.Code != http.StatusMovedPermanently {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusMovedPermanently)
+			}
+			if got := rec.Header().Get("Location"); got != tt.want {
+				t.Errorf("Location = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToHTTPSHostHeader(t *testing.T) {
+	// A normal request line has only the path; the host is in Host.
+	r := httptest.NewRequest(http.MethodPost, "/api/students", nil)
+	r.URL.Host = ""
+	r.Host = "school.example.com"
+	rec := httptest.NewRecorder()
+	ToHTTPS(":443", http.NotFoundHandler())(rec, r)
+
+	if got := rec.Header().Get("Location"); got != "https://school.example.com/api/students" {
+		t.Errorf("Location = %q", got)
+	}
+}
+
+func TestToHTTPSLeavesACMEChallenges(t *testing.T) {
+	acme := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("token for " + r.URL.Path))
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "http://api.example.com"+ACMEChallengePrefix+"abc123", nil)
+	rec := httptest.NewRecorder()
+	ToHTTPS(":443", acme)(rec, r)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (not redirected)", rec.Code, http.StatusOK)
+	}
+	if loc := rec.Header().Get("Location"); loc != "" {
+		t.Errorf("ACME challenge redirected to %s", loc)
+	}
+	if got := rec.Body.String(); got != "token for "+ACMEChallengePrefix+"abc123" {
+		t.Errorf("body = %q, want the ACME handler's", got)
+	}
+}
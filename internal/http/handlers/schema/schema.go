@@ -0,0 +1,60 @@
+// Package schema serves JSON Schema documents for the API's payloads.
+package schema
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/aanand-mishra/students-api/internal/http/middleware/requestid"
+	"github.com/aanand-mishra/students-api/internal/schema"
+	"github.com/aanand-mishra/students-api/internal/types"
+	"github.com/aanand-mishra/students-api/internal/utils/response"
+	"github.com/aanand-mishra/students-api/internal/validation"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Student handles GET /api/schemas/student
+// Returns a JSON Schema (draft-07) describing a student payload, so
+// clients can validate before sending.
+//
+//	curl http://localhost:8082/api/schemas/student
+//
+// The schema is generated once, on the first request, from types.Student
+// and the configured required fields — the same rules the server checks.
+//
+// Error responses:
+//
+//	500 Internal — the schema could not be generated
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func Student() http.HandlerFunc {
+	var (
+		once sync.Once
+		doc  []byte
+		err  error
+	)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
+		once.Do(func() {
+			doc, err = schema.GenerateJSONSchema(types.Student{},
+				schema.WithRequired(validation.RequiredFields()...))
+		})
+		if err != nil {
+			log.Error("error generating student schema", slog.String("error", err.Error()))
+			response.WriteJSON(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/schema+json")
+		w.Write(doc)
+	}
+}
+
+// logFromContext returns the request's logger, which adds the request ID
+// to every line (see requestid.Logger).
+func logFromContext(ctx context.Context) *slog.Logger {
+	return requestid.Logger(ctx)
+}
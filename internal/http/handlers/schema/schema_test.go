@@ -0,0 +1,52 @@
+package schema
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/aanand-mishra/students-api/internal/validation"
+)
+
+func TestStudent(t *testing.T) {
+	if err := validation.SetRequiredFields([]string{"name", "phone"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { validation.SetRequiredFields(validation.DefaultRequiredFields) })
+
+	h := Student()
+	for i := 0; i < 2; i++ { // the second request is served from the cached document
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, "/api/schemas/student", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/schema+json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+
+		var doc struct {
+			Title      string                    `json:"title"`
+			Required   []string                  `json:"required"`
+			Properties map[string]map[string]any `json:"properties"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+			t.Fatalf("body is not JSON: %v", err)
+		}
+		if doc.Title != "Student" {
+			t.Errorf("title = %q", doc.Title)
+		}
+		if !reflect.DeepEqual(doc.Required, []string{"name", "phone"}) {
+			t.Errorf("required = %v, want the configured fields", doc.Required)
+		}
+		if age := doc.Properties["age"]; age["minimum"] != 1.0 || age["maximum"] != 150.0 {
+			t.Errorf("age = %v, want the validate tag's bounds", age)
+		}
+		if phone := doc.Properties["phone"]; phone["pattern"] == nil {
+			t.Errorf("phone = %v, want the E.164 pattern", phone)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package student
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/aanand-mishra/students-api/internal/http/middleware"
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/utils/response"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Anonymize handles POST /api/students/{id}/anonymize
+// Erases a student's personal data on request (the GDPR "right to
+// erasure"), for the cases where deleting isn't enough.
+//
+// DELETE /api/students/{id} only hides a student: the name, email and
+// phone stay in the database so the delete can be undone. Removing the
+// row instead would break the records that must be kept and refer to it
+// (fees, grades…). Anonymizing keeps the row but overwrites what
+// identifies the person:
+//
+//	name  → "[deleted]"
+//	email → "[deleted@example.com]"
+//	phone and photo → removed
+//
+// The student is deleted at the same time, and its history keeps what
+// happened and when, but no longer the values. This can't be undone.
+//
+// Only tokens with the "admin" role may call it (see the rbac package).
+//
+//	curl -X POST -H "Authorization: Bearer $ADMIN_TOKEN" \
+//	     http://localhost:8082/api/students/1/anonymize
+//
+// Success response (200 OK):
+//
+//	{ "status": "anonymized" }
+//
+// Error responses:
+//
+//	400 Bad Request  — invalid id
+//	403 Forbidden    — the token doesn't have the admin role
+//	404 Not Found    — no student with that id, deleted or not
+//	500 Internal     — database error
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func Anonymize(storage storage.Storage, photoDir string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
+		id := r.PathValue("id")
+		log.Info("anonymizing a student", slog.String("id", id))
+
+		intID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New("invalid id: must be an integer")))
+			return
+		}
+
+		if err := storage.AnonymizeStudentByID(r.Context(), middleware.TenantFromContext(r.Context()), intID); err != nil {
+			log.Error("error anonymizing student",
+				slog.String("id", id),
+				slog.String("error", err.Error()))
+			writeStorageError(w, err)
+			return
+		}
+
+		// The database no longer points at the photo, but the file would
+		// still be served from /photos/.
+		for _, ext := range []string{".jpg", ".png"} {
+			path := filepath.Join(photoDir, fmt.Sprintf("%d%s", intID, ext))
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Error("error removing photo of anonymized student",
+					slog.String("id", id),
+					slog.String("error", err.Error()))
+			}
+		}
+
+		log.Info("student anonymized", slog.String("id", id))
+		response.WriteJSON(w, http.StatusOK, map[string]string{"status": "anonymized"})
+	}
+}
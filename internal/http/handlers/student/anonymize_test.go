@@ -0,0 +1,57 @@
+package student_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aanand-mishra/students-api/internal/http/handlers/student"
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+func TestAnonymize(t *testing.T) {
+	db := newStore(t)
+	rakesh := seed(t, db, "Rakesh", "rakesh@test.com", 35)
+	id := fmt.Sprint(rakesh.ID)
+
+	photoDir := t.TempDir()
+	photo := filepath.Join(photoDir, id+".jpg")
+	if err := os.WriteFile(photo, []byte("jpeg"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := serve(student.Anonymize(db, photoDir), request{method: http.MethodPost, target: "/api/students/" + id + "/anonymize", id: id})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d\nbody: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if body := decode(t, rec); body["status"] != "anonymized" {
+		t.Errorf(`body = %v, want {"status": "anonymized"}`, body)
+	}
+
+	if _, err := os.Stat(photo); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("photo still on disk: %v", err)
+	}
+	// Anonymizing deletes the student too.
+	if _, err := db.GetStudentByID(context.Background(), types.DefaultTenant, int64(rakesh.ID)); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetStudentByID after anonymize: err = %v, want ErrNotFound", err)
+	}
+	// The email is free again.
+	seed(t, db, "Rakesh", "rakesh@test.com", 35)
+
+	for _, tt := range []struct {
+		id     string
+		status int
+		code   string
+	}{
+		{"abc", http.StatusBadRequest, "BAD_REQUEST"},
+		{"999", http.StatusNotFound, "NOT_FOUND"},
+	} {
+		rec := serve(student.Anonymize(db, photoDir), request{method: http.MethodPost, target: "/api/students/" + tt.id + "/anonymize", id: tt.id})
+		checkError(t, rec, tt.status, tt.code)
+	}
+}
@@ -0,0 +1,103 @@
+package student
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/events"
+	"github.com/aanand-mishra/students-api/internal/http/middleware"
+	"github.com/aanand-mishra/students-api/internal/utils/response"
+)
+
+// heartbeatInterval is how often an idle event stream gets a comment
+// line, so proxies and load balancers don't close it for inactivity.
+const heartbeatInterval = 15 * time.Second
+
+// ─────────────────────────────────────────────────────────────────────────────
+// StreamEvents handles GET /api/students/events
+// Streams every change to a student of the caller's tenant as
+// Server-Sent Events, for as long as the client stays connected — so a dashboard can update live instead of
+// polling:
+//
+//	const source = new EventSource("/v1/api/students/events");
+//	source.onmessage = (e) => console.log(JSON.parse(e.data));
+//
+// (EventSource can't send an Authorization header; browsers need a
+// polyfill that can, or a proxy that adds it.)
+//
+// Each event is one "data:" line with an events.Event as JSON:
+//
+//	data: {"type":"updated","student_id":1,"student":{"id":1,"name":"Rakesh",...}}
+//
+// "type" is "created", "updated" or "deleted"; deletes carry only the
+// student_id. Only changes made after connecting are sent. A client that
+// falls too far behind misses events. The stream ends when the server
+// shuts down; EventSource reconnects on its own.
+//
+// Error responses:
+//
+//	500 Internal — the connection can't stream
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func StreamEvents(bus *events.EventBus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			response.WriteJSON(w, http.StatusInternalServerError,
+				response.GeneralError(errors.New("streaming is not supported")))
+			return
+		}
+
+		// The stream lasts far longer than http_server.write_timeout,
+		// which would otherwise close the connection under it.
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+			log.Warn("cannot lift the write deadline; the stream will be cut at write_timeout",
+				slog.String("error", err.Error()))
+		}
+
+		changes := bus.Subscribe(middleware.TenantFromContext(r.Context()))
+		defer bus.Unsubscribe(changes)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		log.Info("client subscribed to student events")
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			var err error
+			select {
+			case <-r.Context().Done():
+				log.Info("client unsubscribed from student events")
+				return
+
+			case event, ok := <-changes:
+				if !ok {
+					// The bus was closed: the server is shutting down.
+					return
+				}
+				data, _ := json.Marshal(event)
+				_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+
+			case <-heartbeat.C:
+				// Lines starting with ":" are comments; clients ignore them.
+				_, err = fmt.Fprint(w, ": heartbeat\n\n")
+			}
+			if err != nil {
+				log.Info("student event stream ended", slog.String("error", err.Error()))
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
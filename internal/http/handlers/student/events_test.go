@@ -0,0 +1,108 @@
+package student_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/events"
+	"github.com/aanand-mishra/students-api/internal/http/handlers/student"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+func TestStreamEvents(t *testing.T) {
+	bus := events.NewEventBus()
+	t.Cleanup(bus.Close)
+
+	srv := httptest.NewServer(student.StreamEvents(bus))
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+	if cc := resp.Header.Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("Cache-Control = %q, want no-cache", cc)
+	}
+
+	// The response headers are flushed after subscribing, so these are
+	// seen. Another tenant's change is not sent.
+	bus.OnCreate(ctx, types.Student{ID: 1, Name: "Other", TenantID: "other-school"})
+	bus.OnCreate(ctx, types.Student{ID: 2, Name: "Rakesh", TenantID: types.DefaultTenant})
+	bus.OnDelete(ctx, types.DefaultTenant, 2)
+
+	lines := bufio.NewScanner(resp.Body)
+	var got []events.Event
+	for len(got) < 2 && lines.Scan() {
+		data, ok := strings.CutPrefix(lines.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var e events.Event
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			t.Fatalf("event is not JSON: %v\n%s", err, data)
+		}
+		got = append(got, e)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("got %d events, want 2 (scan error: %v)", len(got), lines.Err())
+	}
+	if got[0].Type != events.Created || got[0].StudentID != 2 || got[0].Student == nil || got[0].Student.Name != "Rakesh" {
+		t.Errorf("first event = %+v, want Rakesh created", got[0])
+	}
+	if got[1].Type != events.Deleted || got[1].StudentID != 2 || got[1].Student != nil {
+		t.Errorf("second event = %+v, want student 2 deleted", got[1])
+	}
+}
+
+func TestStreamEventsEndsWhenBusCloses(t *testing.T) {
+	bus := events.NewEventBus()
+	srv := httptest.NewServer(student.StreamEvents(bus))
+	t.Cleanup(srv.Close)
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	bus.Close()
+	done := make(chan struct{})
+	go func() {
+		io := bufio.NewReader(resp.Body)
+		for {
+			if _, err := io.ReadString('\n'); err != nil {
+				close(done)
+				return
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream still open after the bus closed")
+	}
+}
+
+// noFlushWriter is a ResponseWriter that can't stream.
+type noFlushWriter struct{ http.ResponseWriter }
+
+func TestStreamEventsNeedsFlusher(t *testing.T) {
+	rec := httptest.NewRecorder()
+	student.StreamEvents(events.NewEventBus())(noFlushWriter{rec}, httptest.NewRequest(http.MethodGet, "/api/students/events", nil))
+	checkError(t, rec, http.StatusInternalServerError, "INTERNAL_ERROR")
+}
@@ -0,0 +1,168 @@
+package student
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/http/middleware"
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/types"
+	"github.com/aanand-mishra/students-api/internal/utils/response"
+)
+
+// Exporter is implemented by storage backends that can stream every
+// student of a tenant as a tar.gz archive (e.g. *sqlite.SQLite).
+type Exporter interface {
+	ExportStudents(ctx context.Context, tenantID string, w io.Writer) error
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Export handles GET /api/students/export.tar.gz
+// Downloads all of the caller's tenant's students as a gzipped tar archive containing students.csv.
+//
+//	curl -o export.tar.gz http://localhost:8082/api/students/export.tar.gz
+//	tar -xzf export.tar.gz   # → students.csv
+//
+// The archive is streamed straight from the database to the client, so it
+// works for tables far too big to build in memory. Unlike the other list
+// endpoints it reads the database directly, bypassing the cache.
+//
+// Error responses:
+//
+//	500 Internal — the export could not be started
+//
+// Once the download has started, a failure can only be logged; the client
+// sees a truncated archive, which gzip rejects.
+// ─────────────────────────────────────────────────────────────────────────────
+func Export(exporter Exporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
+		log.Info("exporting students")
+
+		filename := fmt.Sprintf("students-export-%s.tar.gz", time.Now().UTC().Format("2006-01-02"))
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+		out := &startedWriter{w: w}
+		if err := exporter.ExportStudents(r.Context(), middleware.TenantFromContext(r.Context()), out); err != nil {
+			if !out.started {
+				log.Error("student export failed", slog.String("error", err.Error()))
+				w.Header().Del("Content-Disposition")
+				writeStorageError(w, err)
+				return
+			}
+			log.Error("student export interrupted", slog.String("error", err.Error()))
+			return
+		}
+
+		log.Info("students exported", slog.Int64("bytes", out.n))
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ExportCSV handles GET /api/students/export
+// Downloads the students as a plain CSV file, ready to open in Excel:
+//
+//	curl -o students.csv http://localhost:8082/api/students/export
+//	curl -o cs.csv "http://localhost:8082/api/students/export?age_min=18&sort=name"
+//
+//	id,name,email,age,created_at
+//	1,Rakesh,rakesh@test.com,35,2024-05-01T09:30:00Z
+//
+// It takes the same filter and sort parameters as GET /api/students
+// (name, email, age_min, age_max, sort, order), so part of the list can
+// be exported, but no paging: every match is in the file. Without a
+// filter it reads through the cache like the unfiltered list.
+//
+// The students are read from storage first, so they are in memory
+// either way; the CSV itself is not built up there — each row is
+// flushed to the client as soon as it is encoded. For a table too big
+// to load at all, use the tar.gz export, which streams straight from
+// the database.
+//
+// Error responses:
+//
+//	400 Bad Request — invalid filter or sort parameter
+//	500 Internal    — database error
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func ExportCSV(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
+		log.Info("exporting students as CSV")
+
+		opts, err := parseFilters(r)
+		if err != nil {
+			response.WriteJSON(w, http.StatusBadRequest, response.BadRequestError(err))
+			return
+		}
+
+		var students []types.Student
+		if opts.HasFilters() || len(opts.Sort.Fields) > 0 {
+			students, _, err = storage.GetStudentsFiltered(r.Context(), middleware.TenantFromContext(r.Context()), opts)
+		} else {
+			students, err = storage.GetStudents(r.Context(), middleware.TenantFromContext(r.Context()))
+			err = allowStale(w, r, err)
+		}
+		if err != nil {
+			log.Error("error getting students", slog.String("error", err.Error()))
+			writeStorageError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="students.csv"`)
+
+		cw := csv.NewWriter(w)
+		if err := writeCSVRow(cw, []string{"id", "name", "email", "age", "created_at"}); err != nil {
+			log.Error("student CSV export interrupted", slog.String("error", err.Error()))
+			return
+		}
+		for _, student := range students {
+			err := writeCSVRow(cw, []string{
+				strconv.Itoa(student.ID),
+				student.Name,
+				student.Email,
+				strconv.Itoa(student.Age),
+				student.CreatedAt.UTC().Format(time.RFC3339),
+			})
+			if err != nil {
+				// The status line has gone out; all that's left is to stop.
+				log.Error("student CSV export interrupted", slog.String("error", err.Error()))
+				return
+			}
+		}
+
+		log.Info("students exported as CSV", slog.Int("count", len(students)))
+	}
+}
+
+// writeCSVRow writes one record and flushes it through to the client.
+func writeCSVRow(cw *csv.Writer, record []string) error {
+	if err := cw.Write(record); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// startedWriter records whether anything has been written yet — i.e.
+// whether it is still possible to send an error status instead.
+type startedWriter struct {
+	w       io.Writer
+	started bool
+	n       int64
+}
+
+func (s *startedWriter) Write(p []byte) (int, error) {
+	s.started = true
+	n, err := s.w.Write(p)
+	s.n += int64(n)
+	return n, err
+}
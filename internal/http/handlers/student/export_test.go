@@ -0,0 +1,155 @@
+package student_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aanand-mishra/students-api/internal/http/handlers/student"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+func TestExport(t *testing.T) {
+	db := newStore(t)
+	seed(t, db, "Rakesh", "rakesh@test.com", 35)
+	priya := seed(t, db, "Priya", "priya@test.com", 22)
+	seed(t, db, "Amit", "amit@test.com", 17)
+	if err := db.DeleteStudentByID(context.Background(), types.DefaultTenant, int64(priya.ID)); err != nil {
+		t.Fatalf("DeleteStudentByID: %v", err)
+	}
+	if _, err := db.CreateStudent(context.Background(), "other", "Neha", "neha@test.com", 30, "", ""); err != nil {
+		t.Fatalf("CreateStudent: %v", err)
+	}
+
+	rec := serve(student.Export(db), request{method: http.MethodGet, target: "/api/students/export.tar.gz"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d\nbody: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/gzip" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, `attachment; filename="students-export-`) {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+
+	gz, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("body is not gzip: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatalf("reading the tar header: %v", err)
+	}
+	if hdr.Name != "students.csv" {
+		t.Errorf("archive holds %q, want students.csv", hdr.Name)
+	}
+
+	// The header's size must match the contents, or tar fails the read.
+	records, err := csv.NewReader(tr).ReadAll()
+	if err != nil {
+		t.Fatalf("students.csv is not CSV: %v", err)
+	}
+	if strings.Join(records[0], ",") != "id,name,email,age,photo_url,department,phone,created_at,updated_at" {
+		t.Errorf("header = %v", records[0])
+	}
+	var names []string
+	for _, r := range records[1:] {
+		names = append(names, r[1])
+	}
+	// In id order, without the deleted student or the other tenant's.
+	if strings.Join(names, ",") != "Rakesh,Amit" {
+		t.Errorf("names = %v, want [Rakesh Amit]", names)
+	}
+
+	if _, err := tr.Next(); err != io.EOF {
+		t.Errorf("after students.csv: %v, want the end of the archive", err)
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	db := newStore(t)
+	seed(t, db, "Rakesh", "rakesh@test.com", 35)
+	seed(t, db, "Priya", "priya@test.com", 22)
+	seed(t, db, "Amit", "amit@test.com", 17)
+
+	tests := []struct {
+		name   string
+		target string
+		want   []string // names, in order
+	}{
+		{"everyone, newest first", "/api/students/export", []string{"Amit", "Priya", "Rakesh"}},
+		{"filtered and sorted", "/api/students/export?age_min=18&sort=name", []string{"Priya", "Rakesh"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(student.ExportCSV(db), request{method: http.MethodGet, target: tt.target})
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d\nbody: %s", rec.Code, http.StatusOK, rec.Body)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			if cd := rec.Header().Get("Content-Disposition"); cd != `attachment; filename="students.csv"` {
+				t.Errorf("Content-Disposition = %q", cd)
+			}
+
+			records, err := csv.NewReader(rec.Body).ReadAll()
+			if err != nil {
+				t.Fatalf("body is not CSV: %v", err)
+			}
+			if strings.Join(records[0], ",") != "id,name,email,age,created_at" {
+				t.Errorf("header = %v", records[0])
+			}
+			var names []string
+			for _, r := range records[1:] {
+				names = append(names, r[1])
+			}
+			if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("names = %v, want %v", names, tt.want)
+			}
+		})
+	}
+
+	t.Run("bad filter", func(t *testing.T) {
+		rec := serve(student.ExportCSV(db), request{method: http.MethodGet, target: "/api/students/export?age_min=x"})
+		checkError(t, rec, http.StatusBadRequest, "BAD_REQUEST")
+	})
+	t.Run("database error", func(t *testing.T) {
+		rec := serve(student.ExportCSV(stubStorage{err: errDatabase}), request{method: http.MethodGet, target: "/api/students/export"})
+		checkError(t, rec, http.StatusInternalServerError, "INTERNAL_ERROR")
+	})
+}
+
+// failingExporter writes written, then fails.
+type failingExporter struct {
+	written string
+}
+
+func (f failingExporter) ExportStudents(_ context.Context, _ string, w io.Writer) error {
+	if f.written != "" {
+		io.WriteString(w, f.written)
+	}
+	return errors.New("disk read error")
+}
+
+func TestExportFailure(t *testing.T) {
+	// Nothing sent yet: the client gets a proper error.
+	rec := serve(student.Export(failingExporter{}), request{method: http.MethodGet, target: "/api/students/export.tar.gz"})
+	checkError(t, rec, http.StatusInternalServerError, "INTERNAL_ERROR")
+	if cd := rec.Header().Get("Content-Disposition"); cd != "" {
+		t.Errorf("Content-Disposition = %q on an error", cd)
+	}
+
+	// Part of the archive is out: all that can be done is to stop.
+	rec = serve(student.Export(failingExporter{written: "partial"}), request{method: http.MethodGet, target: "/api/students/export.tar.gz"})
+	if rec.Code != http.StatusOK || rec.Body.String() != "partial" {
+		t.Errorf("status = %d, body = %q; want the truncated archive", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,103 @@
+package student_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	apitest "github.com/aanand-mishra/students-api/internal/testing"
+)
+
+// timestampFields vary from run to run, so scrub replaces their values
+// before a body is compared with its golden file.
+var timestampFields = map[string]bool{"created_at": true, "updated_at": true, "deleted_at": true}
+
+// scrub returns resp's body with every timestamp replaced by a fixed
+// placeholder.
+func scrub(t *testing.T, resp *http.Response) []byte {
+	t.Helper()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		t.Fatalf("body is not JSON: %v\nbody: %s", err, body)
+	}
+
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			for key, field := range v {
+				if timestampFields[key] && field != nil {
+					v[key] = "[timestamp]"
+					continue
+				}
+				walk(field)
+			}
+		case []any:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	walk(value)
+
+	scrubbed, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("re-encode body: %v", err)
+	}
+	return scrubbed
+}
+
+// TestGolden pins the JSON shape of every kind of response the student
+// endpoints send. A failure means a response changed shape: if that was
+// intended, regenerate the files with
+//
+//	UPDATE_GOLDEN=true go test ./internal/http/handlers/student -run TestGolden
+//
+// and review the diff of testdata/golden before committing it.
+func TestGolden(t *testing.T) {
+	ts := apitest.NewTestServer(t)
+	rakesh := ts.CreateStudent("Rakesh", "rakesh@test.com", 35)
+	ts.CreateStudent("Priya", "priya@test.com", 22)
+	amit := ts.CreateStudent("Amit", "amit@test.com", 28)
+
+	tests := []struct {
+		name   string
+		send   func() *http.Response
+		status int
+	}{
+		{"get", func() *http.Response { return ts.GET(fmt.Sprintf("/api/students/%d", rakesh)) }, http.StatusOK},
+		{"list", func() *http.Response { return ts.GET("/api/students?page=1&per_page=2&sort=id") }, http.StatusOK},
+		{"create", func() *http.Response {
+			return ts.POST("/api/students", `{"name":"Neha","email":"neha@test.com","age":30}`)
+		}, http.StatusCreated},
+		{"update", func() *http.Response {
+			return ts.PUT(fmt.Sprintf("/api/students/%d", rakesh), `{"name":"Rakesh K","email":"rakesh@test.com","age":36,"version":1}`)
+		}, http.StatusOK},
+		{"delete", func() *http.Response { return ts.DELETE(fmt.Sprintf("/api/students/%d", amit)) }, http.StatusOK},
+		{"validation_error", func() *http.Response {
+			return ts.POST("/api/students", `{"name":"","email":"x@test.com","age":200}`)
+		}, http.StatusUnprocessableEntity},
+		{"not_found", func() *http.Response { return ts.GET("/api/students/999") }, http.StatusNotFound},
+		{"conflict", func() *http.Response {
+			return ts.POST("/api/students", `{"name":"Another Priya","email":"priya@test.com","age":23}`)
+		}, http.StatusConflict},
+	}
+
+	// The cases run in order: update and delete change what later ones see.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := tt.send()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			apitest.AssertGolden(t, tt.name, scrub(t, resp))
+		})
+	}
+}
@@ -0,0 +1,382 @@
+package student
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aanand-mishra/students-api/internal/http/middleware"
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/types"
+	"github.com/aanand-mishra/students-api/internal/utils"
+	"github.com/aanand-mishra/students-api/internal/utils/response"
+	"github.com/aanand-mishra/students-api/internal/validation"
+)
+
+// maxImportRows is the most data rows an uploaded CSV file may have.
+const maxImportRows = 10000
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Import handles POST /api/students/import
+// Creates or updates students from a CSV file sent as the request body.
+//
+// The first line is a header naming the columns, in any order:
+//
+//	name,email,age,department,phone,external_id
+//	Rakesh,rakesh@test.com,35,CS,+14155552671,SIS-1001
+//
+//	curl -X POST --data-binary @students.csv \
+//	     -H "Content-Type: text/csv" http://localhost:8082/api/students/import
+//
+// name, email and age are required; department and phone are optional.
+// external_id
+// is optional too, but it is
+// what makes an import safe to re-run: a row whose external_id already
+// exists updates that student instead of adding another one. Rows without
+// it are always inserted.
+//
+// The whole file is imported atomically — one bad row and nothing is
+// written.
+//
+// A file uploaded as multipart/form-data is handled by ImportCSV
+// instead, which imports the good rows and reports the bad ones.
+//
+// Success response (200 OK):
+//
+//	{ "created": 10, "updated": 2 }
+//
+// Error responses:
+//
+//	400 Bad Request  — not CSV or missing columns
+//	409 Conflict     — a row's email belongs to another student
+//	413 Too Large    — file larger than import.max_bytes (10 MB)
+//	422 Unprocessable — a row fails validation
+//	500 Internal     — database error
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func Import(storage storage.Storage, maxBytes int64) http.HandlerFunc {
+	importCSV := ImportCSV(storage, maxBytes)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+			importCSV(w, r)
+			return
+		}
+
+		log := logFromContext(r.Context())
+		log.Info("importing students")
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+		students, err := readImportCSV(r.Body)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				response.WriteJSON(w, http.StatusRequestEntityTooLarge,
+					response.PayloadTooLargeError(fmt.Errorf("file is larger than %s", formatSize(maxBytes))))
+				return
+			}
+			response.WriteJSON(w, http.StatusBadRequest, response.BadRequestError(err))
+			return
+		}
+
+		for i, student := range students {
+			if err := validation.Validator().Struct(student); err != nil {
+				status, resp := validationError(err)
+				// +2: rows are 1-based and line 1 is the header.
+				resp.Error = fmt.Sprintf("line %d: %s", i+2, resp.Error)
+				response.WriteJSON(w, status, resp)
+				return
+			}
+		}
+
+		results, err := storage.ImportStudents(r.Context(), middleware.TenantFromContext(r.Context()), students)
+		if err != nil {
+			log.Error("error importing students", slog.String("error", err.Error()))
+			writeStorageError(w, err)
+			return
+		}
+
+		var summary types.ImportSummary
+		for _, result := range results {
+			if result.Action == types.UpsertActionCreated {
+				summary.Created++
+			} else {
+				summary.Updated++
+			}
+		}
+
+		log.Info("students imported",
+			slog.Int("created", summary.Created),
+			slog.Int("updated", summary.Updated))
+		response.WriteJSON(w, http.StatusOK, summary)
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ImportCSV handles POST /api/students/import with a file upload
+// Creates students from a CSV file uploaded as multipart/form-data in a
+// field named "file" — what a plain HTML form sends:
+//
+//	curl -F file=@class-7b.csv http://localhost:8082/api/students/import
+//
+// The columns are the same as for Import. Unlike Import, a bad row
+// doesn't sink the file: every row is checked, the valid ones are
+// created together in one transaction (BulkCreateStudents), and the
+// others are listed with the reason. Rows are always created, never
+// updated, so external_id is ignored here.
+//
+// Success response (207 Multi-Status) — row is the line in the file:
+//
+//	{
+//	  "imported": 28,
+//	  "failed": 2,
+//	  "errors": [
+//	    { "row": 3,  "error": "age must be an integer" },
+//	    { "row": 17, "error": "a student with this email already exists" }
+//	  ]
+//	}
+//
+// Error responses:
+//
+//	400 Bad Request  — no "file" field, not CSV, or missing columns
+//	403 Forbidden    — the rows would take the number of students over
+//	                   max_students (nothing is written)
+//	413 Too Large    — file larger than import.max_bytes (10 MB), or
+//	                   more than 10 000 rows
+//	500 Internal     — database error (nothing is written)
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func ImportCSV(storage storage.Storage, maxBytes int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
+		log.Info("importing students from an uploaded CSV file")
+
+		tooLarge := func() {
+			response.WriteJSON(w, http.StatusRequestEntityTooLarge,
+				response.PayloadTooLargeError(fmt.Errorf("file is larger than %s", formatSize(maxBytes))))
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes+multipartOverhead)
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				tooLarge()
+				return
+			}
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New(`multipart field "file" is required`)))
+			return
+		}
+		defer file.Close()
+
+		if header.Size > maxBytes {
+			tooLarge()
+			return
+		}
+
+		rows, err := parseImportCSV(file, maxImportRows)
+		if err != nil {
+			if errors.Is(err, errTooManyRows) {
+				response.WriteJSON(w, http.StatusRequestEntityTooLarge, response.PayloadTooLargeError(err))
+				return
+			}
+			response.WriteJSON(w, http.StatusBadRequest, response.BadRequestError(err))
+			return
+		}
+
+		// Collect every failing row rather than stopping at the first;
+		// valid holds the others.
+		result := types.CSVImportResult{Errors: make([]types.ImportRowError, 0)}
+		fail := func(line int, err error) {
+			result.Failed++
+			result.Errors = append(result.Errors, types.ImportRowError{Row: line, Error: err.Error()})
+		}
+
+		valid := make([]importRow, 0, len(rows))
+		for _, row := range rows {
+			if row.err != nil {
+				fail(row.line, row.err)
+				continue
+			}
+			if err := validation.Validator().Struct(row.student); err != nil {
+				_, resp := validationError(err)
+				fail(row.line, errors.New(resp.Error))
+				continue
+			}
+			valid = append(valid, row)
+		}
+
+		if len(valid) > 0 {
+			batch := make([]types.Student, len(valid))
+			for i, row := range valid {
+				batch[i] = row.student
+				batch[i].ExternalID = nil
+			}
+
+			_, err := storage.BulkCreateStudents(r.Context(), middleware.TenantFromContext(r.Context()), batch)
+			failed, partial := bulkRowErrors(err)
+			if err != nil && !partial {
+				log.Error("error importing students", slog.String("error", err.Error()))
+				writeStorageError(w, err)
+				return
+			}
+
+			for i, row := range valid {
+				if rowErr := failed[i]; rowErr != nil {
+					fail(row.line, rowErr)
+					continue
+				}
+				result.Imported++
+			}
+		}
+
+		// Rows were checked in file order, but duplicates are only found
+		// afterwards; list the errors in file order too.
+		slices.SortFunc(result.Errors, func(a, b types.ImportRowError) int { return a.Row - b.Row })
+
+		log.Info("students imported from CSV file",
+			slog.Int("imported", result.Imported),
+			slog.Int("failed", result.Failed))
+		response.WriteJSON(w, http.StatusMultiStatus, result)
+	}
+}
+
+// formatSize writes a byte count for an error message, in MB when it is
+// a whole number of them.
+func formatSize(n int64) string {
+	if n%(1<<20) == 0 {
+		return fmt.Sprintf("%d MB", n>>20)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+// errTooManyRows is returned by parseImportCSV for a file over its row
+// limit.
+var errTooManyRows = fmt.Errorf("CSV file has more than %d rows", maxImportRows)
+
+// importRow is one data line of an import file: the student it holds,
+// or why it couldn't be read.
+type importRow struct {
+	line    int // line in the file; the header is line 1
+	student types.Student
+	err     error
+}
+
+// readImportCSV parses an import file into students, failing on the
+// first line that isn't a valid student record.
+func readImportCSV(body io.Reader) ([]types.Student, error) {
+	rows, err := parseImportCSV(body, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	students := make([]types.Student, 0, len(rows))
+	for _, row := range rows {
+		if row.err != nil {
+			return nil, fmt.Errorf("line %d: %w", row.line, row.err)
+		}
+		students = append(students, row.student)
+	}
+	return students, nil
+}
+
+// parseImportCSV parses an import file. Column order comes from the
+// header line; an empty external_id cell means "none".
+//
+// A line with the wrong number of fields or a non-integer age is
+// returned with err set, and parsing goes on. Only a problem with the
+// file as a whole — no header, a missing column, broken quoting, no rows,
+// more than maxRows rows (when maxRows > 0) — is returned as an error.
+func parseImportCSV(body io.Reader, maxRows int) ([]importRow, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("CSV file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"name", "email", "age"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing CSV column: %s", required)
+		}
+	}
+	externalCol, hasExternal := columns["external_id"]
+	departmentCol, hasDepartment := columns["department"]
+	phoneCol, hasPhone := columns["phone"]
+
+	rows := make([]importRow, 0)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if maxRows > 0 && len(rows) == maxRows {
+			return nil, errTooManyRows
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && errors.Is(err, csv.ErrFieldCount) {
+			rows = append(rows, importRow{
+				line: parseErr.StartLine,
+				err:  fmt.Errorf("expected %d fields, got %d", len(header), len(record)),
+			})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+
+		row := importRow{}
+		row.line, _ = reader.FieldPos(0)
+
+		age, err := strconv.Atoi(strings.TrimSpace(record[columns["age"]]))
+		if err != nil {
+			row.err = errors.New("age must be an integer")
+			rows = append(rows, row)
+			continue
+		}
+
+		row.student = types.Student{
+			Name:  strings.TrimSpace(record[columns["name"]]),
+			Email: utils.NormalizeEmail(record[columns["email"]]),
+			Age:   age,
+		}
+		if hasDepartment {
+			row.student.Department = strings.TrimSpace(record[departmentCol])
+		}
+		if hasPhone {
+			row.student.Phone = strings.TrimSpace(record[phoneCol])
+		}
+		if hasExternal {
+			if id := strings.TrimSpace(record[externalCol]); id != "" {
+				row.student.ExternalID = &id
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("CSV file has no rows")
+	}
+
+	return rows, nil
+}
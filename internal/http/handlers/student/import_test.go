@@ -0,0 +1,186 @@
+package student_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aanand-mishra/students-api/internal/http/handlers/student"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+func TestImport(t *testing.T) {
+	const header = "name,email,age,department,phone,external_id\n"
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+		want   types.ImportSummary
+	}{
+		{"created", header + "Priya,priya@test.com,22,CS,+14155552671,SIS-2\nAmit,amit@test.com,19,,,\n", http.StatusOK, "", types.ImportSummary{Created: 2}},
+		{"updated by external_id", header + "Rakesh K,rakesh@test.com,36,,,SIS-1\n", http.StatusOK, "", types.ImportSummary{Updated: 1}},
+		{"columns in any order", "age,email,name\n22,priya@test.com,Priya\n", http.StatusOK, "", types.ImportSummary{Created: 1}},
+		{"empty file", "", http.StatusBadRequest, "BAD_REQUEST", types.ImportSummary{}},
+		{"missing column", "name,email\nPriya,priya@test.com\n", http.StatusBadRequest, "BAD_REQUEST", types.ImportSummary{}},
+		{"no rows", header, http.StatusBadRequest, "BAD_REQUEST", types.ImportSummary{}},
+		{"bad age", header + "Priya,priya@test.com,old,,,\n", http.StatusBadRequest, "BAD_REQUEST", types.ImportSummary{}},
+		{"wrong field count", header + "Priya,priya@test.com\n", http.StatusBadRequest, "BAD_REQUEST", types.ImportSummary{}},
+		{"broken quoting", header + "\"Priya,priya@test.com,22,,,\n", http.StatusBadRequest, "BAD_REQUEST", types.ImportSummary{}},
+		{"invalid row", header + "Priya,priya@test.com,22,,,\n,nameless@test.com,22,,,\n", http.StatusUnprocessableEntity, "VALIDATION_ERROR", types.ImportSummary{}},
+		{"email taken", header + "Other,rakesh@test.com,22,,,\n", http.StatusConflict, "DUPLICATE_ENTRY", types.ImportSummary{}},
+		{"too large", header + strings.Repeat("Priya,priya@test.com,22,,,\n", 100), http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE", types.ImportSummary{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newStore(t)
+			externalID := "SIS-1"
+			if _, err := db.ImportStudents(context.Background(), types.DefaultTenant, []types.Student{
+				{Name: "Rakesh", Email: "rakesh@test.com", Age: 35, ExternalID: &externalID},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			rec := serve(student.Import(db, 1024), request{method: http.MethodPost, target: "/api/students/import", body: tt.body,
+				headers: map[string]string{"Content-Type": "text/csv"}})
+
+			if tt.code != "" {
+				checkError(t, rec, tt.status, tt.code)
+				return
+			}
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d\nbody: %s", rec.Code, tt.status, rec.Body)
+			}
+			var got types.ImportSummary
+			json.Unmarshal(rec.Body.Bytes(), &got)
+			if got != tt.want {
+				t.Errorf("summary = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportRerun(t *testing.T) {
+	db := newStore(t)
+	importCSV := func(body string) types.ImportSummary {
+		t.Helper()
+		rec := serve(student.Import(db, 1<<20), request{method: http.MethodPost, target: "/api/students/import", body: body,
+			headers: map[string]string{"Content-Type": "text/csv"}})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d\nbody: %s", rec.Code, http.StatusOK, rec.Body)
+		}
+		var summary types.ImportSummary
+		json.Unmarshal(rec.Body.Bytes(), &summary)
+		return summary
+	}
+
+	const file = "name,email,age,external_id\nPriya,priya@test.com,22,SIS-1\nAmit,amit@test.com,19,SIS-2\n"
+	if got := importCSV(file); got != (types.ImportSummary{Created: 2}) {
+		t.Errorf("first run = %+v, want 2 created", got)
+	}
+	// The same file again changes nothing but is still accepted.
+	if got := importCSV(file); got != (types.ImportSummary{Updated: 2}) {
+		t.Errorf("second run = %+v, want 2 updated", got)
+	}
+	// A corrected file updates the rows it matches, by external_id.
+	if got := importCSV("name,email,age,external_id\nPriya S,priya.s@test.com,23,SIS-1\n"); got != (types.ImportSummary{Updated: 1}) {
+		t.Errorf("corrected run = %+v, want 1 updated", got)
+	}
+
+	students, err := db.GetStudents(context.Background(), types.DefaultTenant)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(students) != 2 {
+		t.Fatalf("%d students after three imports, want 2", len(students))
+	}
+	priya, err := db.GetStudentByExternalID(context.Background(), types.DefaultTenant, "SIS-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if priya.Name != "Priya S" || priya.Email != "priya.s@test.com" || priya.Age != 23 {
+		t.Errorf("SIS-1 = %+v, want the corrected values", priya)
+	}
+}
+
+// csvUpload builds a multipart/form-data request uploading content in
+// field.
+func csvUpload(t *testing.T, field, content string) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile(field, "students.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte(content))
+	mw.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/api/students/import", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestImportCSVUpload(t *testing.T) {
+	db := newStore(t)
+	seed(t, db, "Rakesh", "rakesh@test.com", 35)
+
+	r := csvUpload(t, "file", "name,email,age\n"+
+		"Priya,priya@test.com,22\n"+
+		"Amit,amit@test.com,old\n"+
+		"Other,RAKESH@test.com,40\n"+
+		",nameless@test.com,20\n"+
+		"Short,row\n"+
+		"Neha,neha@test.com,19\n")
+	rec := httptest.NewRecorder()
+	student.Import(db, 1<<20)(rec, r)
+
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("status = %d, want %d\nbody: %s", rec.Code, http.StatusMultiStatus, rec.Body)
+	}
+	var got types.CSVImportResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Imported != 2 || got.Failed != 4 {
+		t.Errorf("imported %d, failed %d; want 2 and 4", got.Imported, got.Failed)
+	}
+	var rows []int
+	for _, e := range got.Errors {
+		rows = append(rows, e.Row)
+		if e.Error == "" {
+			t.Errorf("row %d has no error message", e.Row)
+		}
+	}
+	if len(rows) != 4 || rows[0] != 3 || rows[1] != 4 || rows[2] != 5 || rows[3] != 6 {
+		t.Errorf("failed rows = %v, want [3 4 5 6] in file order", rows)
+	}
+
+	failures := []struct {
+		name    string
+		field   string
+		content string
+		max     int64
+		status  int
+		code    string
+	}{
+		{"no file field", "upload", "name,email,age\n", 1 << 20, http.StatusBadRequest, "BAD_REQUEST"},
+		{"missing column", "file", "name,age\nPriya,22\n", 1 << 20, http.StatusBadRequest, "BAD_REQUEST"},
+		{"file too large", "file", "name,email,age\n" + strings.Repeat("Priya,priya@test.com,22\n", 100), 1024, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE"},
+	}
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			student.ImportCSV(db, tt.max)(rec, csvUpload(t, tt.field, tt.content))
+			checkError(t, rec, tt.status, tt.code)
+		})
+	}
+}
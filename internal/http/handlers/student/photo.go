@@ -0,0 +1,170 @@
+package student
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/aanand-mishra/students-api/internal/http/middleware"
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/utils/response"
+)
+
+// maxPhotoBytes is the largest photo we accept (5 MB).
+const maxPhotoBytes = 5 << 20
+
+// multipartOverhead is extra room allowed on top of maxPhotoBytes for the
+// multipart boundaries and part headers surrounding the file itself.
+const multipartOverhead = 64 << 10
+
+// Magic numbers — the first bytes every file of that format starts with.
+// We trust these rather than the file extension or the client-supplied
+// Content-Type, both of which are trivial to fake.
+var (
+	jpegMagic = []byte{0xFF, 0xD8, 0xFF}
+	pngMagic  = []byte{0x89, 'P', 'N', 'G'}
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// UploadPhoto handles POST /api/students/{id}/photo
+// Stores a JPEG or PNG profile photo for an existing student.
+//
+// Request: multipart/form-data with the image in a field named "photo".
+//
+//	curl -F photo=@me.jpg http://localhost:8082/api/students/1/photo
+//
+// The file is saved as <photoDir>/<id>.jpg (or .png), replacing any
+// previous photo, and is then served from GET /photos/<id>.jpg.
+//
+// Success response (200 OK):
+//
+//	{ "photo_url": "/photos/1.jpg" }
+//
+// Error responses:
+//
+//	400 Bad Request  — invalid id, missing "photo" field, or not a JPEG/PNG
+//	413 Too Large    — file larger than 5 MB
+//	500 Internal     — student not found, file system or database error
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func UploadPhoto(storage storage.Storage, photoDir string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
+		id := r.PathValue("id")
+		log.Info("uploading student photo", slog.String("id", id))
+
+		intID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New("invalid id: must be an integer")))
+			return
+		}
+
+		// Make sure the student exists before we write anything to disk.
+		if _, err := storage.GetStudentByID(r.Context(), middleware.TenantFromContext(r.Context()), intID); err != nil {
+			writeStorageError(w, err)
+			return
+		}
+
+		// MaxBytesReader makes reads fail once the body exceeds the limit,
+		// so a huge upload is rejected without being buffered in full.
+		r.Body = http.MaxBytesReader(w, r.Body, maxPhotoBytes+multipartOverhead)
+
+		file, header, err := r.FormFile("photo")
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				response.WriteJSON(w, http.StatusRequestEntityTooLarge,
+					response.PayloadTooLargeError(errors.New("photo must be 5 MB or smaller")))
+				return
+			}
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New(`multipart field "photo" is required`)))
+			return
+		}
+		defer file.Close()
+
+		if header.Size > maxPhotoBytes {
+			response.WriteJSON(w, http.StatusRequestEntityTooLarge,
+				response.PayloadTooLargeError(errors.New("photo must be 5 MB or smaller")))
+			return
+		}
+
+		// Sniff the first 4 bytes to decide the real format.
+		magic := make([]byte, 4)
+		if _, err := io.ReadFull(file, magic); err != nil {
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New("photo must be a JPEG or PNG image")))
+			return
+		}
+
+		var ext string
+		switch {
+		case bytes.HasPrefix(magic, jpegMagic):
+			ext = ".jpg"
+		case bytes.HasPrefix(magic, pngMagic):
+			ext = ".png"
+		default:
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New("photo must be a JPEG or PNG image")))
+			return
+		}
+
+		filename := fmt.Sprintf("%d%s", intID, ext)
+		if err := savePhoto(photoDir, filename, io.MultiReader(bytes.NewReader(magic), file)); err != nil {
+			log.Error("error saving student photo",
+				slog.String("id", id),
+				slog.String("error", err.Error()))
+			response.WriteJSON(w, http.StatusInternalServerError,
+				response.GeneralError(err))
+			return
+		}
+
+		// A student has at most one photo: remove one in the other format.
+		for _, other := range []string{".jpg", ".png"} {
+			if other != ext {
+				os.Remove(filepath.Join(photoDir, fmt.Sprintf("%d%s", intID, other)))
+			}
+		}
+
+		photoURL := "/photos/" + filename
+		if err := storage.SetStudentPhotoURL(r.Context(), middleware.TenantFromContext(r.Context()), intID, photoURL); err != nil {
+			writeStorageError(w, err)
+			return
+		}
+
+		log.Info("student photo uploaded", slog.String("id", id),
+			slog.String("photo_url", photoURL))
+		response.WriteJSON(w, http.StatusOK, map[string]string{"photo_url": photoURL})
+	}
+}
+
+// savePhoto writes src to dir/filename via a temporary file and a rename,
+// so a failed or partial upload never replaces an existing photo.
+func savePhoto(dir, filename string, src io.Reader) error {
+	tmp, err := os.CreateTemp(dir, filename+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("savePhoto: create temp: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once the rename succeeded
+
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return fmt.Errorf("savePhoto: write: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("savePhoto: close: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, filename)); err != nil {
+		return fmt.Errorf("savePhoto: rename: %w", err)
+	}
+
+	return nil
+}
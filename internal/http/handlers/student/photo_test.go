@@ -0,0 +1,129 @@
+package student_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aanand-mishra/students-api/internal/http/handlers/student"
+	apitest "github.com/aanand-mishra/students-api/internal/testing"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// multipartPhoto builds a multipart body with content in a file field.
+func multipartPhoto(t *testing.T, field, filename string, content []byte) (*bytes.Buffer, string) {
+	t.Helper()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile(field, filename)
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	part.Write(content)
+	mw.Close()
+
+	return &body, mw.FormDataContentType()
+}
+
+// newPhotoServer starts a test server that also serves the photo upload
+// route, saving photos to dir. The route is built on first use, once the
+// server's storage exists.
+func newPhotoServer(t *testing.T, dir string) *apitest.TestServer {
+	var ts *apitest.TestServer
+	ts = apitest.NewTestServer(t, apitest.WithRoute("POST /api/students/{id}/photo",
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			student.UploadPhoto(ts.Storage, dir)(w, r)
+		})))
+	return ts
+}
+
+func TestUploadPhoto(t *testing.T) {
+	jpeg := append([]byte{0xFF, 0xD8, 0xFF, 0xE0}, make([]byte, 1024)...)
+	png := append([]byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}, make([]byte, 1024)...)
+
+	tests := []struct {
+		name     string
+		field    string
+		filename string
+		content  []byte
+		badID    bool
+		want     int
+		wantFile string
+	}{
+		{"valid JPEG", "photo", "me.jpg", jpeg, false, http.StatusOK, "%d.jpg"},
+		{"valid PNG", "photo", "me.png", png, false, http.StatusOK, "%d.png"},
+		{"oversized", "photo", "big.jpg", append(jpeg, make([]byte, 5<<20)...), false, http.StatusRequestEntityTooLarge, ""},
+		{"text disguised as JPEG", "photo", "fake.jpg", []byte("not an image at all"), false, http.StatusBadRequest, ""},
+		{"too short to sniff", "photo", "tiny.jpg", []byte{0xFF}, false, http.StatusBadRequest, ""},
+		{"wrong field", "image", "me.jpg", jpeg, false, http.StatusBadRequest, ""},
+		{"unknown student", "photo", "me.jpg", jpeg, true, http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			ts := newPhotoServer(t, dir)
+			id := ts.CreateStudent("Rakesh", "rakesh@test.com", 35)
+			if tt.badID {
+				id = 999
+			}
+
+			body, contentType := multipartPhoto(t, tt.field, tt.filename, tt.content)
+			resp, err := ts.Server.Client().Post(fmt.Sprintf("%s/api/students/%d/photo", ts.Server.URL, id), contentType, body)
+			if err != nil {
+				t.Fatalf("POST: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+
+			if resp.StatusCode == http.StatusRequestEntityTooLarge {
+				var body map[string]string
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body["error_code"] != "PAYLOAD_TOO_LARGE" {
+					t.Errorf("error_code = %q (%v), want PAYLOAD_TOO_LARGE", body["error_code"], err)
+				}
+			}
+
+			entries, _ := os.ReadDir(dir)
+			if tt.wantFile == "" {
+				if len(entries) != 0 {
+					t.Errorf("photo directory has %d files after a rejected upload, want 0", len(entries))
+				}
+				return
+			}
+
+			filename := fmt.Sprintf(tt.wantFile, id)
+			var got map[string]string
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if got["photo_url"] != "/photos/"+filename {
+				t.Errorf("photo_url = %q, want /photos/%s", got["photo_url"], filename)
+			}
+			saved, err := os.ReadFile(filepath.Join(dir, filename))
+			if err != nil {
+				t.Fatalf("read saved photo: %v", err)
+			}
+			if !bytes.Equal(saved, tt.content) {
+				t.Error("saved photo differs from the upload")
+			}
+
+			s, err := ts.Storage.GetStudentByID(context.Background(), types.DefaultTenant, id)
+			if err != nil {
+				t.Fatalf("GetStudentByID: %v", err)
+			}
+			if s.PhotoURL != got["photo_url"] {
+				t.Errorf("stored photo_url = %q, want %q", s.PhotoURL, got["photo_url"])
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package student
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aanand-mishra/students-api/internal/http/middleware"
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/types"
+	"github.com/aanand-mishra/students-api/internal/utils/response"
+)
+
+// statusRequest is the body of PATCH /api/students/{id}/status.
+type statusRequest struct {
+	Status string `json:"status"`
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// SetStatus handles PATCH /api/students/{id}/status
+// Marks a student active, inactive or suspended — e.g. to suspend one
+// without deleting their record. The change is audited like any other
+// update.
+//
+// Request body (JSON):
+//
+//	{ "status": "suspended" }
+//
+// Success response (200 OK) — the updated student:
+//
+//	{ "id": 1, "name": "Rakesh", ..., "status": "suspended", "version": 4 }
+//
+// Error responses:
+//
+//	400 Bad Request  — invalid id, empty body, malformed JSON, or a status
+//	                   other than active, inactive or suspended
+//	404 Not Found    — no student with this id
+//	413 Too Large    — body over the configured limit
+//	500 Internal     — database error
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func SetStatus(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
+		id := r.PathValue("id")
+		log.Info("setting student status", slog.String("id", id))
+
+		intID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New("invalid id: must be an integer")))
+			return
+		}
+
+		var req statusRequest
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if errors.Is(err, io.EOF) {
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New("request body is empty")))
+			return
+		}
+		if err != nil {
+			writeDecodeError(w, err)
+			return
+		}
+
+		if !slices.Contains(types.StudentStatuses, req.Status) {
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(fmt.Errorf("status must be one of: %s",
+					strings.Join(types.StudentStatuses, ", "))))
+			return
+		}
+
+		if err := storage.SetStudentStatus(r.Context(), middleware.TenantFromContext(r.Context()), intID, req.Status); err != nil {
+			log.Error("error setting student status",
+				slog.String("id", id),
+				slog.String("error", err.Error()))
+			writeStorageError(w, err)
+			return
+		}
+
+		student, err := storage.GetStudentByID(r.Context(), middleware.TenantFromContext(r.Context()), intID)
+		if err != nil {
+			log.Error("error getting student after status change",
+				slog.String("id", id),
+				slog.String("error", err.Error()))
+			writeStorageError(w, err)
+			return
+		}
+
+		log.Info("student status set",
+			slog.String("id", id),
+			slog.String("status", req.Status))
+		response.WriteJSON(w, http.StatusOK, student)
+	}
+}
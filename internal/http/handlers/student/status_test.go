@@ -0,0 +1,57 @@
+package student_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aanand-mishra/students-api/internal/http/handlers/student"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+func TestSetStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     string // "" for the seeded student
+		body   string
+		status int
+		code   string
+	}{
+		{"suspended", "", `{"status":"suspended"}`, http.StatusOK, ""},
+		{"invalid id", "abc", `{"status":"active"}`, http.StatusBadRequest, "BAD_REQUEST"},
+		{"empty body", "", ``, http.StatusBadRequest, "BAD_REQUEST"},
+		{"malformed JSON", "", `{"status"`, http.StatusBadRequest, "BAD_REQUEST"},
+		{"unknown status", "", `{"status":"expelled"}`, http.StatusBadRequest, "BAD_REQUEST"},
+		{"not found", "999", `{"status":"inactive"}`, http.StatusNotFound, "NOT_FOUND"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newStore(t)
+			rakesh := seed(t, db, "Rakesh", "rakesh@test.com", 35)
+
+			id := tt.id
+			if id == "" {
+				id = fmt.Sprint(rakesh.ID)
+			}
+			rec := serve(student.SetStatus(db), request{method: http.MethodPatch, target: "/api/students/" + id + "/status", id: id, body: tt.body})
+
+			if tt.code != "" {
+				checkError(t, rec, tt.status, tt.code)
+				return
+			}
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d\nbody: %s", rec.Code, tt.status, rec.Body)
+			}
+			if body := decode(t, rec); body["status"] != types.StudentStatusSuspended || body["version"] != float64(2) {
+				t.Errorf("body = %v, want status suspended at version 2", body)
+			}
+
+			stored, _ := db.GetStudentByID(context.Background(), types.DefaultTenant, int64(rakesh.ID))
+			if stored.Status != types.StudentStatusSuspended {
+				t.Errorf("stored status = %q, want suspended", stored.Status)
+			}
+		})
+	}
+}
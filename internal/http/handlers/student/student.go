@@ -23,16 +23,29 @@
 package student
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"math"
+	"mime"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 
+	"github.com/aanand-mishra/students-api/internal/http/mergepatch"
+	"github.com/aanand-mishra/students-api/internal/http/middleware"
+	"github.com/aanand-mishra/students-api/internal/http/middleware/bodylimit"
+	"github.com/aanand-mishra/students-api/internal/http/middleware/requestid"
 	"github.com/aanand-mishra/students-api/internal/storage"
 	"github.com/aanand-mishra/students-api/internal/types"
+	"github.com/aanand-mishra/students-api/internal/utils"
 	"github.com/aanand-mishra/students-api/internal/utils/response"
+	"github.com/aanand-mishra/students-api/internal/validation"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -50,7 +63,11 @@ import (
 //
 // Error responses:
 //
-//	400 Bad Request  — empty body, malformed JSON, or failed validation
+//	400 Bad Request  — empty body or malformed JSON
+//	403 Forbidden    — the maximum number of students (max_students) is
+//	                   reached
+//	409 Conflict     — another student already uses this email
+//	422 Unprocessable — failed validation, one entry per field in "errors"
 //	500 Internal     — database error
 //
 // ─────────────────────────────────────────────────────────────────────────────
@@ -59,9 +76,10 @@ func New(storage storage.Storage) http.HandlerFunc {
 	// It captures `storage` in the closure below.
 
 	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
 		// Structured log: every request gets an Info log so we can trace
 		// activity in production logs.
-		slog.Info("creating a student")
+		log.Info("creating a student")
 
 		// ── Step 1: Decode JSON body into a Student struct ────────────
 		var student types.Student
@@ -74,40 +92,43 @@ func New(storage storage.Storage) http.HandlerFunc {
 		if errors.Is(err, io.EOF) {
 			// io.EOF means the body was completely empty — nothing to decode.
 			response.WriteJSON(w, http.StatusBadRequest,
-				response.GeneralError(errors.New("request body is empty")))
+				response.BadRequestError(errors.New("request body is empty")))
 			return // stop further processing
 		}
 
 		if err != nil {
-			// Any other decode error: malformed JSON, wrong types, etc.
-			response.WriteJSON(w, http.StatusBadRequest, response.GeneralError(err))
+			// Any other decode error: malformed JSON, wrong types, or a
+			// body over the size limit (see writeDecodeError).
+			writeDecodeError(w, err)
 			return
 		}
 
+		// Emails are case-insensitive: store and validate the canonical form.
+		student.Email = utils.NormalizeEmail(student.Email)
+
 		// ── Step 2: Validate the decoded struct ───────────────────────
-		// validator.New().Struct(v) checks all validate:"..." tags on v.
+		// Struct(v) checks v against its validate:"..." tags and the
+		// configured required fields (see the validation package).
 		// It returns nil if everything is valid, or a ValidationErrors
 		// (which implements the error interface) if any rule fails.
-		if err := validator.New().Struct(student); err != nil {
-			// Type-assert the error to ValidationErrors so we can inspect
-			// each individual field error (field name, broken tag, etc.).
-			validateErrs := err.(validator.ValidationErrors)
-			response.WriteJSON(w, http.StatusBadRequest,
-				response.ValidationError(validateErrs))
+		if err := validation.Validator().Struct(student); err != nil {
+			// validationError picks out each individual field error
+			// (field name, broken tag, etc.) for the 422 response.
+			status, resp := validationError(err)
+			response.WriteJSON(w, status, resp)
 			return
 		}
 
 		// ── Step 3: Persist to database ───────────────────────────────
 		// We call the Storage interface method — not SQLite directly.
 		// This keeps the handler database-agnostic.
-		lastID, err := storage.CreateStudent(student.Name, student.Email, student.Age)
+		lastID, err := storage.CreateStudent(r.Context(), middleware.TenantFromContext(r.Context()), student.Name, student.Email, student.Age, student.Department, student.Phone)
 		if err != nil {
-			response.WriteJSON(w, http.StatusInternalServerError,
-				response.GeneralError(err))
+			writeStorageError(w, err)
 			return
 		}
 
-		slog.Info("student created", slog.Int64("id", lastID))
+		log.Info("student created", slog.Int64("id", lastID))
 
 		// ── Step 4: Return 201 Created with the new student's ID ──────
 		// map[string]int64 encodes to: {"id": 1}
@@ -125,19 +146,26 @@ func New(storage storage.Storage) http.HandlerFunc {
 //
 //	{ "id": 1, "name": "Rakesh", "email": "rakesh@test.com", "age": 35 }
 //
+// The response carries an ETag (a hash of the student) and Last-Modified
+// (their updated_at). A client that sends either back, as If-None-Match or
+// If-Modified-Since, gets 304 Not Modified with no body while the student
+// is unchanged — so polling for changes costs almost nothing.
+//
 // Error responses:
 //
 //	400 Bad Request  — id is not a valid integer
-//	500 Internal     — database error or student not found
+//	404 Not Found    — no student with that id
+//	500 Internal     — database error
 //
 // ─────────────────────────────────────────────────────────────────────────────
 func GetByID(storage storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
 		// r.PathValue("id") extracts the {id} segment from the URL.
 		// This works because Go 1.22+ supports named path parameters in
 		// the ServeMux pattern: "GET /api/students/{id}"
 		id := r.PathValue("id")
-		slog.Info("getting a student", slog.String("id", id))
+		log.Info("getting a student", slog.String("id", id))
 
 		// The URL gives us a string; the database needs int64.
 		// strconv.ParseInt(s, base, bitSize) converts string → int64.
@@ -146,17 +174,26 @@ func GetByID(storage storage.Storage) http.HandlerFunc {
 		if err != nil {
 			// The client sent something like "/api/students/abc"
 			response.WriteJSON(w, http.StatusBadRequest,
-				response.GeneralError(errors.New("invalid id: must be an integer")))
+				response.BadRequestError(errors.New("invalid id: must be an integer")))
 			return
 		}
 
-		student, err := storage.GetStudentByID(intID)
+		student, err := storage.GetStudentByID(r.Context(), middleware.TenantFromContext(r.Context()), intID)
+		err = allowStale(w, r, err)
 		if err != nil {
-			slog.Error("error getting student",
+			log.Error("error getting student",
 				slog.String("id", id),
 				slog.String("error", err.Error()))
-			response.WriteJSON(w, http.StatusInternalServerError,
-				response.GeneralError(err))
+			// A missing student is 404, anything else 500 (or 503 when
+			// the database is down).
+			writeStorageError(w, err)
+			return
+		}
+
+		// If the tag can't be computed, fall through and send the
+		// student anyway — conditional GET is only an optimisation.
+		etag, err := response.ETag(student)
+		if err == nil && response.CheckNotModified(w, r, etag, student.UpdatedAt) {
 			return
 		}
 
@@ -165,27 +202,440 @@ func GetByID(storage storage.Storage) http.HandlerFunc {
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
-// GetList handles GET /api/students
-// Returns a JSON array of all students in the database.
+// GetByExternalID handles GET /api/students/external/{external_id}
+// Fetches a student by the ID another system knows them by (see Import).
 //
 // Success response (200 OK):
 //
-//	[
-//	  { "id": 1, "name": "Rakesh", ... },
-//	  { "id": 2, "name": "Priya",  ... }
-//	]
+//	{ "id": 1, "name": "Rakesh", "email": "rakesh@test.com", "age": 35,
+//	  "external_id": "SIS-1001" }
+//
+// Error responses:
+//
+//	404 Not Found    — no student has that external ID
+//	500 Internal     — database error
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func GetByExternalID(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
+		externalID := r.PathValue("external_id")
+		log.Info("getting a student by external id", slog.String("external_id", externalID))
+
+		student, err := storage.GetStudentByExternalID(r.Context(), middleware.TenantFromContext(r.Context()), externalID)
+		if err != nil {
+			log.Error("error getting student",
+				slog.String("external_id", externalID),
+				slog.String("error", err.Error()))
+			writeStorageError(w, err)
+			return
+		}
+
+		response.WriteJSON(w, http.StatusOK, student)
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// GetByEmail handles GET /api/students/by-email?email=...
+// Fetches the student with that email, ignoring case, for clients that
+// know their users by email rather than by our id:
+//
+//	curl "http://localhost:8082/api/students/by-email?email=rakesh@test.com"
+//
+// Success response (200 OK):
+//
+//	{ "id": 1, "name": "Rakesh", "email": "rakesh@test.com", "age": 35 }
+//
+// Error responses:
+//
+//	400 Bad Request  — email is missing or blank
+//	404 Not Found    — no student has that email
+//	500 Internal     — database error
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func GetByEmail(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
+		// Not logged: an email address is personal data.
+		log.Info("getting a student by email")
+
+		email := utils.NormalizeEmail(r.URL.Query().Get("email"))
+		if email == "" {
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New("query parameter email is required")))
+			return
+		}
+
+		student, err := storage.GetStudentByEmail(r.Context(), middleware.TenantFromContext(r.Context()), email)
+		if err != nil {
+			log.Error("error getting student by email", slog.String("error", err.Error()))
+			writeStorageError(w, err)
+			return
+		}
+
+		response.WriteJSON(w, http.StatusOK, student)
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Stats handles GET /api/students/stats
+// Returns summary figures for dashboards, without listing any students.
+//
+// Success response (200 OK):
+//
+//	{ "total": 42, "avg_age": 21.5, "min_age": 17, "max_age": 35 }
+//
+// Students without an age count towards total only.
+//
+// Error responses:
+//
+//	500 Internal     — database error
+//	503 Unavailable  — the database can't be reached
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func Stats(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
+		log.Info("getting student stats")
+
+		stats, err := storage.GetStudentStats(r.Context(), middleware.TenantFromContext(r.Context()))
+		if err != nil {
+			log.Error("error getting student stats", slog.String("error", err.Error()))
+			writeStorageError(w, err)
+			return
+		}
+
+		response.WriteJSON(w, http.StatusOK, stats)
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// GetRandom handles GET /api/students/random
+// Returns one randomly chosen student — handy for demos and smoke tests
+// that don't know any ids in advance.
+//
+// Success response (200 OK):
+//
+//	{ "id": 7, "name": "Priya", "email": "priya@test.com", "age": 22 }
+//
+// Error responses:
+//
+//	404 Not Found    — there are no students
+//	500 Internal     — database error
+//
+// The response is marked Cache-Control: no-store so caches and CDNs never
+// serve the same "random" student twice.
+// ─────────────────────────────────────────────────────────────────────────────
+func GetRandom(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
+		log.Info("getting a random student")
+
+		w.Header().Set("Cache-Control", "no-store")
+
+		student, err := storage.GetRandomStudent(r.Context(), middleware.TenantFromContext(r.Context()))
+		if err != nil {
+			writeStorageError(w, err)
+			return
+		}
+
+		response.WriteJSON(w, http.StatusOK, student)
+	}
+}
+
+// Page sizes for GetList when the client doesn't pick one.
+const (
+	defaultPage    = 1
+	defaultPerPage = 20
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// GetList handles GET /api/students?page=1&per_page=20
+// Returns one page of students, newest first (by created_at, then id,
+// both descending), with the total count.
+//
+// Success response (200 OK):
+//
+//	{
+//	  "data": [
+//	    { "id": 1, "name": "Rakesh", ... },
+//	    { "id": 2, "name": "Priya",  ... }
+//	  ],
+//	  "total": 243,
+//	  "page": 1,
+//	  "per_page": 20
+//	}
+//
+// "data" is an empty array [] (not null) past the last page. A missing or
+// invalid page or per_page falls back to page 1 / 20 per page.
+//
+// Optional filters narrow the list; "total" then counts the matches:
+//
+//	?name=rak          name contains "rak" (case-insensitive)
+//	?email=test.com    email contains "test.com"
+//	?age_min=18        age >= 18
+//	?age_max=25        age <= 25
+//
+// ?sort= orders the list by one or more of id, name, email, age,
+// created_at and updated_at, most significant first; ?order= gives asc
+// (the default) or desc for each, in the same order:
+//
+//	?sort=age,name&order=desc,asc   oldest first, then by name
+//
+// Students that tie on every sort column are in id order. Without
+// ?sort= the newest students come first.
+//
+// Pages can also be fetched by cursor, which stays consistent while
+// students are added or deleted between requests (an offset shifts).
+// In id order (?sort=id), every page carries "next_cursor" and
+// "prev_cursor" when there is such a page; pass them back as ?after=
+// and ?before=:
+//
+//	?after=MjA&per_page=20     the 20 students after the cursor
+//	?before=MjE&per_page=20    the 20 students before it
+//
+// A cursor page has no "page" field, and "total" still counts every
+// match. Cursors page in id order, so they can't be combined with
+// ?sort=, with ?page= or with each other.
+//
+// Every page also comes with an X-Total-Count header and a Link header
+// to the first, previous, next and last pages (see
+// response.WritePaginatedJSON).
+//
+// Query parameter ?format=jsonl switches the body to newline-delimited
+// JSON (Content-Type: application/x-ndjson), one student per line. That
+// format is meant for bulk reads, so it returns every (matching) student,
+// unpaged.
+//
+// Error responses:
+//
+//	400 Bad Request — per_page above maxPerPage, non-integer age filter,
+//	                  unknown sort column or order, a filter or sort
+//	                  the storage can't apply, an invalid cursor, or a
+//	                  cursor together with page, sort or another cursor
+//	500 Internal    — database error
 //
-// Returns an empty array [] (not null) when there are no students.
 // ─────────────────────────────────────────────────────────────────────────────
-func GetList(storage storage.Storage) http.HandlerFunc {
+func GetList(storage storage.Storage, maxPerPage int) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		slog.Info("getting all students")
+		log := logFromContext(r.Context())
+		log.Info("getting all students")
 
-		students, err := storage.GetStudents()
+		opts, err := parseFilters(r)
 		if err != nil {
-			slog.Error("error getting students", slog.String("error", err.Error()))
-			response.WriteJSON(w, http.StatusInternalServerError,
-				response.GeneralError(err))
+			response.WriteJSON(w, http.StatusBadRequest, response.BadRequestError(err))
+			return
+		}
+
+		if r.URL.Query().Get("format") == "jsonl" {
+			var students []types.Student
+			if opts.HasFilters() || len(opts.Sort.Fields) > 0 {
+				students, _, err = storage.GetStudentsFiltered(r.Context(), middleware.TenantFromContext(r.Context()), opts)
+			} else {
+				// Unfiltered, the cached full list can be used.
+				students, err = storage.GetStudents(r.Context(), middleware.TenantFromContext(r.Context()))
+				err = allowStale(w, r, err)
+			}
+			if err != nil {
+				log.Error("error getting students", slog.String("error", err.Error()))
+				writeStorageError(w, err)
+				return
+			}
+			response.WriteJSONL(w, students)
+			return
+		}
+
+		perPage := positiveQueryInt(r, "per_page", defaultPerPage)
+		if perPage > maxPerPage {
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(fmt.Errorf("per_page must be at most %d", maxPerPage)))
+			return
+		}
+
+		if query := r.URL.Query(); query.Has("after") || query.Has("before") {
+			if err := parseCursor(r, &opts); err != nil {
+				response.WriteJSON(w, http.StatusBadRequest, response.BadRequestError(err))
+				return
+			}
+			// One row more than the page shows whether there is another
+			// page beyond it.
+			opts.Limit = perPage + 1
+
+			students, total, err := storage.GetStudentsFiltered(r.Context(), middleware.TenantFromContext(r.Context()), opts)
+			if err != nil {
+				log.Error("error getting students", slog.String("error", err.Error()))
+				writeStorageError(w, err)
+				return
+			}
+
+			result := cursorPage(students, total, perPage, opts.AfterID > 0)
+			response.WritePaginatedJSON(w, http.StatusOK, result, response.PaginationMeta{
+				URL:        r.URL,
+				Total:      total,
+				PerPage:    perPage,
+				NextCursor: result.NextCursor,
+				PrevCursor: result.PrevCursor,
+				// Before an id no student has: the last perPage students.
+				LastCursor: encodeCursor(math.MaxInt),
+			})
+			return
+		}
+
+		page := positiveQueryInt(r, "page", defaultPage)
+		// Keeps the offset below from overflowing.
+		if page > math.MaxInt32/perPage {
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New("page is out of range")))
+			return
+		}
+		opts.Offset = (page - 1) * perPage
+		opts.Limit = perPage
+
+		students, total, err := storage.GetStudentsFiltered(r.Context(), middleware.TenantFromContext(r.Context()), opts)
+		if err != nil {
+			log.Error("error getting students", slog.String("error", err.Error()))
+			writeStorageError(w, err)
+			return
+		}
+
+		result := types.StudentPage{
+			Data:    students,
+			Total:   total,
+			Page:    page,
+			PerPage: perPage,
+		}
+		// An offset page in id order can hand over to cursors.
+		if inIDOrder(opts.Sort) && len(students) > 0 {
+			if page > 1 {
+				result.PrevCursor = encodeCursor(students[0].ID)
+			}
+			if int64(opts.Offset+len(students)) < total {
+				result.NextCursor = encodeCursor(students[len(students)-1].ID)
+			}
+		}
+		response.WritePaginatedJSON(w, http.StatusOK, result, response.PaginationMeta{
+			URL:     r.URL,
+			Total:   total,
+			Page:    page,
+			PerPage: perPage,
+		})
+	}
+}
+
+// inIDOrder reports whether sort is ascending id order, the order
+// cursors page in.
+func inIDOrder(sort types.SortOptions) bool {
+	return len(sort.Fields) == 1 && sort.Fields[0].Key == "id" && !sort.Fields[0].Desc
+}
+
+// parseCursor reads ?after= or ?before= into opts, refusing the
+// combinations that make no sense with a cursor.
+func parseCursor(r *http.Request, opts *types.FilterOptions) error {
+	query := r.URL.Query()
+	switch {
+	case query.Has("page"):
+		return errors.New("page and after/before can't be used together")
+	case query.Has("after") && query.Has("before"):
+		return errors.New("after and before can't be used together")
+	case len(opts.Sort.Fields) > 0:
+		return errors.New("cursors page in id order: sort can't be used with after/before")
+	}
+
+	var err error
+	if query.Has("after") {
+		opts.AfterID, err = decodeCursor(query.Get("after"))
+	} else {
+		opts.BeforeID, err = decodeCursor(query.Get("before"))
+	}
+	return err
+}
+
+// cursorPage turns the students read for a cursor request — up to one
+// more than perPage — into the page and the cursors around it. forward
+// is true for ?after=, whose extra row is at the end; ?before= reads
+// backwards, so its extra row is at the start.
+func cursorPage(students []types.Student, total int64, perPage int, forward bool) types.StudentPage {
+	more := len(students) > perPage
+	if more {
+		if forward {
+			students = students[:perPage]
+		} else {
+			students = students[1:]
+		}
+	}
+
+	result := types.StudentPage{Data: students, Total: total, PerPage: perPage}
+	if len(students) == 0 {
+		return result
+	}
+
+	first, last := encodeCursor(students[0].ID), encodeCursor(students[len(students)-1].ID)
+	// The cursor came from a neighbouring page, so that side always has one.
+	if forward {
+		result.PrevCursor = first
+		if more {
+			result.NextCursor = last
+		}
+	} else {
+		result.NextCursor = last
+		if more {
+			result.PrevCursor = first
+		}
+	}
+	return result
+}
+
+// encodeCursor makes the opaque cursor for a student id. Clients must
+// not rely on what is inside; it is base64 only so that it looks opaque.
+func encodeCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+// decodeCursor is the reverse of encodeCursor.
+func decodeCursor(cursor string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.New("invalid cursor")
+	}
+	id, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, errors.New("invalid cursor")
+	}
+	return id, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Search handles GET /api/students/search?q=...
+// Finds students by part of their name or email, ignoring case:
+//
+//	curl "http://localhost:8082/api/students/search?q=rak"
+//
+// Success response (200 OK) — every match, newest first, unpaged:
+//
+//	[ { "id": 1, "name": "Rakesh", "email": "rakesh@test.com", "age": 35 } ]
+//
+// Error responses:
+//
+//	400 Bad Request — q is missing or blank
+//	500 Internal    — database error
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func Search(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
+		q := strings.TrimSpace(r.URL.Query().Get("q"))
+		log.Info("searching students", slog.String("q", q))
+
+		if q == "" {
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New("query parameter q is required")))
+			return
+		}
+
+		students, err := storage.SearchStudents(r.Context(), middleware.TenantFromContext(r.Context()), q)
+		if err != nil {
+			log.Error("error searching students", slog.String("error", err.Error()))
+			writeStorageError(w, err)
 			return
 		}
 
@@ -193,33 +643,142 @@ func GetList(storage storage.Storage) http.HandlerFunc {
 	}
 }
 
+// parseFilters reads GetList's filter query parameters. Only the age
+// bounds can be malformed; name and email are taken as given.
+func parseFilters(r *http.Request) (types.FilterOptions, error) {
+	query := r.URL.Query()
+	opts := types.FilterOptions{
+		Name:  strings.TrimSpace(query.Get("name")),
+		Email: utils.NormalizeEmail(query.Get("email")),
+	}
+
+	for _, bound := range []struct {
+		param string
+		dest  **int
+	}{
+		{"age_min", &opts.AgeMin},
+		{"age_max", &opts.AgeMax},
+	} {
+		raw := query.Get(bound.param)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return types.FilterOptions{}, fmt.Errorf("%s must be an integer", bound.param)
+		}
+		*bound.dest = &n
+	}
+
+	sort, err := parseSort(query.Get("sort"), query.Get("order"))
+	if err != nil {
+		return types.FilterOptions{}, err
+	}
+	opts.Sort = sort
+
+	return opts, nil
+}
+
+// parseSort parses ?sort=age,name&order=desc,asc. Sort keys must be in
+// types.SortKeys — they end up as column names, so anything else is
+// refused rather than passed on. Keys without a matching order are
+// ascending.
+func parseSort(sortParam, orderParam string) (types.SortOptions, error) {
+	if sortParam == "" {
+		if orderParam != "" {
+			return types.SortOptions{}, errors.New("order requires sort")
+		}
+		return types.SortOptions{}, nil
+	}
+
+	keys := strings.Split(sortParam, ",")
+	var orders []string
+	if orderParam != "" {
+		orders = strings.Split(orderParam, ",")
+	}
+	if len(orders) > len(keys) {
+		return types.SortOptions{}, errors.New("order has more values than sort")
+	}
+
+	fields := make([]types.SortField, 0, len(keys))
+	for i, key := range keys {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !slices.Contains(types.SortKeys, key) {
+			return types.SortOptions{}, fmt.Errorf("cannot sort by %q: must be one of %s",
+				key, strings.Join(types.SortKeys, ", "))
+		}
+
+		field := types.SortField{Key: key}
+		if i < len(orders) {
+			switch strings.ToLower(strings.TrimSpace(orders[i])) {
+			case "asc":
+			case "desc":
+				field.Desc = true
+			default:
+				return types.SortOptions{}, fmt.Errorf("order must be asc or desc, got %q", orders[i])
+			}
+		}
+		fields = append(fields, field)
+	}
+
+	return types.SortOptions{Fields: fields}, nil
+}
+
+// positiveQueryInt reads a positive integer query parameter, returning def
+// when it is missing or not a positive integer.
+func positiveQueryInt(r *http.Request, name string, def int) int {
+	n, err := strconv.Atoi(r.URL.Query().Get(name))
+	if err != nil || n < 1 {
+		return def
+	}
+	return n
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 // Update handles PUT /api/students/{id}
 // Replaces ALL fields of an existing student.
 //
-// Request body (JSON) — all fields required for a PUT:
+// Request body (JSON) — all fields required for a PUT, plus the version
+// of the student the change was made to:
 //
-//	{ "name": "Rakesh Updated", "email": "new@test.com", "age": 36 }
+//	{ "name": "Rakesh Updated", "email": "new@test.com", "age": 36, "version": 3 }
 //
-// Success response (200 OK) — the updated student:
+// Updates use optimistic locking, so two clients editing the same student
+// can't silently overwrite each other:
+//
+//  1. GET the student and note its "version".
+//  2. PUT the changed student with that version.
+//  3. If someone else updated the student in between, the stored version
+//     has moved on and the PUT fails with 409 Conflict (VERSION_CONFLICT).
+//     GET the student again, reapply the change and retry.
+//
+// Every successful write — PUT, PATCH, photo upload, upsert, import —
+// bumps the version by one.
+//
+// Success response (200 OK) — the updated student, with its new version:
 //
-//	{ "id": 1, "name": "Rakesh Updated", "email": "new@test.com", "age": 36 }
+//	{ "id": 1, "name": "Rakesh Updated", "email": "new@test.com", "age": 36, "version": 4 }
 //
 // Error responses:
 //
-//	400 Bad Request  — invalid id, empty body, or validation failure
+//	400 Bad Request  — invalid id, empty body or no version
+//	404 Not Found    — no student with that id
+//	422 Unprocessable — failed validation
+//	409 Conflict     — the student changed since that version, or another
+//	                   student already uses the new email
 //	500 Internal     — database error
 //
 // ─────────────────────────────────────────────────────────────────────────────
 func Update(storage storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
 		id := r.PathValue("id")
-		slog.Info("updating a student", slog.String("id", id))
+		log.Info("updating a student", slog.String("id", id))
 
 		intID, err := strconv.ParseInt(id, 10, 64)
 		if err != nil {
 			response.WriteJSON(w, http.StatusBadRequest,
-				response.GeneralError(errors.New("invalid id: must be an integer")))
+				response.BadRequestError(errors.New("invalid id: must be an integer")))
 			return
 		}
 
@@ -228,41 +787,166 @@ func Update(storage storage.Storage) http.HandlerFunc {
 		err = json.NewDecoder(r.Body).Decode(&student)
 		if errors.Is(err, io.EOF) {
 			response.WriteJSON(w, http.StatusBadRequest,
-				response.GeneralError(errors.New("request body is empty")))
+				response.BadRequestError(errors.New("request body is empty")))
 			return
 		}
 		if err != nil {
-			response.WriteJSON(w, http.StatusBadRequest, response.GeneralError(err))
+			writeDecodeError(w, err)
 			return
 		}
 
+		// Versions start at 1, so 0 means the client left it out. An
+		// update without one could overwrite changes it never saw.
+		if student.Version < 1 {
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New("version is required: send the version from the student you are updating")))
+			return
+		}
+
+		student.Email = utils.NormalizeEmail(student.Email)
+
 		// Validate the update payload using the same rules as creation
-		if err := validator.New().Struct(student); err != nil {
-			validateErrs := err.(validator.ValidationErrors)
+		if err := validation.Validator().Struct(student); err != nil {
+			status, resp := validationError(err)
+			response.WriteJSON(w, status, resp)
+			return
+		}
+
+		// Persist and retrieve the updated record. The storage refuses
+		// with ErrVersionConflict if student.Version is out of date.
+		updated, err := storage.UpdateStudentByID(r.Context(), middleware.TenantFromContext(r.Context()), intID, student)
+		if err != nil {
+			log.Error("error updating student",
+				slog.String("id", id),
+				slog.String("error", err.Error()))
+			writeStorageError(w, err)
+			return
+		}
+
+		log.Info("student updated", slog.String("id", id))
+		response.WriteJSON(w, http.StatusOK, updated)
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Patch handles PATCH /api/students/{id}
+// Changes only the fields present in the body, using JSON Merge Patch
+// (RFC 7396) semantics — see the mergepatch package.
+//
+// Request: Content-Type: application/merge-patch+json
+//
+//	{ "age": 21, "photo_url": null }
+//
+// A field that is left out keeps its value; null clears it (only allowed
+// for photo_url, department and phone; the other fields are required). Only the
+// fields in the patch are written to the database (PatchStudentByID).
+//
+// Success response (200 OK) — the updated student:
+//
+//	{ "id": 1, "name": "Rakesh", "email": "rakesh@test.com", "age": 21 }
+//
+// Error responses:
+//
+//	400 Bad Request  — invalid id or body, unknown field
+//	404 Not Found    — no student with that id
+//	409 Conflict     — another student already uses the new email
+//	415 Unsupported  — Content-Type is not application/merge-patch+json
+//	422 Unprocessable — the patched student fails validation (e.g. a
+//	                    required field set to null)
+//	500 Internal     — database error
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func Patch(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
+		id := r.PathValue("id")
+		log.Info("patching a student", slog.String("id", id))
+
+		intID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
 			response.WriteJSON(w, http.StatusBadRequest,
-				response.ValidationError(validateErrs))
+				response.BadRequestError(errors.New("invalid id: must be an integer")))
 			return
 		}
 
-		// Persist and retrieve the updated record
-		updated, err := storage.UpdateStudentByID(intID, student)
+		// ParseMediaType ignores parameters such as "; charset=utf-8".
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != mergepatch.ContentType {
+			response.WriteJSON(w, http.StatusUnsupportedMediaType,
+				response.Error(response.ErrCodeUnsupportedMediaType,
+					fmt.Errorf("Content-Type must be %s", mergepatch.ContentType)))
+			return
+		}
+
+		// RawMessage keeps each value undecoded, so Apply can tell an
+		// explicit null from a missing key.
+		var patch map[string]json.RawMessage
+		err = json.NewDecoder(r.Body).Decode(&patch)
+		if errors.Is(err, io.EOF) {
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New("request body is empty")))
+			return
+		}
+		if errors.As(err, new(*http.MaxBytesError)) {
+			writeDecodeError(w, err)
+			return
+		}
+		if err != nil || patch == nil {
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New("patch must be a JSON object")))
+			return
+		}
+
+		original, err := storage.GetStudentByID(r.Context(), middleware.TenantFromContext(r.Context()), intID)
+		// A stale copy is good enough to merge into: only the patched
+		// fields are written.
+		err = allowStaleBase(r, err)
+		if err != nil {
+			log.Error("error getting student to patch",
+				slog.String("id", id),
+				slog.String("error", err.Error()))
+			writeStorageError(w, err)
+			return
+		}
+
+		student, err := mergepatch.Apply(original, patch)
+		if err != nil {
+			response.WriteJSON(w, http.StatusBadRequest, response.BadRequestError(err))
+			return
+		}
+
+		student.Email = utils.NormalizeEmail(student.Email)
+
+		// The result must still be a valid student.
+		if err := validation.Validator().Struct(student); err != nil {
+			status, resp := validationError(err)
+			response.WriteJSON(w, status, resp)
+			return
+		}
+
+		// Only the fields in the patch are written, so a concurrent change
+		// to any other field isn't overwritten with what we read above.
+		updated, err := storage.PatchStudentByID(r.Context(), middleware.TenantFromContext(r.Context()), intID, mergepatch.Fields(student, patch))
 		if err != nil {
-			slog.Error("error updating student",
+			log.Error("error patching student",
 				slog.String("id", id),
 				slog.String("error", err.Error()))
-			response.WriteJSON(w, http.StatusInternalServerError,
-				response.GeneralError(err))
+			writeStorageError(w, err)
 			return
 		}
 
-		slog.Info("student updated", slog.String("id", id))
+		log.Info("student patched", slog.String("id", id))
 		response.WriteJSON(w, http.StatusOK, updated)
 	}
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
 // Delete handles DELETE /api/students/{id}
-// Permanently removes a student record from the database.
+// Soft-deletes a student: it disappears from every endpoint but stays in
+// the database, and POST /api/students/{id}/restore brings it back.
+//
+// Nothing is ever removed for good: the personal data stays too. To
+// erase it, use POST /api/students/{id}/anonymize (see Anonymize).
 //
 // Success response (200 OK):
 //
@@ -271,31 +955,462 @@ func Update(storage storage.Storage) http.HandlerFunc {
 // Error responses:
 //
 //	400 Bad Request  — invalid id
+//	404 Not Found    — no student with that id, or already deleted
 //	500 Internal     — database error
 //
 // ─────────────────────────────────────────────────────────────────────────────
 func Delete(storage storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
 		id := r.PathValue("id")
-		slog.Info("deleting a student", slog.String("id", id))
+		log.Info("deleting a student", slog.String("id", id))
 
 		intID, err := strconv.ParseInt(id, 10, 64)
 		if err != nil {
 			response.WriteJSON(w, http.StatusBadRequest,
-				response.GeneralError(errors.New("invalid id: must be an integer")))
+				response.BadRequestError(errors.New("invalid id: must be an integer")))
 			return
 		}
 
-		if err := storage.DeleteStudentByID(intID); err != nil {
-			slog.Error("error deleting student",
+		if err := storage.DeleteStudentByID(r.Context(), middleware.TenantFromContext(r.Context()), intID); err != nil {
+			log.Error("error deleting student",
 				slog.String("id", id),
 				slog.String("error", err.Error()))
-			response.WriteJSON(w, http.StatusInternalServerError,
-				response.GeneralError(err))
+			writeStorageError(w, err)
 			return
 		}
 
-		slog.Info("student deleted", slog.String("id", id))
+		log.Info("student deleted", slog.String("id", id))
 		response.WriteJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 	}
 }
+
+// maxBatchDeleteIDs caps how many students one BatchDelete request can
+// delete. SQLite also limits how many ? placeholders one statement may
+// have, and this stays well below that.
+const maxBatchDeleteIDs = 1000
+
+// batchDeleteRequest is the body of DELETE /api/students/batch.
+type batchDeleteRequest struct {
+	IDs []int64 `json:"ids"`
+}
+
+// missingIDsResponse is the 404 response of BatchDelete: the usual error
+// plus which IDs matched no student.
+type missingIDsResponse struct {
+	response.Response
+	MissingIDs []int64 `json:"missing_ids"`
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// BatchDelete handles DELETE /api/students/batch
+// Deletes many students at once, all or nothing.
+//
+// Request body (JSON):
+//
+//	{ "ids": [1, 2, 3] }
+//
+// Success response (200 OK):
+//
+//	{ "deleted": 3 }
+//
+// Error responses:
+//
+//	400 Bad Request  — empty body, malformed JSON, no ids, an id that isn't
+//	                   positive, or more than maxBatchDeleteIDs ids
+//	404 Not Found    — some ids match no student; nothing is deleted:
+//	                   { "status": "error", ..., "missing_ids": [3] }
+//	500 Internal     — database error
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func BatchDelete(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
+		log.Info("deleting students in batch")
+
+		var req batchDeleteRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if errors.Is(err, io.EOF) {
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New("request body is empty")))
+			return
+		}
+		if err != nil {
+			writeDecodeError(w, err)
+			return
+		}
+
+		switch {
+		case len(req.IDs) == 0:
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New("ids must contain at least one id")))
+			return
+		case len(req.IDs) > maxBatchDeleteIDs:
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(fmt.Errorf("ids can contain at most %d ids", maxBatchDeleteIDs)))
+			return
+		}
+		for i, id := range req.IDs {
+			if id <= 0 {
+				response.WriteJSON(w, http.StatusBadRequest,
+					response.BadRequestError(fmt.Errorf("invalid id at index %d: must be a positive integer", i)))
+				return
+			}
+		}
+
+		n, err := storage.BulkDeleteStudents(r.Context(), middleware.TenantFromContext(r.Context()), req.IDs)
+		if err != nil {
+			if missing, ok := missingIDs(err); ok {
+				response.WriteJSON(w, http.StatusNotFound, missingIDsResponse{
+					Response:   response.NotFoundError(err),
+					MissingIDs: missing,
+				})
+				return
+			}
+			log.Error("error deleting students", slog.String("error", err.Error()))
+			writeStorageError(w, err)
+			return
+		}
+
+		log.Info("students deleted in batch", slog.Int64("count", n))
+		response.WriteJSON(w, http.StatusOK, map[string]int64{"deleted": n})
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Restore handles POST /api/students/{id}/restore
+// Undoes a DELETE, making the student visible again.
+//
+//	curl -X POST http://localhost:8082/api/students/1/restore
+//
+// Success response (200 OK):
+//
+//	{ "status": "restored" }
+//
+// Error responses:
+//
+//	400 Bad Request  — invalid id
+//	404 Not Found    — no deleted student with this id
+//	409 Conflict     — another student has taken its email since
+//	500 Internal     — database error
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func Restore(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
+		id := r.PathValue("id")
+		log.Info("restoring a student", slog.String("id", id))
+
+		intID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New("invalid id: must be an integer")))
+			return
+		}
+
+		if err := storage.RestoreStudentByID(r.Context(), middleware.TenantFromContext(r.Context()), intID); err != nil {
+			log.Error("error restoring student",
+				slog.String("id", id),
+				slog.String("error", err.Error()))
+			writeStorageError(w, err)
+			return
+		}
+
+		log.Info("student restored", slog.String("id", id))
+		response.WriteJSON(w, http.StatusOK, map[string]string{"status": "restored"})
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// BatchCreate handles POST /api/students/batch
+// Creates many students in one request, e.g. a whole class roster.
+//
+// Request body (JSON array, ids are ignored):
+//
+//	[
+//	  { "name": "Rakesh", "email": "rakesh@test.com", "age": 35 },
+//	  { "name": "",       "email": "priya@test.com",  "age": 22 }
+//	]
+//
+// Every student is validated, and the valid ones are created in a single
+// transaction. Invalid students and ones whose email is taken are left
+// out, so one bad row doesn't sink the rest.
+//
+// Success response (207 Multi-Status) — one result per student, in order:
+//
+//	[
+//	  { "index": 0, "status": 201, "id": 7 },
+//	  { "index": 1, "status": 422, "error": "field Name is required", "error_code": "VALIDATION_ERROR" }
+//	]
+//
+// Error responses:
+//
+//	400 Bad Request  — empty body, malformed JSON or an empty array
+//	403 Forbidden    — the batch would take the number of students over
+//	                   max_students (nothing is written)
+//	500 Internal     — database error (nothing is written)
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func BatchCreate(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
+		log.Info("creating students in batch")
+
+		var students []types.Student
+		err := json.NewDecoder(r.Body).Decode(&students)
+		if errors.Is(err, io.EOF) {
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New("request body is empty")))
+			return
+		}
+		if err != nil {
+			writeDecodeError(w, err)
+			return
+		}
+		if len(students) == 0 {
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New("request body must contain at least one student")))
+			return
+		}
+
+		results := make([]types.BatchCreateResult, len(students))
+
+		// Validate every student, collecting all the failures rather than
+		// stopping at the first. valid holds the indexes of the others.
+		valid := make([]int, 0, len(students))
+		for i := range students {
+			results[i].Index = i
+			students[i].Email = utils.NormalizeEmail(students[i].Email)
+
+			if err := validation.Validator().Struct(students[i]); err != nil {
+				status, resp := validationError(err)
+				results[i].Status = status
+				results[i].Error, results[i].ErrorCode = resp.Error, resp.ErrorCode
+				continue
+			}
+			valid = append(valid, i)
+		}
+
+		if len(valid) > 0 {
+			batch := make([]types.Student, len(valid))
+			for n, i := range valid {
+				batch[n] = students[i]
+			}
+
+			ids, err := storage.BulkCreateStudents(r.Context(), middleware.TenantFromContext(r.Context()), batch)
+			failed, partial := bulkRowErrors(err)
+			if err != nil && !partial {
+				log.Error("error creating students", slog.String("error", err.Error()))
+				writeStorageError(w, err)
+				return
+			}
+
+			// ids and failed are indexed like batch; map back to the
+			// positions in the request.
+			for n, i := range valid {
+				if rowErr := failed[n]; rowErr != nil {
+					resp := response.DuplicateEmailError(rowErr)
+					results[i].Status = http.StatusConflict
+					results[i].Error, results[i].ErrorCode = resp.Error, resp.ErrorCode
+					continue
+				}
+				results[i].Status = http.StatusCreated
+				results[i].ID = ids[n]
+			}
+		}
+
+		log.Info("students created in batch",
+			slog.Int("submitted", len(students)),
+			slog.Int("created", countStatus(results, http.StatusCreated)))
+		response.WriteJSON(w, http.StatusMultiStatus, results)
+	}
+}
+
+// bulkRowErrors returns the per-student errors if err is a
+// storage.BulkError (some students were created, these were not).
+// Like writeStorageError, it is at package level to reach the storage
+// package.
+func bulkRowErrors(err error) (map[int]error, bool) {
+	var bulkErr *storage.BulkError
+	if errors.As(err, &bulkErr) {
+		return bulkErr.Rows, true
+	}
+	return nil, false
+}
+
+// missingIDs returns the IDs a storage.MissingIDsError lists, if err is
+// one.
+func missingIDs(err error) ([]int64, bool) {
+	var missingErr *storage.MissingIDsError
+	if errors.As(err, &missingErr) {
+		return missingErr.IDs, true
+	}
+	return nil, false
+}
+
+// countStatus returns how many results have the given status.
+func countStatus(results []types.BatchCreateResult, status int) int {
+	n := 0
+	for _, result := range results {
+		if result.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Upsert handles PUT /api/students/batch/upsert
+// Creates or updates many students in one request, matched by email.
+//
+// Request body (JSON array, ids are ignored):
+//
+//	[
+//	  { "name": "Rakesh", "email": "rakesh@test.com", "age": 35 },
+//	  { "name": "Priya",  "email": "priya@test.com",  "age": 22 }
+//	]
+//
+// Success response (207 Multi-Status) — one result per student, in order:
+//
+//	[
+//	  { "id": 1, "email": "rakesh@test.com", "action": "updated" },
+//	  { "id": 2, "email": "priya@test.com",  "action": "created" }
+//	]
+//
+// Error responses:
+//
+//	400 Bad Request  — empty body or malformed JSON
+//	403 Forbidden    — the new students would take the number of students
+//	                   over max_students (nothing is written)
+//	422 Unprocessable — a student fails validation (nothing is written)
+//	500 Internal     — database error (nothing is written)
+//	503 Unavailable  — the database can't be reached (nothing is written)
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func Upsert(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
+		log.Info("upserting students")
+
+		var students []types.Student
+		err := json.NewDecoder(r.Body).Decode(&students)
+		if errors.Is(err, io.EOF) {
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New("request body is empty")))
+			return
+		}
+		if err != nil {
+			writeDecodeError(w, err)
+			return
+		}
+		if len(students) == 0 {
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New("request body must contain at least one student")))
+			return
+		}
+
+		// Validate every student before writing anything, and say which
+		// element failed so the client can fix it.
+		for i := range students {
+			students[i].Email = utils.NormalizeEmail(students[i].Email)
+
+			if err := validation.Validator().Struct(students[i]); err != nil {
+				status, resp := validationError(err)
+				resp.Error = fmt.Sprintf("student at index %d: %s", i, resp.Error)
+				response.WriteJSON(w, status, resp)
+				return
+			}
+		}
+
+		results, err := storage.UpsertStudents(r.Context(), middleware.TenantFromContext(r.Context()), students)
+		if err != nil {
+			log.Error("error upserting students", slog.String("error", err.Error()))
+			writeStorageError(w, err)
+			return
+		}
+
+		log.Info("students upserted", slog.Int("count", len(results)))
+		response.WriteJSON(w, http.StatusMultiStatus, results)
+	}
+}
+
+// writeStorageError maps an error returned by the storage layer to the
+// matching HTTP status and error response.
+//
+// It lives at package level (not inside a handler) because within each
+// handler the `storage` parameter shadows the storage package, so the
+// sentinel errors are only reachable from here.
+func writeStorageError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		response.NotFound(w, err.Error())
+	case errors.Is(err, storage.ErrDuplicateEmail):
+		response.WriteJSON(w, http.StatusConflict, response.DuplicateEmailError(err))
+	case errors.Is(err, storage.ErrVersionConflict):
+		response.WriteJSON(w, http.StatusConflict, response.Error(response.ErrCodeVersionConflict, err))
+	case errors.Is(err, storage.ErrCapacityExceeded):
+		response.WriteJSON(w, http.StatusForbidden, response.Error(response.ErrCodeCapacityExceeded, err))
+	case errors.Is(err, storage.ErrUnsupportedFilter):
+		response.WriteJSON(w, http.StatusBadRequest, response.BadRequestError(err))
+	case errors.Is(err, storage.ErrUnavailable):
+		response.WriteJSON(w, http.StatusServiceUnavailable, response.GeneralError(err))
+	default:
+		response.WriteJSON(w, http.StatusInternalServerError, response.GeneralError(err))
+	}
+}
+
+// writeDecodeError answers a request whose JSON body could not be
+// decoded: 413 if reading it ran into the body size limit (see the
+// bodylimit middleware), 400 for anything else — malformed JSON, wrong
+// types and the like.
+func writeDecodeError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		bodylimit.WriteTooLarge(w, tooLarge.Limit)
+		return
+	}
+	response.WriteJSON(w, http.StatusBadRequest, response.BadRequestError(err))
+}
+
+// validationError returns the status and body for an error from
+// validation.Validator().Struct: 422 with the failing fields for
+// validator.ValidationErrors, 400 for anything else. The validator
+// returns other errors too — *validator.InvalidValidationError for a
+// value that isn't a struct — and those carry no field errors to report.
+func validationError(err error) (int, response.Response) {
+	var validateErrs validator.ValidationErrors
+	if errors.As(err, &validateErrs) {
+		return http.StatusUnprocessableEntity, response.ValidationError(validateErrs)
+	}
+	return http.StatusBadRequest, response.BadRequestError(err)
+}
+
+// allowStale turns a storage.ErrStale result into a success: the value
+// that came with it is usable, so it returns nil after adding an HTTP
+// Warning header telling the client the data may be out of date.
+// Any other error is returned unchanged.
+func allowStale(w http.ResponseWriter, r *http.Request, err error) error {
+	if errors.Is(err, storage.ErrStale) {
+		logFromContext(r.Context()).Warn("serving stale data", slog.String("error", err.Error()))
+		w.Header().Set("Warning", `110 - "Response is Stale"`)
+		return nil
+	}
+	return err
+}
+
+// allowStaleBase is allowStale for a read that a write is then based on,
+// such as the student a PATCH is merged into. The response is what the
+// write stored, which isn't stale, so no Warning header is added.
+func allowStaleBase(r *http.Request, err error) error {
+	if errors.Is(err, storage.ErrStale) {
+		logFromContext(r.Context()).Warn("writing based on stale data", slog.String("error", err.Error()))
+		return nil
+	}
+	return err
+}
+
+// logFromContext returns the request's logger, which adds the request ID
+// to every line (see requestid.Logger).
+func logFromContext(ctx context.Context) *slog.Logger {
+	return requestid.Logger(ctx)
+}
@@ -0,0 +1,1370 @@
+package student_test
+
+import (
+	"bufio"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aanand-mishra/students-api/internal/config"
+	"github.com/aanand-mishra/students-api/internal/http/handlers/student"
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/storage/cache"
+	"github.com/aanand-mishra/students-api/internal/storage/sqlite"
+	apitest "github.com/aanand-mishra/students-api/internal/testing"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// newStore opens a fresh SQLite database in t.TempDir(). It is closed
+// when the test ends.
+func newStore(t *testing.T) *sqlite.SQLite {
+	t.Helper()
+
+	db, err := sqlite.New(&config.Config{
+		StoragePath: filepath.Join(t.TempDir(), "test.db"),
+		Database:    config.Database{AutoMigrate: true},
+		SQLite:      config.SQLite{JournalMode: config.JournalModeWAL, BusyTimeoutMs: 5000},
+	})
+	if err != nil {
+		t.Fatalf("sqlite.New: %v", err)
+	}
+	t.Cleanup(func() { db.Db.Close() })
+	return db
+}
+
+// seed creates a student in the default tenant and returns it as stored.
+func seed(t *testing.T, db storage.Storage, name, email string, age int) types.Student {
+	t.Helper()
+
+	ctx := context.Background()
+	id, err := db.CreateStudent(ctx, types.DefaultTenant, name, email, age, "", "")
+	if err != nil {
+		t.Fatalf("CreateStudent(%s): %v", email, err)
+	}
+	s, err := db.GetStudentByID(ctx, types.DefaultTenant, id)
+	if err != nil {
+		t.Fatalf("GetStudentByID(%d): %v", id, err)
+	}
+	return s
+}
+
+// request describes one call to a handler.
+type request struct {
+	method  string
+	target  string
+	body    string
+	id      string            // the {id} path value, if any
+	headers map[string]string // extra request headers
+}
+
+// serve calls h with req and returns the recorded response. The path
+// value is set the way the router would set it.
+func serve(h http.Handler, req request) *httptest.ResponseRecorder {
+	var body io.Reader
+	if req.body != "" {
+		body = strings.NewReader(req.body)
+	}
+	r := httptest.NewRequest(req.method, req.target, body)
+	if req.id != "" {
+		r.SetPathValue("id", req.id)
+	}
+	for k, v := range req.headers {
+		r.Header.Set(k, v)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+// decode unmarshals the recorded JSON body into a generic map, failing
+// the test if it isn't a JSON object.
+func decode(t *testing.T, rec *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body is not a JSON object: %v\n%s", err, rec.Body)
+	}
+	return body
+}
+
+// checkError asserts rec is a JSON error response with the given status
+// and error_code.
+func checkError(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d\nbody: %s", rec.Code, status, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	body := decode(t, rec)
+	if body["status"] != "error" {
+		t.Errorf(`status field = %v, want "error"`, body["status"])
+	}
+	if body["error_code"] != code {
+		t.Errorf("error_code = %v, want %s", body["error_code"], code)
+	}
+	if msg, _ := body["error"].(string); msg == "" {
+		t.Error("error message is empty")
+	}
+}
+
+// stubStorage answers every call it implements with student and err, and
+// records the tenant it was asked about. Methods it doesn't implement
+// panic (through the nil embedded Storage), so a test notices a call it
+// didn't expect.
+type stubStorage struct {
+	storage.Storage
+	student types.Student
+	err     error
+}
+
+var errDatabase = errors.New("database is on fire")
+
+func (s stubStorage) CreateStudent(context.Context, string, string, string, int, string, string) (int64, error) {
+	return int64(s.student.ID), s.err
+}
+
+func (s stubStorage) GetStudentByID(context.Context, string, int64) (types.Student, error) {
+	return s.student, s.err
+}
+
+func (s stubStorage) GetStudents(context.Context, string) ([]types.Student, error) {
+	return []types.Student{s.student}, s.err
+}
+
+func (s stubStorage) GetStudentsFiltered(context.Context, string, types.FilterOptions) ([]types.Student, int64, error) {
+	return []types.Student{s.student}, 1, s.err
+}
+
+func (s stubStorage) UpdateStudentByID(context.Context, string, int64, types.Student) (types.Student, error) {
+	return s.student, s.err
+}
+
+func (s stubStorage) DeleteStudentByID(context.Context, string, int64) error {
+	return s.err
+}
+
+func (s stubStorage) GetStudentStats(context.Context, string) (types.StudentStats, error) {
+	return types.StudentStats{}, s.err
+}
+
+func (s stubStorage) GetRandomStudent(context.Context, string) (types.Student, error) {
+	return s.student, s.err
+}
+
+func (s stubStorage) SearchStudents(context.Context, string, string) ([]types.Student, error) {
+	return nil, s.err
+}
+
+func (s stubStorage) BulkCreateStudents(context.Context, string, []types.Student) ([]int64, error) {
+	return nil, s.err
+}
+
+func (s stubStorage) UpsertStudents(context.Context, string, []types.Student) ([]types.UpsertResult, error) {
+	return nil, s.err
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		store    func(*testing.T) storage.Storage
+		body     string
+		status   int
+		wantCode string // error_code, for failures
+	}{
+		{"created", nil, `{"name":"Rakesh","email":"Rakesh@Test.com","age":35}`, http.StatusCreated, ""},
+		{"empty body", nil, ``, http.StatusBadRequest, "BAD_REQUEST"},
+		{"malformed JSON", nil, `{"name":`, http.StatusBadRequest, "BAD_REQUEST"},
+		{"wrong type", nil, `{"name":"Rakesh","email":"r@test.com","age":"old"}`, http.StatusBadRequest, "BAD_REQUEST"},
+		{"missing name", nil, `{"email":"r@test.com","age":35}`, http.StatusUnprocessableEntity, "VALIDATION_ERROR"},
+		{"age out of range", nil, `{"name":"Rakesh","email":"r@test.com","age":200}`, http.StatusUnprocessableEntity, "VALIDATION_ERROR"},
+		{"invalid phone", nil, `{"name":"Rakesh","email":"r@test.com","age":35,"phone":"12345"}`, http.StatusUnprocessableEntity, "VALIDATION_ERROR"},
+		{"duplicate email", func(t *testing.T) storage.Storage {
+			db := newStore(t)
+			seed(t, db, "Rakesh", "rakesh@test.com", 35)
+			return db
+		}, `{"name":"Other","email":"RAKESH@test.com","age":20}`, http.StatusConflict, "DUPLICATE_ENTRY"},
+		{"capacity reached", func(*testing.T) storage.Storage {
+			return stubStorage{err: storage.ErrCapacityExceeded}
+		}, `{"name":"Rakesh","email":"r@test.com","age":35}`, http.StatusForbidden, "CAPACITY_EXCEEDED"},
+		{"database unavailable", func(*testing.T) storage.Storage {
+			return stubStorage{err: storage.ErrUnavailable}
+		}, `{"name":"Rakesh","email":"r@test.com","age":35}`, http.StatusServiceUnavailable, "INTERNAL_ERROR"},
+		{"database error", func(*testing.T) storage.Storage {
+			return stubStorage{err: errDatabase}
+		}, `{"name":"Rakesh","email":"r@test.com","age":35}`, http.StatusInternalServerError, "INTERNAL_ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var db storage.Storage
+			if tt.store != nil {
+				db = tt.store(t)
+			} else {
+				db = newStore(t)
+			}
+
+			rec := serve(student.New(db), request{method: http.MethodPost, target: "/api/students", body: tt.body})
+
+			if tt.wantCode != "" {
+				checkError(t, rec, tt.status, tt.wantCode)
+				return
+			}
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d\nbody: %s", rec.Code, tt.status, rec.Body)
+			}
+			body := decode(t, rec)
+			id, ok := body["id"].(float64)
+			if !ok || len(body) != 1 {
+				t.Fatalf(`body = %v, want {"id": <id>}`, body)
+			}
+
+			stored, err := db.GetStudentByID(context.Background(), types.DefaultTenant, int64(id))
+			if err != nil {
+				t.Fatalf("created student not stored: %v", err)
+			}
+			if stored.Email != "rakesh@test.com" {
+				t.Errorf("stored email = %q, want it normalized to rakesh@test.com", stored.Email)
+			}
+		})
+	}
+}
+
+func TestNewDuplicateEmail(t *testing.T) {
+	db := newStore(t)
+	existing := seed(t, db, "Rakesh", "rakesh@test.com", 35)
+
+	rec := serve(student.New(db), request{method: http.MethodPost, target: "/api/students",
+		body: `{"name":"Other","email":"rakesh@test.com","age":20}`})
+
+	checkError(t, rec, http.StatusConflict, "DUPLICATE_ENTRY")
+	if msg, _ := decode(t, rec)["error"].(string); !strings.Contains(msg, storage.ErrDuplicateEmail.Error()) {
+		t.Errorf("error = %q, want it to say %q", msg, storage.ErrDuplicateEmail)
+	}
+
+	// The existing student is untouched and no second one was created.
+	students, err := db.GetStudents(context.Background(), types.DefaultTenant)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(students) != 1 || students[0].Name != existing.Name || students[0].Version != existing.Version {
+		t.Errorf("students after the conflict = %+v, want only %+v", students, existing)
+	}
+}
+
+func TestNewValidationErrorLists(t *testing.T) {
+	rec := serve(student.New(newStore(t)), request{
+		method: http.MethodPost, target: "/api/students",
+		body: `{"phone":"12345"}`,
+	})
+	checkError(t, rec, http.StatusUnprocessableEntity, "VALIDATION_ERROR")
+
+	var body struct {
+		Errors []struct {
+			Field   string `json:"field"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+
+	fields := map[string]bool{}
+	for _, e := range body.Errors {
+		fields[e.Field] = true
+		if e.Message == "" {
+			t.Errorf("field %s has no message", e.Field)
+		}
+	}
+	for _, want := range []string{"Name", "Email", "Age", "Phone"} {
+		if !fields[want] {
+			t.Errorf("errors = %+v, want one for %s", body.Errors, want)
+		}
+	}
+}
+
+func TestNewAgeBounds(t *testing.T) {
+	tests := []struct {
+		age     int
+		status  int
+		message string // the error, for failures
+	}{
+		{-5, http.StatusUnprocessableEntity, "field Age must be at least 1"},
+		{0, http.StatusUnprocessableEntity, "field Age is required"},
+		{1, http.StatusCreated, ""},
+		{150, http.StatusCreated, ""},
+		{151, http.StatusUnprocessableEntity, "field Age must be at most 150"},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.age), func(t *testing.T) {
+			rec := serve(student.New(newStore(t)), request{
+				method: http.MethodPost, target: "/api/students",
+				body: fmt.Sprintf(`{"name":"Rakesh","email":"r@test.com","age":%d}`, tt.age),
+			})
+
+			if tt.message == "" {
+				if rec.Code != tt.status {
+					t.Fatalf("status = %d, want %d\nbody: %s", rec.Code, tt.status, rec.Body)
+				}
+				return
+			}
+			checkError(t, rec, tt.status, "VALIDATION_ERROR")
+			if msg := decode(t, rec)["error"]; msg != tt.message {
+				t.Errorf("error = %q, want %q", msg, tt.message)
+			}
+		})
+	}
+}
+
+func TestNewBodyTooLarge(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, 16)
+		student.New(newStore(t))(w, r)
+	})
+	rec := serve(h, request{method: http.MethodPost, target: "/api/students",
+		body: `{"name":"Rakesh","email":"rakesh@test.com","age":35}`})
+
+	checkError(t, rec, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE")
+}
+
+func TestGetByID(t *testing.T) {
+	db := newStore(t)
+	rakesh := seed(t, db, "Rakesh", "rakesh@test.com", 35)
+	id := fmt.Sprint(rakesh.ID)
+
+	t.Run("found", func(t *testing.T) {
+		rec := serve(student.GetByID(db), request{method: http.MethodGet, target: "/api/students/" + id, id: id})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		if rec.Header().Get("ETag") == "" || rec.Header().Get("Last-Modified") == "" {
+			t.Errorf("ETag = %q, Last-Modified = %q, want both set",
+				rec.Header().Get("ETag"), rec.Header().Get("Last-Modified"))
+		}
+
+		body := decode(t, rec)
+		for _, key := range []string{"id", "name", "email", "age", "created_at", "updated_at", "version", "status"} {
+			if _, ok := body[key]; !ok {
+				t.Errorf("body has no %q: %v", key, body)
+			}
+		}
+		if _, ok := body["tenant_id"]; ok {
+			t.Error("body exposes tenant_id")
+		}
+		if body["name"] != "Rakesh" || body["email"] != "rakesh@test.com" || body["age"] != float64(35) {
+			t.Errorf("body = %v, want Rakesh, rakesh@test.com, 35", body)
+		}
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		first := serve(student.GetByID(db), request{method: http.MethodGet, target: "/api/students/" + id, id: id})
+		rec := serve(student.GetByID(db), request{method: http.MethodGet, target: "/api/students/" + id, id: id,
+			headers: map[string]string{"If-None-Match": first.Header().Get("ETag")}})
+
+		if rec.Code != http.StatusNotModified {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotModified)
+		}
+		if rec.Body.Len() != 0 {
+			t.Errorf("304 has a body: %s", rec.Body)
+		}
+	})
+
+	t.Run("stale from cache", func(t *testing.T) {
+		stale := stubStorage{student: rakesh, err: fmt.Errorf("read: %w", storage.ErrStale)}
+		rec := serve(student.GetByID(stale), request{method: http.MethodGet, target: "/api/students/" + id, id: id})
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		if w := rec.Header().Get("Warning"); !strings.HasPrefix(w, "110") {
+			t.Errorf("Warning = %q, want a 110 stale warning", w)
+		}
+	})
+
+	failures := []struct {
+		name   string
+		store  storage.Storage
+		id     string
+		status int
+		code   string
+	}{
+		{"invalid id", db, "abc", http.StatusBadRequest, "BAD_REQUEST"},
+		{"not found", db, "999", http.StatusNotFound, "NOT_FOUND"},
+		{"unavailable", stubStorage{err: storage.ErrUnavailable}, "1", http.StatusServiceUnavailable, "INTERNAL_ERROR"},
+		{"database error", stubStorage{err: errDatabase}, "1", http.StatusInternalServerError, "INTERNAL_ERROR"},
+	}
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(student.GetByID(tt.store), request{method: http.MethodGet, target: "/api/students/" + tt.id, id: tt.id})
+			checkError(t, rec, tt.status, tt.code)
+		})
+	}
+}
+
+// TestStorageErrorCodes checks that each storage sentinel, even when
+// wrapped, reaches the client as its documented status and error_code.
+func TestStorageErrorCodes(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"not found", storage.ErrNotFound, http.StatusNotFound, "NOT_FOUND"},
+		{"duplicate email", storage.ErrDuplicateEmail, http.StatusConflict, "DUPLICATE_ENTRY"},
+		{"version conflict", storage.ErrVersionConflict, http.StatusConflict, "VERSION_CONFLICT"},
+		{"capacity exceeded", storage.ErrCapacityExceeded, http.StatusForbidden, "CAPACITY_EXCEEDED"},
+		{"unsupported filter", storage.ErrUnsupportedFilter, http.StatusBadRequest, "BAD_REQUEST"},
+		{"unavailable", storage.ErrUnavailable, http.StatusServiceUnavailable, "INTERNAL_ERROR"},
+		{"anything else", errDatabase, http.StatusInternalServerError, "INTERNAL_ERROR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := stubStorage{err: fmt.Errorf("DeleteStudentByID: %w", tt.err)}
+			rec := serve(student.Delete(store), request{method: http.MethodDelete, target: "/api/students/1", id: "1"})
+			checkError(t, rec, tt.status, tt.code)
+		})
+	}
+}
+
+func TestGetList(t *testing.T) {
+	db := newStore(t)
+	for i := 1; i <= 25; i++ {
+		seed(t, db, fmt.Sprintf("Student %02d", i), fmt.Sprintf("s%02d@test.com", i), 17+i)
+	}
+	list := student.GetList(db, 50)
+
+	type page struct {
+		Data       []types.Student `json:"data"`
+		Total      int64           `json:"total"`
+		Page       int             `json:"page"`
+		PerPage    int             `json:"per_page"`
+		NextCursor string          `json:"next_cursor"`
+		PrevCursor string          `json:"prev_cursor"`
+	}
+	get := func(t *testing.T, target string) (page, *httptest.ResponseRecorder) {
+		t.Helper()
+		rec := serve(list, request{method: http.MethodGet, target: target})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, want %d\nbody: %s", target, rec.Code, http.StatusOK, rec.Body)
+		}
+		var p page
+		if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+			t.Fatalf("GET %s: decode: %v", target, err)
+		}
+		return p, rec
+	}
+
+	t.Run("first page", func(t *testing.T) {
+		p, rec := get(t, "/api/students")
+		if len(p.Data) != 20 || p.Total != 25 || p.Page != 1 || p.PerPage != 20 {
+			t.Errorf("page = %d students, total %d, page %d, per_page %d; want 20, 25, 1, 20",
+				len(p.Data), p.Total, p.Page, p.PerPage)
+		}
+		if got := rec.Header().Get("X-Total-Count"); got != "25" {
+			t.Errorf("X-Total-Count = %q, want 25", got)
+		}
+		link := rec.Header().Get("Link")
+		for _, rel := range []string{`rel="first"`, `rel="next"`, `rel="last"`} {
+			if !strings.Contains(link, rel) {
+				t.Errorf("Link = %q, want %s", link, rel)
+			}
+		}
+		if strings.Contains(link, `rel="prev"`) {
+			t.Errorf("Link = %q, want no prev on the first page", link)
+		}
+		// Newest first by default.
+		if p.Data[0].Email != "s25@test.com" {
+			t.Errorf("first student = %s, want the newest, s25@test.com", p.Data[0].Email)
+		}
+	})
+
+	t.Run("deleted students are not counted", func(t *testing.T) {
+		db := newStore(t)
+		var ids []int
+		for i := 1; i <= 5; i++ {
+			ids = append(ids, seed(t, db, fmt.Sprintf("Student %d", i), fmt.Sprintf("d%d@test.com", i), 20+i).ID)
+		}
+		if err := db.DeleteStudentByID(context.Background(), types.DefaultTenant, int64(ids[0])); err != nil {
+			t.Fatalf("DeleteStudentByID: %v", err)
+		}
+
+		// An offset page, then the cursor page after it.
+		target := "/api/students?sort=id&per_page=2"
+		for i := 0; i < 2; i++ {
+			rec := serve(student.GetList(db, 50), request{method: http.MethodGet, target: target})
+			if got := rec.Header().Get("X-Total-Count"); got != "4" {
+				t.Errorf("GET %s: X-Total-Count = %q, want 4", target, got)
+			}
+			var p page
+			if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+				t.Fatalf("GET %s: decode: %v", target, err)
+			}
+			target = "/api/students?per_page=2&after=" + p.NextCursor
+		}
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		p, _ := get(t, "/api/students?page=2&per_page=20")
+		if len(p.Data) != 5 || p.Page != 2 {
+			t.Errorf("page 2 has %d students (page %d), want 5", len(p.Data), p.Page)
+		}
+	})
+
+	t.Run("past the end", func(t *testing.T) {
+		_, rec := get(t, "/api/students?page=9")
+		if !strings.Contains(rec.Body.String(), `"data":[]`) {
+			t.Errorf("body = %s, want an empty data array", rec.Body)
+		}
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		p, _ := get(t, "/api/students?age_min=40&age_max=41&name=student")
+		if p.Total != 2 {
+			t.Errorf("total = %d, want 2 students aged 40-41", p.Total)
+		}
+	})
+
+	t.Run("sorted", func(t *testing.T) {
+		p, _ := get(t, "/api/students?sort=age,name&order=asc,desc&per_page=3")
+		if p.Data[0].Age != 18 || p.Data[2].Age != 20 {
+			t.Errorf("ages = %d..%d, want 18..20", p.Data[0].Age, p.Data[2].Age)
+		}
+	})
+
+	t.Run("cursor paging", func(t *testing.T) {
+		first, _ := get(t, "/api/students?sort=id&per_page=10")
+		if first.NextCursor == "" {
+			t.Fatal("an id-ordered page has no next_cursor")
+		}
+		second, rec := get(t, "/api/students?after="+first.NextCursor+"&per_page=10")
+		if len(second.Data) != 10 || second.Data[0].ID != first.Data[9].ID+1 {
+			t.Errorf("page after the cursor starts at %d, want %d", second.Data[0].ID, first.Data[9].ID+1)
+		}
+		if second.PrevCursor == "" || second.NextCursor == "" {
+			t.Errorf("middle cursor page has prev %q, next %q; want both", second.PrevCursor, second.NextCursor)
+		}
+		if !strings.Contains(rec.Header().Get("Link"), "after=") {
+			t.Errorf("Link = %q, want a cursor link", rec.Header().Get("Link"))
+		}
+
+		back, _ := get(t, "/api/students?before="+second.PrevCursor+"&per_page=10")
+		if len(back.Data) != 10 || back.Data[0].ID != first.Data[0].ID {
+			t.Errorf("page before the cursor starts at %d, want %d", back.Data[0].ID, first.Data[0].ID)
+		}
+	})
+
+	failures := []struct {
+		name   string
+		store  storage.Storage
+		target string
+		status int
+		code   string
+	}{
+		{"per_page too large", db, "/api/students?per_page=51", http.StatusBadRequest, "BAD_REQUEST"},
+		{"non-integer age", db, "/api/students?age_min=old", http.StatusBadRequest, "BAD_REQUEST"},
+		{"unknown sort column", db, "/api/students?sort=password", http.StatusBadRequest, "BAD_REQUEST"},
+		{"order without sort", db, "/api/students?order=desc", http.StatusBadRequest, "BAD_REQUEST"},
+		{"too many orders", db, "/api/students?sort=age&order=asc,desc", http.StatusBadRequest, "BAD_REQUEST"},
+		{"unknown order", db, "/api/students?sort=age&order=sideways", http.StatusBadRequest, "BAD_REQUEST"},
+		{"page out of range", db, "/api/students?page=2147483647&per_page=50", http.StatusBadRequest, "BAD_REQUEST"},
+		{"invalid cursor", db, "/api/students?after=!!!", http.StatusBadRequest, "BAD_REQUEST"},
+		{"non-numeric cursor", db, "/api/students?after=YWJj", http.StatusBadRequest, "BAD_REQUEST"},
+		{"cursor and page", db, "/api/students?after=MQ&page=2", http.StatusBadRequest, "BAD_REQUEST"},
+		{"after and before", db, "/api/students?after=MQ&before=Mg", http.StatusBadRequest, "BAD_REQUEST"},
+		{"cursor and sort", db, "/api/students?after=MQ&sort=age", http.StatusBadRequest, "BAD_REQUEST"},
+		{"unsupported filter", stubStorage{err: storage.ErrUnsupportedFilter}, "/api/students?name=x", http.StatusBadRequest, "BAD_REQUEST"},
+		{"database error", stubStorage{err: errDatabase}, "/api/students", http.StatusInternalServerError, "INTERNAL_ERROR"},
+		{"database error on cursor page", stubStorage{err: errDatabase}, "/api/students?after=MQ", http.StatusInternalServerError, "INTERNAL_ERROR"},
+		{"database error as JSONL", stubStorage{err: errDatabase}, "/api/students?format=jsonl", http.StatusInternalServerError, "INTERNAL_ERROR"},
+	}
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(student.GetList(tt.store, 50), request{method: http.MethodGet, target: tt.target})
+			checkError(t, rec, tt.status, tt.code)
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     string // "" for the seeded student
+		body   string
+		status int
+		code   string
+	}{
+		{"updated", "", `{"name":"Rakesh K","email":"RK@test.com","age":36,"version":1}`, http.StatusOK, ""},
+		{"invalid id", "abc", `{}`, http.StatusBadRequest, "BAD_REQUEST"},
+		{"empty body", "", ``, http.StatusBadRequest, "BAD_REQUEST"},
+		{"malformed JSON", "", `{"name"`, http.StatusBadRequest, "BAD_REQUEST"},
+		{"no version", "", `{"name":"Rakesh","email":"r@test.com","age":36}`, http.StatusBadRequest, "BAD_REQUEST"},
+		{"invalid", "", `{"name":"","email":"r@test.com","age":36,"version":1}`, http.StatusUnprocessableEntity, "VALIDATION_ERROR"},
+		{"not found", "999", `{"name":"Rakesh","email":"r@test.com","age":36,"version":1}`, http.StatusNotFound, "NOT_FOUND"},
+		{"stale version", "", `{"name":"Rakesh","email":"r@test.com","age":36,"version":7}`, http.StatusConflict, "VERSION_CONFLICT"},
+		{"email taken", "", `{"name":"Rakesh","email":"priya@test.com","age":36,"version":1}`, http.StatusConflict, "DUPLICATE_ENTRY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newStore(t)
+			rakesh := seed(t, db, "Rakesh", "rakesh@test.com", 35)
+			seed(t, db, "Priya", "priya@test.com", 22)
+
+			id := tt.id
+			if id == "" {
+				id = fmt.Sprint(rakesh.ID)
+			}
+			rec := serve(student.Update(db), request{method: http.MethodPut, target: "/api/students/" + id, id: id, body: tt.body})
+
+			if tt.code != "" {
+				checkError(t, rec, tt.status, tt.code)
+				return
+			}
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d\nbody: %s", rec.Code, tt.status, rec.Body)
+			}
+
+			var got types.Student
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if got.ID != rakesh.ID || got.Name != "Rakesh K" || got.Email != "rk@test.com" || got.Age != 36 {
+				t.Errorf("updated = %+v", got)
+			}
+			if got.Version != 2 {
+				t.Errorf("version = %d, want 2", got.Version)
+			}
+			if !got.CreatedAt.Equal(rakesh.CreatedAt) {
+				t.Errorf("created_at changed from %v to %v", rakesh.CreatedAt, got.CreatedAt)
+			}
+		})
+	}
+
+	t.Run("database error", func(t *testing.T) {
+		rec := serve(student.Update(stubStorage{err: errDatabase}), request{method: http.MethodPut, target: "/api/students/1", id: "1",
+			body: `{"name":"Rakesh","email":"r@test.com","age":36,"version":1}`})
+		checkError(t, rec, http.StatusInternalServerError, "INTERNAL_ERROR")
+	})
+}
+
+func TestDelete(t *testing.T) {
+	db := newStore(t)
+	rakesh := seed(t, db, "Rakesh", "rakesh@test.com", 35)
+	id := fmt.Sprint(rakesh.ID)
+
+	rec := serve(student.Delete(db), request{method: http.MethodDelete, target: "/api/students/" + id, id: id})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if body := decode(t, rec); body["status"] != "deleted" || len(body) != 1 {
+		t.Errorf(`body = %v, want {"status": "deleted"}`, body)
+	}
+
+	// Gone from reads, and a second delete finds nothing.
+	rec = serve(student.GetByID(db), request{method: http.MethodGet, target: "/api/students/" + id, id: id})
+	checkError(t, rec, http.StatusNotFound, "NOT_FOUND")
+
+	failures := []struct {
+		name   string
+		store  storage.Storage
+		id     string
+		status int
+		code   string
+	}{
+		{"already deleted", db, id, http.StatusNotFound, "NOT_FOUND"},
+		{"not found", db, "999", http.StatusNotFound, "NOT_FOUND"},
+		{"invalid id", db, "abc", http.StatusBadRequest, "BAD_REQUEST"},
+		{"database error", stubStorage{err: errDatabase}, "1", http.StatusInternalServerError, "INTERNAL_ERROR"},
+	}
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(student.Delete(tt.store), request{method: http.MethodDelete, target: "/api/students/" + tt.id, id: tt.id})
+			checkError(t, rec, tt.status, tt.code)
+		})
+	}
+}
+
+func TestGetListJSONL(t *testing.T) {
+	ts := apitest.NewTestServer(t)
+	for i := 1; i <= 10; i++ {
+		ts.CreateStudent(fmt.Sprintf("Student %d", i), fmt.Sprintf("s%d@test.com", i), 20+i)
+	}
+
+	resp := ts.GET("/api/students?format=jsonl")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q, want application/x-ndjson", ct)
+	}
+
+	// Each line is read and decoded on its own, the way a streaming
+	// client would.
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var s types.Student
+		if err := json.Unmarshal(scanner.Bytes(), &s); err != nil {
+			t.Fatalf("line %q is not a JSON object: %v", scanner.Text(), err)
+		}
+		seen[s.Email] = true
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	if len(seen) != 10 {
+		t.Errorf("got %d distinct students, want 10", len(seen))
+	}
+}
+
+func TestGetListJSONLFiltered(t *testing.T) {
+	ts := apitest.NewTestServer(t)
+	ts.CreateStudent("Young", "young@test.com", 18)
+	ts.CreateStudent("Old", "old@test.com", 60)
+
+	resp := ts.GET("/api/students?format=jsonl&age_min=30")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	body, _ := io.ReadAll(resp.Body)
+
+	var s types.Student
+	if err := json.Unmarshal(body, &s); err != nil {
+		t.Fatalf("body is not one JSON object: %v\n%s", err, body)
+	}
+	if s.Email != "old@test.com" {
+		t.Errorf("got %s, want old@test.com", s.Email)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	ts := apitest.NewTestServer(t)
+	existing := ts.CreateStudent("Rakesh", "rakesh@test.com", 35)
+
+	resp := ts.PUT("/api/students/batch/upsert", []map[string]any{
+		{"name": "Rakesh K", "email": "rakesh@test.com", "age": 36},
+		{"name": "Priya", "email": "priya@test.com", "age": 22},
+	})
+	if resp.StatusCode != http.StatusMultiStatus {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusMultiStatus)
+	}
+
+	var results []types.UpsertResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if r := results[0]; r.StudentID != existing || r.Action != types.UpsertActionUpdated {
+		t.Errorf("results[0] = %+v, want id %d updated", r, existing)
+	}
+	if r := results[1]; r.Email != "priya@test.com" || r.Action != types.UpsertActionCreated {
+		t.Errorf("results[1] = %+v, want priya@test.com created", r)
+	}
+}
+
+func TestUpsertRejectsBadBody(t *testing.T) {
+	ts := apitest.NewTestServer(t)
+
+	tests := []struct {
+		name string
+		body any
+		want int
+	}{
+		{"malformed JSON", `[{"name":`, http.StatusBadRequest},
+		{"empty array", `[]`, http.StatusBadRequest},
+		{"invalid student", []map[string]any{{"name": "No Email", "age": 20}}, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := ts.PUT("/api/students/batch/upsert", tt.body); resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+
+	// A rejected batch writes nothing.
+	if n, _ := ts.Storage.CountStudents(context.Background(), types.DefaultTenant); n != 0 {
+		t.Errorf("CountStudents = %d after rejected batches, want 0", n)
+	}
+}
+
+func TestEmailCaseVariants(t *testing.T) {
+	ts := apitest.NewTestServer(t)
+
+	resp := ts.POST("/api/students", map[string]any{"name": "Rakesh", "email": "rakesh@test.com", "age": 35})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("first create: status = %d, want %d", resp.StatusCode, http.StatusCreated)
+	}
+
+	resp = ts.POST("/api/students", map[string]any{"name": "Rakesh", "email": "RAKESH@TEST.COM", "age": 35})
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("create RAKESH@TEST.COM: status = %d, want %d", resp.StatusCode, http.StatusConflict)
+	}
+
+	for _, email := range []string{"rakesh@test.com", "RAKESH@TEST.COM", "Rakesh@Test.Com"} {
+		resp := ts.GET("/api/students/by-email?email=" + email)
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("by-email %s: status = %d, want %d", email, resp.StatusCode, http.StatusOK)
+			continue
+		}
+		var s types.Student
+		if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if s.Email != "rakesh@test.com" {
+			t.Errorf("by-email %s: got %s, want rakesh@test.com", email, s.Email)
+		}
+	}
+}
+
+func TestGetByExternalID(t *testing.T) {
+	db := newStore(t)
+	externalID := "SIS-1001"
+	if _, err := db.ImportStudents(context.Background(), types.DefaultTenant, []types.Student{
+		{Name: "Rakesh", Email: "rakesh@test.com", Age: 35, ExternalID: &externalID},
+	}); err != nil {
+		t.Fatalf("ImportStudents: %v", err)
+	}
+
+	get := func(id string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/students/external/"+id, nil)
+		r.SetPathValue("external_id", id)
+		rec := httptest.NewRecorder()
+		student.GetByExternalID(db)(rec, r)
+		return rec
+	}
+
+	rec := get(externalID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if body := decode(t, rec); body["external_id"] != externalID || body["email"] != "rakesh@test.com" {
+		t.Errorf("body = %v, want Rakesh with external_id %s", body, externalID)
+	}
+
+	checkError(t, get("SIS-9999"), http.StatusNotFound, "NOT_FOUND")
+}
+
+func TestGetByEmail(t *testing.T) {
+	db := newStore(t)
+	seed(t, db, "Rakesh", "rakesh@test.com", 35)
+
+	tests := []struct {
+		name   string
+		store  storage.Storage
+		target string
+		status int
+		code   string
+	}{
+		{"found", db, "/api/students/by-email?email=Rakesh@Test.com", http.StatusOK, ""},
+		{"missing email", db, "/api/students/by-email", http.StatusBadRequest, "BAD_REQUEST"},
+		{"blank email", db, "/api/students/by-email?email=%20", http.StatusBadRequest, "BAD_REQUEST"},
+		{"not found", db, "/api/students/by-email?email=nobody@test.com", http.StatusNotFound, "NOT_FOUND"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(student.GetByEmail(tt.store), request{method: http.MethodGet, target: tt.target})
+			if tt.code != "" {
+				checkError(t, rec, tt.status, tt.code)
+				return
+			}
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if body := decode(t, rec); body["name"] != "Rakesh" {
+				t.Errorf("body = %v, want Rakesh", body)
+			}
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	db := newStore(t)
+	seed(t, db, "Rakesh", "rakesh@test.com", 35)
+	seed(t, db, "Priya", "priya@test.com", 21)
+
+	rec := serve(student.Stats(db), request{method: http.MethodGet, target: "/api/students/stats"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var stats types.StudentStats
+	json.Unmarshal(rec.Body.Bytes(), &stats)
+	want := types.StudentStats{Total: 2, AvgAge: 28, MinAge: 21, MaxAge: 35}
+	if stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+
+	rec = serve(student.Stats(stubStorage{err: errDatabase}), request{method: http.MethodGet, target: "/api/students/stats"})
+	checkError(t, rec, http.StatusInternalServerError, "INTERNAL_ERROR")
+
+	rec = serve(student.Stats(stubStorage{err: storage.ErrUnavailable}), request{method: http.MethodGet, target: "/api/students/stats"})
+	checkError(t, rec, http.StatusServiceUnavailable, "INTERNAL_ERROR")
+}
+
+func TestGetRandom(t *testing.T) {
+	db := newStore(t)
+
+	rec := serve(student.GetRandom(db), request{method: http.MethodGet, target: "/api/students/random"})
+	checkError(t, rec, http.StatusNotFound, "NOT_FOUND")
+
+	seed(t, db, "Rakesh", "rakesh@test.com", 35)
+	rec = serve(student.GetRandom(db), request{method: http.MethodGet, target: "/api/students/random"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Cache-Control = %q, want no-store", got)
+	}
+	if body := decode(t, rec); body["name"] != "Rakesh" {
+		t.Errorf("body = %v, want the only student", body)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	db := newStore(t)
+	seed(t, db, "Rakesh", "rakesh@test.com", 35)
+	seed(t, db, "Priya", "priya@test.com", 22)
+	seed(t, db, "Rakhi", "rakhi@other.com", 19)
+
+	tests := []struct {
+		name   string
+		store  storage.Storage
+		q      string
+		want   []string // names, in order: newest first
+		status int
+		code   string
+	}{
+		{"by name", db, "RAK", []string{"Rakhi", "Rakesh"}, http.StatusOK, ""},
+		{"by email", db, "other.com", []string{"Rakhi"}, http.StatusOK, ""},
+		{"no match", db, "zzz", []string{}, http.StatusOK, ""},
+		{"blank", db, "%20", nil, http.StatusBadRequest, "BAD_REQUEST"},
+		{"encrypted storage", stubStorage{err: storage.ErrUnsupportedFilter}, "rak", nil, http.StatusBadRequest, "BAD_REQUEST"},
+		{"database error", stubStorage{err: errDatabase}, "rak", nil, http.StatusInternalServerError, "INTERNAL_ERROR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(student.Search(tt.store), request{method: http.MethodGet, target: "/api/students/search?q=" + tt.q})
+			if tt.code != "" {
+				checkError(t, rec, tt.status, tt.code)
+				return
+			}
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			var got []types.Student
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("body is not a JSON array: %v\n%s", err, rec.Body)
+			}
+			names := make([]string, 0, len(got))
+			for _, s := range got {
+				names = append(names, s.Name)
+			}
+			if fmt.Sprint(names) != fmt.Sprint(tt.want) {
+				t.Errorf("names = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatch(t *testing.T) {
+	const mergePatch = "application/merge-patch+json"
+
+	tests := []struct {
+		name        string
+		id          string // "" for the seeded student
+		contentType string
+		body        string
+		status      int
+		code        string
+	}{
+		{"patched", "", mergePatch, `{"age":36,"phone":"+14155552671"}`, http.StatusOK, ""},
+		{"with charset", "", mergePatch + "; charset=utf-8", `{"age":36,"phone":"+14155552671"}`, http.StatusOK, ""},
+		{"invalid id", "abc", mergePatch, `{}`, http.StatusBadRequest, "BAD_REQUEST"},
+		{"wrong content type", "", "application/json", `{"age":36}`, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE"},
+		{"empty body", "", mergePatch, ``, http.StatusBadRequest, "BAD_REQUEST"},
+		{"not an object", "", mergePatch, `[1,2]`, http.StatusBadRequest, "BAD_REQUEST"},
+		{"null body", "", mergePatch, `null`, http.StatusBadRequest, "BAD_REQUEST"},
+		{"unknown field", "", mergePatch, `{"password":"x"}`, http.StatusBadRequest, "BAD_REQUEST"},
+		{"required field set to null", "", mergePatch, `{"name":null}`, http.StatusBadRequest, "BAD_REQUEST"},
+		{"invalid value", "", mergePatch, `{"age":0}`, http.StatusUnprocessableEntity, "VALIDATION_ERROR"},
+		{"not found", "999", mergePatch, `{"age":36}`, http.StatusNotFound, "NOT_FOUND"},
+		{"email taken", "", mergePatch, `{"email":"Priya@test.com"}`, http.StatusConflict, "DUPLICATE_ENTRY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newStore(t)
+			rakesh := seed(t, db, "Rakesh", "rakesh@test.com", 35)
+			seed(t, db, "Priya", "priya@test.com", 22)
+
+			id := tt.id
+			if id == "" {
+				id = fmt.Sprint(rakesh.ID)
+			}
+			rec := serve(student.Patch(db), request{method: http.MethodPatch, target: "/api/students/" + id, id: id, body: tt.body,
+				headers: map[string]string{"Content-Type": tt.contentType}})
+
+			if tt.code != "" {
+				checkError(t, rec, tt.status, tt.code)
+				return
+			}
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d\nbody: %s", rec.Code, tt.status, rec.Body)
+			}
+			var got types.Student
+			json.Unmarshal(rec.Body.Bytes(), &got)
+			if got.Age != 36 || got.Phone != "+14155552671" || got.Name != "Rakesh" || got.Email != "rakesh@test.com" {
+				t.Errorf("patched = %+v, want only age and phone changed", got)
+			}
+		})
+	}
+}
+
+// staleReads is a storage whose GetStudentByID answers from the wrapped
+// storage, but flagged stale, like the cache serving its last copy while
+// the database is unreachable.
+type staleReads struct {
+	storage.Storage
+}
+
+func (s staleReads) GetStudentByID(ctx context.Context, tenantID string, id int64) (types.Student, error) {
+	student, err := s.Storage.GetStudentByID(ctx, tenantID, id)
+	if err != nil {
+		return student, err
+	}
+	return student, fmt.Errorf("GetStudentByID: %w", storage.ErrStale)
+}
+
+func TestPatchFromStaleRead(t *testing.T) {
+	db := newStore(t)
+	rakesh := seed(t, db, "Rakesh", "rakesh@test.com", 35)
+	id := fmt.Sprint(rakesh.ID)
+
+	rec := serve(student.Patch(staleReads{db}), request{method: http.MethodPatch, target: "/api/students/" + id, id: id,
+		body: `{"age":36}`, headers: map[string]string{"Content-Type": "application/merge-patch+json"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d\nbody: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	// The response is the student as written, not the stale copy.
+	if w := rec.Header().Get("Warning"); w != "" {
+		t.Errorf("Warning = %q, want none", w)
+	}
+	if got := decode(t, rec); got["age"] != float64(36) {
+		t.Errorf("age = %v, want 36", got["age"])
+	}
+}
+
+func TestPatchClearsField(t *testing.T) {
+	db := newStore(t)
+	id, err := db.CreateStudent(context.Background(), types.DefaultTenant, "Rakesh", "rakesh@test.com", 35, "CS", "+14155552671")
+	if err != nil {
+		t.Fatalf("CreateStudent: %v", err)
+	}
+	target := fmt.Sprint(id)
+
+	rec := serve(student.Patch(db), request{method: http.MethodPatch, target: "/api/students/" + target, id: target,
+		body: `{"phone":null}`, headers: map[string]string{"Content-Type": "application/merge-patch+json"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d\nbody: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	stored, err := db.GetStudentByID(context.Background(), types.DefaultTenant, id)
+	if err != nil {
+		t.Fatalf("GetStudentByID: %v", err)
+	}
+	if stored.Phone != "" || stored.Department != "CS" || stored.Version != 2 {
+		t.Errorf("stored = %+v, want only the phone cleared and the version bumped", stored)
+	}
+}
+
+func TestBatchDelete(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		code    string
+		missing []int64
+	}{
+		{"deleted", `{"ids":[1,2]}`, http.StatusOK, "", nil},
+		{"empty body", ``, http.StatusBadRequest, "BAD_REQUEST", nil},
+		{"malformed JSON", `{"ids":`, http.StatusBadRequest, "BAD_REQUEST", nil},
+		{"no ids", `{"ids":[]}`, http.StatusBadRequest, "BAD_REQUEST", nil},
+		{"non-positive id", `{"ids":[1,0]}`, http.StatusBadRequest, "BAD_REQUEST", nil},
+		{"too many ids", `{"ids":[` + strings.Repeat("1,", 1000) + `1]}`, http.StatusBadRequest, "BAD_REQUEST", nil},
+		{"some missing", `{"ids":[1,7,9]}`, http.StatusNotFound, "NOT_FOUND", []int64{7, 9}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newStore(t)
+			seed(t, db, "Rakesh", "rakesh@test.com", 35)
+			seed(t, db, "Priya", "priya@test.com", 22)
+
+			rec := serve(student.BatchDelete(db), request{method: http.MethodDelete, target: "/api/students/batch", body: tt.body})
+
+			if tt.code != "" {
+				checkError(t, rec, tt.status, tt.code)
+				if tt.missing != nil {
+					var body struct {
+						MissingIDs []int64 `json:"missing_ids"`
+					}
+					json.Unmarshal(rec.Body.Bytes(), &body)
+					if fmt.Sprint(body.MissingIDs) != fmt.Sprint(tt.missing) {
+						t.Errorf("missing_ids = %v, want %v", body.MissingIDs, tt.missing)
+					}
+					// All or nothing: student 1 is still there.
+					if _, err := db.GetStudentByID(context.Background(), types.DefaultTenant, 1); err != nil {
+						t.Errorf("student 1 was deleted by a failed batch: %v", err)
+					}
+				}
+				return
+			}
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d\nbody: %s", rec.Code, tt.status, rec.Body)
+			}
+			if body := decode(t, rec); body["deleted"] != float64(2) {
+				t.Errorf(`body = %v, want {"deleted": 2}`, body)
+			}
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	db := newStore(t)
+	rakesh := seed(t, db, "Rakesh", "rakesh@test.com", 35)
+	id := fmt.Sprint(rakesh.ID)
+
+	// Not deleted yet, so there's nothing to restore.
+	rec := serve(student.Restore(db), request{method: http.MethodPost, target: "/api/students/" + id + "/restore", id: id})
+	checkError(t, rec, http.StatusNotFound, "NOT_FOUND")
+
+	if err := db.DeleteStudentByID(context.Background(), types.DefaultTenant, int64(rakesh.ID)); err != nil {
+		t.Fatalf("DeleteStudentByID: %v", err)
+	}
+	rec = serve(student.Restore(db), request{method: http.MethodPost, target: "/api/students/" + id + "/restore", id: id})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d\nbody: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if body := decode(t, rec); body["status"] != "restored" {
+		t.Errorf(`body = %v, want {"status": "restored"}`, body)
+	}
+	if _, err := db.GetStudentByID(context.Background(), types.DefaultTenant, int64(rakesh.ID)); err != nil {
+		t.Errorf("restored student not readable: %v", err)
+	}
+
+	rec = serve(student.Restore(db), request{method: http.MethodPost, target: "/api/students/x/restore", id: "x"})
+	checkError(t, rec, http.StatusBadRequest, "BAD_REQUEST")
+}
+
+func TestBatchCreate(t *testing.T) {
+	db := newStore(t)
+	seed(t, db, "Rakesh", "rakesh@test.com", 35)
+
+	rec := serve(student.BatchCreate(db), request{method: http.MethodPost, target: "/api/students/batch", body: `[
+		{"name":"Priya","email":"priya@test.com","age":22},
+		{"name":"","email":"nameless@test.com","age":22},
+		{"name":"Rakesh again","email":"RAKESH@test.com","age":40},
+		{"name":"Amit","email":"amit@test.com","age":19}
+	]`})
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("status = %d, want %d\nbody: %s", rec.Code, http.StatusMultiStatus, rec.Body)
+	}
+
+	var results []types.BatchCreateResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := []struct {
+		status int
+		code   string
+	}{
+		{http.StatusCreated, ""},
+		{http.StatusUnprocessableEntity, "VALIDATION_ERROR"},
+		{http.StatusConflict, "DUPLICATE_ENTRY"},
+		{http.StatusCreated, ""},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.Index != i || r.Status != w.status || r.ErrorCode != w.code {
+			t.Errorf("result %d = %+v, want status %d, code %q", i, r, w.status, w.code)
+		}
+		if (r.Status == http.StatusCreated) != (r.ID != 0) {
+			t.Errorf("result %d: id = %d with status %d", i, r.ID, r.Status)
+		}
+	}
+
+	failures := []struct {
+		name   string
+		store  storage.Storage
+		body   string
+		status int
+		code   string
+	}{
+		{"empty body", db, ``, http.StatusBadRequest, "BAD_REQUEST"},
+		{"not an array", db, `{"name":"Priya"}`, http.StatusBadRequest, "BAD_REQUEST"},
+		{"empty array", db, `[]`, http.StatusBadRequest, "BAD_REQUEST"},
+		{"capacity reached", stubStorage{err: storage.ErrCapacityExceeded}, `[{"name":"A","email":"a@test.com","age":20}]`, http.StatusForbidden, "CAPACITY_EXCEEDED"},
+		{"database error", stubStorage{err: errDatabase}, `[{"name":"A","email":"a@test.com","age":20}]`, http.StatusInternalServerError, "INTERNAL_ERROR"},
+	}
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(student.BatchCreate(tt.store), request{method: http.MethodPost, target: "/api/students/batch", body: tt.body})
+			checkError(t, rec, tt.status, tt.code)
+		})
+	}
+}
+
+func TestGetRandomVaries(t *testing.T) {
+	ts := apitest.NewTestServer(t)
+	for i := 1; i <= 10; i++ {
+		ts.CreateStudent(fmt.Sprintf("Student %d", i), fmt.Sprintf("s%d@test.com", i), 20+i)
+	}
+
+	// With 10 students, 10 draws all landing on the same one has a
+	// probability of 10⁻⁹: if they do, the choice isn't random.
+	seen := map[int]int{}
+	for range 10 {
+		resp := ts.GET("/api/students/random")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+		}
+		if cc := resp.Header.Get("Cache-Control"); cc != "no-store" {
+			t.Errorf("Cache-Control = %q, want no-store", cc)
+		}
+		var s types.Student
+		json.NewDecoder(resp.Body).Decode(&s)
+		resp.Body.Close()
+		seen[s.ID]++
+	}
+
+	if len(seen) < 2 {
+		t.Errorf("10 random draws all returned the same student: %v", seen)
+	}
+}
+
+func TestUpsertStorageErrors(t *testing.T) {
+	body := `[{"name":"Rakesh","email":"rakesh@test.com","age":35}]`
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"capacity reached", storage.ErrCapacityExceeded, http.StatusForbidden, "CAPACITY_EXCEEDED"},
+		{"database unavailable", storage.ErrUnavailable, http.StatusServiceUnavailable, "INTERNAL_ERROR"},
+		{"database error", errDatabase, http.StatusInternalServerError, "INTERNAL_ERROR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(student.Upsert(stubStorage{err: tt.err}), request{method: http.MethodPut, target: "/api/students/batch/upsert", body: body})
+			checkError(t, rec, tt.status, tt.code)
+		})
+	}
+}
+
+// downStorage is a database that has gone away: every call fails with a
+// dropped connection once down is set.
+type downStorage struct {
+	storage.Storage
+	down bool
+}
+
+func (d *downStorage) fail() error {
+	if d.down {
+		return fmt.Errorf("query: %w", driver.ErrBadConn)
+	}
+	return nil
+}
+
+func (d *downStorage) GetStudentByID(ctx context.Context, tenantID string, id int64) (types.Student, error) {
+	if err := d.fail(); err != nil {
+		return types.Student{}, err
+	}
+	return d.Storage.GetStudentByID(ctx, tenantID, id)
+}
+
+func (d *downStorage) GetStudents(ctx context.Context, tenantID string) ([]types.Student, error) {
+	if err := d.fail(); err != nil {
+		return nil, err
+	}
+	return d.Storage.GetStudents(ctx, tenantID)
+}
+
+func (d *downStorage) GetStudentsFiltered(ctx context.Context, tenantID string, opts types.FilterOptions) ([]types.Student, int64, error) {
+	if err := d.fail(); err != nil {
+		return nil, 0, err
+	}
+	return d.Storage.GetStudentsFiltered(ctx, tenantID, opts)
+}
+
+func (d *downStorage) CreateStudent(ctx context.Context, tenantID, name, email string, age int, department, phone string) (int64, error) {
+	if err := d.fail(); err != nil {
+		return 0, err
+	}
+	return d.Storage.CreateStudent(ctx, tenantID, name, email, age, department, phone)
+}
+
+func TestDegradedDatabase(t *testing.T) {
+	inner := &downStorage{Storage: newStore(t)}
+	db := cache.New(inner, config.Database{StaleReadOnFailure: true})
+	rakesh := seed(t, db, "Rakesh", "rakesh@test.com", 35)
+	id := fmt.Sprint(rakesh.ID)
+
+	// Warm the cache, then take the database away.
+	serve(student.GetList(db, 50), request{method: http.MethodGet, target: "/api/students?format=jsonl"})
+	inner.down = true
+
+	for _, tt := range []struct {
+		name string
+		h    http.Handler
+		req  request
+	}{
+		{"GET one", student.GetByID(db), request{method: http.MethodGet, target: "/api/students/" + id, id: id}},
+		{"GET list", student.GetList(db, 50), request{method: http.MethodGet, target: "/api/students?format=jsonl"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(tt.h, tt.req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d\nbody: %s", rec.Code, http.StatusOK, rec.Body)
+			}
+			if w := rec.Header().Get("Warning"); w != `110 - "Response is Stale"` {
+				t.Errorf("Warning = %q, want the stale warning", w)
+			}
+			if !strings.Contains(rec.Body.String(), `"name":"Rakesh"`) {
+				t.Errorf("body = %s, want the cached student", rec.Body)
+			}
+		})
+	}
+
+	t.Run("POST", func(t *testing.T) {
+		rec := serve(student.New(db), request{method: http.MethodPost, target: "/api/students",
+			body: `{"name":"Priya","email":"priya@test.com","age":22}`})
+		checkError(t, rec, http.StatusServiceUnavailable, "INTERNAL_ERROR")
+	})
+
+	t.Run("GET uncached", func(t *testing.T) {
+		rec := serve(student.GetByID(db), request{method: http.MethodGet, target: "/api/students/99", id: "99"})
+		checkError(t, rec, http.StatusInternalServerError, "INTERNAL_ERROR")
+	})
+}
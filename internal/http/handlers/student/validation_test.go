@@ -0,0 +1,39 @@
+package student
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aanand-mishra/students-api/internal/types"
+	"github.com/aanand-mishra/students-api/internal/validation"
+)
+
+func TestValidationError(t *testing.T) {
+	fieldErrs := validation.Validator().Struct(types.Student{})
+	if fieldErrs == nil {
+		t.Fatal("an empty student passed validation")
+	}
+	// Not a struct: the validator answers with an
+	// *validator.InvalidValidationError instead of field errors.
+	invalid := validation.Validator().Struct(42)
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"field errors", fieldErrs, http.StatusUnprocessableEntity, "VALIDATION_ERROR"},
+		{"wrapped field errors", fmt.Errorf("validating: %w", fieldErrs), http.StatusUnprocessableEntity, "VALIDATION_ERROR"},
+		{"not a struct", invalid, http.StatusBadRequest, "BAD_REQUEST"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := validationError(tt.err)
+			if status != tt.status || resp.ErrorCode != tt.code {
+				t.Errorf("validationError = %d %s, want %d %s", status, resp.ErrorCode, tt.status, tt.code)
+			}
+		})
+	}
+}
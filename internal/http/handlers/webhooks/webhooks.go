@@ -0,0 +1,192 @@
+// Package webhooks serves /api/webhooks: registering, listing and
+// removing the URLs that are notified of changes to students. Delivery
+// itself is done by the webhook package.
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/aanand-mishra/students-api/internal/http/middleware"
+	"github.com/aanand-mishra/students-api/internal/http/middleware/bodylimit"
+	"github.com/aanand-mishra/students-api/internal/http/middleware/requestid"
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/types"
+	"github.com/aanand-mishra/students-api/internal/utils/response"
+	"github.com/aanand-mishra/students-api/internal/webhook"
+)
+
+// createRequest is the body of POST /api/webhooks.
+type createRequest struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret"`
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Create handles POST /api/webhooks
+// Registers a URL to be sent every change to the caller's tenant's students
+// from now on.
+//
+// Request body (JSON):
+//
+//	{ "url": "https://lms.example.com/hooks/students", "secret": "optional" }
+//
+// Without a secret, a random one is generated. Either way it is returned
+// here, and only here: keep it to check the X-Webhook-Signature header of
+// each delivery.
+//
+// Success response (201 Created):
+//
+//	{ "id": 1, "url": "https://lms.example.com/hooks/students",
+//	  "secret": "9f86d081...", "created_at": "2024-06-01T10:00:00Z" }
+//
+// Error responses:
+//
+//	400 Bad Request  — empty body, malformed JSON, or a url that isn't an
+//	                   absolute http or https URL, or points at a
+//	                   loopback, private or link-local address (see
+//	                   webhook.CheckURL)
+//	500 Internal     — database error
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func Create(store webhook.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
+		log.Info("creating a webhook")
+
+		var req createRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if errors.Is(err, io.EOF) {
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New("request body is empty")))
+			return
+		}
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				bodylimit.WriteTooLarge(w, tooLarge.Limit)
+				return
+			}
+			response.WriteJSON(w, http.StatusBadRequest, response.BadRequestError(err))
+			return
+		}
+
+		if err := webhook.CheckURL(req.URL); err != nil {
+			response.WriteJSON(w, http.StatusBadRequest, response.BadRequestError(err))
+			return
+		}
+		if req.Secret == "" {
+			req.Secret = newSecret()
+		}
+
+		sub, err := store.CreateWebhook(r.Context(), middleware.TenantFromContext(r.Context()), types.WebhookSubscription{URL: req.URL, Secret: req.Secret})
+		if err != nil {
+			log.Error("error creating webhook", slog.String("error", err.Error()))
+			response.WriteJSON(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+
+		log.Info("webhook created", slog.Int64("id", sub.ID))
+		response.WriteJSON(w, http.StatusCreated, sub)
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// List handles GET /api/webhooks
+// Returns every webhook the caller's tenant registered, in id order,
+// without its secret.
+//
+// Success response (200 OK):
+//
+//	[ { "id": 1, "url": "https://lms.example.com/hooks/students",
+//	    "created_at": "2024-06-01T10:00:00Z" } ]
+//
+// Error responses:
+//
+//	500 Internal     — database error
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func List(store webhook.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
+		log.Info("listing webhooks")
+
+		subs, err := store.GetWebhooks(r.Context(), middleware.TenantFromContext(r.Context()))
+		if err != nil {
+			log.Error("error listing webhooks", slog.String("error", err.Error()))
+			response.WriteJSON(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+
+		for i := range subs {
+			subs[i].Secret = ""
+		}
+		response.WriteJSON(w, http.StatusOK, subs)
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Delete handles DELETE /api/webhooks/{id}
+// Unregisters a webhook. Deliveries already under way still finish.
+//
+// Success response (200 OK):
+//
+//	{ "status": "deleted" }
+//
+// Error responses:
+//
+//	400 Bad Request  — id is not a valid integer
+//	404 Not Found    — no webhook with that id in the caller's tenant
+//	500 Internal     — database error
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func Delete(store webhook.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logFromContext(r.Context())
+		id := r.PathValue("id")
+		log.Info("deleting a webhook", slog.String("id", id))
+
+		intID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			response.WriteJSON(w, http.StatusBadRequest,
+				response.BadRequestError(errors.New("invalid id: must be an integer")))
+			return
+		}
+
+		if err := store.DeleteWebhook(r.Context(), middleware.TenantFromContext(r.Context()), intID); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				response.NotFound(w, "no webhook found")
+				return
+			}
+			log.Error("error deleting webhook",
+				slog.String("id", id),
+				slog.String("error", err.Error()))
+			response.WriteJSON(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+
+		log.Info("webhook deleted", slog.String("id", id))
+		response.WriteJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+	}
+}
+
+// newSecret returns 32 random bytes, hex encoded.
+func newSecret() string {
+	b := make([]byte, 32)
+	// crypto/rand.Read never fails on the platforms Go supports.
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// logFromContext returns the request's logger, which adds the request ID
+// to every line (see requestid.Logger).
+func logFromContext(ctx context.Context) *slog.Logger {
+	return requestid.Logger(ctx)
+}
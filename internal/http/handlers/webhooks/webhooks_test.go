@@ -0,0 +1,155 @@
+package webhooks_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aanand-mishra/students-api/internal/config"
+	"github.com/aanand-mishra/students-api/internal/http/handlers/webhooks"
+	"github.com/aanand-mishra/students-api/internal/http/middleware/auth"
+	"github.com/aanand-mishra/students-api/internal/storage/sqlite"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+func newStore(t *testing.T) *sqlite.SQLite {
+	t.Helper()
+
+	db, err := sqlite.New(&config.Config{
+		StoragePath: filepath.Join(t.TempDir(), "test.db"),
+		Database:    config.Database{AutoMigrate: true},
+		SQLite:      config.SQLite{JournalMode: config.JournalModeWAL, BusyTimeoutMs: 5000},
+	})
+	if err != nil {
+		t.Fatalf("sqlite.New: %v", err)
+	}
+	t.Cleanup(func() { db.Db.Close() })
+	return db
+}
+
+// serve runs h on a request made with a token of tenant, the way
+// auth.JWTMiddleware would hand it over.
+func serve(h http.HandlerFunc, tenant, method, target, body, id string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req = req.WithContext(auth.NewContext(req.Context(), &auth.Claims{TenantID: tenant}))
+	if id != "" {
+		req.SetPathValue("id", id)
+	}
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	return rec
+}
+
+func list(t *testing.T, db *sqlite.SQLite, tenant string) []types.WebhookSubscription {
+	t.Helper()
+
+	rec := serve(webhooks.List(db), tenant, http.MethodGet, "/api/webhooks", "", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("List: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var subs []types.WebhookSubscription
+	if err := json.Unmarshal(rec.Body.Bytes(), &subs); err != nil {
+		t.Fatalf("List: decode: %v", err)
+	}
+	return subs
+}
+
+func TestCreate(t *testing.T) {
+	db := newStore(t)
+
+	rec := serve(webhooks.Create(db), "acme", http.MethodPost, "/api/webhooks",
+		`{"url": "https://lms.example.com/hooks/students"}`, "")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d\nbody: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+	var sub types.WebhookSubscription
+	if err := json.Unmarshal(rec.Body.Bytes(), &sub); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if sub.ID == 0 || len(sub.Secret) != 64 {
+		t.Errorf("got id %d and a %d-character secret, want an id and a generated 64-character secret", sub.ID, len(sub.Secret))
+	}
+
+	failures := []struct {
+		name string
+		body string
+	}{
+		{"empty body", ``},
+		{"malformed JSON", `{"url":`},
+		{"no url", `{"secret": "x"}`},
+		{"not http", `{"url": "ftp://lms.example.com/"}`},
+		{"localhost", `{"url": "http://localhost:9000/hook"}`},
+		{"loopback", `{"url": "http://127.0.0.1:9000/hook"}`},
+		{"private network", `{"url": "http://10.1.2.3/hook"}`},
+		{"cloud metadata", `{"url": "http://169.254.169.254/latest/meta-data/"}`},
+		{"IPv6 loopback", `{"url": "http://[::1]/hook"}`},
+	}
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(webhooks.Create(db), "acme", http.MethodPost, "/api/webhooks", tt.body, "")
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d\nbody: %s", rec.Code, http.StatusBadRequest, rec.Body)
+			}
+		})
+	}
+
+	if subs := list(t, db, "acme"); len(subs) != 1 {
+		t.Errorf("acme has %d webhooks, want only the valid one", len(subs))
+	}
+}
+
+func TestTenantScoping(t *testing.T) {
+	db := newStore(t)
+	ctx := context.Background()
+
+	acme, err := db.CreateWebhook(ctx, "acme", types.WebhookSubscription{URL: "https://acme.example.com/hook", Secret: "acme-secret"})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	if _, err := db.CreateWebhook(ctx, "other", types.WebhookSubscription{URL: "https://other.example.com/hook", Secret: "other-secret"}); err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+
+	// Each tenant lists only its own, without the secret.
+	subs := list(t, db, "acme")
+	if len(subs) != 1 || subs[0].URL != "https://acme.example.com/hook" {
+		t.Fatalf("acme lists %+v, want only its own webhook", subs)
+	}
+	if subs[0].Secret != "" {
+		t.Error("List returned the secret")
+	}
+	if subs := list(t, db, types.DefaultTenant); len(subs) != 0 {
+		t.Errorf("the default tenant lists %+v, want none", subs)
+	}
+
+	// Another tenant can't delete it: to them it doesn't exist.
+	id := fmt.Sprint(acme.ID)
+	rec := serve(webhooks.Delete(db), "other", http.MethodDelete, "/api/webhooks/"+id, "", id)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("other tenant's delete: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if subs := list(t, db, "acme"); len(subs) != 1 {
+		t.Fatalf("acme has %d webhooks after another tenant's delete, want 1", len(subs))
+	}
+
+	rec = serve(webhooks.Delete(db), "acme", http.MethodDelete, "/api/webhooks/"+id, "", id)
+	if rec.Code != http.StatusOK {
+		t.Errorf("delete: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if subs := list(t, db, "acme"); len(subs) != 0 {
+		t.Errorf("acme still has %+v after deleting it", subs)
+	}
+	if subs := list(t, db, "other"); len(subs) != 1 {
+		t.Errorf("other has %d webhooks, want its 1 untouched", len(subs))
+	}
+
+	rec = serve(webhooks.Delete(db), "acme", http.MethodDelete, "/api/webhooks/abc", "", "abc")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid id: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,92 @@
+// Package limit caps how many connections the HTTP server holds open at
+// once, so a flood of connections can't starve everyone else.
+//
+// It works below HTTP, on the net.Listener: http.Server.Serve pulls
+// connections from Accept, and this wrapper decides which ones it gets.
+package limit
+
+import (
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+// ServiceUnavailable is the raw response sent to connections over the
+// limit. It is written before any request has been read, which is as
+// early as a server can say "busy" in HTTP/1.1.
+var ServiceUnavailable = []byte("HTTP/1.1 503 Service Unavailable\r\nConnection: close\r\nContent-Length: 0\r\n\r\n")
+
+// rejectTimeout bounds how long writing the rejection may take, so slow
+// clients can't tie up goroutines.
+const rejectTimeout = time.Second
+
+// Listener wraps l so that at most max connections are open at a time.
+//
+// A connection accepted while max are already open is sent rejection
+// (if non-nil) and closed straight away; it never reaches the server.
+// Pass nil for TLS listeners — the client expects a handshake there, not
+// plain text. A max of 0 or less disables the limit and returns l as is.
+//
+// A slot is freed when the server closes the connection, including idle
+// keep-alive connections it times out.
+func Listener(l net.Listener, max int, rejection []byte) net.Listener {
+	if max <= 0 {
+		return l
+	}
+	return &listener{
+		Listener:  l,
+		sem:       make(chan struct{}, max),
+		rejection: rejection,
+	}
+}
+
+type listener struct {
+	net.Listener
+	sem       chan struct{} // one token per open connection
+	rejection []byte
+}
+
+// Accept returns the next connection that fits under the limit. Over-limit
+// connections are rejected here and never returned.
+func (l *listener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		select {
+		case l.sem <- struct{}{}:
+			return &limitedConn{Conn: conn, release: func() { <-l.sem }}, nil
+		default:
+			slog.Warn("connection limit reached, rejecting",
+				slog.String("remote_addr", conn.RemoteAddr().String()),
+				slog.Int("max_connections", cap(l.sem)))
+			// Off the accept loop, so one slow client can't stall it.
+			go l.reject(conn)
+		}
+	}
+}
+
+func (l *listener) reject(c net.Conn) {
+	defer c.Close()
+	if l.rejection != nil {
+		c.SetWriteDeadline(time.Now().Add(rejectTimeout))
+		c.Write(l.rejection)
+	}
+}
+
+// limitedConn gives back its semaphore slot when closed. http.Server may call
+// Close more than once, hence the sync.Once.
+type limitedConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *limitedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
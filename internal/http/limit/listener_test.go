@@ -0,0 +1,113 @@
+package limit
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestListenerDisabled(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+
+	for _, max := range []int{0, -1} {
+		if got := Listener(l, max, ServiceUnavailable); got != l {
+			t.Errorf("Listener(l, %d) wrapped the listener; want it returned as is", max)
+		}
+	}
+}
+
+func TestListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			started <- struct{}{}
+			<-release
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})}
+	go srv.Serve(Listener(l, 1, ServiceUnavailable))
+	t.Cleanup(func() { srv.Close() })
+	addr := l.Addr().String()
+
+	// The first connection takes the only slot and holds it.
+	first, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	io.WriteString(first, "GET /slow HTTP/1.1\r\nHost: test\r\nConnection: close\r\n\r\n")
+	<-started
+
+	// The second is turned away before it sends anything.
+	second, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	second.SetReadDeadline(time.Now().Add(5 * time.Second))
+	got, err := io.ReadAll(second)
+	second.Close()
+	if err != nil {
+		t.Fatalf("reading the rejection: %v", err)
+	}
+	if string(got) != string(ServiceUnavailable) {
+		t.Errorf("over the limit got %q, want %q", got, ServiceUnavailable)
+	}
+
+	// Finishing the first request closes its connection and frees the slot.
+	close(release)
+	first.SetReadDeadline(time.Now().Add(5 * time.Second))
+	resp, err := http.ReadResponse(bufio.NewReader(first), nil)
+	if err != nil {
+		t.Fatalf("reading the first response: %v", err)
+	}
+	resp.Body.Close()
+	first.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("first request got %d, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}, Timeout: 5 * time.Second}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := client.Get("http://" + addr + "/")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusNoContent {
+				break
+			}
+		}
+		// The server may not have closed its end of the first connection yet.
+		if time.Now().After(deadline) {
+			t.Fatalf("the slot was never freed: last attempt got %v, %v", resp, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLimitedConnCloseTwice(t *testing.T) {
+	l := &listener{sem: make(chan struct{}, 2)}
+	l.sem <- struct{}{}
+	l.sem <- struct{}{}
+
+	server, client := net.Pipe()
+	defer client.Close()
+	conn := &limitedConn{Conn: server, release: func() { <-l.sem }}
+
+	conn.Close()
+	conn.Close()
+	if len(l.sem) != 1 {
+		t.Errorf("%d slots taken after closing one connection twice, want 1", len(l.sem))
+	}
+}
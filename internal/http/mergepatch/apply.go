@@ -0,0 +1,137 @@
+// Package mergepatch applies JSON Merge Patch documents (RFC 7396) to
+// students.
+//
+// A merge patch is a JSON object listing only the fields to change:
+//
+//	{ "age": 21 }            → set age, leave everything else alone
+//	{ "photo_url": null }    → clear photo_url
+//
+// So each field can be in one of three states, which is why the patch is
+// decoded as map[string]json.RawMessage rather than into a types.Student:
+// a struct can't tell "absent" from "null" from "zero value".
+//
+//	absent → key not in the map      → keep the original value
+//	null   → raw value is `null`     → clear the field
+//	value  → anything else           → decode and set it
+package mergepatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// ContentType is the media type clients must send merge patches with.
+const ContentType = "application/merge-patch+json"
+
+// Apply returns original with patch applied. original is not modified.
+//
+// Every error means the patch itself is invalid (a client error):
+// unknown keys, wrong value types, clearing a required field, or trying
+// to change a field that can't be patched.
+func Apply(original types.Student, patch map[string]json.RawMessage) (types.Student, error) {
+	patched := original
+
+	for key, raw := range patch {
+		null := isNull(raw)
+
+		switch key {
+		case "name":
+			if null {
+				return types.Student{}, fmt.Errorf("field name is required and cannot be null")
+			}
+			if err := json.Unmarshal(raw, &patched.Name); err != nil {
+				return types.Student{}, fmt.Errorf("field name must be a string")
+			}
+
+		case "email":
+			if null {
+				return types.Student{}, fmt.Errorf("field email is required and cannot be null")
+			}
+			if err := json.Unmarshal(raw, &patched.Email); err != nil {
+				return types.Student{}, fmt.Errorf("field email must be a string")
+			}
+
+		case "age":
+			if null {
+				return types.Student{}, fmt.Errorf("field age is required and cannot be null")
+			}
+			if err := json.Unmarshal(raw, &patched.Age); err != nil {
+				return types.Student{}, fmt.Errorf("field age must be an integer")
+			}
+
+		case "department":
+			// Optional, so null clears it.
+			if null {
+				patched.Department = ""
+				continue
+			}
+			if err := json.Unmarshal(raw, &patched.Department); err != nil {
+				return types.Student{}, fmt.Errorf("field department must be a string")
+			}
+
+		case "phone":
+			// Optional, so null clears it.
+			if null {
+				patched.Phone = ""
+				continue
+			}
+			if err := json.Unmarshal(raw, &patched.Phone); err != nil {
+				return types.Student{}, fmt.Errorf("field phone must be a string")
+			}
+
+		case "photo_url":
+			// Photos are set by uploading one; a patch can only remove it.
+			if !null {
+				return types.Student{}, fmt.Errorf("field photo_url can only be cleared with null; upload a photo to set it")
+			}
+			patched.PhotoURL = ""
+
+		case "status":
+			// Status changes go through their own endpoint.
+			return types.Student{}, fmt.Errorf("field status cannot be patched; use PATCH /api/students/{id}/status")
+
+		case "id", "created_at", "updated_at", "version":
+			return types.Student{}, fmt.Errorf("field %s cannot be changed", key)
+
+		default:
+			return types.Student{}, fmt.Errorf("unknown field: %s", key)
+		}
+	}
+
+	return patched, nil
+}
+
+// Fields returns the storage fields a patch changes, for
+// storage.PatchStudentByID: each key in patch mapped to its value in
+// patched (the result of Apply), or nil where the patch cleared it.
+// Fields not in the patch are left out, so they keep their stored values.
+func Fields(patched types.Student, patch map[string]json.RawMessage) map[string]interface{} {
+	fields := make(map[string]interface{}, len(patch))
+	for key, raw := range patch {
+		if isNull(raw) {
+			fields[key] = nil
+			continue
+		}
+		switch key {
+		case "name":
+			fields[key] = patched.Name
+		case "email":
+			fields[key] = patched.Email
+		case "age":
+			fields[key] = patched.Age
+		case "department":
+			fields[key] = patched.Department
+		case "phone":
+			fields[key] = patched.Phone
+		}
+	}
+	return fields
+}
+
+// isNull reports whether raw is the JSON literal null.
+func isNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
@@ -0,0 +1,112 @@
+package mergepatch
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// parse decodes a merge patch document the way the Patch handler does.
+func parse(t *testing.T, doc string) map[string]json.RawMessage {
+	t.Helper()
+
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(doc), &patch); err != nil {
+		t.Fatalf("patch %s is not a JSON object: %v", doc, err)
+	}
+	return patch
+}
+
+func TestApply(t *testing.T) {
+	original := types.Student{
+		ID:         1,
+		Name:       "Rakesh",
+		Email:      "rakesh@test.com",
+		Age:        35,
+		Department: "CS",
+		Phone:      "+14155552671",
+		PhotoURL:   "/photos/1.jpg",
+		Version:    3,
+	}
+
+	tests := []struct {
+		name    string
+		patch   string
+		want    func(s *types.Student) // changes expected from original
+		wantErr string
+	}{
+		{"empty patch", `{}`, func(s *types.Student) {}, ""},
+		{"set fields", `{"name":"Rakesh K","email":"rk@test.com","age":36}`, func(s *types.Student) {
+			s.Name, s.Email, s.Age = "Rakesh K", "rk@test.com", 36
+		}, ""},
+		{"set optional fields", `{"department":"EE","phone":"+14155550000"}`, func(s *types.Student) {
+			s.Department, s.Phone = "EE", "+14155550000"
+		}, ""},
+		{"clear optional fields", `{"department":null,"phone":null,"photo_url":null}`, func(s *types.Student) {
+			s.Department, s.Phone, s.PhotoURL = "", "", ""
+		}, ""},
+		{"null name", `{"name":null}`, nil, "field name is required and cannot be null"},
+		{"null email", `{"email":null}`, nil, "field email is required and cannot be null"},
+		{"null age", `{"age":null}`, nil, "field age is required and cannot be null"},
+		{"name of the wrong type", `{"name":5}`, nil, "field name must be a string"},
+		{"email of the wrong type", `{"email":true}`, nil, "field email must be a string"},
+		{"fractional age", `{"age":20.5}`, nil, "field age must be an integer"},
+		{"department of the wrong type", `{"department":1}`, nil, "field department must be a string"},
+		{"phone of the wrong type", `{"phone":[]}`, nil, "field phone must be a string"},
+		{"setting photo_url", `{"photo_url":"/photos/2.jpg"}`, nil, "can only be cleared"},
+		{"status", `{"status":"inactive"}`, nil, "field status cannot be patched"},
+		{"id", `{"id":2}`, nil, "field id cannot be changed"},
+		{"version", `{"version":9}`, nil, "field version cannot be changed"},
+		{"created_at", `{"created_at":null}`, nil, "field created_at cannot be changed"},
+		{"unknown field", `{"password":"x"}`, nil, "unknown field: password"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply(original, parse(t, tt.patch))
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Apply() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			want := original
+			tt.want(&want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Apply() = %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestApplyLeavesOriginalAlone(t *testing.T) {
+	original := types.Student{Name: "Rakesh", Phone: "+14155552671"}
+
+	if _, err := Apply(original, parse(t, `{"name":"Priya","phone":null}`)); err != nil {
+		t.Fatal(err)
+	}
+	if original.Name != "Rakesh" || original.Phone != "+14155552671" {
+		t.Errorf("Apply modified the original: %+v", original)
+	}
+}
+
+func TestFields(t *testing.T) {
+	patch := parse(t, `{"age":36,"phone":null,"department":"EE"}`)
+	patched, err := Apply(types.Student{Name: "Rakesh", Age: 35, Phone: "+14155552671"}, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// name isn't in the patch, so it isn't written back.
+	want := map[string]interface{}{"age": 36, "phone": nil, "department": "EE"}
+	if got := Fields(patched, patch); !reflect.DeepEqual(got, want) {
+		t.Errorf("Fields() = %v, want %v", got, want)
+	}
+}
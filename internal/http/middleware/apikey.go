@@ -0,0 +1,43 @@
+// Package middleware contains http.Handler wrappers that run before (and
+// sometimes after) the real handlers: authentication, logging, limits…
+//
+// Every middleware has the same shape — it takes the next handler and
+// returns a new one:
+//
+//	router.Handle("GET /admin/x", middleware.RequireAPIKey(key, handler))
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/aanand-mishra/students-api/internal/http/middleware/requestid"
+	"github.com/aanand-mishra/students-api/internal/utils/response"
+)
+
+// APIKeyHeader is the request header that carries the admin API key.
+const APIKeyHeader = "X-API-Key"
+
+// RequireAPIKey only lets requests through to next when they carry the
+// given key in the X-API-Key header. An empty key locks the route
+// entirely, so forgetting to configure one never leaves it open.
+func RequireAPIKey(key string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given := r.Header.Get(APIKeyHeader)
+
+		// ConstantTimeCompare takes the same time whether the first or the
+		// last byte differs, so the key can't be guessed by timing.
+		if key == "" || subtle.ConstantTimeCompare([]byte(given), []byte(key)) != 1 {
+			requestid.Logger(r.Context()).Warn("rejected request with invalid API key",
+				slog.String("path", r.URL.Path),
+				slog.String("remote_addr", r.RemoteAddr))
+			response.WriteJSON(w, http.StatusUnauthorized,
+				response.UnauthorizedError(errors.New("missing or invalid API key")))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+// sign returns a header carrying a token for claims, signed with secret.
+// A zero ExpiresAt becomes an hour from now.
+func sign(t *testing.T, secret string, claims Claims) string {
+	t.Helper()
+
+	if claims.ExpiresAt == nil {
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	return "Bearer " + token
+}
+
+func TestJWTMiddleware(t *testing.T) {
+	acme := Claims{TenantID: "acme", Role: "viewer"}
+	noTenant := Claims{Role: "viewer"}
+	expired := Claims{RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))}}
+
+	tests := []struct {
+		name   string
+		header string
+		opts   []Option
+		status int
+	}{
+		{"valid token", sign(t, testSecret, acme), nil, http.StatusOK},
+		{"no tenant, single tenant", sign(t, testSecret, noTenant), nil, http.StatusOK},
+		{"no tenant, multi-tenant", sign(t, testSecret, noTenant), []Option{RequireTenant()}, http.StatusUnauthorized},
+		{"tenant, multi-tenant", sign(t, testSecret, acme), []Option{RequireTenant()}, http.StatusOK},
+		{"missing header", "", nil, http.StatusUnauthorized},
+		{"wrong scheme", "Basic dXNlcjpwYXNz", nil, http.StatusUnauthorized},
+		{"wrong secret", sign(t, "other-secret", acme), nil, http.StatusUnauthorized},
+		{"expired", sign(t, testSecret, expired), nil, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *Claims
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, _ = ClaimsFromContext(r.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/students", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			JWTMiddleware(testSecret, tt.opts...)(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d\nbody: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status != http.StatusOK {
+				if got != nil {
+					t.Error("handler ran for a refused request")
+				}
+				if rec.Header().Get("WWW-Authenticate") == "" {
+					t.Error("401 without a WWW-Authenticate header")
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("no claims in the handler's context")
+			}
+		})
+	}
+}
+
+func TestAuthenticateRequireTenant(t *testing.T) {
+	header := sign(t, testSecret, Claims{Role: "editor"})
+
+	if _, err := Authenticate(testSecret, header); err != nil {
+		t.Errorf("Authenticate without RequireTenant = %v, want nil", err)
+	}
+	if _, err := Authenticate(testSecret, header, RequireTenant()); err == nil {
+		t.Error("Authenticate with RequireTenant accepted a token without tenant_id")
+	}
+
+	claims, err := Authenticate(testSecret, sign(t, testSecret, Claims{TenantID: "acme"}), RequireTenant())
+	if err != nil || claims.TenantID != "acme" {
+		t.Errorf("Authenticate = %+v, %v; want tenant acme", claims, err)
+	}
+}
+
+func TestEmptySecretRefusesEverything(t *testing.T) {
+	if _, err := Authenticate("", sign(t, "", Claims{TenantID: "acme"})); err == nil {
+		t.Error("Authenticate with an empty secret = nil error")
+	}
+}
@@ -0,0 +1,64 @@
+// Package bodylimit caps the size of request bodies, so a client can't
+// exhaust the server's memory by sending a huge payload to a handler that
+// decodes it whole (json.NewDecoder reads as much as it is given).
+package bodylimit
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/aanand-mishra/students-api/internal/utils/response"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// BodyLimit refuses request bodies larger than maxBytes with
+// 413 Request Entity Too Large:
+//
+//	{ "status": "error", "error": "request body must be 1 MB or smaller", "error_code": "PAYLOAD_TOO_LARGE" }
+//
+// A body that declares its size (Content-Length) is refused before the
+// handler runs. One that doesn't (chunked) is wrapped in
+// http.MaxBytesReader, so reading past the limit fails with an
+// *http.MaxBytesError — handlers that decode bodies answer that with 413
+// too — and the connection is closed after the response.
+//
+// exempt lists routes, as ServeMux patterns (e.g.
+// "POST /api/students/{id}/photo"), that accept bigger bodies and enforce
+// their own limits. patternOf reports the pattern a request will be
+// routed to (see router.Router.Pattern).
+// ─────────────────────────────────────────────────────────────────────────────
+func BodyLimit(maxBytes int64, patternOf func(*http.Request) string, exempt ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if slices.Contains(exempt, patternOf(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.ContentLength > maxBytes {
+				WriteTooLarge(w, maxBytes)
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WriteTooLarge answers 413 for a body over limit bytes. Handlers call it
+// when a read fails with an *http.MaxBytesError, whose Limit it takes.
+func WriteTooLarge(w http.ResponseWriter, limit int64) {
+	response.WriteJSON(w, http.StatusRequestEntityTooLarge,
+		response.PayloadTooLargeError(fmt.Errorf("request body must be %s or smaller", formatSize(limit))))
+}
+
+// formatSize writes a byte count for an error message, in MB when it is
+// a whole number of them.
+func formatSize(n int64) string {
+	if n%(1<<20) == 0 {
+		return fmt.Sprintf("%d MB", n>>20)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
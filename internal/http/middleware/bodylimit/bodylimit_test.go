@@ -0,0 +1,64 @@
+package bodylimit
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLimit(t *testing.T) {
+	// The handler reads the whole body, as a JSON decoder would, and
+	// answers a read past the limit like the handlers do.
+	h := BodyLimit(16, func(r *http.Request) string { return r.Method + " " + r.URL.Path }, "POST /upload")(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, err := io.ReadAll(r.Body); err != nil {
+				var maxErr *http.MaxBytesError
+				if errors.As(err, &maxErr) {
+					WriteTooLarge(w, maxErr.Limit)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		chunked bool
+		want    int
+	}{
+		{"within the limit", "/api/students", strings.Repeat("x", 16), false, http.StatusNoContent},
+		{"Content-Length over it", "/api/students", strings.Repeat("x", 17), false, http.StatusRequestEntityTooLarge},
+		{"chunked body over it", "/api/students", strings.Repeat("x", 17), true, http.StatusRequestEntityTooLarge},
+		{"exempt route", "/upload", strings.Repeat("x", 1000), false, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want != http.StatusRequestEntityTooLarge {
+				return
+			}
+			var body map[string]string
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if body["error_code"] != "PAYLOAD_TOO_LARGE" || body["error"] != "request body must be 16 bytes or smaller" {
+				t.Errorf("body = %v, want PAYLOAD_TOO_LARGE naming the limit", body)
+			}
+		})
+	}
+}
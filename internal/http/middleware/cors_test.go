@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	h := CORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/students", nil))
+
+	if rec.Code != http.StatusTeapot {
+		t.Errorf("status = %d; CORS must not change the response", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+	}
+	for _, header := range []string{"ETag", "Link", "X-Total-Count"} {
+		if !strings.Contains(rec.Header().Get("Access-Control-Expose-Headers"), header) {
+			t.Errorf("Access-Control-Expose-Headers = %q, want %s in it", rec.Header().Get("Access-Control-Expose-Headers"), header)
+		}
+	}
+}
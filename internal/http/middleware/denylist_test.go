@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStaticDenyList(t *testing.T) {
+	list := NewStaticDenyList([]string{
+		"203.0.113.7",
+		"192.168.1.0/24",
+		"2001:db8::/32",
+		"::1",
+		"not-an-ip", // skipped
+	})
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"203.0.113.7", true},
+		{"203.0.113.8", false},
+		{"192.168.1.0", true},
+		{"192.168.1.254", true},
+		{"192.168.2.1", false},
+		{"::ffff:192.168.1.9", true}, // IPv4-mapped IPv6
+		{"2001:db8::1", true},
+		{"2001:db8:ffff::42", true},
+		{"2001:db9::1", false},
+		{"[::1]", true},
+		{"::2", false},
+		{"fe80::1%eth0", false},
+		{"garbage", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := list.IsDenied(tt.ip); got != tt.want {
+			t.Errorf("IsDenied(%q) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestFileDenyListReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "denied.txt")
+
+	// A missing file is an empty list.
+	list := FileDenyListSource(path)
+	if list.IsDenied("203.0.113.7") {
+		t.Fatal("missing file denied an IP")
+	}
+
+	write := func(content string, mtime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// Set the mtime explicitly: two writes within the filesystem's
+		// timestamp resolution would otherwise look unchanged.
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		// Skip the wait for the next check.
+		list.mu.Lock()
+		list.lastChecked = time.Time{}
+		list.mu.Unlock()
+	}
+
+	start := time.Now().Add(-time.Hour)
+	write("# abusive clients\n203.0.113.7\n\n10.0.0.0/8  # whole range\n", start)
+	for ip, want := range map[string]bool{"203.0.113.7": true, "10.1.2.3": true, "198.51.100.1": false} {
+		if got := list.IsDenied(ip); got != want {
+			t.Errorf("after first write: IsDenied(%s) = %v, want %v", ip, got, want)
+		}
+	}
+
+	write("198.51.100.1\n", start.Add(time.Minute))
+	for ip, want := range map[string]bool{"203.0.113.7": false, "10.1.2.3": false, "198.51.100.1": true} {
+		if got := list.IsDenied(ip); got != want {
+			t.Errorf("after reload: IsDenied(%s) = %v, want %v", ip, got, want)
+		}
+	}
+
+	// Between checks the file isn't looked at again.
+	os.WriteFile(path, []byte("203.0.113.7\n"), 0o644)
+	if list.IsDenied("203.0.113.7") {
+		t.Error("file re-read before fileCheckInterval passed")
+	}
+
+	// Deleting the file empties the list.
+	os.Remove(path)
+	list.mu.Lock()
+	list.lastChecked = time.Time{}
+	list.mu.Unlock()
+	if list.IsDenied("198.51.100.1") {
+		t.Error("deleted file still denies")
+	}
+}
+
+func TestMultiDenyList(t *testing.T) {
+	list := MultiDenyList{NewStaticDenyList([]string{"203.0.113.7"}), NewStaticDenyList([]string{"2001:db8::/32"})}
+	if !list.IsDenied("203.0.113.7") || !list.IsDenied("2001:db8::5") || list.IsDenied("198.51.100.1") {
+		t.Error("MultiDenyList doesn't deny exactly what its sources do")
+	}
+}
+
+func TestIPDenyList(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	h := IPDenyList(NewStaticDenyList([]string{"192.168.1.0/24", "2001:db8::/32"}))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }))
+
+	tests := []struct {
+		remoteAddr string
+		status     int
+	}{
+		{"192.168.1.50:51234", http.StatusForbidden},
+		{"[2001:db8::7]:51234", http.StatusForbidden},
+		{"192.168.2.50:51234", http.StatusNoContent},
+		{"[2001:db9::7]:51234", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/api/students", nil)
+		r.RemoteAddr = tt.remoteAddr
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.remoteAddr, rec.Code, tt.status)
+		}
+		if tt.status == http.StatusForbidden && !strings.Contains(rec.Body.String(), `"error_code":"FORBIDDEN"`) {
+			t.Errorf("%s: body = %s, want a FORBIDDEN error", tt.remoteAddr, rec.Body)
+		}
+	}
+
+	// One WARN line per blocked request, naming the IP and path.
+	var blocked []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var entry map[string]any
+		json.Unmarshal([]byte(line), &entry)
+		if entry["msg"] == "blocked request from denied IP" {
+			blocked = append(blocked, entry)
+		}
+	}
+	if len(blocked) != 2 {
+		t.Fatalf("logged %d blocks, want 2:\n%s", len(blocked), logs.String())
+	}
+	if blocked[0]["level"] != "WARN" || blocked[0]["ip"] != "192.168.1.50" || blocked[0]["path"] != "/api/students" {
+		t.Errorf("block log = %v", blocked[0])
+	}
+	if blocked[1]["ip"] != "2001:db8::7" {
+		t.Errorf("IPv6 block log ip = %v", blocked[1]["ip"])
+	}
+}
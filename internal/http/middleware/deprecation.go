@@ -0,0 +1,14 @@
+package middleware
+
+import "net/http"
+
+// Deprecated marks every response of next with a "Deprecation: true"
+// header (RFC 9745), telling clients the route they called is on its way
+// out. main.go wraps the unversioned /api routes in it, so clients still
+// on /api/students find out they should move to /v1/api/students.
+func Deprecated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/http/middleware/auth"
+	"github.com/aanand-mishra/students-api/internal/http/middleware/requestid"
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/types"
+	"github.com/aanand-mishra/students-api/internal/utils/response"
+)
+
+// IdempotencyKeyHeader is the request header a client sets to make a
+// POST safe to retry.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set to "true" on a replayed response.
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// maxIdempotencyKeyLen bounds the header; a UUID is 36 characters.
+const maxIdempotencyKeyLen = 255
+
+// idempotencyLease is how long a key stays claimed by a request that is
+// still running. It outlives any request the server's timeouts allow,
+// and lets the key go again if the server died mid-request.
+const idempotencyLease = time.Minute
+
+// IdempotencyStore keeps the responses Idempotency replays (implemented
+// by *sqlite.SQLite and *postgres.Postgres).
+type IdempotencyStore interface {
+	ClaimIdempotencyKey(ctx context.Context, key string, expiresAt time.Time) (bool, error)
+	GetIdempotencyResult(ctx context.Context, key string) (types.IdempotencyResult, error)
+	StoreIdempotencyResult(ctx context.Context, result types.IdempotencyResult) error
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Idempotency makes requests that carry an Idempotency-Key header safe to
+// retry: the first request with a key is handled and its response kept
+// for ttl; any later request with the same key gets that response again
+// (with Idempotent-Replayed: true) and never reaches next.
+//
+//	POST /api/students   Idempotency-Key: 5f0c…  → 201 {"id": 7}
+//	POST /api/students   Idempotency-Key: 5f0c…  → 201 {"id": 7}  (replayed)
+//
+// A repeat that arrives while the first request is still running gets
+// 409 Conflict — the client should retry shortly. Requests without the
+// header pass straight through.
+//
+// Keys are per client: behind JWTMiddleware, the token's subject (and
+// tenant, if it has one) is part of the stored key, so two clients can't
+// see each other's responses by picking the same key.
+//
+// 5xx responses are not kept: the key is released so a retry can
+// succeed once whatever failed has recovered.
+// ─────────────────────────────────────────────────────────────────────────────
+func Idempotency(store IdempotencyStore, ttl time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		log := requestid.Logger(r.Context())
+		if len(key) > maxIdempotencyKeyLen {
+			response.WriteJSON(w, http.StatusBadRequest, response.BadRequestError(
+				fmt.Errorf("%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLen)))
+			return
+		}
+		if claims, ok := auth.ClaimsFromContext(r.Context()); ok {
+			if claims.Subject != "" {
+				key = claims.Subject + "/" + key
+			}
+			if claims.TenantID != "" {
+				key = claims.TenantID + "/" + key
+			}
+		}
+
+		claimed, err := store.ClaimIdempotencyKey(r.Context(), key, time.Now().Add(idempotencyLease))
+		if err != nil {
+			log.Error("error claiming idempotency key", slog.String("error", err.Error()))
+			response.WriteJSON(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		if !claimed {
+			replayIdempotent(w, r, store, key)
+			return
+		}
+
+		rec := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+		// The key must not stay claimed if the handler panics, or every
+		// retry would get 409 until the lease runs out.
+		completed := false
+		defer func() {
+			if !completed {
+				releaseIdempotencyKey(r, store, key)
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+		completed = true
+
+		// The client may be gone by now; the result is still worth keeping.
+		ctx := context.WithoutCancel(r.Context())
+		if rec.status >= http.StatusInternalServerError {
+			releaseIdempotencyKey(r, store, key)
+			return
+		}
+		err = store.StoreIdempotencyResult(ctx, types.IdempotencyResult{
+			Key:         key,
+			StatusCode:  rec.status,
+			ContentType: rec.Header().Get("Content-Type"),
+			Body:        rec.body.Bytes(),
+			ExpiresAt:   time.Now().Add(ttl),
+		})
+		if err != nil {
+			// The response has been sent; a retry will just run again.
+			log.Error("error storing idempotent response", slog.String("error", err.Error()))
+			releaseIdempotencyKey(r, store, key)
+		}
+	})
+}
+
+// replayIdempotent answers a request whose key was already claimed:
+// with the stored response, or 409 if the first request hasn't finished.
+func replayIdempotent(w http.ResponseWriter, r *http.Request, store IdempotencyStore, key string) {
+	log := requestid.Logger(r.Context())
+
+	result, err := store.GetIdempotencyResult(r.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && result.InFlight()) {
+		// ErrNotFound: the entry expired between the claim and now —
+		// rare enough that asking the client to retry is fine.
+		w.Header().Set("Retry-After", "1")
+		response.WriteJSON(w, http.StatusConflict, response.Error(response.ErrCodeIdempotencyInFlight,
+			errors.New("a request with this Idempotency-Key is still being processed")))
+		return
+	}
+	if err != nil {
+		log.Error("error reading idempotent response", slog.String("error", err.Error()))
+		response.WriteJSON(w, http.StatusInternalServerError, response.GeneralError(err))
+		return
+	}
+
+	log.Info("replaying idempotent response", slog.Int("status", result.StatusCode))
+	if result.ContentType != "" {
+		w.Header().Set("Content-Type", result.ContentType)
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(result.StatusCode)
+	w.Write(result.Body)
+}
+
+// releaseIdempotencyKey frees key, logging (not failing) on error — the
+// claim then simply lapses after idempotencyLease.
+func releaseIdempotencyKey(r *http.Request, store IdempotencyStore, key string) {
+	if err := store.ReleaseIdempotencyKey(context.WithoutCancel(r.Context()), key); err != nil {
+		requestid.Logger(r.Context()).Error("error releasing idempotency key",
+			slog.String("error", err.Error()))
+	}
+}
+
+// recordingWriter passes the response through to the client while
+// keeping a copy of its status and body.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(status int) {
+	rw.status = status
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recordingWriter) Write(p []byte) (int, error) {
+	rw.body.Write(p)
+	return rw.ResponseWriter.Write(p)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (rw *recordingWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
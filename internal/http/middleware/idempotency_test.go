@@ -0,0 +1,247 @@
+package middleware_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/config"
+	"github.com/aanand-mishra/students-api/internal/http/handlers/student"
+	"github.com/aanand-mishra/students-api/internal/http/middleware"
+	"github.com/aanand-mishra/students-api/internal/http/middleware/auth"
+	"github.com/aanand-mishra/students-api/internal/storage/sqlite"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+func newIdempotencyStore(t *testing.T) *sqlite.SQLite {
+	t.Helper()
+
+	db, err := sqlite.New(&config.Config{
+		StoragePath: filepath.Join(t.TempDir(), "test.db"),
+		Database:    config.Database{AutoMigrate: true},
+		SQLite:      config.SQLite{JournalMode: config.JournalModeWAL, BusyTimeoutMs: 5000},
+	})
+	if err != nil {
+		t.Fatalf("sqlite.New: %v", err)
+	}
+	t.Cleanup(func() { db.Db.Close() })
+	return db
+}
+
+// post sends a POST /api/students with key (if any), made with a token
+// for subject in tenant (if subject isn't empty).
+func post(h http.Handler, key, subject, tenant, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/students", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	}
+	if subject != "" {
+		claims := &auth.Claims{TenantID: tenant}
+		claims.Subject = subject
+		req = req.WithContext(auth.NewContext(req.Context(), claims))
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// counting wraps next, counting the requests that reach it.
+func counting(calls *atomic.Int32, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// status answers every request with code and a small JSON body.
+func status(code int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		w.Write([]byte(`{"status":"done"}`))
+	})
+}
+
+const rakesh = `{"name":"Rakesh","email":"rakesh@test.com","age":35}`
+
+func TestIdempotencyReplays(t *testing.T) {
+	db := newIdempotencyStore(t)
+	var calls atomic.Int32
+	h := middleware.Idempotency(db, time.Hour, counting(&calls, student.New(db)))
+
+	first := post(h, "key-1", "client", "", rakesh)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first: status = %d, want %d\nbody: %s", first.Code, http.StatusCreated, first.Body)
+	}
+	if first.Header().Get(middleware.IdempotentReplayedHeader) != "" {
+		t.Error("first response is marked as replayed")
+	}
+
+	second := post(h, "key-1", "client", "", rakesh)
+	if second.Code != first.Code || second.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %s, want %d %s", second.Code, second.Body, first.Code, first.Body)
+	}
+	if got := second.Header().Get(middleware.IdempotentReplayedHeader); got != "true" {
+		t.Errorf("%s = %q, want true", middleware.IdempotentReplayedHeader, got)
+	}
+	if got := second.Header().Get("Content-Type"); got != first.Header().Get("Content-Type") {
+		t.Errorf("replayed Content-Type = %q, want %q", got, first.Header().Get("Content-Type"))
+	}
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("handler ran %d times, want 1", n)
+	}
+	if n, err := db.CountStudents(context.Background(), types.DefaultTenant); err != nil || n != 1 {
+		t.Errorf("CountStudents = %d, %v; want 1: the replay must not insert again", n, err)
+	}
+}
+
+func TestIdempotencyWithoutKey(t *testing.T) {
+	db := newIdempotencyStore(t)
+	var calls atomic.Int32
+	h := middleware.Idempotency(db, time.Hour, counting(&calls, status(http.StatusCreated)))
+
+	post(h, "", "client", "", rakesh)
+	post(h, "", "client", "", rakesh)
+	if n := calls.Load(); n != 2 {
+		t.Errorf("handler ran %d times, want 2: requests without a key aren't deduplicated", n)
+	}
+
+	rec := post(h, strings.Repeat("k", 256), "client", "", rakesh)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("256-character key: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	db := newIdempotencyStore(t)
+	entered, release := make(chan struct{}), make(chan struct{})
+	h := middleware.Idempotency(db, time.Hour, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- post(h, "key-1", "client", "", rakesh) }()
+	<-entered
+
+	rec := post(h, "key-1", "client", "", rakesh)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("while in flight: status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if got := rec.Header().Get("Retry-After"); got == "" {
+		t.Error("409 without Retry-After")
+	}
+	var body struct {
+		ErrorCode string `json:"error_code"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.ErrorCode != "IDEMPOTENCY_IN_FLIGHT" {
+		t.Errorf("body = %s, want error_code IDEMPOTENCY_IN_FLIGHT", rec.Body)
+	}
+
+	close(release)
+	if first := <-done; first.Code != http.StatusCreated {
+		t.Errorf("first: status = %d, want %d", first.Code, http.StatusCreated)
+	}
+	if rec := post(h, "key-1", "client", "", rakesh); rec.Code != http.StatusCreated {
+		t.Errorf("after it finished: status = %d, want the replayed %d", rec.Code, http.StatusCreated)
+	}
+}
+
+func TestIdempotencyServerErrorReleasesKey(t *testing.T) {
+	db := newIdempotencyStore(t)
+	var calls atomic.Int32
+	h := middleware.Idempotency(db, time.Hour, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			status(http.StatusServiceUnavailable).ServeHTTP(w, r)
+			return
+		}
+		status(http.StatusCreated).ServeHTTP(w, r)
+	}))
+
+	if rec := post(h, "key-1", "client", "", rakesh); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("first: status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	rec := post(h, "key-1", "client", "", rakesh)
+	if rec.Code != http.StatusCreated || rec.Header().Get(middleware.IdempotentReplayedHeader) != "" {
+		t.Errorf("retry: status = %d (replayed %q), want a fresh %d", rec.Code,
+			rec.Header().Get(middleware.IdempotentReplayedHeader), http.StatusCreated)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("handler ran %d times, want 2", n)
+	}
+}
+
+func TestIdempotencyPanicReleasesKey(t *testing.T) {
+	db := newIdempotencyStore(t)
+	var calls atomic.Int32
+	h := middleware.Idempotency(db, time.Hour, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			panic("boom")
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	func() {
+		defer func() { recover() }()
+		post(h, "key-1", "client", "", rakesh)
+	}()
+	if rec := post(h, "key-1", "client", "", rakesh); rec.Code != http.StatusCreated {
+		t.Errorf("retry after a panic: status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+}
+
+func TestIdempotencyExpires(t *testing.T) {
+	db := newIdempotencyStore(t)
+	var calls atomic.Int32
+	h := middleware.Idempotency(db, 50*time.Millisecond, counting(&calls, status(http.StatusCreated)))
+
+	post(h, "key-1", "client", "", rakesh)
+	post(h, "key-1", "client", "", rakesh)
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("handler ran %d times within the TTL, want 1", n)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	rec := post(h, "key-1", "client", "", rakesh)
+	if n := calls.Load(); n != 2 {
+		t.Errorf("handler ran %d times, want 2: the stored response has expired", n)
+	}
+	if rec.Header().Get(middleware.IdempotentReplayedHeader) != "" {
+		t.Error("response after expiry is marked as replayed")
+	}
+}
+
+func TestIdempotencyKeysArePerClient(t *testing.T) {
+	db := newIdempotencyStore(t)
+	var calls atomic.Int32
+	h := middleware.Idempotency(db, time.Hour, counting(&calls, status(http.StatusCreated)))
+
+	// The same key from different subjects, or the same subject in
+	// different tenants, are different requests.
+	for _, client := range []struct{ subject, tenant string }{
+		{"alice", ""},
+		{"bob", ""},
+		{"alice", "acme"},
+		{"alice", "other"},
+	} {
+		if rec := post(h, "key-1", client.subject, client.tenant, rakesh); rec.Header().Get(middleware.IdempotentReplayedHeader) != "" {
+			t.Errorf("%s in %q got another client's response", client.subject, client.tenant)
+		}
+	}
+	if n := calls.Load(); n != 4 {
+		t.Errorf("handler ran %d times, want 4", n)
+	}
+
+	if rec := post(h, "key-1", "alice", "acme", rakesh); rec.Header().Get(middleware.IdempotentReplayedHeader) != "true" {
+		t.Error("alice in acme repeating her key was not replayed")
+	}
+}
@@ -0,0 +1,197 @@
+// Package logger writes one structured log line per completed request:
+// method, path, status, duration, response size and request ID.
+//
+// It also places a types.AuditContext in the request context, so changes
+// made while handling the request are audited with its details, and
+// records the response size histogram.
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/audit"
+	"github.com/aanand-mishra/students-api/internal/http/middleware/requestid"
+	"github.com/aanand-mishra/students-api/internal/metrics"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// maxAuditBody is how much of a request body is kept for the audit log.
+const maxAuditBody = 64 << 10
+
+// Option customises Logger.
+type Option func(*options)
+
+type options struct {
+	largeResponse int64
+	sampleRate    func() float64
+}
+
+// WithLargeResponse logs responses bigger than threshold bytes at WARN
+// instead of INFO; a response that size usually means a client should be
+// paging or using the export endpoint. 0 or less (the default) disables
+// the warning.
+func WithLargeResponse(threshold int64) Option {
+	return func(o *options) { o.largeResponse = threshold }
+}
+
+// WithSampleRate gives a sampleRate() fraction of requests (0.0 to 1.0) a
+// DEBUG line with the request's headers and query — see sampled for how
+// they are picked. Only that DEBUG line is sampled; INFO and above always
+// log. sampleRate is called per request, so the rate can change at run
+// time. Without it no request is sampled.
+func WithSampleRate(sampleRate func() float64) Option {
+	return func(o *options) { o.sampleRate = sampleRate }
+}
+
+// responseWriter wraps a ResponseWriter to remember the status code the
+// handler sent and how many body bytes it wrote — neither of which
+// http.ResponseWriter itself exposes.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush passes through to the underlying writer when it supports it.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Logger logs every request to log once the handler returns, at INFO:
+//
+//	level=INFO msg="request completed" request_id=... method=GET
+//	  path=/api/students status=200 duration=1.2ms response_bytes=512
+//
+// It belongs inside recovery.Recovery and requestid.RequestID (for the
+// request ID) but outside the per-route auth check, so requests turned
+// away with a 401 are logged too.
+// ─────────────────────────────────────────────────────────────────────────────
+func Logger(log *slog.Logger, opts ...Option) func(http.Handler) http.Handler {
+	o := options{sampleRate: func() float64 { return 0 }}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			requestID := requestid.FromContext(r.Context())
+			log := log.With(slog.String("request_id", requestID))
+
+			if log.Enabled(r.Context(), slog.LevelDebug) && sampled(requestID, o.sampleRate()) {
+				log.Debug("request received",
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("query", r.URL.RawQuery),
+					slog.String("client_ip", clientIP(r)),
+					slog.String("user_agent", r.UserAgent()),
+					slog.Int64("content_length", r.ContentLength))
+			}
+
+			ac := &types.AuditContext{
+				ClientIP:  clientIP(r),
+				UserAgent: r.UserAgent(),
+				RequestID: requestID,
+			}
+
+			// Only bodies that can change data are worth auditing. The body
+			// is read up front and replaced so the handler still sees it all.
+			if r.Body != nil && r.Method != http.MethodGet && r.Method != http.MethodHead {
+				if body, err := io.ReadAll(io.LimitReader(r.Body, maxAuditBody)); err == nil {
+					ac.RequestBody = audit.RedactBody(body)
+					r.Body = struct {
+						io.Reader
+						io.Closer
+					}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+				}
+			}
+
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, r.WithContext(types.WithAuditContext(r.Context(), ac)))
+
+			duration := time.Since(start)
+			ac.Finish(rw.status, duration)
+			metrics.ResponseSizeBytes.Observe(float64(rw.bytes))
+
+			attrs := []any{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", rw.status),
+				slog.Duration("duration", duration),
+				slog.Int("response_bytes", rw.bytes),
+			}
+
+			if o.largeResponse > 0 && int64(rw.bytes) > o.largeResponse {
+				log.Warn("large response", append(attrs,
+					slog.Int64("threshold_bytes", o.largeResponse),
+					slog.String("query", r.URL.RawQuery),
+					slog.String("content_type", rw.Header().Get("Content-Type")),
+					slog.String("client_ip", ac.ClientIP))...)
+				return
+			}
+
+			log.Info("request completed", attrs...)
+		})
+	}
+}
+
+// sampled reports whether the request with the given ID falls within
+// the sampleRate fraction of requests that get DEBUG logging.
+//
+// The decision is deterministic: the first 8 bytes of the ID seed the
+// draw, so every instance a request passes through with the same
+// X-Request-ID makes the same choice and a sampled request can be traced
+// end to end. Without an ID it is a plain random draw.
+func sampled(id string, sampleRate float64) bool {
+	switch {
+	case sampleRate <= 0:
+		return false
+	case sampleRate >= 1:
+		return true
+	}
+
+	if id == "" {
+		return rand.Float64() < sampleRate
+	}
+
+	// IDs shorter than 8 bytes are zero-padded.
+	var seed [8]byte
+	copy(seed[:], id)
+	src := rand.NewSource(int64(binary.BigEndian.Uint64(seed[:])))
+	return rand.New(src).Float64() < sampleRate
+}
+
+// clientIP returns the request's remote IP without the port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
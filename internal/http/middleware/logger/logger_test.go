@@ -0,0 +1,234 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aanand-mishra/students-api/internal/http/middleware/requestid"
+	"github.com/aanand-mishra/students-api/internal/metrics"
+)
+
+// captureLogs returns a logger writing JSON lines at level to the
+// returned buffer.
+func captureLogs(level slog.Level) (*slog.Logger, *bytes.Buffer) {
+	var logs bytes.Buffer
+	return slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: level})), &logs
+}
+
+// logLines decodes every JSON log line written to logs.
+func logLines(t *testing.T, logs *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line %q is not JSON: %v", line, err)
+		}
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+// responseSizeCount reads how many responses the response size
+// histogram has recorded, from the /metrics output.
+func responseSizeCount(t *testing.T) int {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	metrics.Handler()(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "response_size_bytes_count "); ok {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				t.Fatalf("response_size_bytes_count = %q", value)
+			}
+			return n
+		}
+	}
+	t.Fatal("no response_size_bytes_count in /metrics")
+	return 0
+}
+
+func TestLoggerRequestFields(t *testing.T) {
+	log, logs := captureLogs(slog.LevelInfo)
+
+	// A request turned away by auth is logged like any other.
+	h := requestid.RequestID()(Logger(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "missing token", http.StatusUnauthorized)
+	})))
+	req := httptest.NewRequest(http.MethodDelete, "/api/students/7", nil)
+	req.Header.Set(requestid.Header, "req-42")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := logLines(t, logs)
+	if len(lines) != 1 {
+		t.Fatalf("logged %d lines, want 1:\n%s", len(lines), logs)
+	}
+	line := lines[0]
+	want := map[string]any{
+		"level":      "INFO",
+		"msg":        "request completed",
+		"method":     http.MethodDelete,
+		"path":       "/api/students/7",
+		"status":     float64(http.StatusUnauthorized),
+		"request_id": "req-42",
+	}
+	for k, v := range want {
+		if line[k] != v {
+			t.Errorf("%s = %v, want %v", k, line[k], v)
+		}
+	}
+	if _, ok := line["duration"]; !ok {
+		t.Error("no duration logged")
+	}
+}
+
+func TestLoggerResponseSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      int
+		threshold int64
+		wantLevel string
+		wantMsg   string
+	}{
+		{"small response", 10, 100, "INFO", "request completed"},
+		{"at the threshold", 100, 100, "INFO", "request completed"},
+		{"large response", 101, 100, "WARN", "large response"},
+		{"warning disabled", 5000, 0, "INFO", "request completed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, logs := captureLogs(slog.LevelInfo)
+			before := responseSizeCount(t)
+
+			h := Logger(log, WithLargeResponse(tt.threshold))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.WriteHeader(http.StatusCreated)
+				w.Write(bytes.Repeat([]byte("x"), tt.body))
+			}))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/students?page=2", nil))
+
+			if rec.Code != http.StatusCreated || rec.Body.Len() != tt.body {
+				t.Errorf("got %d with %d bytes; the response must pass through unchanged", rec.Code, rec.Body.Len())
+			}
+
+			lines := logLines(t, logs)
+			if len(lines) != 1 {
+				t.Fatalf("logged %d lines, want 1:\n%s", len(lines), logs)
+			}
+			line := lines[0]
+			if line["level"] != tt.wantLevel || line["msg"] != tt.wantMsg {
+				t.Errorf("logged %v %q, want %s %q", line["level"], line["msg"], tt.wantLevel, tt.wantMsg)
+			}
+			if line["status"] != float64(http.StatusCreated) || line["response_bytes"] != float64(tt.body) {
+				t.Errorf("status = %v, response_bytes = %v; want %d, %d", line["status"], line["response_bytes"], http.StatusCreated, tt.body)
+			}
+			if tt.wantLevel == "WARN" {
+				if line["threshold_bytes"] != float64(tt.threshold) || line["query"] != "page=2" || line["content_type"] != "text/plain" {
+					t.Errorf("the warning lacks the details: %v", line)
+				}
+			}
+
+			if got := responseSizeCount(t) - before; got != 1 {
+				t.Errorf("response_size_bytes recorded %d responses, want 1", got)
+			}
+		})
+	}
+}
+
+func TestLoggerKeepsFlusher(t *testing.T) {
+	log, _ := captureLogs(slog.LevelInfo)
+
+	h := Logger(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("the handler's writer is not an http.Flusher")
+		}
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush through ResponseController: %v", err)
+		}
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/students/events", nil))
+
+	if !rec.Flushed {
+		t.Error("the flush didn't reach the underlying writer")
+	}
+}
+
+func TestSampled(t *testing.T) {
+	if sampled("abc", 0) || sampled("", -1) {
+		t.Error("sampled with a rate of 0 or less")
+	}
+	if !sampled("abc", 1) || !sampled("", 2) {
+		t.Error("not sampled with a rate of 1 or more")
+	}
+
+	// The same ID always gets the same answer, on every instance.
+	for i := 0; i < 100; i++ {
+		id := fmt.Sprintf("req-%08d", i)
+		if sampled(id, 0.3) != sampled(id, 0.3) {
+			t.Fatalf("sampled(%q) changed its mind", id)
+		}
+	}
+
+	// And about that fraction of all IDs is picked.
+	picked := 0
+	const n = 10000
+	for i := 0; i < n; i++ {
+		if sampled(fmt.Sprintf("%08x", i*2654435761), 0.25) {
+			picked++
+		}
+	}
+	if picked < n*20/100 || picked > n*30/100 {
+		t.Errorf("sampled %d of %d IDs at rate 0.25", picked, n)
+	}
+}
+
+func TestLoggerDebugSampling(t *testing.T) {
+	tests := []struct {
+		name      string
+		level     slog.Level
+		rate      float64
+		wantDebug bool
+	}{
+		{"every request", slog.LevelDebug, 1, true},
+		{"none", slog.LevelDebug, 0, false},
+		{"debug disabled", slog.LevelInfo, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, logs := captureLogs(tt.level)
+
+			h := Logger(log, WithSampleRate(func() float64 { return tt.rate }))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/students?name=priya", nil))
+
+			var debug map[string]any
+			for _, line := range logLines(t, logs) {
+				if line["level"] == "DEBUG" {
+					debug = line
+				}
+			}
+			if (debug != nil) != tt.wantDebug {
+				t.Fatalf("DEBUG line logged = %v, want %v\n%s", debug != nil, tt.wantDebug, logs)
+			}
+			if debug != nil && (debug["msg"] != "request received" || debug["query"] != "name=priya") {
+				t.Errorf("DEBUG line = %v", debug)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/metrics"
+)
+
+// Metrics records every request in metrics.HTTPRequestDuration and
+// counts it in metrics.HTTPRequestsInFlight while it is being served.
+//
+// Requests are labelled with their route's path as reported by patternOf
+// (see router.Router.Pattern), e.g. /api/students/{id}, so the number of
+// series stays bounded however many students there are. Requests that
+// match no route share the path "unmatched".
+//
+// It should wrap the rest of the chain, so requests rejected by the
+// deny list, rate limit or auth are measured too.
+func Metrics(patternOf func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			metrics.HTTPRequestsInFlight.Inc()
+			defer metrics.HTTPRequestsInFlight.Dec()
+
+			path := patternOf(r)
+			if path == "" {
+				path = "unmatched"
+			} else if _, p, ok := strings.Cut(path, " "); ok {
+				path = p // "GET /api/students/{id}" → "/api/students/{id}"
+			}
+
+			rec := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			metrics.HTTPRequestDuration.Observe(time.Since(start).Seconds(),
+				r.Method, path, strconv.Itoa(rec.status))
+		})
+	}
+}
+
+// statusWriter wraps a ResponseWriter to remember the status code the
+// handler sent, which http.ResponseWriter itself doesn't expose.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusWriter) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (s *statusWriter) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// Flush passes through to the underlying writer when it supports it.
+func (s *statusWriter) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/aanand-mishra/students-api/internal/utils/response"
+)
+
+// PrettyJSON pretty-prints JSON responses for requests that add
+// ?pretty=true to the URL, e.g.
+//
+//	curl "http://localhost:8082/api/students?pretty=true"
+func PrettyJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("pretty") == "true" {
+			w = response.PrettyWriter{ResponseWriter: w}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
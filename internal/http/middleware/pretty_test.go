@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/types"
+	"github.com/aanand-mishra/students-api/internal/utils/response"
+)
+
+func TestPrettyJSON(t *testing.T) {
+	page := types.StudentPage{
+		Data: []types.Student{
+			{ID: 1, Name: "Rakesh", Email: "rakesh@test.com", Age: 35, CreatedAt: time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)},
+			{ID: 2, Name: "Priya", Email: "priya@test.com", Age: 22, Phone: "+14155552671"},
+		},
+		Total:   2,
+		Page:    1,
+		PerPage: 20,
+	}
+	h := PrettyJSON(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response.WriteJSON(w, http.StatusOK, page)
+	}))
+
+	get := func(target string) string {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d", target, rec.Code)
+		}
+		return rec.Body.String()
+	}
+
+	compact := get("/api/students")
+	pretty := get("/api/students?pretty=true")
+	notPretty := get("/api/students?pretty=1")
+
+	if strings.Count(compact, "\n") != 1 || notPretty != compact {
+		t.Errorf("without pretty=true the body isn't compact:\n%s", notPretty)
+	}
+	if !strings.HasPrefix(pretty, "{\n  \"data\": [\n    {\n      \"id\": 1,") {
+		t.Errorf("pretty body isn't indented by two spaces:\n%s", pretty)
+	}
+
+	// Both forms hold the same data.
+	var a, b any
+	if err := json.Unmarshal([]byte(compact), &a); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(pretty), &b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("compact and pretty bodies decode differently:\n%v\n%v", a, b)
+	}
+}
+
+func TestPrettyJSONDevDefault(t *testing.T) {
+	response.SetPrettyDefault(true)
+	t.Cleanup(func() { response.SetPrettyDefault(false) })
+
+	h := PrettyJSON(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if got := rec.Body.String(); got != "{\n  \"status\": \"ok\"\n}\n" {
+		t.Errorf("dev body = %q, want it pretty without ?pretty=true", got)
+	}
+}
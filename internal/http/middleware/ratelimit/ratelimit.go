@@ -0,0 +1,209 @@
+// Package ratelimit limits how often each client (by IP) may call the
+// API, so one client can't starve the others or hammer an expensive
+// endpoint.
+//
+// Every client gets a token bucket (golang.org/x/time/rate): it holds up
+// to burst tokens, refills at rps tokens a second, and each request takes
+// one. Short bursts are fine, but a sustained rate above rps is answered
+// 429 Too Many Requests with a Retry-After header:
+//
+//	{ "status": "error", "error": "rate limit exceeded, try again later", "error_code": "RATE_LIMITED" }
+//
+// A background goroutine forgets the buckets of clients that have been
+// idle for a while (see WithIdleTTL); a bucket left alone refills anyway,
+// so dropping it only frees memory.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/utils/response"
+	"golang.org/x/time/rate"
+)
+
+// DefaultIdleTTL is how long a client's bucket is kept after its last
+// request unless WithIdleTTL says otherwise.
+const DefaultIdleTTL = 10 * time.Minute
+
+// errRateLimited is the message of every 429.
+var errRateLimited = errors.New("rate limit exceeded, try again later")
+
+// Option customises RateLimiter.
+type Option func(*limiter)
+
+// WithRoutes gives individual routes limits of their own, in requests per
+// second, keyed by ServeMux pattern exactly as registered (e.g.
+// "GET /admin/db/download": 0.2). Each listed route has its own bucket per
+// client, holding one second's worth of its rate (at least one request) —
+// a shared burst would undo a stricter limit. All other routes share the
+// client's default bucket. A rate of 0 or less leaves the route unlimited.
+//
+// A middleware runs before the mux has picked a route, so patternOf must
+// report the pattern the mux WILL use (see router.Router.Pattern).
+func WithRoutes(routes map[string]float64, patternOf func(*http.Request) string) Option {
+	return func(l *limiter) {
+		l.routes = routes
+		l.patternOf = patternOf
+	}
+}
+
+// WithIdleTTL sets how long a client's buckets are kept after its last
+// request. 0 or less means DefaultIdleTTL.
+func WithIdleTTL(d time.Duration) Option {
+	return func(l *limiter) {
+		if d > 0 {
+			l.idleTTL = d
+		}
+	}
+}
+
+// WithContext stops the eviction goroutine when ctx is cancelled. Without
+// it the goroutine runs for the life of the process.
+func WithContext(ctx context.Context) Option {
+	return func(l *limiter) { l.ctx = ctx }
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// RateLimiter allows each client rps requests a second on average, and up
+// to burst at once (0 or less means one second's worth, at least one).
+// An rps of 0 or less turns the default limit off; routes given their own
+// limit with WithRoutes are still limited.
+//
+// It starts the goroutine that evicts idle buckets, every idle TTL.
+// ─────────────────────────────────────────────────────────────────────────────
+func RateLimiter(rps float64, burst int, opts ...Option) func(http.Handler) http.Handler {
+	l := &limiter{
+		rps:     rps,
+		burst:   burst,
+		idleTTL: DefaultIdleTTL,
+		ctx:     context.Background(),
+		clients: make(map[key]*client),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	go l.evictIdle(l.ctx, l.idleTTL)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, rps, burst := l.limitFor(r)
+			if rps <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			now := time.Now()
+			reservation := l.get(key{route: route, ip: clientIP(r)}, rps, burst, now).ReserveN(now, 1)
+			if delay := reservation.DelayFrom(now); delay > 0 {
+				reservation.CancelAt(now) // the request isn't served, so give the token back
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+				response.WriteJSON(w, http.StatusTooManyRequests, response.RateLimitError(errRateLimited))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// key identifies one bucket: a client on a route with its own limit, or
+// on all other routes (route "").
+type key struct {
+	route, ip string
+}
+
+type client struct {
+	*rate.Limiter
+	lastSeen time.Time
+}
+
+// limiter holds the buckets of every active client.
+type limiter struct {
+	rps       float64
+	burst     int
+	routes    map[string]float64
+	patternOf func(*http.Request) string
+	idleTTL   time.Duration
+	ctx       context.Context
+
+	mu      sync.Mutex
+	clients map[key]*client
+}
+
+// limitFor returns the bucket route, rate and burst that apply to r.
+func (l *limiter) limitFor(r *http.Request) (string, float64, int) {
+	if l.patternOf != nil {
+		route := l.patternOf(r)
+		if rps, ok := l.routes[route]; ok {
+			return route, rps, burstFor(rps, 0)
+		}
+	}
+	return "", l.rps, burstFor(l.rps, l.burst)
+}
+
+// burstFor is burst, or one second's worth of rps (at least 1) when
+// burst isn't set.
+func burstFor(rps float64, burst int) int {
+	if burst > 0 {
+		return burst
+	}
+	return max(1, int(math.Ceil(rps)))
+}
+
+// get returns the bucket for k, creating it if needed.
+func (l *limiter) get(k key, rps float64, burst int, now time.Time) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c, ok := l.clients[k]
+	if !ok {
+		c = &client{Limiter: rate.NewLimiter(rate.Limit(rps), burst)}
+		l.clients[k] = c
+	}
+	c.lastSeen = now
+
+	return c.Limiter
+}
+
+// evictIdle calls evict every interval until ctx is cancelled.
+func (l *limiter) evictIdle(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			l.evict(now)
+		}
+	}
+}
+
+// evict forgets the buckets of clients idle for longer than the TTL.
+func (l *limiter) evict(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for k, c := range l.clients {
+		if now.Sub(c.lastSeen) > l.idleTTL {
+			delete(l.clients, k)
+		}
+	}
+}
+
+// clientIP returns the request's remote IP without the port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
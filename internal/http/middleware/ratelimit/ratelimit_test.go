@@ -0,0 +1,193 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// byPath reports a request's pattern as "METHOD path".
+func byPath(r *http.Request) string { return r.Method + " " + r.URL.Path }
+
+// limitedServer serves every path with 204 behind RateLimiter.
+func limitedServer(t *testing.T, rps float64, burst int, opts ...Option) http.Handler {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	opts = append(opts, WithContext(ctx))
+	return RateLimiter(rps, burst, opts...)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }))
+}
+
+// hit sends n requests from ip and returns their status codes, and the
+// last response.
+func hit(h http.Handler, method, path, ip string, n int) ([]int, *httptest.ResponseRecorder) {
+	var codes []int
+	var rec *httptest.ResponseRecorder
+	for i := 0; i < n; i++ {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+	}
+	return codes, rec
+}
+
+func TestRateLimiter(t *testing.T) {
+	h := limitedServer(t, 1, 3)
+
+	t.Run("burst, then 429", func(t *testing.T) {
+		codes, rec := hit(h, http.MethodGet, "/api/students", "10.0.0.1", 4)
+		want := []int{http.StatusNoContent, http.StatusNoContent, http.StatusNoContent, http.StatusTooManyRequests}
+		for i := range want {
+			if codes[i] != want[i] {
+				t.Fatalf("statuses = %v, want %v", codes, want)
+			}
+		}
+
+		if retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After")); err != nil || retryAfter != 1 {
+			t.Errorf("Retry-After = %q, want 1 second at 1 rps", rec.Header().Get("Retry-After"))
+		}
+		var body map[string]any
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		if body["error_code"] != "RATE_LIMITED" {
+			t.Errorf("error_code = %v, want RATE_LIMITED", body["error_code"])
+		}
+	})
+
+	t.Run("other clients aren't affected", func(t *testing.T) {
+		if codes, _ := hit(h, http.MethodGet, "/api/students", "10.0.0.2", 1); codes[0] != http.StatusNoContent {
+			t.Errorf("another client got %d, want its own bucket", codes[0])
+		}
+	})
+
+	t.Run("routes share the default bucket", func(t *testing.T) {
+		// 10.0.0.1 used up its bucket on GET /api/students above.
+		if codes, _ := hit(h, http.MethodPost, "/api/students", "10.0.0.1", 1); codes[0] != http.StatusTooManyRequests {
+			t.Errorf("got %d on another route, want the shared bucket to be empty", codes[0])
+		}
+	})
+}
+
+func TestRateLimiterDefaultBurst(t *testing.T) {
+	tests := []struct {
+		rps  float64
+		want int // requests served at once
+	}{
+		{5, 5},
+		{2.5, 3},
+		{0.1, 1},
+	}
+	for _, tt := range tests {
+		codes, _ := hit(limitedServer(t, tt.rps, 0), http.MethodGet, "/api/students", "10.0.0.1", tt.want+1)
+		if codes[tt.want-1] != http.StatusNoContent || codes[tt.want] != http.StatusTooManyRequests {
+			t.Errorf("rps %v: statuses = %v, want %d served", tt.rps, codes, tt.want)
+		}
+	}
+}
+
+func TestRateLimiterRefills(t *testing.T) {
+	h := limitedServer(t, 20, 1)
+
+	hit(h, http.MethodGet, "/api/students", "10.0.0.1", 1)
+	time.Sleep(60 * time.Millisecond) // a token every 50ms
+	if codes, _ := hit(h, http.MethodGet, "/api/students", "10.0.0.1", 1); codes[0] != http.StatusNoContent {
+		t.Errorf("got %d after the bucket refilled", codes[0])
+	}
+}
+
+func TestRateLimiterRoutes(t *testing.T) {
+	h := limitedServer(t, 100, 100, WithRoutes(map[string]float64{
+		"GET /admin/db/download": 0.2,
+		"GET /admin/db/stats":    0,
+	}, byPath))
+
+	t.Run("a stricter route", func(t *testing.T) {
+		codes, rec := hit(h, http.MethodGet, "/admin/db/download", "10.0.0.1", 2)
+		if codes[0] != http.StatusNoContent || codes[1] != http.StatusTooManyRequests {
+			t.Fatalf("statuses = %v, want one served then 429", codes)
+		}
+		if got := rec.Header().Get("Retry-After"); got != "5" {
+			t.Errorf("Retry-After = %q, want 5 seconds at 0.2 rps", got)
+		}
+	})
+
+	t.Run("has its own bucket", func(t *testing.T) {
+		if codes, _ := hit(h, http.MethodGet, "/api/students", "10.0.0.1", 1); codes[0] != http.StatusNoContent {
+			t.Errorf("got %d; the route limit must not use up the default bucket", codes[0])
+		}
+	})
+
+	t.Run("a route with no limit", func(t *testing.T) {
+		codes, _ := hit(h, http.MethodGet, "/admin/db/stats", "10.0.0.1", 200)
+		for i, code := range codes {
+			if code != http.StatusNoContent {
+				t.Fatalf("request %d got %d, want no limit", i+1, code)
+			}
+		}
+	})
+}
+
+func TestRateLimiterUnlimited(t *testing.T) {
+	for _, rps := range []float64{0, -1} {
+		codes, _ := hit(limitedServer(t, rps, 1), http.MethodGet, "/api/students", "10.0.0.1", 50)
+		for i, code := range codes {
+			if code != http.StatusNoContent {
+				t.Fatalf("rps %v: request %d got %d, want no limit", rps, i+1, code)
+			}
+		}
+	}
+}
+
+func TestEvict(t *testing.T) {
+	l := &limiter{idleTTL: time.Minute, clients: make(map[key]*client)}
+	start := time.Now()
+
+	l.get(key{ip: "10.0.0.1"}, 1, 1, start)
+	l.get(key{ip: "10.0.0.2"}, 1, 1, start.Add(50*time.Second))
+	l.evict(start.Add(90 * time.Second))
+
+	if _, ok := l.clients[key{ip: "10.0.0.1"}]; ok {
+		t.Error("the idle client's bucket was kept")
+	}
+	if _, ok := l.clients[key{ip: "10.0.0.2"}]; !ok {
+		t.Error("the active client's bucket was dropped")
+	}
+}
+
+func TestEvictIdle(t *testing.T) {
+	l := &limiter{idleTTL: 10 * time.Millisecond, clients: make(map[key]*client)}
+	l.get(key{ip: "10.0.0.1"}, 1, 1, time.Now())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		l.evictIdle(ctx, l.idleTTL)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		l.mu.Lock()
+		n := len(l.clients)
+		l.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the goroutine didn't evict the idle bucket")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the goroutine didn't stop when its context was cancelled")
+	}
+}
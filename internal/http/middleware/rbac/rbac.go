@@ -0,0 +1,79 @@
+// Package rbac decides what an authenticated client may do, from the
+// "role" claim of its token.
+//
+// Roles are ranked; each one may do everything the ones below it may:
+//
+//	viewer → read only (GET routes)
+//	editor → read and write (every method)
+//	admin  → also the irreversible operations, such as erasing a
+//	         student's personal data
+//
+// A token without a role claim counts as a viewer: least privilege, so a
+// token that forgot the claim can't write. Clients that write need the
+// editor role.
+package rbac
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/aanand-mishra/students-api/internal/http/middleware/auth"
+	"github.com/aanand-mishra/students-api/internal/http/middleware/requestid"
+	"github.com/aanand-mishra/students-api/internal/utils/response"
+)
+
+// The roles a token's "role" claim can name.
+const (
+	Viewer = "viewer"
+	Editor = "editor"
+	Admin  = auth.RoleAdmin
+)
+
+// ranks orders the roles. A role missing from it (a typo in a token, say)
+// ranks below viewer and is allowed nothing.
+var ranks = map[string]int{
+	Viewer: 1,
+	Editor: 2,
+	Admin:  3,
+}
+
+// Allows reports whether a token with role may do what required needs.
+// An empty role is a viewer (see the package doc).
+func Allows(role, required string) bool {
+	if role == "" {
+		role = Viewer
+	}
+	return ranks[role] >= ranks[required]
+}
+
+// Require only lets requests through when their token's role is at least
+// role; anything else gets 403 Forbidden. It reads the claims
+// auth.JWTMiddleware stored, so it must be wrapped inside it:
+//
+//	requireToken(rbac.Require(rbac.Editor)(handler))
+//
+// role must be one of the roles above. Anything else is a programming
+// error and panics, so a route given a misspelt role fails at startup
+// rather than on its first request.
+func Require(role string) func(http.Handler) http.Handler {
+	if _, ok := ranks[role]; !ok {
+		panic(fmt.Sprintf("rbac.Require: unknown role %q", role))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := auth.ClaimsFromContext(r.Context())
+			if !ok || !Allows(claims.Role, role) {
+				requestid.Logger(r.Context()).Warn("rejected request without the required role",
+					slog.String("path", r.URL.Path),
+					slog.String("required", role))
+				response.WriteJSON(w, http.StatusForbidden,
+					response.Error(response.ErrCodeForbidden, fmt.Errorf("this requires the %s role", role)))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package rbac
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/aanand-mishra/students-api/internal/http/middleware/auth"
+)
+
+const testSecret = "test-secret"
+
+// token returns a bearer header for a token with role ("" for none),
+// signed with testSecret.
+func token(t *testing.T, role string) string {
+	t.Helper()
+
+	claims := auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+		Role:             role,
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	return "Bearer " + signed
+}
+
+func TestRequire(t *testing.T) {
+	tests := []struct {
+		role     string
+		required string
+		want     int
+	}{
+		{"", Viewer, http.StatusOK},
+		{"", Editor, http.StatusForbidden},
+		{"", Admin, http.StatusForbidden},
+		{Viewer, Viewer, http.StatusOK},
+		{Viewer, Editor, http.StatusForbidden},
+		{Viewer, Admin, http.StatusForbidden},
+		{Editor, Viewer, http.StatusOK},
+		{Editor, Editor, http.StatusOK},
+		{Editor, Admin, http.StatusForbidden},
+		{Admin, Viewer, http.StatusOK},
+		{Admin, Editor, http.StatusOK},
+		{Admin, Admin, http.StatusOK},
+		{"superuser", Viewer, http.StatusForbidden},
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for _, tt := range tests {
+		t.Run(tt.role+"→"+tt.required, func(t *testing.T) {
+			h := auth.JWTMiddleware(testSecret)(Require(tt.required)(ok))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/students", nil)
+			req.Header.Set("Authorization", token(t, tt.role))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d\nbody: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.want == http.StatusForbidden {
+				var body struct {
+					ErrorCode string `json:"error_code"`
+				}
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.ErrorCode != "FORBIDDEN" {
+					t.Errorf("body = %s, want error_code FORBIDDEN", rec.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestRequireWithoutClaims(t *testing.T) {
+	h := Require(Viewer)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/students", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestRequireUnknownRolePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Require(\"owner\") did not panic")
+		}
+	}()
+	Require("owner")
+}
@@ -0,0 +1,103 @@
+// Package recovery turns a panicking handler into a 500 response instead
+// of a dropped connection.
+//
+// net/http already recovers a panic in a handler's goroutine, but all the
+// client sees is the connection closing, and the log gets a bare stack
+// with nothing to tie it to the request. Recovery answers with the usual
+// JSON error and logs the stack next to the method, path and request ID.
+package recovery
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/aanand-mishra/students-api/internal/http/middleware/requestid"
+	"github.com/aanand-mishra/students-api/internal/utils/response"
+)
+
+// errInternal is the message clients see. The panic value itself stays
+// in the log: it may name internals the client has no business seeing.
+var errInternal = errors.New("internal server error")
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Recovery recovers a panic anywhere in next, logs it at ERROR with the
+// stack trace, and answers 500 Internal Server Error:
+//
+//	{ "status": "error", "error": "internal server error", "error_code": "INTERNAL_ERROR" }
+//
+// It should be the outermost middleware, so a panic in any other layer —
+// auth, rate limiting, logging — is caught too. Outside requestid it
+// can't use the request's logger, but still logs the ID from the
+// X-Request-ID response header if one was set.
+//
+// If the handler had already started its response, a 500 can no longer
+// be sent; the connection is aborted instead so the client doesn't
+// mistake a truncated body for a complete one. http.ErrAbortHandler,
+// which handlers panic with on purpose to do just that, is passed on
+// without logging.
+// ─────────────────────────────────────────────────────────────────────────────
+func Recovery(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &responseWriter{ResponseWriter: w}
+
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+
+				logger.Error("panic while handling request",
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("request_id", w.Header().Get(requestid.Header)),
+					slog.String("panic", fmt.Sprint(p)),
+					slog.String("stack", string(debug.Stack())))
+
+				if rw.wroteHeader {
+					panic(http.ErrAbortHandler)
+				}
+				response.WriteJSON(w, http.StatusInternalServerError,
+					response.Error(response.ErrCodeInternal, errInternal))
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// responseWriter records whether the response has been started.
+type responseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (rw *responseWriter) WriteHeader(status int) {
+	rw.wroteHeader = true
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	rw.wroteHeader = true
+	return rw.ResponseWriter.Write(p)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Flush passes through to the underlying writer when it supports it.
+// A flush sends the headers, so it starts the response too.
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		rw.wroteHeader = true
+		f.Flush()
+	}
+}
@@ -0,0 +1,97 @@
+// Package requestid gives every request an ID, so the log lines one
+// request produces — here and in any service it calls — can be found
+// together.
+//
+// The ID comes from the client's X-Request-ID header when it sent a
+// usable one, and is a fresh UUID v4 otherwise. It is echoed back in the
+// response's X-Request-ID header.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// Header carries the request ID, in both directions.
+const Header = "X-Request-ID"
+
+// maxLength bounds an ID taken from a client, so a huge header can't
+// bloat every log line the request writes.
+const maxLength = 128
+
+// contextKey is the context key type for this package. Unexported, so no
+// other package can collide with its keys.
+type contextKey int
+
+const (
+	idKey contextKey = iota
+	loggerKey
+)
+
+// RequestID makes sure every request has an ID. It stores the ID in the
+// request context (see FromContext) together with a logger that adds it
+// to every line (see Logger), and sets it on the response.
+//
+// It should wrap the other middleware, so their log lines carry the ID
+// too.
+func RequestID() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(Header)
+			if !valid(id) {
+				id = newUUID()
+			}
+
+			w.Header().Set(Header, id)
+
+			ctx := context.WithValue(r.Context(), idKey, id)
+			ctx = context.WithValue(ctx, loggerKey, slog.Default().With(slog.String("request_id", id)))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// FromContext returns the request's ID, or "" if RequestID didn't run.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(idKey).(string)
+	return id
+}
+
+// Logger returns the request's logger, which adds request_id to every
+// line. Without one (RequestID didn't run) it returns the default
+// logger, so callers never have to check.
+func Logger(ctx context.Context) *slog.Logger {
+	if log, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return log
+	}
+	return slog.Default()
+}
+
+// valid reports whether a client-sent ID can be used as is: not empty,
+// not too long, and printable ASCII only, so it can't forge log lines.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// newUUID returns a random (version 4) UUID, as described in RFC 9562.
+func newUUID() string {
+	var b [16]byte
+	// crypto/rand.Read never fails on the platforms Go supports.
+	_, _ = rand.Read(b[:])
+
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 9562 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/aanand-mishra/students-api/internal/http/middleware/auth"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// TenantFromContext returns the tenant a request works on: the tenant_id
+// claim of the token auth.JWTMiddleware accepted, or types.DefaultTenant
+// when the token has none (or the route isn't behind JWTMiddleware).
+// With security.multi_tenant on, JWTMiddleware refuses tokens without
+// one (see auth.RequireTenant), so they never get here.
+//
+// Handlers pass it to every storage.Storage call, which only ever reads
+// and writes that tenant's students:
+//
+//	student, err := storage.GetStudentByID(ctx, middleware.TenantFromContext(ctx), id)
+func TenantFromContext(ctx context.Context) string {
+	if claims, ok := auth.ClaimsFromContext(ctx); ok && claims.TenantID != "" {
+		return claims.TenantID
+	}
+	return types.DefaultTenant
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aanand-mishra/students-api/internal/http/middleware/auth"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+func TestTenantFromContext(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{"no token", context.Background(), types.DefaultTenant},
+		{"token without tenant", auth.NewContext(context.Background(), &auth.Claims{}), types.DefaultTenant},
+		{"token with tenant", auth.NewContext(context.Background(), &auth.Claims{TenantID: "acme"}), "acme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TenantFromContext(tt.ctx); got != tt.want {
+				t.Errorf("TenantFromContext = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
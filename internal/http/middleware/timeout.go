@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/http/middleware/requestid"
+	"github.com/aanand-mishra/students-api/internal/utils/response"
+)
+
+// errRequestTimedOut is the message clients see in the 504 body.
+var errRequestTimedOut = errors.New("request timed out")
+
+// Timeout answers 504 Gateway Timeout with the usual JSON error body when
+// a handler hasn't started its response within d.
+//
+// http.Server's WriteTimeout only closes the connection, which clients
+// see as a network error rather than an HTTP one, so d should be shorter
+// than it (main.go uses 80%) for this to get in first.
+//
+// The handler keeps running in its own goroutine after the 504 is sent —
+// a goroutine can't be stopped from outside — but its context is
+// cancelled so database calls give up, and anything it writes afterwards
+// is discarded. If it panics after that, there is no response left for
+// recovery.Recovery to answer with, so the panic is logged here instead,
+// with its stack and the request ID. A handler that has already started writing when d passes
+// is left to finish: by then the status line has gone out and a 504 can
+// no longer be sent.
+//
+// exempt lists routes, as ServeMux patterns (e.g.
+// "GET /api/students/events"), that stream for as long as the client
+// stays connected and so must not be cut off. patternOf reports the
+// pattern a request will be routed to (see router.Router.Pattern).
+func Timeout(d time.Duration, patternOf func(*http.Request) string, exempt ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if slices.Contains(exempt, patternOf(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			tw := &timeoutWriter{w: w, h: make(http.Header), ctx: ctx}
+			done := make(chan struct{})
+			panicked := make(chan handlerPanic, 1)
+
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- handlerPanic{value: p, stack: debug.Stack()}
+						return
+					}
+					close(done)
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+			}()
+
+			select {
+			case <-done:
+			case p := <-panicked:
+				// Re-panic here so the server's own recovery sees it.
+				panic(p.value)
+			case <-ctx.Done():
+				tw.mu.Lock()
+				if tw.wroteHeader {
+					// Too late for a 504; let the handler finish.
+					tw.mu.Unlock()
+					select {
+					case <-done:
+					case p := <-panicked:
+						panic(p.value)
+					}
+					return
+				}
+				tw.timedOut = true
+				tw.mu.Unlock()
+
+				response.WriteJSON(w, http.StatusGatewayTimeout, response.TimeoutError(errRequestTimedOut))
+				go logLatePanic(r, done, panicked)
+			}
+		})
+	}
+}
+
+// handlerPanic is what a handler running under Timeout panicked with,
+// and where.
+type handlerPanic struct {
+	value any
+	stack []byte
+}
+
+// logLatePanic waits for a handler that has already been answered with a
+// 504 to return, and logs it if it panics on the way out. Nothing else
+// reads panicked by then, so without this the panic would vanish.
+func logLatePanic(r *http.Request, done <-chan struct{}, panicked <-chan handlerPanic) {
+	select {
+	case <-done:
+	case p := <-panicked:
+		if p.value == http.ErrAbortHandler {
+			return
+		}
+		requestid.Logger(r.Context()).Error("panic after request timed out",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("panic", fmt.Sprint(p.value)),
+			slog.String("stack", string(p.stack)))
+	}
+}
+
+// timeoutWriter is what the handler writes to under Timeout. The handler
+// gets its own header map, copied to the real one on WriteHeader, so it
+// never touches the real writer once Timeout has sent the 504 instead.
+//
+// A response started after ctx is done counts as timed out, even if
+// Timeout hasn't got round to sending the 504 yet: the handler wakes on
+// the same cancellation and could otherwise beat it.
+type timeoutWriter struct {
+	w   http.ResponseWriter
+	h   http.Header
+	ctx context.Context
+
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (t *timeoutWriter) Header() http.Header {
+	return t.h
+}
+
+func (t *timeoutWriter) WriteHeader(status int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.writeHeaderLocked(status)
+}
+
+func (t *timeoutWriter) writeHeaderLocked(status int) {
+	if !t.wroteHeader && t.ctx.Err() != nil {
+		t.timedOut = true
+	}
+	if t.timedOut || t.wroteHeader {
+		return
+	}
+	t.wroteHeader = true
+
+	dst := t.w.Header()
+	for k, v := range t.h {
+		dst[k] = v
+	}
+	t.w.WriteHeader(status)
+}
+
+func (t *timeoutWriter) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.writeHeaderLocked(http.StatusOK)
+	if t.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	return t.w.Write(p)
+}
+
+// Flush passes through to the underlying writer when it supports it.
+func (t *timeoutWriter) Flush() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.writeHeaderLocked(http.StatusOK)
+	if t.timedOut {
+		return
+	}
+	if f, ok := t.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
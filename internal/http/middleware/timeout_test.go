@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/http/middleware/requestid"
+)
+
+// byPath reports a request's pattern as "METHOD path".
+func byPath(r *http.Request) string { return r.Method + " " + r.URL.Path }
+
+func TestTimeout(t *testing.T) {
+	const d = 50 * time.Millisecond
+
+	t.Run("fast handler", func(t *testing.T) {
+		h := Timeout(d, byPath)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Test", "kept")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("done"))
+		}))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/students", nil))
+
+		if rec.Code != http.StatusCreated || rec.Body.String() != "done" || rec.Header().Get("X-Test") != "kept" {
+			t.Errorf("got %d %q (X-Test %q); want the handler's response", rec.Code, rec.Body, rec.Header().Get("X-Test"))
+		}
+	})
+
+	t.Run("slow handler", func(t *testing.T) {
+		lateWrite := make(chan error, 1)
+		h := Timeout(d, byPath)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done() // what a database call does on cancellation
+			w.Header().Set("X-Test", "too late")
+			_, err := w.Write([]byte("late"))
+			lateWrite <- err
+		}))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/students", nil))
+
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusGatewayTimeout)
+		}
+		var body map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("body is not JSON: %v\n%s", err, rec.Body)
+		}
+		if body["error_code"] != "TIMEOUT" || body["error"] != "request timed out" {
+			t.Errorf("body = %v", body)
+		}
+
+		select {
+		case err := <-lateWrite:
+			if !errors.Is(err, http.ErrHandlerTimeout) {
+				t.Errorf("late Write = %v, want http.ErrHandlerTimeout", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("the handler's context was not cancelled")
+		}
+		if rec.Header().Get("X-Test") != "" || rec.Body.Len() == 0 || body["error"] == "late" {
+			t.Errorf("the late response leaked into the 504")
+		}
+	})
+
+	t.Run("already writing", func(t *testing.T) {
+		h := Timeout(d, byPath)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("part one, "))
+			time.Sleep(2 * d)
+			w.Write([]byte("part two"))
+		}))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/students", nil))
+
+		if rec.Code != http.StatusOK || rec.Body.String() != "part one, part two" {
+			t.Errorf("got %d %q; a started response must be left to finish", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("exempt route", func(t *testing.T) {
+		h := Timeout(d, byPath, "GET /api/students/events")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(2 * d)
+			if r.Context().Err() != nil {
+				t.Error("the exempt route's context was cancelled")
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/students/events", nil))
+
+		if rec.Code != http.StatusNoContent {
+			t.Errorf("status = %d, want the handler's %d", rec.Code, http.StatusNoContent)
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		h := Timeout(d, byPath)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}))
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("recovered %v, want the handler's panic re-raised", p)
+			}
+		}()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/students", nil))
+	})
+
+	t.Run("panic after the 504", func(t *testing.T) {
+		var logs syncBuffer
+		defer slog.SetDefault(slog.Default())
+		slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+
+		h := requestid.RequestID()(Timeout(d, byPath)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			panic("late boom")
+		})))
+		req := httptest.NewRequest(http.MethodGet, "/api/students", nil)
+		req.Header.Set(requestid.Header, "req-955")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusGatewayTimeout)
+		}
+
+		deadline := time.Now().Add(time.Second)
+		for !strings.Contains(logs.String(), "late boom") && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		var entry map[string]any
+		if err := json.Unmarshal(bytes.TrimSpace([]byte(logs.String())), &entry); err != nil {
+			t.Fatalf("log = %q, want the panic logged as one JSON line: %v", logs.String(), err)
+		}
+		if entry["panic"] != "late boom" || entry["request_id"] != "req-955" || entry["stack"] == "" {
+			t.Errorf("log entry = %v, want the panic with its stack and request_id", entry)
+		}
+	})
+}
+
+// syncBuffer is a bytes.Buffer that a handler's goroutine can log to
+// while the test reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestTimeoutDisabled(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for _, d := range []time.Duration{0, -time.Second} {
+		h := Timeout(d, byPath)(next)
+		if _, ok := h.(http.HandlerFunc); !ok {
+			t.Errorf("Timeout(%s) wrapped the handler; want it returned as is", d)
+		}
+	}
+}
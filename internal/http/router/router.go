@@ -0,0 +1,98 @@
+// Package router wraps http.ServeMux so that it can answer OPTIONS
+// requests from the routes actually registered on it.
+//
+// ServeMux matches requests fine on its own, but it can't directly answer
+// "which methods does /api/students support?" — the question behind an
+// OPTIONS request and its Allow header. Router answers it by asking the
+// mux, for each method, whether a request with that method would reach a
+// registered route. So the answer always matches the real routes: add a
+// handler, and the OPTIONS response picks the new method up by itself.
+package router
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/aanand-mishra/students-api/internal/http/middleware"
+)
+
+// methods are the methods probed for, in the order they are listed in
+// Allow headers. OPTIONS itself is always allowed and comes last.
+var methods = []string{
+	http.MethodGet,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// Router is an http.ServeMux that knows which methods each path allows.
+type Router struct {
+	*http.ServeMux
+}
+
+// New returns an empty Router.
+func New() *Router {
+	return &Router{ServeMux: http.NewServeMux()}
+}
+
+// Allowed returns the methods that have a route for r's URL path, plus
+// OPTIONS, in a stable conventional order (GET, POST, PUT, PATCH, DELETE,
+// OPTIONS). It returns nil when no route matches the path at all.
+func (rt *Router) Allowed(r *http.Request) []string {
+	var allowed []string
+
+	for _, method := range methods {
+		probe := r.Clone(r.Context())
+		probe.Method = method
+
+		// Handler reports the pattern that would serve the request, or ""
+		// when nothing would (including "path exists, wrong method").
+		_, pattern := rt.ServeMux.Handler(probe)
+		if strings.HasPrefix(pattern, method+" ") {
+			allowed = append(allowed, method)
+		}
+	}
+
+	if allowed == nil {
+		return nil
+	}
+	return append(allowed, http.MethodOptions)
+}
+
+// Pattern returns the pattern of the route that will serve r, such as
+// "GET /api/students/{id}", or "" if no route matches. Middleware can use
+// it before the mux runs (r.Pattern is only set once it has).
+func (rt *Router) Pattern(r *http.Request) string {
+	_, pattern := rt.ServeMux.Handler(r)
+	return pattern
+}
+
+// WithPrefix returns pattern with prefix put in front of its path, e.g.
+// "GET /v1/api/students" for ("GET /api/students", "/v1"). It is how the
+// same routes are registered under more than one prefix.
+func WithPrefix(pattern, prefix string) string {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		return prefix + pattern
+	}
+	return method + " " + prefix + path
+}
+
+// HandlePreflight answers OPTIONS for every path under prefix (e.g.
+// "/api/") with middleware.Preflight, listing the methods Allowed finds
+// for that path. Paths with no route at all get 404.
+//
+// One handler serves the whole prefix — registering OPTIONS per route
+// would make ServeMux reject overlapping patterns such as
+// /api/students/{id}/photo and /api/students/external/{external_id}.
+func (rt *Router) HandlePreflight(prefix string) {
+	rt.ServeMux.HandleFunc(http.MethodOptions+" "+prefix, func(w http.ResponseWriter, r *http.Request) {
+		allowed := rt.Allowed(r)
+		if allowed == nil {
+			http.NotFound(w, r)
+			return
+		}
+		middleware.Preflight(allowed).ServeHTTP(w, r)
+	})
+}
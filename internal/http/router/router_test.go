@@ -0,0 +1,125 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func ok(w http.ResponseWriter, r *http.Request) {}
+
+// newRouter returns a Router with a few routes like main.go's, and
+// preflight handling for /api/.
+func newRouter() *Router {
+	rt := New()
+	rt.HandleFunc("GET /api/students", ok)
+	rt.HandleFunc("POST /api/students", ok)
+	rt.HandleFunc("GET /api/students/{id}", ok)
+	rt.HandleFunc("PUT /api/students/{id}", ok)
+	rt.HandleFunc("PATCH /api/students/{id}", ok)
+	rt.HandleFunc("DELETE /api/students/{id}", ok)
+	rt.HandleFunc("POST /api/students/{id}/photo", ok)
+	rt.HandleFunc("GET /api/students/external/{external_id}", ok)
+	rt.HandleFunc("GET /health", ok)
+	rt.HandlePreflight("/api/")
+	return rt
+}
+
+func TestAllowed(t *testing.T) {
+	rt := newRouter()
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"/api/students", []string{"GET", "POST", "OPTIONS"}},
+		{"/api/students/1", []string{"GET", "PUT", "PATCH", "DELETE", "OPTIONS"}},
+		{"/api/students/1/photo", []string{"POST", "OPTIONS"}},
+		{"/api/students/external/abc", []string{"GET", "OPTIONS"}},
+		{"/api/nothing", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got := rt.Allowed(httptest.NewRequest(http.MethodOptions, tt.path, nil))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Allowed(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlePreflight(t *testing.T) {
+	rt := newRouter()
+
+	t.Run("known path", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodOptions, "/api/students/7", nil)
+		req.Header.Set("Origin", "https://example.com")
+		req.Header.Set("Access-Control-Request-Method", "PATCH")
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+		}
+		const allow = "GET, PUT, PATCH, DELETE, OPTIONS"
+		for header, want := range map[string]string{
+			"Allow":                        allow,
+			"Access-Control-Allow-Methods": allow,
+			"Access-Control-Allow-Origin":  "*",
+			"Access-Control-Max-Age":       "600",
+		} {
+			if got := rec.Header().Get(header); got != want {
+				t.Errorf("%s = %q, want %q", header, got, want)
+			}
+		}
+		for _, header := range []string{"Authorization", "Content-Type", "If-None-Match"} {
+			if !strings.Contains(rec.Header().Get("Access-Control-Allow-Headers"), header) {
+				t.Errorf("Access-Control-Allow-Headers = %q, want %s in it", rec.Header().Get("Access-Control-Allow-Headers"), header)
+			}
+		}
+	})
+
+	t.Run("unknown path", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/api/nothing", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("outside the prefix", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/health", nil))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func TestPattern(t *testing.T) {
+	rt := newRouter()
+
+	if got := rt.Pattern(httptest.NewRequest(http.MethodGet, "/api/students/3", nil)); got != "GET /api/students/{id}" {
+		t.Errorf("Pattern = %q, want %q", got, "GET /api/students/{id}")
+	}
+	if got := rt.Pattern(httptest.NewRequest(http.MethodGet, "/nowhere", nil)); got != "" {
+		t.Errorf("Pattern = %q for an unknown path, want \"\"", got)
+	}
+}
+
+func TestWithPrefix(t *testing.T) {
+	tests := []struct {
+		pattern, prefix, want string
+	}{
+		{"GET /api/students", "/v1", "GET /v1/api/students"},
+		{"DELETE /api/students/{id}", "/v2", "DELETE /v2/api/students/{id}"},
+		{"/api/students", "/v1", "/v1/api/students"},
+	}
+	for _, tt := range tests {
+		if got := WithPrefix(tt.pattern, tt.prefix); got != tt.want {
+			t.Errorf("WithPrefix(%q, %q) = %q, want %q", tt.pattern, tt.prefix, got, tt.want)
+		}
+	}
+}
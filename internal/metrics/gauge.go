@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync/atomic"
+)
+
+// Gauge is a single value that can go up and down, like a Prometheus
+// gauge. It is safe for concurrent use.
+type Gauge struct {
+	name string
+	help string
+	bits atomic.Uint64 // math.Float64bits of the value
+}
+
+// NewGauge creates a gauge starting at 0.
+func NewGauge(name, help string) *Gauge {
+	return &Gauge{name: name, help: help}
+}
+
+// Add adds delta (which may be negative) to the gauge.
+func (g *Gauge) Add(delta float64) {
+	for {
+		old := g.bits.Load()
+		updated := math.Float64bits(math.Float64frombits(old) + delta)
+		if g.bits.CompareAndSwap(old, updated) {
+			return
+		}
+	}
+}
+
+// Inc adds one to the gauge.
+func (g *Gauge) Inc() { g.Add(1) }
+
+// Dec subtracts one from the gauge.
+func (g *Gauge) Dec() { g.Add(-1) }
+
+func (g *Gauge) collect(_ context.Context, w io.Writer) error {
+	writeHeader(w, g.name, g.help, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, strconv.FormatFloat(math.Float64frombits(g.bits.Load()), 'g', -1, 64))
+	return nil
+}
+
+// gaugeFunc is a gauge whose value is computed on every scrape.
+type gaugeFunc struct {
+	name string
+	help string
+	fn   func(ctx context.Context) (float64, error)
+}
+
+// RegisterGaugeFunc exposes a gauge whose value fn computes each time
+// /metrics is scraped — for values that live elsewhere, like the number
+// of rows in a table. fn gets the scrape request's context. When it
+// fails, the gauge is left out of that scrape.
+func RegisterGaugeFunc(name, help string, fn func(ctx context.Context) (float64, error)) {
+	register(&gaugeFunc{name: name, help: help, fn: fn})
+}
+
+func (g *gaugeFunc) collect(ctx context.Context, w io.Writer) error {
+	value, err := g.fn(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", g.name, err)
+	}
+
+	writeHeader(w, g.name, g.help, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, strconv.FormatFloat(value, 'g', -1, 64))
+	return nil
+}
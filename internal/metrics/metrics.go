@@ -0,0 +1,243 @@
+// Package metrics keeps a few process-wide measurements and serves them
+// on GET /metrics in the Prometheus text exposition format, so any
+// Prometheus server can scrape them.
+//
+// It deliberately implements only the tiny part of the format it needs
+// (histograms and gauges) instead of pulling in the full Prometheus
+// client library.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ResponseSizeBytes records the size of every response body sent.
+// Buckets go from 100 B to 10 MB.
+var ResponseSizeBytes = NewHistogram(
+	"response_size_bytes",
+	"Size of HTTP response bodies in bytes.",
+	[]float64{100, 1_000, 10_000, 100_000, 1_000_000, 10_000_000},
+)
+
+// HTTPRequestDuration records how long each request took to serve, by
+// method, route path (e.g. /api/students/{id}, never the raw URL, which
+// would make a series per student) and status code. The buckets are
+// Prometheus' defaults, 5 ms to 10 s.
+var HTTPRequestDuration = NewHistogramVec(
+	"http_request_duration_seconds",
+	"Time taken to serve HTTP requests, in seconds.",
+	[]float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	"method", "path", "status",
+)
+
+// HTTPRequestsInFlight is the number of requests being served right now.
+var HTTPRequestsInFlight = NewGauge(
+	"http_requests_in_flight",
+	"Number of HTTP requests currently being served.",
+)
+
+// collector is anything Handler can write out.
+type collector interface {
+	collect(ctx context.Context, w io.Writer) error
+}
+
+var (
+	registryMu sync.Mutex
+	// registry is every metric Handler exposes, in output order.
+	registry = []collector{ResponseSizeBytes, HTTPRequestDuration, HTTPRequestsInFlight}
+)
+
+// register adds c to the metrics Handler exposes.
+func register(c collector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry = append(registry, c)
+}
+
+// Histogram counts observations into cumulative buckets, like a
+// Prometheus histogram: each bucket counts the observations <= its bound.
+type Histogram struct {
+	name    string
+	help    string
+	bounds  []float64 // upper bounds, ascending; +Inf is implicit
+	mu      sync.Mutex
+	counts  []uint64 // per-bucket (non-cumulative) counts, plus +Inf
+	sum     float64
+	samples uint64
+}
+
+// NewHistogram creates a histogram with the given ascending bucket bounds.
+func NewHistogram(name, help string, bounds []float64) *Histogram {
+	return &Histogram{
+		name:   name,
+		help:   help,
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)+1),
+	}
+}
+
+// Observe records one value.
+func (h *Histogram) Observe(v float64) {
+	i := 0
+	for i < len(h.bounds) && v > h.bounds[i] {
+		i++
+	}
+
+	h.mu.Lock()
+	h.counts[i]++
+	h.sum += v
+	h.samples++
+	h.mu.Unlock()
+}
+
+func (h *Histogram) collect(_ context.Context, w io.Writer) error {
+	writeHeader(w, h.name, h.help, "histogram")
+	h.writeSeries(w, "")
+	return nil
+}
+
+// writeSeries writes h's bucket, sum and count lines. labels, if not
+// empty, is a rendered label list such as `method="GET",path="/x"`
+// added in front of le.
+func (h *Histogram) writeSeries(w io.Writer, labels string) {
+	h.mu.Lock()
+	counts := append([]uint64(nil), h.counts...)
+	sum, samples := h.sum, h.samples
+	h.mu.Unlock()
+
+	sep, braces := "", ""
+	if labels != "" {
+		sep, braces = ",", "{"+labels+"}"
+	}
+
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += counts[i]
+		fmt.Fprintf(w, "%s_bucket{%s%sle=%q} %d\n", h.name, labels, sep, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+	}
+	cumulative += counts[len(h.bounds)]
+	fmt.Fprintf(w, "%s_bucket{%s%sle=\"+Inf\"} %d\n", h.name, labels, sep, cumulative)
+	fmt.Fprintf(w, "%s_sum%s %s\n", h.name, braces, strconv.FormatFloat(sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count%s %d\n", h.name, braces, samples)
+}
+
+// HistogramVec is a family of histograms with the same buckets, one per
+// combination of label values.
+type HistogramVec struct {
+	name       string
+	help       string
+	bounds     []float64
+	labelNames []string
+
+	mu     sync.Mutex
+	series map[string]*Histogram // keyed by rendered labels
+}
+
+// NewHistogramVec creates a histogram family with the given ascending
+// bucket bounds and label names.
+func NewHistogramVec(name, help string, bounds []float64, labelNames ...string) *HistogramVec {
+	return &HistogramVec{
+		name:       name,
+		help:       help,
+		bounds:     bounds,
+		labelNames: labelNames,
+		series:     make(map[string]*Histogram),
+	}
+}
+
+// Observe records one value in the histogram for labelValues, given in
+// the order of the label names.
+func (v *HistogramVec) Observe(value float64, labelValues ...string) {
+	key := renderLabels(v.labelNames, labelValues)
+
+	v.mu.Lock()
+	h, ok := v.series[key]
+	if !ok {
+		h = NewHistogram(v.name, v.help, v.bounds)
+		v.series[key] = h
+	}
+	v.mu.Unlock()
+
+	h.Observe(value)
+}
+
+func (v *HistogramVec) collect(_ context.Context, w io.Writer) error {
+	v.mu.Lock()
+	series := make(map[string]*Histogram, len(v.series))
+	keys := make([]string, 0, len(v.series))
+	for key, h := range v.series {
+		series[key] = h
+		keys = append(keys, key)
+	}
+	v.mu.Unlock()
+
+	// Sorted so scrapes list the series in a stable order.
+	sort.Strings(keys)
+
+	writeHeader(w, v.name, v.help, "histogram")
+	for _, key := range keys {
+		series[key].writeSeries(w, key)
+	}
+	return nil
+}
+
+// labelEscaper escapes label values as the text format requires.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// renderLabels turns names and values into `a="1",b="2"`.
+func renderLabels(names, values []string) string {
+	var b strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(value))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+// writeHeader writes a metric's HELP and TYPE lines.
+func writeHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Handler serves GET /metrics for a Prometheus scraper.
+//
+//	curl http://localhost:8082/metrics
+//
+// A metric that fails to collect (a RegisterGaugeFunc gauge whose
+// database query failed, say) is left out and logged; the others are
+// still served.
+// ─────────────────────────────────────────────────────────────────────────────
+func Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		registryMu.Lock()
+		collectors := append([]collector(nil), registry...)
+		registryMu.Unlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		for _, c := range collectors {
+			if err := c.collect(r.Context(), w); err != nil {
+				slog.Warn("metric not collected", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
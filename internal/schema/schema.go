@@ -0,0 +1,199 @@
+// Package schema generates JSON Schema (draft-07) documents from Go
+// structs, so clients can validate payloads before sending them.
+//
+// The schema is derived from the same struct tags the server uses, so
+// the document can't drift away from what the server enforces:
+//
+//	json:"name"                  → property "name"
+//	validate:"required"          → listed in "required"
+//	validate:"email"             → "format": "email"
+//	validate:"e164"              → "pattern" of an E.164 phone number
+//	validate:"min=1,max=150"     → "minimum"/"maximum" (numbers) or
+//	                               "minLength"/"maxLength" (strings)
+//
+// A time.Time field becomes a string with "format": "date-time", which is
+// how encoding/json writes it (RFC 3339).
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Draft07 is the $schema URI of the JSON Schema version generated.
+const Draft07 = "http://json-schema.org/draft-07/schema#"
+
+// Option customises GenerateJSONSchema.
+type Option func(*options)
+
+type options struct {
+	required []string
+}
+
+// WithRequired sets the required properties (by JSON name), replacing
+// whatever validate:"required" tags say. Use it when required fields are
+// configured at runtime — see the validation package. With no names,
+// nothing is required.
+func WithRequired(names ...string) Option {
+	return func(o *options) {
+		// Never nil, so an empty list still replaces the tags.
+		o.required = append([]string{}, names...)
+	}
+}
+
+// document is a JSON Schema object. Only the keywords we generate exist.
+type document struct {
+	Schema     string               `json:"$schema,omitempty"`
+	Title      string               `json:"title,omitempty"`
+	Type       any                  `json:"type"` // a string, or a list for nullable types
+	Format     string               `json:"format,omitempty"`
+	Pattern    string               `json:"pattern,omitempty"`
+	Minimum    *float64             `json:"minimum,omitempty"`
+	Maximum    *float64             `json:"maximum,omitempty"`
+	MinLength  *int                 `json:"minLength,omitempty"`
+	MaxLength  *int                 `json:"maxLength,omitempty"`
+	Properties map[string]*document `json:"properties,omitempty"`
+	Required   []string             `json:"required,omitempty"`
+}
+
+// timeType is time.Time, the one struct type a field may have.
+var timeType = reflect.TypeOf(time.Time{})
+
+// e164Pattern is what the validation package's e164 rule matches.
+const e164Pattern = `^\+[1-9]\d{1,14}$`
+
+// GenerateJSONSchema returns the JSON Schema describing v, which must be
+// a struct (or a pointer to one). Only exported fields with a json tag
+// become properties.
+func GenerateJSONSchema(v any, opts ...Option) ([]byte, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("schema: %T is not a struct", v)
+	}
+
+	doc := &document{
+		Schema:     Draft07,
+		Title:      t.Name(),
+		Type:       "object",
+		Properties: make(map[string]*document),
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+
+		prop, required, err := property(field)
+		if err != nil {
+			return nil, fmt.Errorf("schema: field %s: %w", field.Name, err)
+		}
+		doc.Properties[name] = prop
+		if required {
+			doc.Required = append(doc.Required, name)
+		}
+	}
+
+	if o.required != nil {
+		for _, name := range o.required {
+			if _, ok := doc.Properties[name]; !ok {
+				return nil, fmt.Errorf("schema: required property %q does not exist", name)
+			}
+		}
+		doc.Required = o.required
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// property builds the schema of one field and reports whether its
+// validate tag marks it required.
+func property(field reflect.StructField) (*document, bool, error) {
+	t := field.Type
+	nullable := t.Kind() == reflect.Pointer
+	if nullable {
+		t = t.Elem()
+	}
+
+	var typ string
+	switch t.Kind() {
+	case reflect.String:
+		typ = "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		typ = "integer"
+	case reflect.Float32, reflect.Float64:
+		typ = "number"
+	case reflect.Bool:
+		typ = "boolean"
+	case reflect.Struct:
+		if t != timeType {
+			return nil, false, fmt.Errorf("unsupported type %s", t)
+		}
+		typ = "string"
+	default:
+		return nil, false, fmt.Errorf("unsupported type %s", t)
+	}
+
+	prop := &document{Type: typ}
+	if t == timeType {
+		prop.Format = "date-time"
+	}
+	if nullable {
+		prop.Type = []string{typ, "null"}
+	}
+
+	required := false
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "required":
+			required = true
+		case "email":
+			prop.Format = "email"
+		case "e164":
+			prop.Pattern = e164Pattern
+		case "min", "gte", "max", "lte":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid %s value %q", key, value)
+			}
+			setBound(prop, typ, key == "min" || key == "gte", n)
+		}
+	}
+
+	return prop, required, nil
+}
+
+// setBound records a min/max rule: a value range for numbers, a length
+// range for strings (which is what validator's min/max mean for them).
+func setBound(prop *document, typ string, lower bool, n float64) {
+	if typ == "string" {
+		length := int(n)
+		if lower {
+			prop.MinLength = &length
+		} else {
+			prop.MaxLength = &length
+		}
+		return
+	}
+
+	if lower {
+		prop.Minimum = &n
+	} else {
+		prop.Maximum = &n
+	}
+}
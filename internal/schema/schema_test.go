@@ -0,0 +1,116 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type sample struct {
+	Name     string     `json:"name" validate:"required,min=2,max=50"`
+	Email    string     `json:"email,omitempty" validate:"required,email"`
+	Age      int        `json:"age" validate:"omitempty,gte=1,lte=150"`
+	Score    float64    `json:"score"`
+	Active   bool       `json:"active"`
+	Phone    string     `json:"phone" validate:"omitempty,e164"`
+	Nickname *string    `json:"nickname"`
+	Joined   time.Time  `json:"joined"`
+	Left     *time.Time `json:"left"`
+	Internal string     `json:"-"`
+	NoTag    string
+}
+
+// generate returns the decoded schema of v.
+func generate(t *testing.T, v any, opts ...Option) map[string]any {
+	t.Helper()
+
+	doc, err := GenerateJSONSchema(v, opts...)
+	if err != nil {
+		t.Fatalf("GenerateJSONSchema: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(doc, &decoded); err != nil {
+		t.Fatalf("the schema is not JSON: %v", err)
+	}
+	return decoded
+}
+
+func TestGenerateJSONSchema(t *testing.T) {
+	doc := generate(t, sample{})
+
+	if doc["$schema"] != Draft07 || doc["title"] != "sample" || doc["type"] != "object" {
+		t.Errorf("header = %v %v %v", doc["$schema"], doc["title"], doc["type"])
+	}
+	if got := doc["required"]; !reflect.DeepEqual(got, []any{"name", "email"}) {
+		t.Errorf("required = %v, want [name email]", got)
+	}
+
+	want := map[string]map[string]any{
+		"name":     {"type": "string", "minLength": 2.0, "maxLength": 50.0},
+		"email":    {"type": "string", "format": "email"},
+		"age":      {"type": "integer", "minimum": 1.0, "maximum": 150.0},
+		"score":    {"type": "number"},
+		"active":   {"type": "boolean"},
+		"phone":    {"type": "string", "pattern": `^\+[1-9]\d{1,14}$`},
+		"nickname": {"type": []any{"string", "null"}},
+		"joined":   {"type": "string", "format": "date-time"},
+		"left":     {"type": []any{"string", "null"}, "format": "date-time"},
+	}
+	props := doc["properties"].(map[string]any)
+	if len(props) != len(want) {
+		t.Errorf("properties = %v; want only the exported fields with a json name", props)
+	}
+	for name, wantProp := range want {
+		if got := props[name]; !reflect.DeepEqual(got, any(map[string]any(wantProp))) {
+			t.Errorf("%s = %v, want %v", name, got, wantProp)
+		}
+	}
+}
+
+func TestGenerateJSONSchemaPointer(t *testing.T) {
+	if doc := generate(t, &sample{}); doc["title"] != "sample" {
+		t.Errorf("title = %v; a pointer to a struct should describe the struct", doc["title"])
+	}
+}
+
+func TestWithRequired(t *testing.T) {
+	doc := generate(t, sample{}, WithRequired("age", "phone"))
+	if got := doc["required"]; !reflect.DeepEqual(got, []any{"age", "phone"}) {
+		t.Errorf("required = %v, want the tags replaced by [age phone]", got)
+	}
+
+	if doc := generate(t, sample{}, WithRequired()); doc["required"] != nil {
+		t.Errorf("required = %v, want none", doc["required"])
+	}
+
+	if _, err := GenerateJSONSchema(sample{}, WithRequired("shoe_size")); err == nil {
+		t.Error("an unknown required property was accepted")
+	}
+}
+
+func TestGenerateJSONSchemaErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+	}{
+		{"nil", nil},
+		{"not a struct", 42},
+		{"unsupported field type", struct {
+			Tags []string `json:"tags"`
+		}{}},
+		{"unsupported struct field", struct {
+			Inner struct{} `json:"inner"`
+		}{}},
+		{"bad bound", struct {
+			Age int `json:"age" validate:"min=young"`
+		}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := GenerateJSONSchema(tt.v); err == nil {
+				t.Error("GenerateJSONSchema succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	for _, backend := range []string{"", BackendNone} {
+		if r, err := New(backend); r != nil || err != nil {
+			t.Errorf("New(%q) = %v, %v; want no resolver", backend, r, err)
+		}
+	}
+	if _, err := New("keychain"); err == nil {
+		t.Error(`New("keychain") = nil error, want an unknown backend error`)
+	}
+
+	// Without credentials in the environment, neither backend can start.
+	for _, key := range []string{"AWS_REGION", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "VAULT_ADDR", "VAULT_TOKEN"} {
+		t.Setenv(key, "")
+	}
+	for _, backend := range []string{BackendAWSSSM, BackendVault} {
+		if _, err := New(backend); err == nil {
+			t.Errorf("New(%q) without credentials = nil error", backend)
+		}
+	}
+
+	t.Setenv("VAULT_ADDR", "https://vault.internal:8200/")
+	t.Setenv("VAULT_TOKEN", "s.token")
+	r, err := New(BackendVault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := r.(*VaultResolver); v.Addr != "https://vault.internal:8200" || v.Path != "secret/data/students-api" {
+		t.Errorf("vault resolver = %+v", v)
+	}
+}
+
+func TestVaultResolve(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("X-Vault-Token") != "s.token":
+			w.WriteHeader(http.StatusForbidden)
+		case r.URL.Path == "/v1/secret/data/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/v1/secret/data/students-api":
+			w.Write([]byte(`{"data":{"data":{"AdminAPIKey":"k3y","Port":8080}}}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	resolver := func(path, token string) *VaultResolver {
+		return &VaultResolver{Addr: srv.URL, Token: token, Path: path, Client: srv.Client()}
+	}
+	ctx := context.Background()
+
+	if got, err := resolver("secret/data/students-api", "s.token").Resolve(ctx, "AdminAPIKey"); err != nil || got != "k3y" {
+		t.Errorf("Resolve(AdminAPIKey) = %q, %v; want k3y", got, err)
+	}
+
+	notFound := []struct {
+		name, path, key string
+	}{
+		{"missing key", "secret/data/students-api", "JWTSecret"},
+		{"not a string", "secret/data/students-api", "Port"},
+		{"missing secret", "secret/data/missing", "AdminAPIKey"},
+	}
+	for _, tt := range notFound {
+		if _, err := resolver(tt.path, "s.token").Resolve(ctx, tt.key); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: err = %v, want ErrNotFound", tt.name, err)
+		}
+	}
+
+	if _, err := resolver("secret/data/students-api", "wrong").Resolve(ctx, "AdminAPIKey"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("bad token: err = %v, want a status error", err)
+	}
+}
@@ -0,0 +1,177 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// SSMResolver reads secrets from AWS Systems Manager Parameter Store.
+// A secret named "AdminAPIKey" is read from the parameter Prefix+"AdminAPIKey".
+type SSMResolver struct {
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // only for temporary credentials
+	Prefix          string // e.g. "/students-api/"
+
+	Client *http.Client
+}
+
+// NewSSMFromEnv builds an SSMResolver from the standard AWS variables
+// (AWS_REGION, AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY,
+// AWS_SESSION_TOKEN) plus SECRETS_PREFIX (default "/students-api/").
+func NewSSMFromEnv() (*SSMResolver, error) {
+	r := &SSMResolver{
+		Region:          os.Getenv("AWS_REGION"),
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		Prefix:          envOr("SECRETS_PREFIX", "/students-api/"),
+		Client:          &http.Client{Timeout: 10 * time.Second},
+	}
+	if r.Region == "" || r.AccessKeyID == "" || r.SecretAccessKey == "" {
+		return nil, errors.New("secrets: AWS_REGION, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	return r, nil
+}
+
+// Resolve calls the SSM GetParameter action (with decryption, so
+// SecureString parameters come back as plain text).
+func (s *SSMResolver) Resolve(ctx context.Context, name string) (string, error) {
+	payload, err := json.Marshal(map[string]any{
+		"Name":           s.Prefix + name,
+		"WithDecryption": true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("ssm: encode request: %w", err)
+	}
+
+	host := "ssm." + s.Region + ".amazonaws.com"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://"+host+"/", bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("ssm: build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AmazonSSM.GetParameter")
+	s.sign(req, host, payload, time.Now().UTC())
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("ssm: request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("ssm: read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if strings.Contains(string(body), "ParameterNotFound") {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("ssm: %s: %s", resp.Status, body)
+	}
+
+	var out struct {
+		Parameter struct {
+			Value string `json:"Value"`
+		} `json:"Parameter"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return "", fmt.Errorf("ssm: decode response: %w", err)
+	}
+
+	return out.Parameter.Value, nil
+}
+
+// sign adds AWS Signature Version 4 headers to req.
+//
+// SigV4 in short: build a canonical text form of the request, hash it,
+// and HMAC it with a key derived from the secret key, date, region and
+// service. AWS repeats the same steps and compares signatures.
+func (s *SSMResolver) sign(req *http.Request, host string, payload []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	scope := date + "/" + s.Region + "/ssm/aws4_request"
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	// Canonical headers must be lower-case and sorted by name.
+	headers := [][2]string{
+		{"content-type", req.Header.Get("Content-Type")},
+		{"host", host},
+		{"x-amz-date", amzDate},
+	}
+	if s.SessionToken != "" {
+		headers = append(headers, [2]string{"x-amz-security-token", s.SessionToken})
+	}
+	headers = append(headers, [2]string{"x-amz-target", req.Header.Get("X-Amz-Target")})
+
+	var canonicalHeaders strings.Builder
+	names := make([]string, 0, len(headers))
+	for _, h := range headers {
+		canonicalHeaders.WriteString(h[0] + ":" + h[1] + "\n")
+		names = append(names, h[0])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		"/",
+		"", // no query string
+		canonicalHeaders.String(),
+		signedHeaders,
+		hashHex(payload),
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "ssm")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hashHex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// envOr returns the environment variable key, or fallback if it is unset.
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
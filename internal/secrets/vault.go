@@ -0,0 +1,77 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// VaultResolver reads secrets from a HashiCorp Vault KV version 2 engine.
+// All secrets live as keys of one Vault secret at Path; a secret named
+// "AdminAPIKey" is the "AdminAPIKey" key of that secret.
+type VaultResolver struct {
+	Addr  string // e.g. https://vault.internal:8200
+	Token string
+	Path  string // API path of the KV v2 secret, e.g. "secret/data/students-api"
+
+	Client *http.Client
+}
+
+// NewVaultFromEnv builds a VaultResolver from VAULT_ADDR, VAULT_TOKEN and
+// VAULT_SECRET_PATH (default "secret/data/students-api").
+func NewVaultFromEnv() (*VaultResolver, error) {
+	v := &VaultResolver{
+		Addr:   strings.TrimRight(os.Getenv("VAULT_ADDR"), "/"),
+		Token:  os.Getenv("VAULT_TOKEN"),
+		Path:   strings.Trim(envOr("VAULT_SECRET_PATH", "secret/data/students-api"), "/"),
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+	if v.Addr == "" || v.Token == "" {
+		return nil, errors.New("secrets: VAULT_ADDR and VAULT_TOKEN must be set")
+	}
+	return v, nil
+}
+
+// Resolve reads the secret at Path and returns its name key.
+func (v *VaultResolver) Resolve(ctx context.Context, name string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.Addr+"/v1/"+v.Path, nil)
+	if err != nil {
+		return "", fmt.Errorf("vault: build request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault: request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault: unexpected status %s", resp.Status)
+	}
+
+	// KV v2 wraps the key/value pairs twice: {"data": {"data": {...}}}.
+	var out struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("vault: decode response: %w", err)
+	}
+
+	value, ok := out.Data.Data[name].(string)
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	return value, nil
+}
@@ -0,0 +1,58 @@
+// Package selftest runs a quick end-to-end check of the storage layer at
+// startup.
+//
+// Configuration mistakes — a wrong DB path, a read-only directory, a
+// schema that does not match the code — otherwise only surface on the
+// first real request. Running a full create → read → update → delete
+// round-trip at boot turns them into an immediate, clearly-logged exit.
+package selftest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// SentinelName is the name given to the temporary test student, chosen so
+// it cannot be confused with a real record if a run is interrupted.
+const SentinelName = "__startup_test__"
+
+// sentinelEmail keeps the test student clear of the unique email index.
+// The sentinel is created in types.DefaultTenant.
+const sentinelEmail = "__startup_test__@selftest.invalid"
+
+// Run creates a sentinel student, reads it back, updates it and deletes
+// it again. It returns the first step that failed, or nil if all passed.
+// Deletes are soft, so the sentinel's row stays behind, marked deleted
+// and invisible to every read; its email is free for the next run.
+func Run(ctx context.Context, store storage.Storage) error {
+	id, err := store.CreateStudent(ctx, types.DefaultTenant, SentinelName, sentinelEmail, 1, "", "")
+	if err != nil {
+		return fmt.Errorf("selftest: create: %w", err)
+	}
+
+	got, err := store.GetStudentByID(ctx, types.DefaultTenant, id)
+	if err != nil {
+		return fmt.Errorf("selftest: get: %w", err)
+	}
+	if got.Name != SentinelName {
+		return fmt.Errorf("selftest: get: expected name %q, got %q", SentinelName, got.Name)
+	}
+
+	got.Age = 2
+	updated, err := store.UpdateStudentByID(ctx, types.DefaultTenant, id, got)
+	if err != nil {
+		return fmt.Errorf("selftest: update: %w", err)
+	}
+	if updated.Age != 2 {
+		return fmt.Errorf("selftest: update: expected age 2, got %d", updated.Age)
+	}
+
+	if err := store.DeleteStudentByID(ctx, types.DefaultTenant, id); err != nil {
+		return fmt.Errorf("selftest: delete: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package selftest
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aanand-mishra/students-api/internal/config"
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/storage/sqlite"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+func newStore(t *testing.T) *sqlite.SQLite {
+	t.Helper()
+
+	db, err := sqlite.New(&config.Config{
+		StoragePath: filepath.Join(t.TempDir(), "test.db"),
+		Database:    config.Database{AutoMigrate: true},
+		SQLite:      config.SQLite{JournalMode: config.JournalModeWAL, BusyTimeoutMs: 5000},
+	})
+	if err != nil {
+		t.Fatalf("sqlite.New: %v", err)
+	}
+	t.Cleanup(func() { db.Db.Close() })
+	return db
+}
+
+func TestRun(t *testing.T) {
+	db := newStore(t)
+	ctx := context.Background()
+
+	// A second run must work too: the first one's sentinel is deleted,
+	// so its email is free again.
+	for run := 1; run <= 2; run++ {
+		if err := Run(ctx, db); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+	}
+
+	if n, err := db.CountStudents(ctx, types.DefaultTenant); err != nil || n != 0 {
+		t.Errorf("CountStudents = %d, %v after self-tests, want 0", n, err)
+	}
+}
+
+// failingStore fails the storage call named by step.
+type failingStore struct {
+	storage.Storage
+	step string
+}
+
+var errInjected = errors.New("injected failure")
+
+func (f failingStore) CreateStudent(ctx context.Context, tenantID, name, email string, age int, department, phone string) (int64, error) {
+	if f.step == "create" {
+		return 0, errInjected
+	}
+	return f.Storage.CreateStudent(ctx, tenantID, name, email, age, department, phone)
+}
+
+func (f failingStore) GetStudentByID(ctx context.Context, tenantID string, id int64) (types.Student, error) {
+	if f.step == "get" {
+		return types.Student{}, errInjected
+	}
+	return f.Storage.GetStudentByID(ctx, tenantID, id)
+}
+
+func (f failingStore) UpdateStudentByID(ctx context.Context, tenantID string, id int64, s types.Student) (types.Student, error) {
+	if f.step == "update" {
+		return types.Student{}, errInjected
+	}
+	return f.Storage.UpdateStudentByID(ctx, tenantID, id, s)
+}
+
+func (f failingStore) DeleteStudentByID(ctx context.Context, tenantID string, id int64) error {
+	if f.step == "delete" {
+		return errInjected
+	}
+	return f.Storage.DeleteStudentByID(ctx, tenantID, id)
+}
+
+func TestRunReportsFailedStep(t *testing.T) {
+	for _, step := range []string{"create", "get", "update", "delete"} {
+		t.Run(step, func(t *testing.T) {
+			err := Run(context.Background(), failingStore{Storage: newStore(t), step: step})
+			if !errors.Is(err, errInjected) {
+				t.Fatalf("Run error = %v, want the injected failure", err)
+			}
+			if want := "selftest: " + step + ":"; !strings.HasPrefix(err.Error(), want) {
+				t.Errorf("Run error = %q, want it to start with %q", err, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+// Package cache provides CachingStorage, a storage.Storage decorator that
+// keeps recently read students in memory.
+//
+// DECORATOR PATTERN:
+// ──────────────────
+// CachingStorage both IS a storage.Storage and WRAPS one. main.go hands
+// the wrapped value to the handlers, which can't tell the difference.
+// Methods it doesn't override are promoted from the embedded Storage
+// unchanged.
+//
+// Besides speeding up reads, the cache lets the API degrade gracefully:
+// with StaleReadOnFailure enabled, a read that hits a database error is
+// answered from the last cached copy (reported via storage.ErrStale)
+// instead of failing outright.
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/config"
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/types"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// entry is a cached value and when it was stored.
+type entry[T any] struct {
+	value    T
+	storedAt time.Time
+}
+
+// CachingStorage caches GetStudentByID and GetStudents results.
+//
+// IDs are unique across tenants, so students are cached by ID alone, but
+// a cached student is only served to its own tenant. Each tenant has its
+// own cached list.
+type CachingStorage struct {
+	storage.Storage
+
+	ttl                time.Duration
+	staleReadOnFailure bool
+
+	mu       sync.RWMutex
+	students map[int64]entry[types.Student]
+	lists    map[string]entry[[]types.Student]
+}
+
+// New wraps inner with a cache configured from cfg and registers the
+// cache as a hook on inner, so every successful write invalidates it.
+func New(inner storage.Storage, cfg config.Database) *CachingStorage {
+	c := &CachingStorage{
+		Storage:            inner,
+		ttl:                cfg.CacheTTL,
+		staleReadOnFailure: cfg.StaleReadOnFailure,
+		students:           make(map[int64]entry[types.Student]),
+		lists:              make(map[string]entry[[]types.Student]),
+	}
+	inner.RegisterHook(c)
+	return c
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Reads
+// ─────────────────────────────────────────────────────────────────────────────
+
+// GetStudentByID serves a fresh cached copy if there is one, otherwise
+// reads through to the database and caches the result.
+func (c *CachingStorage) GetStudentByID(ctx context.Context, tenantID string, id int64) (types.Student, error) {
+	c.mu.RLock()
+	cached, ok := c.students[id]
+	c.mu.RUnlock()
+	ok = ok && cached.value.TenantID == tenantID
+
+	if ok && c.fresh(cached.storedAt) {
+		return cached.value, nil
+	}
+
+	student, err := c.Storage.GetStudentByID(ctx, tenantID, id)
+	if err != nil {
+		if ok && c.canServeStale(err) {
+			return cached.value, fmt.Errorf("%w: %w", storage.ErrStale, err)
+		}
+		return types.Student{}, err
+	}
+
+	c.mu.Lock()
+	c.students[id] = entry[types.Student]{value: student, storedAt: time.Now()}
+	c.mu.Unlock()
+
+	return student, nil
+}
+
+// GetStudents works like GetStudentByID for the tenant's full list.
+func (c *CachingStorage) GetStudents(ctx context.Context, tenantID string) ([]types.Student, error) {
+	c.mu.RLock()
+	cached, ok := c.lists[tenantID]
+	c.mu.RUnlock()
+
+	if ok && c.fresh(cached.storedAt) {
+		return cached.value, nil
+	}
+
+	students, err := c.Storage.GetStudents(ctx, tenantID)
+	if err != nil {
+		if ok && c.canServeStale(err) {
+			return cached.value, fmt.Errorf("%w: %w", storage.ErrStale, err)
+		}
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.lists[tenantID] = entry[[]types.Student]{value: students, storedAt: time.Now()}
+	c.mu.Unlock()
+
+	return students, nil
+}
+
+// fresh reports whether a value stored at t is still within the TTL.
+func (c *CachingStorage) fresh(t time.Time) bool {
+	return time.Since(t) < c.ttl
+}
+
+// canServeStale reports whether a failed read may fall back to the cache.
+// "Not found" is a real answer from a working database, not a failure.
+func (c *CachingStorage) canServeStale(err error) bool {
+	return c.staleReadOnFailure && !errors.Is(err, storage.ErrNotFound)
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Writes — never served from cache. Failures to reach the database are
+// reported as storage.ErrUnavailable so the handlers can answer 503.
+// ─────────────────────────────────────────────────────────────────────────────
+
+func (c *CachingStorage) CreateStudent(ctx context.Context, tenantID string, name string, email string, age int, department string, phone string) (int64, error) {
+	id, err := c.Storage.CreateStudent(ctx, tenantID, name, email, age, department, phone)
+	return id, unavailable(err)
+}
+
+func (c *CachingStorage) UpdateStudentByID(ctx context.Context, tenantID string, id int64, student types.Student) (types.Student, error) {
+	updated, err := c.Storage.UpdateStudentByID(ctx, tenantID, id, student)
+	return updated, unavailable(err)
+}
+
+func (c *CachingStorage) PatchStudentByID(ctx context.Context, tenantID string, id int64, fields map[string]interface{}) (types.Student, error) {
+	updated, err := c.Storage.PatchStudentByID(ctx, tenantID, id, fields)
+	return updated, unavailable(err)
+}
+
+func (c *CachingStorage) DeleteStudentByID(ctx context.Context, tenantID string, id int64) error {
+	return unavailable(c.Storage.DeleteStudentByID(ctx, tenantID, id))
+}
+
+func (c *CachingStorage) AnonymizeStudentByID(ctx context.Context, tenantID string, id int64) error {
+	return unavailable(c.Storage.AnonymizeStudentByID(ctx, tenantID, id))
+}
+
+func (c *CachingStorage) RestoreStudentByID(ctx context.Context, tenantID string, id int64) error {
+	return unavailable(c.Storage.RestoreStudentByID(ctx, tenantID, id))
+}
+
+func (c *CachingStorage) SetStudentPhotoURL(ctx context.Context, tenantID string, id int64, url string) error {
+	return unavailable(c.Storage.SetStudentPhotoURL(ctx, tenantID, id, url))
+}
+
+func (c *CachingStorage) SetStudentStatus(ctx context.Context, tenantID string, id int64, status string) error {
+	return unavailable(c.Storage.SetStudentStatus(ctx, tenantID, id, status))
+}
+
+func (c *CachingStorage) UpsertStudents(ctx context.Context, tenantID string, students []types.Student) ([]types.UpsertResult, error) {
+	results, err := c.Storage.UpsertStudents(ctx, tenantID, students)
+	return results, unavailable(err)
+}
+
+func (c *CachingStorage) BulkCreateStudents(ctx context.Context, tenantID string, students []types.Student) ([]int64, error) {
+	ids, err := c.Storage.BulkCreateStudents(ctx, tenantID, students)
+	return ids, unavailable(err)
+}
+
+func (c *CachingStorage) BulkDeleteStudents(ctx context.Context, tenantID string, ids []int64) (int64, error) {
+	n, err := c.Storage.BulkDeleteStudents(ctx, tenantID, ids)
+	return n, unavailable(err)
+}
+
+func (c *CachingStorage) ImportStudents(ctx context.Context, tenantID string, students []types.Student) ([]types.UpsertResult, error) {
+	results, err := c.Storage.ImportStudents(ctx, tenantID, students)
+	return results, unavailable(err)
+}
+
+// unavailable wraps err with storage.ErrUnavailable when it says the
+// database couldn't be reached (see isConnectivityError). Every other
+// error — not found, duplicate email, an unknown field in a patch… — is
+// the database answering, and is returned unchanged.
+func unavailable(err error) error {
+	if err == nil || !isConnectivityError(err) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", storage.ErrUnavailable, err)
+}
+
+// isConnectivityError reports whether err is a failure to reach or use
+// the database itself rather than a problem with the request:
+//
+//   - a dropped or closed connection (database/sql, the network)
+//   - SQLite: the file is locked, can't be opened or can't be read
+//   - Postgres: a connection exception (SQLSTATE class 08), the server
+//     shutting down or starting up (57P01-57P03), or out of resources
+//     (class 53)
+func isConnectivityError(err error) bool {
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.As(err, &netErr) {
+		return true
+	}
+
+	// database/sql has no sentinel for a *sql.DB that has been closed.
+	if strings.Contains(err.Error(), "sql: database is closed") {
+		return true
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code {
+		case sqlite3.ErrBusy, sqlite3.ErrLocked, sqlite3.ErrCantOpen, sqlite3.ErrIoErr:
+			return true
+		}
+		return false
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		code := string(pqErr.Code)
+		return strings.HasPrefix(code, "08") || strings.HasPrefix(code, "53") ||
+			code == "57P01" || code == "57P02" || code == "57P03"
+	}
+
+	return false
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// storage.Hook — keeps the cache consistent with writes.
+// ─────────────────────────────────────────────────────────────────────────────
+
+// OnCreate drops the tenant's cached list, which no longer includes every
+// student.
+func (c *CachingStorage) OnCreate(ctx context.Context, student types.Student) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.lists, student.TenantID)
+}
+
+// OnUpdate caches the new version and drops the tenant's cached list.
+func (c *CachingStorage) OnUpdate(ctx context.Context, old, new types.Student) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.students[int64(new.ID)] = entry[types.Student]{value: new, storedAt: time.Now()}
+	delete(c.lists, new.TenantID)
+}
+
+// OnDelete forgets the student and drops the tenant's cached list.
+func (c *CachingStorage) OnDelete(ctx context.Context, tenantID string, studentID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.students, studentID)
+	delete(c.lists, tenantID)
+}
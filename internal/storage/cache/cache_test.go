@@ -0,0 +1,202 @@
+package cache
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/config"
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/storage/sqlite"
+	"github.com/aanand-mishra/students-api/internal/types"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// flakyStorage serves one student until down is set, then fails every
+// call with err.
+type flakyStorage struct {
+	storage.Storage
+	student types.Student
+	down    bool
+	err     error
+}
+
+func (f *flakyStorage) RegisterHook(storage.Hook) {}
+
+func (f *flakyStorage) GetStudentByID(_ context.Context, tenantID string, id int64) (types.Student, error) {
+	if f.down {
+		return types.Student{}, f.err
+	}
+	if int64(f.student.ID) != id || f.student.TenantID != tenantID {
+		return types.Student{}, storage.ErrNotFound
+	}
+	return f.student, nil
+}
+
+func (f *flakyStorage) GetStudents(context.Context, string) ([]types.Student, error) {
+	if f.down {
+		return nil, f.err
+	}
+	return []types.Student{f.student}, nil
+}
+
+func newFlaky(staleReadOnFailure bool) (*flakyStorage, *CachingStorage) {
+	inner := &flakyStorage{
+		student: types.Student{ID: 1, Name: "Rakesh", TenantID: types.DefaultTenant},
+		err:     fmt.Errorf("GetStudentByID: %w", driver.ErrBadConn),
+	}
+	// A zero TTL makes every cached copy stale at once, so each read
+	// goes through to inner.
+	return inner, New(inner, config.Database{StaleReadOnFailure: staleReadOnFailure})
+}
+
+func TestStaleReadOnFailure(t *testing.T) {
+	ctx := context.Background()
+	inner, c := newFlaky(true)
+
+	if _, err := c.GetStudentByID(ctx, types.DefaultTenant, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetStudents(ctx, types.DefaultTenant); err != nil {
+		t.Fatal(err)
+	}
+	inner.down = true
+
+	s, err := c.GetStudentByID(ctx, types.DefaultTenant, 1)
+	if !errors.Is(err, storage.ErrStale) || !errors.Is(err, driver.ErrBadConn) {
+		t.Errorf("err = %v, want ErrStale wrapping the database error", err)
+	}
+	if s.Name != "Rakesh" {
+		t.Errorf("stale student = %+v, want the cached copy", s)
+	}
+
+	list, err := c.GetStudents(ctx, types.DefaultTenant)
+	if !errors.Is(err, storage.ErrStale) || len(list) != 1 {
+		t.Errorf("list = %v, err = %v; want the cached list with ErrStale", list, err)
+	}
+
+	// Another tenant never sees the cached student, stale or not.
+	if _, err := c.GetStudentByID(ctx, "other-school", 1); errors.Is(err, storage.ErrStale) {
+		t.Errorf("other tenant got a stale copy: %v", err)
+	}
+	// Nothing cached: the failure goes through.
+	if _, err := c.GetStudentByID(ctx, types.DefaultTenant, 2); errors.Is(err, storage.ErrStale) || err == nil {
+		t.Errorf("uncached id: err = %v, want the database error", err)
+	}
+	// "Not found" is an answer, not a failure: never served stale.
+	inner.err = storage.ErrNotFound
+	if _, err := c.GetStudentByID(ctx, types.DefaultTenant, 1); !errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrStale) {
+		t.Errorf("not found: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStaleReadDisabled(t *testing.T) {
+	ctx := context.Background()
+	inner, c := newFlaky(false)
+
+	c.GetStudentByID(ctx, types.DefaultTenant, 1)
+	inner.down = true
+
+	if _, err := c.GetStudentByID(ctx, types.DefaultTenant, 1); errors.Is(err, storage.ErrStale) || err == nil {
+		t.Errorf("err = %v, want the database error", err)
+	}
+}
+
+func TestFreshCacheSkipsDatabase(t *testing.T) {
+	ctx := context.Background()
+	inner := &flakyStorage{student: types.Student{ID: 1, Name: "Rakesh", TenantID: types.DefaultTenant}, err: errors.New("down")}
+	c := New(inner, config.Database{CacheTTL: time.Minute})
+
+	c.GetStudentByID(ctx, types.DefaultTenant, 1)
+	inner.down = true
+	if _, err := c.GetStudentByID(ctx, types.DefaultTenant, 1); err != nil {
+		t.Errorf("fresh cached read went to the database: %v", err)
+	}
+	// An update caches the new version; a delete forgets the student.
+	renamed := inner.student
+	renamed.Name = "Rakesh K"
+	c.OnUpdate(ctx, inner.student, renamed)
+	if s, err := c.GetStudentByID(ctx, types.DefaultTenant, 1); err != nil || s.Name != "Rakesh K" {
+		t.Errorf("after OnUpdate: %+v, %v; want the new version from the cache", s, err)
+	}
+	c.OnDelete(ctx, types.DefaultTenant, 1)
+	if _, err := c.GetStudentByID(ctx, types.DefaultTenant, 1); err == nil {
+		t.Error("read after OnDelete was served from the cache")
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool // wrapped with ErrUnavailable
+	}{
+		{"nil", nil, false},
+		{"bad connection", fmt.Errorf("CreateStudent: %w", driver.ErrBadConn), true},
+		{"network error", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, true},
+		{"connection reset", fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{"connection cut mid-reply", io.ErrUnexpectedEOF, true},
+		{"closed database", errors.New("CreateStudent: sql: database is closed"), true},
+		{"sqlite busy", fmt.Errorf("exec: %w", sqlite3.Error{Code: sqlite3.ErrBusy}), true},
+		{"sqlite I/O error", sqlite3.Error{Code: sqlite3.ErrIoErr}, true},
+		{"postgres connection failure", &pq.Error{Code: "08006"}, true},
+		{"postgres shutting down", &pq.Error{Code: "57P01"}, true},
+		{"postgres out of connections", &pq.Error{Code: "53300"}, true},
+
+		{"not found", storage.ErrNotFound, false},
+		{"duplicate email", fmt.Errorf("x: %w", storage.ErrDuplicateEmail), false},
+		{"version conflict", storage.ErrVersionConflict, false},
+		{"capacity exceeded", storage.ErrCapacityExceeded, false},
+		{"unknown patch field", errors.New(`PatchStudentByID: field "password" cannot be patched`), false},
+		{"sqlite constraint", sqlite3.Error{Code: sqlite3.ErrConstraint}, false},
+		{"postgres unique violation", &pq.Error{Code: "23505"}, false},
+		{"postgres syntax error", &pq.Error{Code: "42601"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := unavailable(tt.err)
+			if wrapped := errors.Is(got, storage.ErrUnavailable); wrapped != tt.want {
+				t.Errorf("unavailable(%v) = %v; wrapped = %v, want %v", tt.err, got, wrapped, tt.want)
+			}
+			if tt.err != nil && !errors.Is(got, tt.err) {
+				t.Errorf("unavailable(%v) = %v, lost the original error", tt.err, got)
+			}
+		})
+	}
+}
+
+func TestPatchErrorsPassThrough(t *testing.T) {
+	db, err := sqlite.New(&config.Config{
+		StoragePath: filepath.Join(t.TempDir(), "test.db"),
+		Database:    config.Database{AutoMigrate: true},
+		SQLite:      config.SQLite{JournalMode: config.JournalModeWAL, BusyTimeoutMs: 5000},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New(db, config.Database{})
+	ctx := context.Background()
+
+	id, err := c.CreateStudent(ctx, types.DefaultTenant, "Rakesh", "rakesh@test.com", 35, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.PatchStudentByID(ctx, types.DefaultTenant, id, map[string]interface{}{"password": "x"}); err == nil || errors.Is(err, storage.ErrUnavailable) {
+		t.Errorf("unknown field: err = %v, want a plain error", err)
+	}
+
+	// Once the database is gone, writes are reported unavailable.
+	db.Db.Close()
+	if _, err := c.CreateStudent(ctx, types.DefaultTenant, "Priya", "priya@test.com", 22, "", ""); !errors.Is(err, storage.ErrUnavailable) {
+		t.Errorf("closed database: err = %v, want ErrUnavailable", err)
+	}
+}
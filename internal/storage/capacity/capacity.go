@@ -0,0 +1,117 @@
+// Package capacity provides CappedStorage, a storage.Storage decorator
+// that caps how many students each tenant may have at once — for demo
+// instances and trial tiers that should not grow past a fixed size.
+//
+//	handlers → CachingStorage → CappedStorage → EncryptingStorage → SQLite
+//
+// Before anything that adds students it counts the tenant's live ones
+// (SELECT COUNT(*) FROM students WHERE tenant_id = ? AND deleted_at IS
+// NULL, through CountStudents) and refuses with storage.ErrCapacityExceeded if the
+// new students would not fit. Deleted students don't count, so deleting
+// one makes room again; restoring one needs room like creating one.
+//
+// The count and the insert are two statements, so a mutex makes them one
+// step for this process. Several servers sharing a PostgreSQL database
+// can still race each other past the cap by a few students.
+package capacity
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// CappedStorage refuses to hold more than max live students per tenant.
+type CappedStorage struct {
+	storage.Storage
+
+	max int64
+
+	// mu is held from the count until the students are written, so two
+	// requests can't both see room for the last place.
+	mu sync.Mutex
+}
+
+// New wraps inner, allowing at most max students per tenant. max must be positive;
+// with no limit configured, don't wrap the storage at all.
+func New(inner storage.Storage, max int) *CappedStorage {
+	return &CappedStorage{Storage: inner, max: int64(max)}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Writes that add students
+// ─────────────────────────────────────────────────────────────────────────────
+
+func (c *CappedStorage) CreateStudent(ctx context.Context, tenantID string, name string, email string, age int, department string, phone string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.checkRoom(ctx, tenantID, 1); err != nil {
+		return 0, err
+	}
+	return c.Storage.CreateStudent(ctx, tenantID, name, email, age, department, phone)
+}
+
+// BulkCreateStudents is all or nothing as far as the cap goes: if the
+// whole batch doesn't fit, none of it is created.
+func (c *CappedStorage) BulkCreateStudents(ctx context.Context, tenantID string, students []types.Student) ([]int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.checkRoom(ctx, tenantID, len(students)); err != nil {
+		return nil, err
+	}
+	return c.Storage.BulkCreateStudents(ctx, tenantID, students)
+}
+
+// UpsertStudents can't know in advance which students are new and which
+// are updates, so it needs room for every one of them. Near the cap that
+// refuses some batches that would only have updated students.
+func (c *CappedStorage) UpsertStudents(ctx context.Context, tenantID string, students []types.Student) ([]types.UpsertResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.checkRoom(ctx, tenantID, len(students)); err != nil {
+		return nil, err
+	}
+	return c.Storage.UpsertStudents(ctx, tenantID, students)
+}
+
+// ImportStudents needs room for every row, for the same reason as
+// UpsertStudents.
+func (c *CappedStorage) ImportStudents(ctx context.Context, tenantID string, students []types.Student) ([]types.UpsertResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.checkRoom(ctx, tenantID, len(students)); err != nil {
+		return nil, err
+	}
+	return c.Storage.ImportStudents(ctx, tenantID, students)
+}
+
+// RestoreStudentByID brings a deleted student back into the count.
+func (c *CappedStorage) RestoreStudentByID(ctx context.Context, tenantID string, id int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.checkRoom(ctx, tenantID, 1); err != nil {
+		return err
+	}
+	return c.Storage.RestoreStudentByID(ctx, tenantID, id)
+}
+
+// checkRoom returns storage.ErrCapacityExceeded unless n more students
+// fit under the tenant's cap. The caller must hold c.mu.
+func (c *CappedStorage) checkRoom(ctx context.Context, tenantID string, n int) error {
+	count, err := c.Storage.CountStudents(ctx, tenantID)
+	if err != nil {
+		return fmt.Errorf("capacity: count students: %w", err)
+	}
+	if count+int64(n) > c.max {
+		return fmt.Errorf("%w: %d of %d in use", storage.ErrCapacityExceeded, count, c.max)
+	}
+	return nil
+}
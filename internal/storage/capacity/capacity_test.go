@@ -0,0 +1,138 @@
+package capacity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/aanand-mishra/students-api/internal/config"
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/storage/sqlite"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+const limit = 3
+
+// newStore returns a CappedStorage allowing limit students per tenant,
+// over a fresh SQLite database.
+func newStore(t *testing.T) *CappedStorage {
+	t.Helper()
+
+	db, err := sqlite.New(&config.Config{
+		StoragePath: filepath.Join(t.TempDir(), "test.db"),
+		Database:    config.Database{AutoMigrate: true},
+		SQLite:      config.SQLite{JournalMode: config.JournalModeWAL, BusyTimeoutMs: 5000},
+	})
+	if err != nil {
+		t.Fatalf("sqlite.New: %v", err)
+	}
+	t.Cleanup(func() { db.Db.Close() })
+
+	return New(db, limit)
+}
+
+// create adds student number i to tenant.
+func create(store *CappedStorage, tenant string, i int) (int64, error) {
+	return store.CreateStudent(context.Background(), tenant, fmt.Sprintf("Student %d", i),
+		fmt.Sprintf("s%d@%s.test", i, tenant), 20, "", "")
+}
+
+func TestCreateStudentAtTheCap(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing int
+		wantErr  bool
+	}{
+		{"N-1 existing: the Nth fits", limit - 1, false},
+		{"N existing: the N+1th is refused", limit, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newStore(t)
+			for i := 0; i < tt.existing; i++ {
+				if _, err := create(store, types.DefaultTenant, i); err != nil {
+					t.Fatalf("creating student %d of %d: %v", i+1, tt.existing, err)
+				}
+			}
+
+			_, err := create(store, types.DefaultTenant, tt.existing)
+			if got := errors.Is(err, storage.ErrCapacityExceeded); got != tt.wantErr {
+				t.Fatalf("creating student %d = %v, want ErrCapacityExceeded %v", tt.existing+1, err, tt.wantErr)
+			}
+
+			// There are never N+1.
+			count, err := store.CountStudents(context.Background(), types.DefaultTenant)
+			if err != nil {
+				t.Fatalf("CountStudents: %v", err)
+			}
+			if count > limit {
+				t.Errorf("count = %d, over the cap of %d", count, limit)
+			}
+		})
+	}
+}
+
+func TestDeleteMakesRoom(t *testing.T) {
+	store := newStore(t)
+	ctx := context.Background()
+
+	var ids []int64
+	for i := 0; i < limit; i++ {
+		id, err := create(store, types.DefaultTenant, i)
+		if err != nil {
+			t.Fatalf("CreateStudent: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := store.DeleteStudentByID(ctx, types.DefaultTenant, ids[0]); err != nil {
+		t.Fatalf("DeleteStudentByID: %v", err)
+	}
+	if _, err := create(store, types.DefaultTenant, limit); err != nil {
+		t.Fatalf("creating after a delete: %v", err)
+	}
+
+	// Full again, so the deleted student can't come back.
+	if err := store.RestoreStudentByID(ctx, types.DefaultTenant, ids[0]); !errors.Is(err, storage.ErrCapacityExceeded) {
+		t.Errorf("RestoreStudentByID at the cap = %v, want ErrCapacityExceeded", err)
+	}
+}
+
+func TestBulkCreateAllOrNothing(t *testing.T) {
+	store := newStore(t)
+	ctx := context.Background()
+
+	if _, err := create(store, types.DefaultTenant, 0); err != nil {
+		t.Fatalf("CreateStudent: %v", err)
+	}
+
+	batch := make([]types.Student, limit)
+	for i := range batch {
+		batch[i] = types.Student{Name: fmt.Sprintf("Batch %d", i), Email: fmt.Sprintf("b%d@test.com", i), Age: 20}
+	}
+	if _, err := store.BulkCreateStudents(ctx, types.DefaultTenant, batch); !errors.Is(err, storage.ErrCapacityExceeded) {
+		t.Fatalf("BulkCreateStudents past the cap = %v, want ErrCapacityExceeded", err)
+	}
+	if count, _ := store.CountStudents(ctx, types.DefaultTenant); count != 1 {
+		t.Errorf("count = %d after a refused batch, want 1: none of it may be created", count)
+	}
+
+	if _, err := store.BulkCreateStudents(ctx, types.DefaultTenant, batch[:limit-1]); err != nil {
+		t.Errorf("BulkCreateStudents filling the cap: %v", err)
+	}
+}
+
+func TestCapIsPerTenant(t *testing.T) {
+	store := newStore(t)
+
+	for i := 0; i < limit; i++ {
+		if _, err := create(store, "school-a", i); err != nil {
+			t.Fatalf("CreateStudent: %v", err)
+		}
+	}
+	if _, err := create(store, "school-b", 0); err != nil {
+		t.Errorf("another tenant's full cap refused a create: %v", err)
+	}
+}
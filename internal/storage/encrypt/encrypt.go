@@ -0,0 +1,465 @@
+// Package encrypt provides EncryptingStorage, a storage.Storage decorator
+// that keeps student names and emails encrypted in the database.
+//
+// Values are encrypted on the way in and decrypted on the way out, so
+// both the handlers above and the database below are unaware of it:
+//
+//	handlers → CachingStorage → EncryptingStorage → SQLite
+//	           (plaintext)                          (ciphertext)
+//
+// Names use randomised encryption. Emails use deterministic encryption
+// (see crypto.EncryptDeterministic) so the unique email index, upserts by
+// email and exact lookups still work in SQL. Anything fuzzier, such as
+// substring search or sorting by name, decrypts in the application
+// instead.
+package encrypt
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/aanand-mishra/students-api/internal/crypto"
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/types"
+	"github.com/aanand-mishra/students-api/internal/utils"
+)
+
+// EncryptingStorage encrypts Name and Email of every student it stores.
+type EncryptingStorage struct {
+	storage.Storage
+
+	key []byte
+}
+
+// New wraps inner, encrypting with key (crypto.KeySize bytes).
+func New(inner storage.Storage, key []byte) *EncryptingStorage {
+	return &EncryptingStorage{Storage: inner, key: key}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Writes
+// ─────────────────────────────────────────────────────────────────────────────
+
+func (e *EncryptingStorage) CreateStudent(ctx context.Context, tenantID string, name string, email string, age int, department string, phone string) (int64, error) {
+	encName, encEmail, err := e.encryptFields(name, email)
+	if err != nil {
+		return 0, err
+	}
+	return e.Storage.CreateStudent(ctx, tenantID, encName, encEmail, age, department, phone)
+}
+
+func (e *EncryptingStorage) UpdateStudentByID(ctx context.Context, tenantID string, id int64, student types.Student) (types.Student, error) {
+	var err error
+	student.Name, student.Email, err = e.encryptFields(student.Name, student.Email)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	updated, err := e.Storage.UpdateStudentByID(ctx, tenantID, id, student)
+	if err != nil {
+		return types.Student{}, err
+	}
+	return e.decryptStudent(updated), nil
+}
+
+// PatchStudentByID encrypts name and email if the patch sets them; the
+// other fields are stored as is.
+func (e *EncryptingStorage) PatchStudentByID(ctx context.Context, tenantID string, id int64, fields map[string]interface{}) (types.Student, error) {
+	encrypted := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		encrypted[key] = value
+	}
+
+	if name, ok := fields["name"].(string); ok {
+		encName, err := crypto.Encrypt(name, e.key)
+		if err != nil {
+			return types.Student{}, err
+		}
+		encrypted["name"] = encName
+	}
+	if email, ok := fields["email"].(string); ok {
+		encEmail, err := crypto.EncryptDeterministic(utils.NormalizeEmail(email), e.key)
+		if err != nil {
+			return types.Student{}, err
+		}
+		encrypted["email"] = encEmail
+	}
+
+	updated, err := e.Storage.PatchStudentByID(ctx, tenantID, id, encrypted)
+	if err != nil {
+		return types.Student{}, err
+	}
+	return e.decryptStudent(updated), nil
+}
+
+func (e *EncryptingStorage) UpsertStudents(ctx context.Context, tenantID string, students []types.Student) ([]types.UpsertResult, error) {
+	encrypted, err := e.encryptStudents(students)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := e.Storage.UpsertStudents(ctx, tenantID, encrypted)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Email = e.decryptEmail(results[i].Email)
+	}
+	return results, nil
+}
+
+func (e *EncryptingStorage) BulkCreateStudents(ctx context.Context, tenantID string, students []types.Student) ([]int64, error) {
+	encrypted, err := e.encryptStudents(students)
+	if err != nil {
+		return nil, err
+	}
+	return e.Storage.BulkCreateStudents(ctx, tenantID, encrypted)
+}
+
+func (e *EncryptingStorage) ImportStudents(ctx context.Context, tenantID string, students []types.Student) ([]types.UpsertResult, error) {
+	encrypted, err := e.encryptStudents(students)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := e.Storage.ImportStudents(ctx, tenantID, encrypted)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Email = e.decryptEmail(results[i].Email)
+	}
+	return results, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Reads
+// ─────────────────────────────────────────────────────────────────────────────
+
+func (e *EncryptingStorage) GetStudentByID(ctx context.Context, tenantID string, id int64) (types.Student, error) {
+	student, err := e.Storage.GetStudentByID(ctx, tenantID, id)
+	if err != nil {
+		return types.Student{}, err
+	}
+	return e.decryptStudent(student), nil
+}
+
+// GetStudentByExternalID looks up by external ID, which is stored as is.
+func (e *EncryptingStorage) GetStudentByExternalID(ctx context.Context, tenantID string, externalID string) (types.Student, error) {
+	student, err := e.Storage.GetStudentByExternalID(ctx, tenantID, externalID)
+	if err != nil {
+		return types.Student{}, err
+	}
+	return e.decryptStudent(student), nil
+}
+
+// GetStudentByEmail looks up by the email's deterministic ciphertext,
+// which is what the database holds.
+func (e *EncryptingStorage) GetStudentByEmail(ctx context.Context, tenantID string, email string) (types.Student, error) {
+	encEmail, err := crypto.EncryptDeterministic(utils.NormalizeEmail(email), e.key)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	student, err := e.Storage.GetStudentByEmail(ctx, tenantID, encEmail)
+	if err != nil {
+		return types.Student{}, err
+	}
+	return e.decryptStudent(student), nil
+}
+
+func (e *EncryptingStorage) GetStudents(ctx context.Context, tenantID string) ([]types.Student, error) {
+	students, err := e.Storage.GetStudents(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range students {
+		students[i] = e.decryptStudent(students[i])
+	}
+	return students, nil
+}
+
+// GetStudentsFiltered leaves age filters and sorts by other columns to
+// the database. Names and emails are ciphertext there: a substring of the
+// plaintext isn't a substring of the ciphertext, and sorting would order
+// the ciphertexts. A query that filters or sorts by them is answered in
+// Go instead, from every student of the tenant (see filterInGo).
+func (e *EncryptingStorage) GetStudentsFiltered(ctx context.Context, tenantID string, opts types.FilterOptions) ([]types.Student, int64, error) {
+	if needsPlaintext(opts) {
+		return e.filterInGo(ctx, tenantID, opts)
+	}
+
+	students, total, err := e.Storage.GetStudentsFiltered(ctx, tenantID, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range students {
+		students[i] = e.decryptStudent(students[i])
+	}
+	return students, total, nil
+}
+
+// SearchStudents matches parts of names and emails, which the database
+// can't see, so it decrypts every student of the tenant and matches them
+// here: case-insensitively, newest first, like the SQL search.
+func (e *EncryptingStorage) SearchStudents(ctx context.Context, tenantID string, query string) ([]types.Student, error) {
+	students, err := e.GetStudents(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToLower(query)
+	matches := make([]types.Student, 0)
+	for _, student := range students {
+		if containsFold(student.Name, query) || containsFold(student.Email, query) {
+			matches = append(matches, student)
+		}
+	}
+	slices.SortFunc(matches, func(a, b types.Student) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+	return matches, nil
+}
+
+func (e *EncryptingStorage) GetRandomStudent(ctx context.Context, tenantID string) (types.Student, error) {
+	student, err := e.Storage.GetRandomStudent(ctx, tenantID)
+	if err != nil {
+		return types.Student{}, err
+	}
+	return e.decryptStudent(student), nil
+}
+
+// GetAuditLog decrypts the before and after snapshots. The audit hook is
+// registered below this decorator, so they were recorded encrypted.
+func (e *EncryptingStorage) GetAuditLog(ctx context.Context, tenantID string, studentID int64) ([]types.AuditEntry, error) {
+	entries, err := e.Storage.GetAuditLog(ctx, tenantID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].Before = e.decryptSnapshot(entries[i].Before)
+		entries[i].After = e.decryptSnapshot(entries[i].After)
+	}
+	return entries, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Hooks — observers registered through the decorator see plaintext.
+// ─────────────────────────────────────────────────────────────────────────────
+
+// RegisterHook registers hook on the inner storage, wrapped so that the
+// students it receives are decrypted first.
+func (e *EncryptingStorage) RegisterHook(hook storage.Hook) {
+	e.Storage.RegisterHook(decryptingHook{hook: hook, e: e})
+}
+
+type decryptingHook struct {
+	hook storage.Hook
+	e    *EncryptingStorage
+}
+
+func (d decryptingHook) OnCreate(ctx context.Context, student types.Student) {
+	d.hook.OnCreate(ctx, d.e.decryptStudent(student))
+}
+
+func (d decryptingHook) OnUpdate(ctx context.Context, old, new types.Student) {
+	d.hook.OnUpdate(ctx, d.e.decryptStudent(old), d.e.decryptStudent(new))
+}
+
+func (d decryptingHook) OnDelete(ctx context.Context, tenantID string, studentID int64) {
+	d.hook.OnDelete(ctx, tenantID, studentID)
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Helpers
+// ─────────────────────────────────────────────────────────────────────────────
+
+// encryptFields encrypts a name and an email. The email is normalised
+// first: deterministic encryption only matches identical input.
+func (e *EncryptingStorage) encryptFields(name, email string) (string, string, error) {
+	encName, err := crypto.Encrypt(name, e.key)
+	if err != nil {
+		return "", "", err
+	}
+	encEmail, err := crypto.EncryptDeterministic(utils.NormalizeEmail(email), e.key)
+	if err != nil {
+		return "", "", err
+	}
+	return encName, encEmail, nil
+}
+
+// encryptStudents returns a copy of students with encrypted fields; the
+// caller's slice is left as it was.
+func (e *EncryptingStorage) encryptStudents(students []types.Student) ([]types.Student, error) {
+	encrypted := make([]types.Student, len(students))
+	for i, student := range students {
+		var err error
+		student.Name, student.Email, err = e.encryptFields(student.Name, student.Email)
+		if err != nil {
+			return nil, err
+		}
+		encrypted[i] = student
+	}
+	return encrypted, nil
+}
+
+// decryptStudent decrypts Name and Email.
+//
+// Values that don't decrypt are returned unchanged: rows written before
+// encryption was switched on are still plaintext, and keep working until
+// they are next updated.
+func (e *EncryptingStorage) decryptStudent(student types.Student) types.Student {
+	if name, err := crypto.Decrypt(student.Name, e.key); err == nil {
+		student.Name = name
+	}
+	student.Email = e.decryptEmail(student.Email)
+	return student
+}
+
+// decryptSnapshot decrypts a student stored as JSON in the audit log.
+// Anything that isn't a student is returned unchanged.
+func (e *EncryptingStorage) decryptSnapshot(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return raw
+	}
+	var student types.Student
+	if err := json.Unmarshal(raw, &student); err != nil {
+		return raw
+	}
+	plain, err := json.Marshal(e.decryptStudent(student))
+	if err != nil {
+		return raw
+	}
+	return plain
+}
+
+func (e *EncryptingStorage) decryptEmail(email string) string {
+	plain, err := crypto.DecryptDeterministic(email, e.key)
+	if err != nil {
+		slog.Debug("email is not encrypted; returning as stored")
+		return email
+	}
+	return plain
+}
+
+// needsPlaintext reports whether opts filters or sorts by a field the
+// database only holds encrypted.
+func needsPlaintext(opts types.FilterOptions) bool {
+	if opts.Name != "" || opts.Email != "" {
+		return true
+	}
+	for _, field := range opts.Sort.Fields {
+		if field.Key == "name" || field.Key == "email" {
+			return true
+		}
+	}
+	return false
+}
+
+// filterInGo does what the database does for GetStudentsFiltered, on
+// decrypted students: filter, count, sort (or apply the cursor), then
+// page. It reads the whole tenant for every request, which is the price
+// of searching encrypted data.
+func (e *EncryptingStorage) filterInGo(ctx context.Context, tenantID string, opts types.FilterOptions) ([]types.Student, int64, error) {
+	students, err := e.GetStudents(ctx, tenantID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	name, email := strings.ToLower(opts.Name), strings.ToLower(opts.Email)
+	matches := make([]types.Student, 0, len(students))
+	for _, student := range students {
+		switch {
+		case name != "" && !containsFold(student.Name, name),
+			email != "" && !containsFold(student.Email, email),
+			opts.AgeMin != nil && student.Age < *opts.AgeMin,
+			opts.AgeMax != nil && student.Age > *opts.AgeMax:
+			continue
+		}
+		matches = append(matches, student)
+	}
+	total := int64(len(matches))
+
+	// A cursor narrows the page after the total has been counted, and
+	// orders by id; paging backwards takes the rows nearest the cursor.
+	byID := func(a, b types.Student) int { return cmp.Compare(a.ID, b.ID) }
+	switch {
+	case opts.AfterID > 0:
+		matches = slices.DeleteFunc(matches, func(s types.Student) bool { return int64(s.ID) <= opts.AfterID })
+		slices.SortFunc(matches, byID)
+	case opts.BeforeID > 0:
+		matches = slices.DeleteFunc(matches, func(s types.Student) bool { return int64(s.ID) >= opts.BeforeID })
+		slices.SortFunc(matches, func(a, b types.Student) int { return byID(b, a) })
+	default:
+		compare, err := sortFunc(opts.Sort)
+		if err != nil {
+			return nil, 0, err
+		}
+		slices.SortFunc(matches, compare)
+	}
+
+	if opts.Limit > 0 {
+		from := min(opts.Offset, len(matches))
+		matches = matches[from:min(from+opts.Limit, len(matches))]
+	}
+	if opts.AfterID == 0 && opts.BeforeID > 0 {
+		slices.Reverse(matches)
+	}
+
+	return matches, total, nil
+}
+
+// sortFunc compares students in the order sort asks for, like the SQL
+// ORDER BY: newest first when sort is empty, and id breaking any ties.
+func sortFunc(sort types.SortOptions) (func(a, b types.Student) int, error) {
+	fields := sort.Fields
+	if len(fields) == 0 {
+		fields = []types.SortField{{Key: "created_at", Desc: true}, {Key: "id", Desc: true}}
+	}
+	for _, field := range fields {
+		if !slices.Contains(types.SortKeys, field.Key) {
+			return nil, fmt.Errorf("%w: can't sort by %q", storage.ErrUnsupportedFilter, field.Key)
+		}
+	}
+
+	return func(a, b types.Student) int {
+		for _, field := range fields {
+			var c int
+			switch field.Key {
+			case "id":
+				c = cmp.Compare(a.ID, b.ID)
+			case "name":
+				c = strings.Compare(a.Name, b.Name)
+			case "email":
+				c = strings.Compare(a.Email, b.Email)
+			case "age":
+				c = cmp.Compare(a.Age, b.Age)
+			case "created_at":
+				c = a.CreatedAt.Compare(b.CreatedAt)
+			case "updated_at":
+				c = a.UpdatedAt.Compare(b.UpdatedAt)
+			}
+			if field.Desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(a.ID, b.ID)
+	}, nil
+}
+
+// containsFold reports whether s contains lowerSubstr (already lower
+// case), ignoring case — what SQL's LIKE '%...%' matches.
+func containsFold(s, lowerSubstr string) bool {
+	return strings.Contains(strings.ToLower(s), lowerSubstr)
+}
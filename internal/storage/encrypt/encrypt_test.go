@@ -0,0 +1,208 @@
+package encrypt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aanand-mishra/students-api/internal/config"
+	"github.com/aanand-mishra/students-api/internal/crypto"
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/storage/sqlite"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// newStore returns an EncryptingStorage over a fresh SQLite database,
+// holding the students below (ids 1 to 4, in this order), and the
+// database underneath for checking what is stored.
+func newStore(t *testing.T) (*EncryptingStorage, *sqlite.SQLite) {
+	t.Helper()
+	return newStoreAt(t, filepath.Join(t.TempDir(), "test.db"))
+}
+
+// newStoreAt is newStore with the database file at path.
+func newStoreAt(t *testing.T, path string) (*EncryptingStorage, *sqlite.SQLite) {
+	t.Helper()
+
+	db, err := sqlite.New(&config.Config{
+		StoragePath: path,
+		Database:    config.Database{AutoMigrate: true},
+		SQLite:      config.SQLite{JournalMode: config.JournalModeWAL, BusyTimeoutMs: 5000},
+	})
+	if err != nil {
+		t.Fatalf("sqlite.New: %v", err)
+	}
+	t.Cleanup(func() { db.Db.Close() })
+
+	store := New(db, bytes.Repeat([]byte{7}, crypto.KeySize))
+	for _, s := range []struct {
+		name, email string
+		age         int
+	}{
+		{"Rakesh", "rakesh@test.com", 35},
+		{"Priya", "priya@example.com", 22},
+		{"Amit", "amit@test.com", 28},
+		{"priyanka", "pk@test.com", 40},
+	} {
+		if _, err := store.CreateStudent(context.Background(), types.DefaultTenant, s.name, s.email, s.age, "", ""); err != nil {
+			t.Fatalf("CreateStudent(%s): %v", s.name, err)
+		}
+	}
+	return store, db
+}
+
+func ids(students []types.Student) []int {
+	ids := make([]int, 0, len(students))
+	for _, s := range students {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+func TestStoredEncrypted(t *testing.T) {
+	store, db := newStore(t)
+
+	var name, email string
+	if err := db.Db.QueryRow(`SELECT name, email FROM students WHERE id = 1`).Scan(&name, &email); err != nil {
+		t.Fatalf("reading the row: %v", err)
+	}
+	if name == "Rakesh" || email == "rakesh@test.com" {
+		t.Errorf("stored %q, %q in plaintext", name, email)
+	}
+
+	student, err := store.GetStudentByID(context.Background(), types.DefaultTenant, 1)
+	if err != nil {
+		t.Fatalf("GetStudentByID: %v", err)
+	}
+	if student.Name != "Rakesh" || student.Email != "rakesh@test.com" {
+		t.Errorf("got %q, %q; want them decrypted", student.Name, student.Email)
+	}
+}
+
+// TestFileHasNoPlaintext reads the database files themselves, as a
+// thief with a copy of them would, after an update has also written the
+// student's history.
+func TestFileHasNoPlaintext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	store, db := newStoreAt(t, path)
+	ctx := context.Background()
+
+	student, err := store.GetStudentByID(ctx, types.DefaultTenant, 1)
+	if err != nil {
+		t.Fatalf("GetStudentByID: %v", err)
+	}
+	student.Age++
+	if _, err := store.UpdateStudentByID(ctx, types.DefaultTenant, 1, student); err != nil {
+		t.Fatalf("UpdateStudentByID: %v", err)
+	}
+
+	// Closing checkpoints the WAL into the main file; read both anyway,
+	// in case some of it is left behind.
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	for _, file := range []string{path, path + "-wal"} {
+		raw, err := os.ReadFile(file)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		for _, plaintext := range []string{"rakesh@test.com", "priya@example.com"} {
+			if bytes.Contains(raw, []byte(plaintext)) {
+				t.Errorf("%s contains %q in plaintext", filepath.Base(file), plaintext)
+			}
+		}
+	}
+}
+
+func TestGetStudentsFiltered(t *testing.T) {
+	store, _ := newStore(t)
+	age := func(n int) *int { return &n }
+	sortBy := func(fields ...types.SortField) types.SortOptions { return types.SortOptions{Fields: fields} }
+
+	tests := []struct {
+		name      string
+		opts      types.FilterOptions
+		wantIDs   []int
+		wantTotal int64
+	}{
+		{"name substring ignores case", types.FilterOptions{Name: "PRIY"}, []int{4, 2}, 2},
+		{"email substring", types.FilterOptions{Email: "@test."}, []int{4, 3, 1}, 3},
+		{"name and age", types.FilterOptions{Name: "priy", AgeMax: age(30)}, []int{2}, 1},
+		{"no match", types.FilterOptions{Name: "zed"}, []int{}, 0},
+		{"sort by name", types.FilterOptions{Sort: sortBy(types.SortField{Key: "name"})}, []int{3, 2, 1, 4}, 4},
+		{"sort by email descending", types.FilterOptions{Sort: sortBy(types.SortField{Key: "email", Desc: true})}, []int{1, 2, 4, 3}, 4},
+		{"filter, sort and page", types.FilterOptions{Email: "test", Sort: sortBy(types.SortField{Key: "name"}), Offset: 1, Limit: 1}, []int{1}, 3},
+		{"offset past the end", types.FilterOptions{Name: "a", Offset: 10, Limit: 2}, []int{}, 4},
+		{"after cursor", types.FilterOptions{Email: "test", AfterID: 1, Limit: 1}, []int{3}, 3},
+		{"before cursor", types.FilterOptions{Email: "test", BeforeID: 4, Limit: 2}, []int{1, 3}, 3},
+		{"age only goes to the database", types.FilterOptions{AgeMin: age(30)}, []int{4, 1}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			students, total, err := store.GetStudentsFiltered(context.Background(), types.DefaultTenant, tt.opts)
+			if err != nil {
+				t.Fatalf("GetStudentsFiltered: %v", err)
+			}
+			if got := ids(students); !reflect.DeepEqual(got, tt.wantIDs) {
+				t.Errorf("ids = %v, want %v", got, tt.wantIDs)
+			}
+			if total != tt.wantTotal {
+				t.Errorf("total = %d, want %d", total, tt.wantTotal)
+			}
+			for _, s := range students {
+				if !strings.Contains(s.Email, "@") {
+					t.Errorf("student %d has email %q, want it decrypted", s.ID, s.Email)
+				}
+			}
+		})
+	}
+}
+
+func TestGetStudentsFilteredUnknownSort(t *testing.T) {
+	store, _ := newStore(t)
+
+	_, _, err := store.GetStudentsFiltered(context.Background(), types.DefaultTenant, types.FilterOptions{
+		Name: "a",
+		Sort: types.SortOptions{Fields: []types.SortField{{Key: "shoe_size"}}},
+	})
+	if !errors.Is(err, storage.ErrUnsupportedFilter) {
+		t.Errorf("err = %v, want ErrUnsupportedFilter", err)
+	}
+}
+
+func TestSearchStudents(t *testing.T) {
+	store, _ := newStore(t)
+	if err := store.DeleteStudentByID(context.Background(), types.DefaultTenant, 3); err != nil {
+		t.Fatalf("DeleteStudentByID: %v", err)
+	}
+
+	tests := []struct {
+		query string
+		want  []int // newest first
+	}{
+		{"priy", []int{4, 2}},
+		{"EXAMPLE", []int{2}},
+		{"test.com", []int{4, 1}}, // Amit is deleted
+		{"nobody", []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			students, err := store.SearchStudents(context.Background(), types.DefaultTenant, tt.query)
+			if err != nil {
+				t.Fatalf("SearchStudents: %v", err)
+			}
+			if got := ids(students); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// Hook observes successful mutations made through a Storage.
+//
+// Anything that needs to react to changes (caches, audit trails, event
+// streams…) implements Hook and registers itself with RegisterHook,
+// instead of wrapping every Storage method to intercept writes.
+//
+// Hooks are called synchronously, in registration order, AFTER the change
+// has been committed. They cannot veto or fail the operation: a hook that
+// panics is recovered and logged, and the remaining hooks still run.
+// Keep them fast — slow work should be handed off to a goroutine.
+type Hook interface {
+	// OnCreate is called after a student has been inserted.
+	OnCreate(ctx context.Context, student types.Student)
+
+	// OnUpdate is called after a student has been changed, with the
+	// record as it was before and after the change.
+	OnUpdate(ctx context.Context, old, new types.Student)
+
+	// OnDelete is called after a student of the given tenant has been
+	// removed. (The other two get the tenant as student.TenantID.)
+	OnDelete(ctx context.Context, tenantID string, studentID int64)
+}
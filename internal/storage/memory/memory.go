@@ -0,0 +1,774 @@
+// Package memory provides MemoryStorage, a storage.Storage that keeps
+// every student in a map instead of a database.
+//
+// It behaves like the SQL backends as far as callers can tell — tenants,
+// soft deletes, unique emails and external IDs, versions, newest-first
+// ordering, hooks — but nothing survives the process, and there is no
+// connection that could fail. That makes it handy in tests of code that
+// only needs some storage.Storage, without a database file per test.
+//
+// The audit log is not kept: it is written by the audit hook straight to
+// the SQL backends, so GetAuditLog always returns an empty list.
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/types"
+	"github.com/aanand-mishra/students-api/internal/utils"
+)
+
+// record is a stored student, deleted or not.
+type record struct {
+	student types.Student
+	deleted bool
+}
+
+// MemoryStorage is an in-memory storage.Storage. The zero value is not
+// usable; create one with New. It is safe for concurrent use.
+type MemoryStorage struct {
+	mu       sync.RWMutex
+	students map[int64]record // every tenant's, keyed by id
+	lastID   int64            // ids are never reused, like AUTOINCREMENT
+
+	// hooks are notified after each successful mutation.
+	hooksMu sync.RWMutex
+	hooks   []storage.Hook
+}
+
+// New returns an empty MemoryStorage.
+func New() *MemoryStorage {
+	return &MemoryStorage{students: make(map[int64]record)}
+}
+
+// now is the time stamped on created and updated students. Like SQLite's
+// datetime('now'), it is UTC with whole seconds.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Lookups. The callers hold s.mu.
+// ─────────────────────────────────────────────────────────────────────────────
+
+// live returns the tenant's students that aren't deleted, newest first.
+func (s *MemoryStorage) live(tenantID string) []types.Student {
+	students := make([]types.Student, 0)
+	for _, rec := range s.students {
+		if !rec.deleted && rec.student.TenantID == tenantID {
+			students = append(students, clone(rec.student))
+		}
+	}
+	slices.SortFunc(students, newestFirst)
+	return students
+}
+
+// find returns the tenant's live student with the given id.
+func (s *MemoryStorage) find(tenantID string, id int64) (types.Student, bool) {
+	rec, ok := s.students[id]
+	if !ok || rec.deleted || rec.student.TenantID != tenantID {
+		return types.Student{}, false
+	}
+	return clone(rec.student), true
+}
+
+// findBy returns the tenant's first live student match accepts.
+func (s *MemoryStorage) findBy(tenantID string, match func(types.Student) bool) (types.Student, bool) {
+	for _, student := range s.live(tenantID) {
+		if match(student) {
+			return student, true
+		}
+	}
+	return types.Student{}, false
+}
+
+// conflicts reports whether a live student of the tenant other than id
+// already has student's email or external ID — what the unique indexes
+// of the SQL backends refuse.
+func (s *MemoryStorage) conflicts(tenantID string, id int64, student types.Student) bool {
+	email := utils.NormalizeEmail(student.Email)
+	_, taken := s.findBy(tenantID, func(other types.Student) bool {
+		if int64(other.ID) == id {
+			return false
+		}
+		if other.Email == email {
+			return true
+		}
+		return student.ExternalID != nil && other.ExternalID != nil && *other.ExternalID == *student.ExternalID
+	})
+	return taken
+}
+
+// insert stores a new live student and returns it as stored.
+func (s *MemoryStorage) insert(tenantID string, student types.Student) types.Student {
+	s.lastID++
+	created := now()
+	student = types.Student{
+		ID: int(s.lastID), Name: student.Name, Email: utils.NormalizeEmail(student.Email), Age: student.Age,
+		Department: student.Department, Phone: student.Phone, ExternalID: student.ExternalID,
+		CreatedAt: created, UpdatedAt: created, Version: 1,
+		Status: types.StudentStatusActive, TenantID: tenantID,
+	}
+	s.students[s.lastID] = record{student: clone(student)}
+	return student
+}
+
+// save stores a changed student, bumping its version and updated_at, and
+// returns it as stored.
+func (s *MemoryStorage) save(student types.Student) types.Student {
+	student.Version++
+	student.UpdatedAt = now()
+	s.students[int64(student.ID)] = record{student: clone(student)}
+	return student
+}
+
+// clone copies student, including the string ExternalID points to, so
+// callers can't change a stored student through the pointer.
+func clone(student types.Student) types.Student {
+	if student.ExternalID != nil {
+		id := *student.ExternalID
+		student.ExternalID = &id
+	}
+	return student
+}
+
+// newestFirst orders students by created_at, then id, both descending:
+// the default order of the SQL backends.
+func newestFirst(a, b types.Student) int {
+	if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+		return c
+	}
+	return cmp.Compare(b.ID, a.ID)
+}
+
+func notFound(id int64) error {
+	return fmt.Errorf("%w with id: %d", storage.ErrNotFound, id)
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Reads
+// ─────────────────────────────────────────────────────────────────────────────
+
+func (s *MemoryStorage) GetStudentByID(ctx context.Context, tenantID string, id int64) (types.Student, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	student, ok := s.find(tenantID, id)
+	if !ok {
+		return types.Student{}, notFound(id)
+	}
+	return student, nil
+}
+
+func (s *MemoryStorage) GetStudentByEmail(ctx context.Context, tenantID string, email string) (types.Student, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	email = utils.NormalizeEmail(email)
+	student, ok := s.findBy(tenantID, func(other types.Student) bool { return other.Email == email })
+	if !ok {
+		return types.Student{}, fmt.Errorf("%w with that email", storage.ErrNotFound)
+	}
+	return student, nil
+}
+
+func (s *MemoryStorage) GetStudentByExternalID(ctx context.Context, tenantID string, externalID string) (types.Student, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	student, ok := s.findBy(tenantID, func(other types.Student) bool {
+		return other.ExternalID != nil && *other.ExternalID == externalID
+	})
+	if !ok {
+		return types.Student{}, fmt.Errorf("%w with external_id: %q", storage.ErrNotFound, externalID)
+	}
+	return student, nil
+}
+
+// GetStudents returns every live student of the tenant, newest first.
+func (s *MemoryStorage) GetStudents(ctx context.Context, tenantID string) ([]types.Student, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.live(tenantID), nil
+}
+
+// GetStudentsFiltered applies opts the way the SQL backends do: the
+// filters, then the total, then the sort and the page (by offset or by
+// cursor).
+func (s *MemoryStorage) GetStudentsFiltered(ctx context.Context, tenantID string, opts types.FilterOptions) ([]types.Student, int64, error) {
+	less, err := sortFunc(opts.Sort)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	s.mu.RLock()
+	students := s.live(tenantID)
+	s.mu.RUnlock()
+
+	students = slices.DeleteFunc(students, func(student types.Student) bool {
+		return !matches(student, opts)
+	})
+	total := int64(len(students))
+	slices.SortFunc(students, less)
+
+	switch {
+	case opts.AfterID > 0:
+		students = slices.DeleteFunc(students, func(student types.Student) bool { return int64(student.ID) <= opts.AfterID })
+		slices.SortFunc(students, byID)
+	case opts.BeforeID > 0:
+		students = slices.DeleteFunc(students, func(student types.Student) bool { return int64(student.ID) >= opts.BeforeID })
+		slices.SortFunc(students, byID)
+		// The last Limit before the cursor, still in ascending order.
+		if opts.Limit > 0 && len(students) > opts.Limit {
+			students = students[len(students)-opts.Limit:]
+		}
+		return students, total, nil
+	}
+
+	if opts.Limit > 0 {
+		start := min(opts.Offset, len(students))
+		end := min(start+opts.Limit, len(students))
+		students = students[start:end]
+	}
+	return students, total, nil
+}
+
+// matches reports whether student passes opts' filters. Like LIKE in
+// SQLite, the name and email filters ignore case.
+func matches(student types.Student, opts types.FilterOptions) bool {
+	switch {
+	case opts.Name != "" && !containsFold(student.Name, opts.Name):
+		return false
+	case opts.Email != "" && !containsFold(student.Email, opts.Email):
+		return false
+	case opts.AgeMin != nil && student.Age < *opts.AgeMin:
+		return false
+	case opts.AgeMax != nil && student.Age > *opts.AgeMax:
+		return false
+	}
+	return true
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func byID(a, b types.Student) int {
+	return cmp.Compare(a.ID, b.ID)
+}
+
+// sortFunc returns the comparison for sort, ending in id like the SQL
+// backends' ORDER BY, or newestFirst when sort is empty.
+func sortFunc(sort types.SortOptions) (func(a, b types.Student) int, error) {
+	if len(sort.Fields) == 0 {
+		return newestFirst, nil
+	}
+
+	compares := make([]func(a, b types.Student) int, 0, len(sort.Fields)+1)
+	for _, field := range sort.Fields {
+		compare, ok := sortKeys[field.Key]
+		if !ok {
+			return nil, fmt.Errorf("%w: can't sort by %q", storage.ErrUnsupportedFilter, field.Key)
+		}
+		if field.Desc {
+			asc := compare
+			compare = func(a, b types.Student) int { return asc(b, a) }
+		}
+		compares = append(compares, compare)
+	}
+	compares = append(compares, byID)
+
+	return func(a, b types.Student) int {
+		for _, compare := range compares {
+			if c := compare(a, b); c != 0 {
+				return c
+			}
+		}
+		return 0
+	}, nil
+}
+
+// sortKeys compares students by each of types.SortKeys, ascending.
+var sortKeys = map[string]func(a, b types.Student) int{
+	"id":         byID,
+	"name":       func(a, b types.Student) int { return strings.Compare(a.Name, b.Name) },
+	"email":      func(a, b types.Student) int { return strings.Compare(a.Email, b.Email) },
+	"age":        func(a, b types.Student) int { return cmp.Compare(a.Age, b.Age) },
+	"created_at": func(a, b types.Student) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	"updated_at": func(a, b types.Student) int { return a.UpdatedAt.Compare(b.UpdatedAt) },
+}
+
+// SearchStudents returns the tenant's live students whose name or email
+// contains query, ignoring case, newest first.
+func (s *MemoryStorage) SearchStudents(ctx context.Context, tenantID string, query string) ([]types.Student, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return slices.DeleteFunc(s.live(tenantID), func(student types.Student) bool {
+		return !containsFold(student.Name, query) && !containsFold(student.Email, query)
+	}), nil
+}
+
+func (s *MemoryStorage) GetRandomStudent(ctx context.Context, tenantID string) (types.Student, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	students := s.live(tenantID)
+	if len(students) == 0 {
+		return types.Student{}, storage.ErrNotFound
+	}
+	return students[rand.IntN(len(students))], nil
+}
+
+// CountStudents returns the number of live (not deleted) students of a tenant.
+func (s *MemoryStorage) CountStudents(ctx context.Context, tenantID string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return int64(len(s.live(tenantID))), nil
+}
+
+// GetStudentStats leaves ages of 0 ("not given") out of the age figures,
+// like the SQL backends.
+func (s *MemoryStorage) GetStudentStats(ctx context.Context, tenantID string) (types.StudentStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	students := s.live(tenantID)
+	stats := types.StudentStats{Total: int64(len(students))}
+
+	var sum, n int
+	for _, student := range students {
+		if student.Age == 0 {
+			continue
+		}
+		if n == 0 || student.Age < stats.MinAge {
+			stats.MinAge = student.Age
+		}
+		stats.MaxAge = max(stats.MaxAge, student.Age)
+		sum += student.Age
+		n++
+	}
+	if n > 0 {
+		stats.AvgAge = float64(sum) / float64(n)
+	}
+	return stats, nil
+}
+
+// GetAuditLog always returns an empty list; see the package comment.
+func (s *MemoryStorage) GetAuditLog(ctx context.Context, tenantID string, studentID int64) ([]types.AuditEntry, error) {
+	return []types.AuditEntry{}, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Writes
+// ─────────────────────────────────────────────────────────────────────────────
+
+func (s *MemoryStorage) CreateStudent(ctx context.Context, tenantID string, name string, email string, age int, department string, phone string) (int64, error) {
+	s.mu.Lock()
+	student := types.Student{Name: name, Email: email, Age: age, Department: department, Phone: phone}
+	if s.conflicts(tenantID, 0, student) {
+		s.mu.Unlock()
+		return 0, storage.ErrDuplicateEmail
+	}
+	created := s.insert(tenantID, student)
+	s.mu.Unlock()
+
+	s.notify(func(h storage.Hook) { h.OnCreate(ctx, created) })
+	return int64(created.ID), nil
+}
+
+// UpdateStudentByID replaces the student's fields if it is still at
+// student.Version.
+func (s *MemoryStorage) UpdateStudentByID(ctx context.Context, tenantID string, id int64, student types.Student) (types.Student, error) {
+	s.mu.Lock()
+	old, ok := s.find(tenantID, id)
+	if !ok {
+		s.mu.Unlock()
+		return types.Student{}, notFound(id)
+	}
+	if old.Version != student.Version {
+		s.mu.Unlock()
+		return types.Student{}, fmt.Errorf("%w: student %d is no longer at version %d", storage.ErrVersionConflict, id, student.Version)
+	}
+	if s.conflicts(tenantID, id, types.Student{Email: student.Email}) {
+		s.mu.Unlock()
+		return types.Student{}, storage.ErrDuplicateEmail
+	}
+
+	changed := old
+	changed.Name, changed.Email, changed.Age = student.Name, utils.NormalizeEmail(student.Email), student.Age
+	changed.Department, changed.Phone = student.Department, student.Phone
+	updated := s.save(changed)
+	s.mu.Unlock()
+
+	s.notify(func(h storage.Hook) { h.OnUpdate(ctx, old, updated) })
+	return updated, nil
+}
+
+// PatchStudentByID sets only the fields named in fields. nil clears
+// department, phone and photo_url; the other fields can't be cleared.
+func (s *MemoryStorage) PatchStudentByID(ctx context.Context, tenantID string, id int64, fields map[string]interface{}) (types.Student, error) {
+	s.mu.Lock()
+	old, ok := s.find(tenantID, id)
+	if !ok {
+		s.mu.Unlock()
+		return types.Student{}, notFound(id)
+	}
+	if len(fields) == 0 {
+		s.mu.Unlock()
+		return old, nil
+	}
+
+	changed := old
+	for key, value := range fields {
+		if err := patchField(&changed, key, value); err != nil {
+			s.mu.Unlock()
+			return types.Student{}, fmt.Errorf("PatchStudentByID: %w", err)
+		}
+	}
+	if s.conflicts(tenantID, id, types.Student{Email: changed.Email}) {
+		s.mu.Unlock()
+		return types.Student{}, storage.ErrDuplicateEmail
+	}
+	updated := s.save(changed)
+	s.mu.Unlock()
+
+	s.notify(func(h storage.Hook) { h.OnUpdate(ctx, old, updated) })
+	return updated, nil
+}
+
+// patchField sets one field of student to value, the way the SQL
+// backends' whitelist of patchable columns allows.
+func patchField(student *types.Student, key string, value any) error {
+	if value == nil {
+		switch key {
+		case "department":
+			student.Department = ""
+		case "phone":
+			student.Phone = ""
+		case "photo_url":
+			student.PhotoURL = ""
+		case "name", "email", "age":
+			return fmt.Errorf("field %q cannot be null", key)
+		default:
+			return fmt.Errorf("field %q cannot be patched", key)
+		}
+		return nil
+	}
+
+	var target *string
+	switch key {
+	case "name":
+		target = &student.Name
+	case "email":
+		target = &student.Email
+	case "department":
+		target = &student.Department
+	case "phone":
+		target = &student.Phone
+	case "photo_url":
+		target = &student.PhotoURL
+	case "age":
+		age, ok := value.(int)
+		if !ok {
+			return fmt.Errorf("field %q must be an int, not %T", key, value)
+		}
+		student.Age = age
+		return nil
+	default:
+		return fmt.Errorf("field %q cannot be patched", key)
+	}
+
+	text, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("field %q must be a string, not %T", key, value)
+	}
+	if key == "email" {
+		text = utils.NormalizeEmail(text)
+	}
+	*target = text
+	return nil
+}
+
+// DeleteStudentByID soft-deletes a student; RestoreStudentByID can bring
+// it back.
+func (s *MemoryStorage) DeleteStudentByID(ctx context.Context, tenantID string, id int64) error {
+	s.mu.Lock()
+	if _, ok := s.find(tenantID, id); !ok {
+		s.mu.Unlock()
+		return notFound(id)
+	}
+	rec := s.students[id]
+	rec.deleted = true
+	s.students[id] = rec
+	s.mu.Unlock()
+
+	s.notify(func(h storage.Hook) { h.OnDelete(ctx, tenantID, id) })
+	return nil
+}
+
+// AnonymizeStudentByID erases the student's personal data and
+// soft-deletes it, like the SQL backends. It works on deleted students
+// too.
+func (s *MemoryStorage) AnonymizeStudentByID(ctx context.Context, tenantID string, id int64) error {
+	s.mu.Lock()
+	rec, ok := s.students[id]
+	if !ok || rec.student.TenantID != tenantID {
+		s.mu.Unlock()
+		return notFound(id)
+	}
+	student := rec.student
+	student.Name, student.Email = storage.AnonymizedName, storage.AnonymizedEmail
+	student.Phone, student.PhotoURL = "", ""
+	s.save(student)
+	rec = s.students[id]
+	rec.deleted = true
+	s.students[id] = rec
+	s.mu.Unlock()
+
+	s.notify(func(h storage.Hook) { h.OnDelete(ctx, tenantID, id) })
+	return nil
+}
+
+// BulkDeleteStudents deletes all of ids or, if any is missing, none.
+func (s *MemoryStorage) BulkDeleteStudents(ctx context.Context, tenantID string, ids []int64) (int64, error) {
+	s.mu.Lock()
+	found := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if _, ok := s.find(tenantID, id); ok {
+			found[id] = true
+		}
+	}
+	if err := storage.CheckFound(ids, found); err != nil {
+		s.mu.Unlock()
+		return 0, err
+	}
+	for id := range found {
+		rec := s.students[id]
+		rec.deleted = true
+		s.students[id] = rec
+	}
+	s.mu.Unlock()
+
+	for id := range found {
+		s.notify(func(h storage.Hook) { h.OnDelete(ctx, tenantID, id) })
+	}
+	return int64(len(found)), nil
+}
+
+// RestoreStudentByID undoes DeleteStudentByID, unless a live student has
+// taken the email or external ID since.
+func (s *MemoryStorage) RestoreStudentByID(ctx context.Context, tenantID string, id int64) error {
+	s.mu.Lock()
+	rec, ok := s.students[id]
+	if !ok || !rec.deleted || rec.student.TenantID != tenantID {
+		s.mu.Unlock()
+		return fmt.Errorf("%w with id %d among deleted students", storage.ErrNotFound, id)
+	}
+	if s.conflicts(tenantID, id, rec.student) {
+		s.mu.Unlock()
+		return storage.ErrDuplicateEmail
+	}
+	rec.deleted = false
+	s.students[id] = rec
+	restored := clone(rec.student)
+	s.mu.Unlock()
+
+	s.notify(func(h storage.Hook) { h.OnCreate(ctx, restored) })
+	return nil
+}
+
+func (s *MemoryStorage) SetStudentPhotoURL(ctx context.Context, tenantID string, id int64, url string) error {
+	return s.setField(ctx, tenantID, id, func(student *types.Student) { student.PhotoURL = url })
+}
+
+// SetStudentStatus sets the status as given; the caller checks it is one
+// of types.StudentStatuses.
+func (s *MemoryStorage) SetStudentStatus(ctx context.Context, tenantID string, id int64, status string) error {
+	return s.setField(ctx, tenantID, id, func(student *types.Student) { student.Status = status })
+}
+
+// setField changes one field of a live student with set.
+func (s *MemoryStorage) setField(ctx context.Context, tenantID string, id int64, set func(*types.Student)) error {
+	s.mu.Lock()
+	old, ok := s.find(tenantID, id)
+	if !ok {
+		s.mu.Unlock()
+		return notFound(id)
+	}
+	changed := old
+	set(&changed)
+	updated := s.save(changed)
+	s.mu.Unlock()
+
+	s.notify(func(h storage.Hook) { h.OnUpdate(ctx, old, updated) })
+	return nil
+}
+
+// UpsertStudents creates each student, or updates the tenant's live
+// student with the same email.
+func (s *MemoryStorage) UpsertStudents(ctx context.Context, tenantID string, students []types.Student) ([]types.UpsertResult, error) {
+	s.mu.Lock()
+	results := make([]types.UpsertResult, 0, len(students))
+	var events []func(storage.Hook)
+
+	for _, student := range students {
+		email := utils.NormalizeEmail(student.Email)
+		old, ok := s.findBy(tenantID, func(other types.Student) bool { return other.Email == email })
+		if !ok {
+			created := s.insert(tenantID, types.Student{Name: student.Name, Email: email, Age: student.Age, Department: student.Department, Phone: student.Phone})
+			results = append(results, types.UpsertResult{StudentID: int64(created.ID), Email: email, Action: types.UpsertActionCreated})
+			events = append(events, func(h storage.Hook) { h.OnCreate(ctx, created) })
+			continue
+		}
+
+		changed := old
+		changed.Name, changed.Age, changed.Department, changed.Phone = student.Name, student.Age, student.Department, student.Phone
+		updated := s.save(changed)
+		results = append(results, types.UpsertResult{StudentID: int64(updated.ID), Email: email, Action: types.UpsertActionUpdated})
+		events = append(events, func(h storage.Hook) { h.OnUpdate(ctx, old, updated) })
+	}
+	s.mu.Unlock()
+
+	for _, event := range events {
+		s.notify(event)
+	}
+	return results, nil
+}
+
+// BulkCreateStudents creates every student it can; the ones whose email
+// is taken are reported in a *storage.BulkError.
+func (s *MemoryStorage) BulkCreateStudents(ctx context.Context, tenantID string, students []types.Student) ([]int64, error) {
+	s.mu.Lock()
+	ids := make([]int64, len(students))
+	failed := make(map[int]error)
+	var events []func(storage.Hook)
+
+	for i, student := range students {
+		student := types.Student{Name: student.Name, Email: student.Email, Age: student.Age, Department: student.Department, Phone: student.Phone}
+		if s.conflicts(tenantID, 0, student) {
+			failed[i] = storage.ErrDuplicateEmail
+			continue
+		}
+		created := s.insert(tenantID, student)
+		ids[i] = int64(created.ID)
+		events = append(events, func(h storage.Hook) { h.OnCreate(ctx, created) })
+	}
+	s.mu.Unlock()
+
+	for _, event := range events {
+		s.notify(event)
+	}
+	if len(failed) > 0 {
+		return ids, &storage.BulkError{Rows: failed}
+	}
+	return ids, nil
+}
+
+// ImportStudents upserts students with an external ID on it and creates
+// the rest. Like the SQL backends' transaction it is all or nothing: if
+// one student's email is taken, nothing is written.
+func (s *MemoryStorage) ImportStudents(ctx context.Context, tenantID string, students []types.Student) ([]types.UpsertResult, error) {
+	s.mu.Lock()
+	saved, savedID := maps.Clone(s.students), s.lastID
+	results := make([]types.UpsertResult, 0, len(students))
+	var events []func(storage.Hook)
+
+	for _, student := range students {
+		email := utils.NormalizeEmail(student.Email)
+
+		var (
+			old types.Student
+			ok  bool
+		)
+		if student.ExternalID != nil {
+			old, ok = s.findBy(tenantID, func(other types.Student) bool {
+				return other.ExternalID != nil && *other.ExternalID == *student.ExternalID
+			})
+		}
+		if s.conflicts(tenantID, int64(old.ID), student) {
+			s.students, s.lastID = saved, savedID
+			s.mu.Unlock()
+			return nil, fmt.Errorf("%w: %s", storage.ErrDuplicateEmail, email)
+		}
+
+		if !ok {
+			created := s.insert(tenantID, types.Student{
+				Name: student.Name, Email: email, Age: student.Age, Department: student.Department, Phone: student.Phone,
+				ExternalID: student.ExternalID,
+			})
+			results = append(results, types.UpsertResult{StudentID: int64(created.ID), Email: email, Action: types.UpsertActionCreated})
+			events = append(events, func(h storage.Hook) { h.OnCreate(ctx, created) })
+			continue
+		}
+
+		changed := old
+		changed.Name, changed.Email, changed.Age = student.Name, email, student.Age
+		changed.Department, changed.Phone = student.Department, student.Phone
+		updated := s.save(changed)
+		results = append(results, types.UpsertResult{StudentID: int64(updated.ID), Email: email, Action: types.UpsertActionUpdated})
+		events = append(events, func(h storage.Hook) { h.OnUpdate(ctx, old, updated) })
+	}
+	s.mu.Unlock()
+
+	for _, event := range events {
+		s.notify(event)
+	}
+	return results, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Connection and hooks
+// ─────────────────────────────────────────────────────────────────────────────
+
+// Ping always succeeds: there is no database to lose.
+func (s *MemoryStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close has nothing to release.
+func (s *MemoryStorage) Close() error {
+	return nil
+}
+
+// RegisterHook adds hook to the list notified after every successful
+// mutation. Safe to call while requests are being served.
+func (s *MemoryStorage) RegisterHook(hook storage.Hook) {
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+
+	s.hooks = append(s.hooks, hook)
+}
+
+// notify calls fn once per registered hook, in registration order,
+// recovering a hook that panics so the ones after it still run.
+func (s *MemoryStorage) notify(fn func(storage.Hook)) {
+	s.hooksMu.RLock()
+	hooks := append([]storage.Hook(nil), s.hooks...)
+	s.hooksMu.RUnlock()
+
+	for _, hook := range hooks {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					slog.Error("storage hook panicked",
+						slog.String("hook", fmt.Sprintf("%T", hook)),
+						slog.Any("panic", r))
+				}
+			}()
+			fn(hook)
+		}()
+	}
+}
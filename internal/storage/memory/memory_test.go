@@ -0,0 +1,209 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// newStore returns a MemoryStorage holding the students below, ids 1 to
+// 4 in this order, in the default tenant.
+func newStore(t *testing.T) *MemoryStorage {
+	t.Helper()
+
+	store := New()
+	for _, s := range []struct {
+		name, email string
+		age         int
+	}{
+		{"Rakesh", "rakesh@test.com", 35},
+		{"Priya", "priya@example.com", 22},
+		{"Amit", "amit@test.com", 28},
+		{"priyanka", "pk@test.com", 40},
+	} {
+		if _, err := store.CreateStudent(context.Background(), types.DefaultTenant, s.name, s.email, s.age, "", ""); err != nil {
+			t.Fatalf("CreateStudent(%s): %v", s.name, err)
+		}
+	}
+	return store
+}
+
+func ids(students []types.Student) []int {
+	ids := make([]int, 0, len(students))
+	for _, s := range students {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+func TestPingAndClose(t *testing.T) {
+	store := New()
+	if err := store.Ping(context.Background()); err != nil {
+		t.Errorf("Ping = %v, want nil", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Errorf("Close = %v, want nil", err)
+	}
+}
+
+func TestCreateAndGet(t *testing.T) {
+	store := newStore(t)
+	ctx := context.Background()
+
+	student, err := store.GetStudentByEmail(ctx, types.DefaultTenant, " PRIYA@example.com")
+	if err != nil {
+		t.Fatalf("GetStudentByEmail: %v", err)
+	}
+	if student.ID != 2 || student.Version != 1 || student.Status != types.StudentStatusActive || student.CreatedAt.IsZero() {
+		t.Errorf("student = %+v", student)
+	}
+
+	if _, err := store.CreateStudent(ctx, types.DefaultTenant, "Other", "Rakesh@TEST.com", 20, "", ""); !errors.Is(err, storage.ErrDuplicateEmail) {
+		t.Errorf("CreateStudent with a taken email = %v, want ErrDuplicateEmail", err)
+	}
+	if _, err := store.CreateStudent(ctx, "other-school", "Other", "rakesh@test.com", 20, "", ""); err != nil {
+		t.Errorf("another tenant can't use the email: %v", err)
+	}
+	if _, err := store.GetStudentByID(ctx, "other-school", 1); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("another tenant's GetStudentByID = %v, want ErrNotFound", err)
+	}
+}
+
+func TestUpdateVersions(t *testing.T) {
+	store := newStore(t)
+	ctx := context.Background()
+
+	updated, err := store.UpdateStudentByID(ctx, types.DefaultTenant, 1, types.Student{Name: "Rakesh K", Email: "rakesh@test.com", Age: 36, Version: 1})
+	if err != nil {
+		t.Fatalf("UpdateStudentByID: %v", err)
+	}
+	if updated.Version != 2 || updated.Age != 36 {
+		t.Errorf("updated = %+v, want age 36 at version 2", updated)
+	}
+
+	_, err = store.UpdateStudentByID(ctx, types.DefaultTenant, 1, types.Student{Name: "Stale", Email: "rakesh@test.com", Age: 1, Version: 1})
+	if !errors.Is(err, storage.ErrVersionConflict) {
+		t.Errorf("a stale update = %v, want ErrVersionConflict", err)
+	}
+
+	_, err = store.PatchStudentByID(ctx, types.DefaultTenant, 1, map[string]interface{}{"email": "amit@test.com"})
+	if !errors.Is(err, storage.ErrDuplicateEmail) {
+		t.Errorf("patching to a taken email = %v, want ErrDuplicateEmail", err)
+	}
+	if _, err := store.PatchStudentByID(ctx, types.DefaultTenant, 1, map[string]interface{}{"version": 9}); err == nil {
+		t.Error("patched a field that isn't patchable")
+	}
+}
+
+func TestDeleteAndRestore(t *testing.T) {
+	store := newStore(t)
+	ctx := context.Background()
+
+	if err := store.DeleteStudentByID(ctx, types.DefaultTenant, 3); err != nil {
+		t.Fatalf("DeleteStudentByID: %v", err)
+	}
+	if _, err := store.GetStudentByID(ctx, types.DefaultTenant, 3); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetStudentByID after delete = %v, want ErrNotFound", err)
+	}
+	if _, err := store.BulkDeleteStudents(ctx, types.DefaultTenant, []int64{1, 3}); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("BulkDeleteStudents with a deleted id = %v, want ErrNotFound", err)
+	}
+	if _, err := store.GetStudentByID(ctx, types.DefaultTenant, 1); err != nil {
+		t.Errorf("a failed bulk delete deleted student 1: %v", err)
+	}
+
+	if err := store.RestoreStudentByID(ctx, types.DefaultTenant, 3); err != nil {
+		t.Fatalf("RestoreStudentByID: %v", err)
+	}
+	if _, err := store.GetStudentByID(ctx, types.DefaultTenant, 3); err != nil {
+		t.Errorf("GetStudentByID after restore: %v", err)
+	}
+}
+
+func TestCountStudents(t *testing.T) {
+	store := newStore(t)
+	ctx := context.Background()
+
+	if _, err := store.CreateStudent(ctx, types.DefaultTenant, "Neha", "neha@test.com", 19, "", ""); err != nil {
+		t.Fatalf("CreateStudent: %v", err)
+	}
+	if _, err := store.CreateStudent(ctx, "other", "Neha", "neha@test.com", 19, "", ""); err != nil {
+		t.Fatalf("CreateStudent in another tenant: %v", err)
+	}
+	if n, err := store.CountStudents(ctx, types.DefaultTenant); err != nil || n != 5 {
+		t.Errorf("CountStudents = %d, %v; want 5", n, err)
+	}
+
+	if err := store.DeleteStudentByID(ctx, types.DefaultTenant, 2); err != nil {
+		t.Fatalf("DeleteStudentByID: %v", err)
+	}
+	if n, err := store.CountStudents(ctx, types.DefaultTenant); err != nil || n != 4 {
+		t.Errorf("CountStudents after a soft delete = %d, %v; want 4", n, err)
+	}
+}
+
+func TestGetStudentsFiltered(t *testing.T) {
+	store := newStore(t)
+	age := func(n int) *int { return &n }
+
+	tests := []struct {
+		name      string
+		opts      types.FilterOptions
+		want      []int
+		wantTotal int64
+	}{
+		{"newest first", types.FilterOptions{}, []int{4, 3, 2, 1}, 4},
+		{"name, ignoring case", types.FilterOptions{Name: "PRIY"}, []int{4, 2}, 2},
+		{"age range", types.FilterOptions{AgeMin: age(25), AgeMax: age(35)}, []int{3, 1}, 2},
+		{"sorted, one page", types.FilterOptions{Sort: types.SortOptions{Fields: []types.SortField{{Key: "age", Desc: true}}}, Offset: 1, Limit: 2}, []int{1, 3}, 4},
+		{"after a cursor", types.FilterOptions{AfterID: 1, Limit: 2}, []int{2, 3}, 4},
+		{"before a cursor", types.FilterOptions{BeforeID: 4, Limit: 2}, []int{2, 3}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			students, total, err := store.GetStudentsFiltered(context.Background(), types.DefaultTenant, tt.opts)
+			if err != nil {
+				t.Fatalf("GetStudentsFiltered: %v", err)
+			}
+			if got := ids(students); !reflect.DeepEqual(got, tt.want) || total != tt.wantTotal {
+				t.Errorf("ids = %v, total %d; want %v, total %d", got, total, tt.want, tt.wantTotal)
+			}
+		})
+	}
+
+	bad := types.FilterOptions{Sort: types.SortOptions{Fields: []types.SortField{{Key: "password"}}}}
+	if _, _, err := store.GetStudentsFiltered(context.Background(), types.DefaultTenant, bad); !errors.Is(err, storage.ErrUnsupportedFilter) {
+		t.Errorf("sorting by an unknown key = %v, want ErrUnsupportedFilter", err)
+	}
+}
+
+func TestImportStudentsAllOrNothing(t *testing.T) {
+	store := newStore(t)
+	ctx := context.Background()
+	ext := func(s string) *string { return &s }
+
+	_, err := store.ImportStudents(ctx, types.DefaultTenant, []types.Student{
+		{Name: "New", Email: "new@test.com", Age: 20, ExternalID: ext("S-1")},
+		{Name: "Clash", Email: "amit@test.com", Age: 20, ExternalID: ext("S-2")},
+	})
+	if !errors.Is(err, storage.ErrDuplicateEmail) {
+		t.Fatalf("ImportStudents with a taken email = %v, want ErrDuplicateEmail", err)
+	}
+	if n, _ := store.CountStudents(ctx, types.DefaultTenant); n != 4 {
+		t.Errorf("count = %d after a failed import, want 4", n)
+	}
+
+	// The same file twice updates instead of duplicating.
+	batch := []types.Student{{Name: "New", Email: "new@test.com", Age: 20, ExternalID: ext("S-1")}}
+	if _, err := store.ImportStudents(ctx, types.DefaultTenant, batch); err != nil {
+		t.Fatalf("ImportStudents: %v", err)
+	}
+	results, err := store.ImportStudents(ctx, types.DefaultTenant, batch)
+	if err != nil || len(results) != 1 || results[0].Action != types.UpsertActionUpdated {
+		t.Errorf("re-import = %+v, %v; want one update", results, err)
+	}
+}
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ConfigurePool sizes db's connection pool and logs the result.
+//
+// database/sql's defaults suit neither backend: it opens as many
+// connections as there are concurrent queries (maxOpen 0 keeps that),
+// keeps only 2 of them idle, and never retires one (maxLifetime 0 keeps
+// that too). A retired connection is simply reopened on next use, which
+// lets a PostgreSQL server behind a load balancer shed connections.
+//
+// database/sql lowers the idle limit to maxOpen when it is larger, so the
+// logged max_idle_conns is what the pool will actually keep.
+// ─────────────────────────────────────────────────────────────────────────────
+func ConfigurePool(db *sql.DB, backend string, maxOpen, maxIdle int, maxLifetime time.Duration) {
+	db.SetMaxOpenConns(maxOpen)
+	db.SetMaxIdleConns(maxIdle)
+	db.SetConnMaxLifetime(maxLifetime)
+
+	if maxOpen > 0 && maxIdle > maxOpen {
+		maxIdle = maxOpen
+	}
+	slog.Info("database connection pool configured",
+		slog.String("backend", backend),
+		slog.Int("max_open_conns", db.Stats().MaxOpenConnections),
+		slog.Int("max_idle_conns", maxIdle),
+		slog.Duration("conn_max_lifetime", maxLifetime))
+}
@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/aanand-mishra/students-api/internal/storage"
+)
+
+// RegisterHook adds hook to the list notified after every successful
+// mutation. Safe to call while requests are being served.
+func (p *Postgres) RegisterHook(hook storage.Hook) {
+	p.hooksMu.Lock()
+	defer p.hooksMu.Unlock()
+
+	p.hooks = append(p.hooks, hook)
+}
+
+// notify calls fn once per registered hook, in registration order, with
+// each call isolated by recover() — see sqlite.SQLite.notify.
+func (p *Postgres) notify(fn func(storage.Hook)) {
+	p.hooksMu.RLock()
+	hooks := append([]storage.Hook(nil), p.hooks...)
+	p.hooksMu.RUnlock()
+
+	for _, hook := range hooks {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					slog.Error("storage hook panicked",
+						slog.String("hook", fmt.Sprintf("%T", hook)),
+						slog.Any("panic", r))
+				}
+			}()
+			fn(hook)
+		}()
+	}
+}
@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// The methods below satisfy middleware.IdempotencyStore. They work like
+// the SQLite ones; see there for the details.
+
+// ClaimIdempotencyKey marks key as in flight until expiresAt, unless a
+// live entry for it exists already, and reports whether it did.
+func (p *Postgres) ClaimIdempotencyKey(ctx context.Context, key string, expiresAt time.Time) (bool, error) {
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("ClaimIdempotencyKey: begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM idempotency_cache WHERE expires_at <= now()"); err != nil {
+		return false, fmt.Errorf("ClaimIdempotencyKey: purge: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO idempotency_cache (key, expires_at) VALUES ($1, $2)
+		ON CONFLICT (key) DO NOTHING`, key, expiresAt)
+	if err != nil {
+		return false, fmt.Errorf("ClaimIdempotencyKey: insert: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ClaimIdempotencyKey: rows affected: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("ClaimIdempotencyKey: commit: %w", err)
+	}
+	return n == 1, nil
+}
+
+// GetIdempotencyResult returns the live entry for key, or
+// storage.ErrNotFound.
+func (p *Postgres) GetIdempotencyResult(ctx context.Context, key string) (types.IdempotencyResult, error) {
+	result := types.IdempotencyResult{Key: key}
+	err := p.Db.QueryRowContext(ctx, `
+		SELECT status_code, content_type, response_body, expires_at
+		FROM idempotency_cache
+		WHERE key = $1 AND expires_at > now()`, key).
+		Scan(&result.StatusCode, &result.ContentType, &result.Body, &result.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.IdempotencyResult{}, storage.ErrNotFound
+		}
+		return types.IdempotencyResult{}, fmt.Errorf("GetIdempotencyResult: %w", err)
+	}
+	return result, nil
+}
+
+// StoreIdempotencyResult records the response to replay for result.Key
+// until result.ExpiresAt.
+func (p *Postgres) StoreIdempotencyResult(ctx context.Context, result types.IdempotencyResult) error {
+	_, err := p.Db.ExecContext(ctx, `
+		INSERT INTO idempotency_cache (key, status_code, content_type, response_body, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (key) DO UPDATE SET
+			status_code   = EXCLUDED.status_code,
+			content_type  = EXCLUDED.content_type,
+			response_body = EXCLUDED.response_body,
+			expires_at    = EXCLUDED.expires_at`,
+		result.Key, result.StatusCode, result.ContentType, result.Body, result.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("StoreIdempotencyResult: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey forgets key.
+func (p *Postgres) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	if _, err := p.Db.ExecContext(ctx, "DELETE FROM idempotency_cache WHERE key = $1", key); err != nil {
+		return fmt.Errorf("ReleaseIdempotencyKey: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,1161 @@
+// Package postgres provides a PostgreSQL-backed implementation of the
+// storage.Storage interface, for deployments that outgrow a single
+// SQLite file (several API instances sharing one database, for example).
+//
+// It mirrors the sqlite package method for method. The SQL differs in
+// the usual places:
+//
+//   - placeholders are $1, $2… instead of ?
+//   - ids come from BIGSERIAL and are read back with RETURNING id, as
+//     lib/pq doesn't support LastInsertId
+//   - unique violations are recognised by SQLSTATE 23505
+//
+// Select it with storage_backend: "postgres" and a postgres_dsn (or the
+// DATABASE_URL env var).
+package postgres
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/config"
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/types"
+	"github.com/aanand-mishra/students-api/internal/utils"
+
+	// Registers the "postgres" driver with database/sql; its error type
+	// tells us which constraint a failed write violated.
+	"github.com/lib/pq"
+)
+
+// studentColumns is the column list every student SELECT scans, in the
+// order scanStudent expects.
+const studentColumns = "id, name, email, age, COALESCE(photo_url, ''), external_id, department, phone, created_at, updated_at, version, status, tenant_id"
+
+// Postgres is the PostgreSQL implementation of storage.Storage.
+// Like sqlite.SQLite it wraps a *sql.DB connection pool, which is safe
+// for concurrent use.
+type Postgres struct {
+	Db *sql.DB
+
+	// hooks are notified after each successful mutation (see hooks.go).
+	hooksMu sync.RWMutex
+	hooks   []storage.Hook
+}
+
+// New connects to the database at cfg.PostgresDSN and creates the schema
+// if it isn't there yet.
+//
+// Unlike sql.Open alone, New pings the server, so a wrong DSN or an
+// unreachable database fails at startup rather than on the first request.
+func New(cfg *config.Config) (*Postgres, error) {
+	db, err := sql.Open("postgres", cfg.PostgresDSN)
+	if err != nil {
+		return nil, fmt.Errorf("postgres.New: open db: %w", err)
+	}
+	storage.ConfigurePool(db, config.StorageBackendPostgres,
+		cfg.Database.MaxOpenConns, cfg.Database.MaxIdleConns, cfg.Database.ConnMaxLifetime)
+
+	ctx := context.Background()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("postgres.New: ping: %w", err)
+	}
+
+	if err := createSchema(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("postgres.New: %w", err)
+	}
+
+	return &Postgres{Db: db}, nil
+}
+
+// createSchema creates the tables and indexes, all IF NOT EXISTS, so it is
+// safe to run on every start. It is the SQLite schema after all of its
+// migrations, in PostgreSQL types; there is no history to replay yet.
+func createSchema(ctx context.Context, db *sql.DB) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS students (
+			id          BIGSERIAL PRIMARY KEY,
+			name        TEXT    NOT NULL,
+			email       TEXT    NOT NULL,
+			age         INTEGER NOT NULL,
+			photo_url   TEXT,
+			external_id TEXT,
+			department  TEXT    NOT NULL DEFAULT '',
+			phone       TEXT    NOT NULL DEFAULT '',
+			deleted_at  TIMESTAMPTZ,
+			created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+			updated_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+			version     INTEGER NOT NULL DEFAULT 1,
+			status      TEXT    NOT NULL DEFAULT 'active',
+			tenant_id   TEXT    NOT NULL DEFAULT 'default'
+		)`,
+		// Tables created before soft deletes, phone numbers, timestamps,
+		// versions, statuses or tenants lack the column. Existing rows
+		// get the time the column was added, version 1, status "active"
+		// and the default tenant.
+		`ALTER TABLE students ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ`,
+		`ALTER TABLE students ADD COLUMN IF NOT EXISTS phone TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE students ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now()`,
+		`ALTER TABLE students ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now()`,
+		`ALTER TABLE students ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1`,
+		`ALTER TABLE students ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active'`,
+		`ALTER TABLE students ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default'`,
+		// Emails and external IDs used to be unique across the whole
+		// table; they are now unique per tenant. CREATE INDEX IF NOT
+		// EXISTS can't change an existing index, hence the new names.
+		`DROP INDEX IF EXISTS idx_students_email`,
+		`DROP INDEX IF EXISTS idx_students_external_id`,
+		// Unique regardless of case among a tenant's live students, as in
+		// SQLite. ON CONFLICT (tenant_id, (lower(email))) WHERE deleted_at
+		// IS NULL in UpsertStudents relies on this index.
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_students_tenant_email ON students (tenant_id, lower(email))
+			WHERE deleted_at IS NULL`,
+		// NULLs are distinct, so only students with an external ID
+		// must differ. ImportStudents' ON CONFLICT relies on it.
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_students_tenant_external_id ON students (tenant_id, external_id)
+			WHERE deleted_at IS NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_students_created_at ON students (created_at)`,
+		`CREATE TABLE IF NOT EXISTS audit_log (
+			id              BIGSERIAL PRIMARY KEY,
+			action          TEXT    NOT NULL,
+			student_id      BIGINT  NOT NULL,
+			timestamp       TIMESTAMPTZ NOT NULL,
+			before          JSONB,
+			after           JSONB,
+			request_body    JSONB,
+			response_status INTEGER,
+			client_ip       TEXT,
+			user_agent      TEXT,
+			request_id      TEXT,
+			duration_ms     BIGINT
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_student_id ON audit_log (student_id)`,
+		`CREATE TABLE IF NOT EXISTS idempotency_cache (
+			key           TEXT    PRIMARY KEY,
+			status_code   INTEGER NOT NULL DEFAULT 0,
+			content_type  TEXT    NOT NULL DEFAULT '',
+			response_body BYTEA,
+			expires_at    TIMESTAMPTZ NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_idempotency_cache_expires_at ON idempotency_cache (expires_at)`,
+		`CREATE TABLE IF NOT EXISTS webhooks (
+			id         BIGSERIAL PRIMARY KEY,
+			url        TEXT NOT NULL,
+			secret     TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			tenant_id  TEXT NOT NULL DEFAULT 'default'
+		)`,
+		`ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default'`,
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("create schema: %w", err)
+		}
+	}
+	return nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// CreateStudent inserts a new row. RETURNING hands back the generated key
+// and timestamps in the same round trip.
+// ─────────────────────────────────────────────────────────────────────────────
+func (p *Postgres) CreateStudent(ctx context.Context, tenantID, name, email string, age int, department, phone string) (int64, error) {
+	email = utils.NormalizeEmail(email)
+
+	created := types.Student{Name: name, Email: email, Age: age, Department: department, Phone: phone, Status: types.StudentStatusActive, TenantID: tenantID}
+
+	var id int64
+	err := p.Db.QueryRowContext(ctx,
+		"INSERT INTO students (tenant_id, name, email, age, department, phone) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at, version",
+		tenantID, name, email, age, department, phone,
+	).Scan(&id, &created.CreatedAt, &created.UpdatedAt, &created.Version)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, storage.ErrDuplicateEmail
+		}
+		return 0, fmt.Errorf("CreateStudent: insert: %w", err)
+	}
+
+	created.ID = int(id)
+	created.CreatedAt, created.UpdatedAt = created.CreatedAt.UTC(), created.UpdatedAt.UTC()
+	p.notify(func(h storage.Hook) { h.OnCreate(ctx, created) })
+
+	return id, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// GetStudentByID fetches exactly one student of the tenant by primary key.
+// ─────────────────────────────────────────────────────────────────────────────
+func (p *Postgres) GetStudentByID(ctx context.Context, tenantID string, id int64) (types.Student, error) {
+	row := p.Db.QueryRowContext(ctx,
+		"SELECT "+studentColumns+" FROM students WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL", id, tenantID)
+
+	student, err := scanStudent(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.Student{}, fmt.Errorf("%w with id: %d", storage.ErrNotFound, id)
+		}
+		return types.Student{}, fmt.Errorf("GetStudentByID: scan: %w", err)
+	}
+	return student, nil
+}
+
+// GetStudentByEmail fetches the student with the given email, using the
+// lower(email) index.
+func (p *Postgres) GetStudentByEmail(ctx context.Context, tenantID string, email string) (types.Student, error) {
+	row := p.Db.QueryRowContext(ctx,
+		"SELECT "+studentColumns+" FROM students WHERE tenant_id = $1 AND lower(email) = $2 AND deleted_at IS NULL LIMIT 1",
+		tenantID, utils.NormalizeEmail(email))
+
+	student, err := scanStudent(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.Student{}, fmt.Errorf("%w with that email", storage.ErrNotFound)
+		}
+		return types.Student{}, fmt.Errorf("GetStudentByEmail: scan: %w", err)
+	}
+	return student, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// GetStudents returns every student of the tenant, newest first.
+// ─────────────────────────────────────────────────────────────────────────────
+func (p *Postgres) GetStudents(ctx context.Context, tenantID string) ([]types.Student, error) {
+	rows, err := p.Db.QueryContext(ctx,
+		"SELECT "+studentColumns+" FROM students WHERE tenant_id = $1 AND deleted_at IS NULL"+defaultOrderBy, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("GetStudents: query: %w", err)
+	}
+	defer rows.Close()
+
+	students, err := scanStudents(rows)
+	if err != nil {
+		return nil, fmt.Errorf("GetStudents: %w", err)
+	}
+	return students, nil
+}
+
+// SearchStudents matches query anywhere in the name or email. ILIKE
+// ignores case; the query is a bound parameter with its wildcards
+// escaped, like the filters below.
+func (p *Postgres) SearchStudents(ctx context.Context, tenantID string, query string) ([]types.Student, error) {
+	rows, err := p.Db.QueryContext(ctx, "SELECT "+studentColumns+` FROM students
+		WHERE tenant_id = $2 AND deleted_at IS NULL AND (name ILIKE $1 ESCAPE '\' OR email ILIKE $1 ESCAPE '\')`+
+		defaultOrderBy, likeContains(query), tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("SearchStudents: query: %w", err)
+	}
+	defer rows.Close()
+
+	students, err := scanStudents(rows)
+	if err != nil {
+		return nil, fmt.Errorf("SearchStudents: %w", err)
+	}
+	return students, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// GetStudentsFiltered returns the students matching opts plus how many
+// match in total. Like the SQLite version, the WHERE and ORDER BY are
+// built from fixed fragments and every client value is a placeholder.
+//
+// ILIKE is PostgreSQL's case-insensitive LIKE; SQLite's LIKE already
+// ignores case. The COUNT and the page share a REPEATABLE READ
+// transaction so they see the same snapshot, and the COUNT is
+// countStudents, as CountStudents' is.
+// ─────────────────────────────────────────────────────────────────────────────
+func (p *Postgres) GetStudentsFiltered(ctx context.Context, tenantID string, opts types.FilterOptions) ([]types.Student, int64, error) {
+	where, args := filterWhere(tenantID, opts)
+	// placeholder adds v to args and returns its $n.
+	placeholder := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	orderBy, err := orderByClause(opts.Sort)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	tx, err := p.Db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, 0, fmt.Errorf("GetStudentsFiltered: begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	total, err := countStudents(ctx, tx, tenantID, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("GetStudentsFiltered: count: %w", err)
+	}
+
+	// A cursor narrows the page but not the total; see the SQLite version.
+	switch {
+	case opts.AfterID > 0:
+		where += " AND id > " + placeholder(opts.AfterID)
+		orderBy = " ORDER BY id ASC"
+	case opts.BeforeID > 0:
+		where += " AND id < " + placeholder(opts.BeforeID)
+		orderBy = " ORDER BY id DESC"
+	}
+
+	query := "SELECT " + studentColumns + " FROM students" + where + orderBy
+	if opts.Limit > 0 {
+		query += " LIMIT " + placeholder(opts.Limit) + " OFFSET " + placeholder(opts.Offset)
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("GetStudentsFiltered: query: %w", err)
+	}
+	defer rows.Close()
+
+	students, err := scanStudents(rows)
+	if err != nil {
+		return nil, 0, fmt.Errorf("GetStudentsFiltered: %w", err)
+	}
+	if opts.AfterID == 0 && opts.BeforeID > 0 {
+		slices.Reverse(students)
+	}
+	return students, total, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// GetRandomStudent picks one row at random.
+// ─────────────────────────────────────────────────────────────────────────────
+func (p *Postgres) GetRandomStudent(ctx context.Context, tenantID string) (types.Student, error) {
+	row := p.Db.QueryRowContext(ctx,
+		"SELECT "+studentColumns+" FROM students WHERE tenant_id = $1 AND deleted_at IS NULL ORDER BY random() LIMIT 1", tenantID)
+
+	student, err := scanStudent(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.Student{}, storage.ErrNotFound
+		}
+		return types.Student{}, fmt.Errorf("GetRandomStudent: scan: %w", err)
+	}
+	return student, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// UpdateStudentByID replaces a student's editable fields and returns the
+// stored result. As in SQLite, the row is only updated while its version
+// is still student.Version; otherwise ErrVersionConflict.
+// ─────────────────────────────────────────────────────────────────────────────
+func (p *Postgres) UpdateStudentByID(ctx context.Context, tenantID string, id int64, student types.Student) (types.Student, error) {
+	old, err := p.GetStudentByID(ctx, tenantID, id)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	result, err := p.Db.ExecContext(ctx,
+		"UPDATE students SET name = $1, email = $2, age = $3, department = $4, phone = $5, updated_at = now(), version = version + 1 WHERE id = $6 AND tenant_id = $7 AND version = $8 AND deleted_at IS NULL",
+		student.Name, utils.NormalizeEmail(student.Email), student.Age, student.Department, student.Phone, id, tenantID, student.Version,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return types.Student{}, storage.ErrDuplicateEmail
+		}
+		return types.Student{}, fmt.Errorf("UpdateStudentByID: exec: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return types.Student{}, fmt.Errorf("UpdateStudentByID: rows affected: %w", err)
+	}
+	if affected == 0 {
+		return types.Student{}, fmt.Errorf("%w: student %d is no longer at version %d", storage.ErrVersionConflict, id, student.Version)
+	}
+
+	updated, err := p.GetStudentByID(ctx, tenantID, id)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	p.notify(func(h storage.Hook) { h.OnUpdate(ctx, old, updated) })
+
+	return updated, nil
+}
+
+// patchableColumns whitelists the fields PatchStudentByID may set, mapped
+// to their columns — the only column names put into the SQL text.
+var patchableColumns = map[string]string{
+	"name":       "name",
+	"email":      "email",
+	"age":        "age",
+	"department": "department",
+	"phone":      "phone",
+	"photo_url":  "photo_url",
+}
+
+// nullableColumns may be cleared with a nil value. department and phone
+// are NOT NULL, so clearing them stores "" instead.
+var nullableColumns = map[string]any{
+	"department": "",
+	"phone":      "",
+	"photo_url":  nil,
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// PatchStudentByID updates only the columns named in fields. See the
+// SQLite version for how the SET clause is built.
+// ─────────────────────────────────────────────────────────────────────────────
+func (p *Postgres) PatchStudentByID(ctx context.Context, tenantID string, id int64, fields map[string]interface{}) (types.Student, error) {
+	old, err := p.GetStudentByID(ctx, tenantID, id)
+	if err != nil {
+		return types.Student{}, err
+	}
+	if len(fields) == 0 {
+		return old, nil
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		if _, ok := patchableColumns[key]; !ok {
+			return types.Student{}, fmt.Errorf("PatchStudentByID: field %q cannot be patched", key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sets := make([]string, 0, len(keys))
+	args := make([]any, 0, len(keys)+2)
+	for _, key := range keys {
+		value := fields[key]
+		if value == nil {
+			cleared, ok := nullableColumns[key]
+			if !ok {
+				return types.Student{}, fmt.Errorf("PatchStudentByID: field %q cannot be null", key)
+			}
+			value = cleared
+		}
+		if email, ok := value.(string); ok && key == "email" {
+			value = utils.NormalizeEmail(email)
+		}
+
+		args = append(args, value)
+		sets = append(sets, patchableColumns[key]+" = $"+strconv.Itoa(len(args)))
+	}
+	sets = append(sets, "updated_at = now()", "version = version + 1")
+	args = append(args, id, tenantID)
+
+	query := "UPDATE students SET " + strings.Join(sets, ", ") +
+		" WHERE id = $" + strconv.Itoa(len(args)-1) + " AND tenant_id = $" + strconv.Itoa(len(args)) + " AND deleted_at IS NULL"
+	if _, err := p.Db.ExecContext(ctx, query, args...); err != nil {
+		if isUniqueViolation(err) {
+			return types.Student{}, storage.ErrDuplicateEmail
+		}
+		return types.Student{}, fmt.Errorf("PatchStudentByID: exec: %w", err)
+	}
+
+	updated, err := p.GetStudentByID(ctx, tenantID, id)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	p.notify(func(h storage.Hook) { h.OnUpdate(ctx, old, updated) })
+
+	return updated, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// DeleteStudentByID soft-deletes a student by setting deleted_at.
+// ─────────────────────────────────────────────────────────────────────────────
+func (p *Postgres) DeleteStudentByID(ctx context.Context, tenantID string, id int64) error {
+	result, err := p.Db.ExecContext(ctx,
+		"UPDATE students SET deleted_at = now() WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL", id, tenantID)
+	if err != nil {
+		return fmt.Errorf("DeleteStudentByID: exec: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("DeleteStudentByID: rows affected: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w with id: %d", storage.ErrNotFound, id)
+	}
+
+	p.notify(func(h storage.Hook) { h.OnDelete(ctx, tenantID, id) })
+
+	return nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// AnonymizeStudentByID erases a student's personal data but keeps the
+// row, and drops the snapshots from its audit log entries. See the
+// SQLite version.
+// ─────────────────────────────────────────────────────────────────────────────
+func (p *Postgres) AnonymizeStudentByID(ctx context.Context, tenantID string, id int64) error {
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("AnonymizeStudentByID: begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE students
+		SET name = $1, email = $2, phone = '', photo_url = NULL,
+		    deleted_at = now(), updated_at = now(), version = version + 1
+		WHERE id = $3 AND tenant_id = $4`, storage.AnonymizedName, storage.AnonymizedEmail, id, tenantID)
+	if err != nil {
+		return fmt.Errorf("AnonymizeStudentByID: exec: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("AnonymizeStudentByID: rows affected: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w with id: %d", storage.ErrNotFound, id)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE audit_log SET before = NULL, after = NULL, request_body = NULL WHERE student_id = $1", id)
+	if err != nil {
+		return fmt.Errorf("AnonymizeStudentByID: audit log: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("AnonymizeStudentByID: commit: %w", err)
+	}
+
+	p.notify(func(h storage.Hook) { h.OnDelete(ctx, tenantID, id) })
+
+	return nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// BulkDeleteStudents soft-deletes many students in one transaction. See
+// the SQLite version; here the IDs go in as a single array parameter:
+//
+//	UPDATE students SET deleted_at = now() WHERE tenant_id = $1 AND id = ANY($2) AND deleted_at IS NULL RETURNING id
+//
+// ─────────────────────────────────────────────────────────────────────────────
+func (p *Postgres) BulkDeleteStudents(ctx context.Context, tenantID string, ids []int64) (int64, error) {
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("BulkDeleteStudents: begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		"UPDATE students SET deleted_at = now() WHERE tenant_id = $1 AND id = ANY($2) AND deleted_at IS NULL RETURNING id",
+		tenantID, pq.Array(ids))
+	if err != nil {
+		return 0, fmt.Errorf("BulkDeleteStudents: update: %w", err)
+	}
+	defer rows.Close()
+
+	deleted := make(map[int64]bool, len(ids))
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return 0, fmt.Errorf("BulkDeleteStudents: scan id: %w", err)
+		}
+		deleted[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("BulkDeleteStudents: rows iteration: %w", err)
+	}
+	n := int64(len(deleted))
+
+	if err := storage.CheckFound(ids, deleted); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("BulkDeleteStudents: commit: %w", err)
+	}
+
+	for id := range deleted {
+		p.notify(func(h storage.Hook) { h.OnDelete(ctx, tenantID, id) })
+	}
+
+	return n, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// RestoreStudentByID clears deleted_at on a soft-deleted student. Hooks
+// get OnCreate, as in SQLite.
+// ─────────────────────────────────────────────────────────────────────────────
+func (p *Postgres) RestoreStudentByID(ctx context.Context, tenantID string, id int64) error {
+	result, err := p.Db.ExecContext(ctx,
+		"UPDATE students SET deleted_at = NULL WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NOT NULL", id, tenantID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return storage.ErrDuplicateEmail
+		}
+		return fmt.Errorf("RestoreStudentByID: exec: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("RestoreStudentByID: rows affected: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w with id %d among deleted students", storage.ErrNotFound, id)
+	}
+
+	restored, err := p.GetStudentByID(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	p.notify(func(h storage.Hook) { h.OnCreate(ctx, restored) })
+
+	return nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// SetStudentPhotoURL stores the public URL of a student's uploaded photo.
+// ─────────────────────────────────────────────────────────────────────────────
+func (p *Postgres) SetStudentPhotoURL(ctx context.Context, tenantID string, id int64, url string) error {
+	old, err := p.GetStudentByID(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+
+	result, err := p.Db.ExecContext(ctx,
+		"UPDATE students SET photo_url = $1, updated_at = now(), version = version + 1 WHERE id = $2 AND tenant_id = $3 AND deleted_at IS NULL", url, id, tenantID)
+	if err != nil {
+		return fmt.Errorf("SetStudentPhotoURL: exec: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("SetStudentPhotoURL: rows affected: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w with id: %d", storage.ErrNotFound, id)
+	}
+
+	updated, err := p.GetStudentByID(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	p.notify(func(h storage.Hook) { h.OnUpdate(ctx, old, updated) })
+
+	return nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// SetStudentStatus marks a student active, inactive or suspended.
+// ─────────────────────────────────────────────────────────────────────────────
+func (p *Postgres) SetStudentStatus(ctx context.Context, tenantID string, id int64, status string) error {
+	old, err := p.GetStudentByID(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+
+	result, err := p.Db.ExecContext(ctx,
+		"UPDATE students SET status = $1, updated_at = now(), version = version + 1 WHERE id = $2 AND tenant_id = $3 AND deleted_at IS NULL", status, id, tenantID)
+	if err != nil {
+		return fmt.Errorf("SetStudentStatus: exec: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("SetStudentStatus: rows affected: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w with id: %d", storage.ErrNotFound, id)
+	}
+
+	updated, err := p.GetStudentByID(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	p.notify(func(h storage.Hook) { h.OnUpdate(ctx, old, updated) })
+
+	return nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// UpsertStudents inserts each student, or updates the existing row with
+// the same email in the tenant, inside a single transaction:
+//
+//	INSERT ... ON CONFLICT (tenant_id, (lower(email))) WHERE deleted_at IS NULL DO UPDATE SET name = EXCLUDED.name, ...
+//
+// As in SQLite, each email is looked up first to report whether the row
+// was created or updated, and hooks are notified after Commit.
+// ─────────────────────────────────────────────────────────────────────────────
+func (p *Postgres) UpsertStudents(ctx context.Context, tenantID string, students []types.Student) ([]types.UpsertResult, error) {
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("UpsertStudents: begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	lookup, err := tx.PrepareContext(ctx,
+		"SELECT "+studentColumns+" FROM students WHERE tenant_id = $1 AND lower(email) = $2 AND deleted_at IS NULL")
+	if err != nil {
+		return nil, fmt.Errorf("UpsertStudents: prepare lookup: %w", err)
+	}
+	defer lookup.Close()
+
+	upsert, err := tx.PrepareContext(ctx, `
+		INSERT INTO students (tenant_id, name, email, age, department, phone) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (tenant_id, (lower(email))) WHERE deleted_at IS NULL DO UPDATE
+			SET name = EXCLUDED.name, age = EXCLUDED.age, department = EXCLUDED.department, phone = EXCLUDED.phone,
+				updated_at = now(), version = version + 1
+		RETURNING id, created_at, updated_at, version
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("UpsertStudents: prepare upsert: %w", err)
+	}
+	defer upsert.Close()
+
+	results := make([]types.UpsertResult, 0, len(students))
+	var events []func(storage.Hook)
+
+	for _, student := range students {
+		action := types.UpsertActionUpdated
+		email := utils.NormalizeEmail(student.Email)
+
+		old, err := scanStudent(lookup.QueryRowContext(ctx, tenantID, email))
+		if errors.Is(err, sql.ErrNoRows) {
+			action = types.UpsertActionCreated
+		} else if err != nil {
+			return nil, fmt.Errorf("UpsertStudents: lookup: %w", err)
+		}
+
+		var (
+			id                   int64
+			createdAt, updatedAt time.Time
+			version              int
+		)
+		err = upsert.QueryRowContext(ctx, tenantID, student.Name, email, student.Age, student.Department, student.Phone).Scan(
+			&id, &createdAt, &updatedAt, &version)
+		if err != nil {
+			return nil, fmt.Errorf("UpsertStudents: upsert: %w", err)
+		}
+
+		results = append(results, types.UpsertResult{StudentID: id, Email: email, Action: action})
+
+		current := types.Student{
+			ID: int(id), Name: student.Name, Email: email, Age: student.Age,
+			PhotoURL: old.PhotoURL, ExternalID: old.ExternalID, Department: student.Department, Phone: student.Phone,
+			CreatedAt: createdAt.UTC(), UpdatedAt: updatedAt.UTC(), Version: version,
+			Status: cmp.Or(old.Status, types.StudentStatusActive), TenantID: tenantID,
+		}
+		if action == types.UpsertActionCreated {
+			events = append(events, func(h storage.Hook) { h.OnCreate(ctx, current) })
+		} else {
+			events = append(events, func(h storage.Hook) { h.OnUpdate(ctx, old, current) })
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("UpsertStudents: commit: %w", err)
+	}
+
+	for _, event := range events {
+		p.notify(event)
+	}
+
+	return results, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// BulkCreateStudents inserts a batch of students in one transaction. See
+// the SQLite version for the contract.
+//
+// Unlike SQLite, PostgreSQL aborts the whole transaction when any
+// statement in it fails. Each insert therefore runs under a SAVEPOINT, so
+// a duplicate email can be rolled back on its own and the batch goes on.
+// ─────────────────────────────────────────────────────────────────────────────
+func (p *Postgres) BulkCreateStudents(ctx context.Context, tenantID string, students []types.Student) ([]int64, error) {
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("BulkCreateStudents: begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx,
+		"INSERT INTO students (tenant_id, name, email, age, department, phone) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at, version")
+	if err != nil {
+		return nil, fmt.Errorf("BulkCreateStudents: prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	ids := make([]int64, len(students))
+	failed := make(map[int]error)
+	var events []func(storage.Hook)
+
+	for i, student := range students {
+		email := utils.NormalizeEmail(student.Email)
+
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT bulk_row"); err != nil {
+			return nil, fmt.Errorf("BulkCreateStudents: savepoint: %w", err)
+		}
+
+		created := types.Student{Name: student.Name, Email: email, Age: student.Age, Department: student.Department, Phone: student.Phone, Status: types.StudentStatusActive, TenantID: tenantID}
+		err := stmt.QueryRowContext(ctx, tenantID, student.Name, email, student.Age, student.Department, student.Phone).Scan(
+			&ids[i], &created.CreatedAt, &created.UpdatedAt, &created.Version)
+		if err != nil {
+			if !isUniqueViolation(err) {
+				return nil, fmt.Errorf("BulkCreateStudents: insert row %d: %w", i, err)
+			}
+			if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT bulk_row"); err != nil {
+				return nil, fmt.Errorf("BulkCreateStudents: rollback to savepoint: %w", err)
+			}
+			ids[i] = 0
+			failed[i] = storage.ErrDuplicateEmail
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT bulk_row"); err != nil {
+			return nil, fmt.Errorf("BulkCreateStudents: release savepoint: %w", err)
+		}
+
+		created.ID = int(ids[i])
+		created.CreatedAt, created.UpdatedAt = created.CreatedAt.UTC(), created.UpdatedAt.UTC()
+		events = append(events, func(h storage.Hook) { h.OnCreate(ctx, created) })
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("BulkCreateStudents: commit: %w", err)
+	}
+
+	for _, event := range events {
+		p.notify(event)
+	}
+
+	if len(failed) > 0 {
+		return ids, &storage.BulkError{Rows: failed}
+	}
+	return ids, nil
+}
+
+// GetStudentByExternalID fetches the tenant's student with the given
+// external_id.
+func (p *Postgres) GetStudentByExternalID(ctx context.Context, tenantID string, externalID string) (types.Student, error) {
+	row := p.Db.QueryRowContext(ctx,
+		"SELECT "+studentColumns+" FROM students WHERE tenant_id = $1 AND external_id = $2 AND deleted_at IS NULL", tenantID, externalID)
+
+	student, err := scanStudent(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.Student{}, fmt.Errorf("%w with external_id: %q", storage.ErrNotFound, externalID)
+		}
+		return types.Student{}, fmt.Errorf("GetStudentByExternalID: scan: %w", err)
+	}
+	return student, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ImportStudents writes a batch of imported students in one transaction:
+// upserted on external_id when they have one, inserted otherwise. See the
+// SQLite version for the details.
+// ─────────────────────────────────────────────────────────────────────────────
+func (p *Postgres) ImportStudents(ctx context.Context, tenantID string, students []types.Student) ([]types.UpsertResult, error) {
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ImportStudents: begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	lookup, err := tx.PrepareContext(ctx,
+		"SELECT "+studentColumns+" FROM students WHERE tenant_id = $1 AND external_id = $2 AND deleted_at IS NULL")
+	if err != nil {
+		return nil, fmt.Errorf("ImportStudents: prepare lookup: %w", err)
+	}
+	defer lookup.Close()
+
+	upsert, err := tx.PrepareContext(ctx, `
+		INSERT INTO students (tenant_id, name, email, age, department, phone, external_id) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (tenant_id, external_id) WHERE deleted_at IS NULL DO UPDATE
+			SET name = EXCLUDED.name, email = EXCLUDED.email, age = EXCLUDED.age,
+				department = EXCLUDED.department, phone = EXCLUDED.phone,
+				updated_at = now(), version = version + 1
+		RETURNING id, created_at, updated_at, version
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("ImportStudents: prepare upsert: %w", err)
+	}
+	defer upsert.Close()
+
+	insert, err := tx.PrepareContext(ctx,
+		"INSERT INTO students (tenant_id, name, email, age, department, phone) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at, version")
+	if err != nil {
+		return nil, fmt.Errorf("ImportStudents: prepare insert: %w", err)
+	}
+	defer insert.Close()
+
+	results := make([]types.UpsertResult, 0, len(students))
+	var events []func(storage.Hook)
+
+	for _, student := range students {
+		email := utils.NormalizeEmail(student.Email)
+		action := types.UpsertActionCreated
+
+		var (
+			id                   int64
+			createdAt, updatedAt time.Time
+			version              int
+			old                  types.Student
+		)
+		if student.ExternalID != nil {
+			old, err = scanStudent(lookup.QueryRowContext(ctx, tenantID, *student.ExternalID))
+			switch {
+			case err == nil:
+				action = types.UpsertActionUpdated
+			case !errors.Is(err, sql.ErrNoRows):
+				return nil, fmt.Errorf("ImportStudents: lookup: %w", err)
+			}
+
+			err = upsert.QueryRowContext(ctx, tenantID, student.Name, email, student.Age, student.Department, student.Phone, *student.ExternalID).Scan(
+				&id, &createdAt, &updatedAt, &version)
+			if err != nil {
+				if isUniqueViolation(err) {
+					return nil, fmt.Errorf("%w: %s", storage.ErrDuplicateEmail, email)
+				}
+				return nil, fmt.Errorf("ImportStudents: upsert: %w", err)
+			}
+		} else {
+			err := insert.QueryRowContext(ctx, tenantID, student.Name, email, student.Age, student.Department, student.Phone).Scan(
+				&id, &createdAt, &updatedAt, &version)
+			if err != nil {
+				if isUniqueViolation(err) {
+					return nil, fmt.Errorf("%w: %s", storage.ErrDuplicateEmail, email)
+				}
+				return nil, fmt.Errorf("ImportStudents: insert: %w", err)
+			}
+		}
+
+		results = append(results, types.UpsertResult{StudentID: id, Email: email, Action: action})
+
+		current := types.Student{
+			ID: int(id), Name: student.Name, Email: email, Age: student.Age,
+			PhotoURL: old.PhotoURL, ExternalID: student.ExternalID, Department: student.Department, Phone: student.Phone,
+			CreatedAt: createdAt.UTC(), UpdatedAt: updatedAt.UTC(), Version: version,
+			Status: cmp.Or(old.Status, types.StudentStatusActive), TenantID: tenantID,
+		}
+		if action == types.UpsertActionCreated {
+			events = append(events, func(h storage.Hook) { h.OnCreate(ctx, current) })
+		} else {
+			events = append(events, func(h storage.Hook) { h.OnUpdate(ctx, old, current) })
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ImportStudents: commit: %w", err)
+	}
+
+	for _, event := range events {
+		p.notify(event)
+	}
+
+	return results, nil
+}
+
+// CountStudents returns the number of live (not deleted) students of a tenant.
+func (p *Postgres) CountStudents(ctx context.Context, tenantID string) (int64, error) {
+	n, err := countStudents(ctx, p.Db, tenantID, types.FilterOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("CountStudents: %w", err)
+	}
+	return n, nil
+}
+
+// rowQuerier is a *sql.DB or a *sql.Tx; see countStudents.
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// countStudents counts the tenant's live students matching opts'
+// filters, for CountStudents and GetStudentsFiltered alike. See the
+// SQLite version.
+func countStudents(ctx context.Context, q rowQuerier, tenantID string, opts types.FilterOptions) (int64, error) {
+	where, args := filterWhere(tenantID, opts)
+	var n int64
+	err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM students"+where, args...).Scan(&n)
+	return n, err
+}
+
+// filterWhere builds the WHERE clause selecting the tenant's live
+// students that match opts' filters, numbering its placeholders from
+// $1, and the values for them.
+func filterWhere(tenantID string, opts types.FilterOptions) (string, []any) {
+	var (
+		conds = []string{"tenant_id = $1", "deleted_at IS NULL"}
+		args  = []any{tenantID}
+	)
+	// placeholder adds v to args and returns its $n.
+	placeholder := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if opts.Name != "" {
+		conds = append(conds, `name ILIKE `+placeholder(likeContains(opts.Name))+` ESCAPE '\'`)
+	}
+	if opts.Email != "" {
+		conds = append(conds, `email ILIKE `+placeholder(likeContains(opts.Email))+` ESCAPE '\'`)
+	}
+	if opts.AgeMin != nil {
+		conds = append(conds, "age >= "+placeholder(*opts.AgeMin))
+	}
+	if opts.AgeMax != nil {
+		conds = append(conds, "age <= "+placeholder(*opts.AgeMax))
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// CountAllStudents returns the number of live students across every
+// tenant, for the students_total metric. See the SQLite version.
+func (p *Postgres) CountAllStudents(ctx context.Context) (int64, error) {
+	var n int64
+	if err := p.Db.QueryRowContext(ctx, "SELECT COUNT(*) FROM students WHERE deleted_at IS NULL").Scan(&n); err != nil {
+		return 0, fmt.Errorf("CountAllStudents: %w", err)
+	}
+	return n, nil
+}
+
+// GetStudentStats is the SQLite query as is. AVG of an integer column is
+// NUMERIC in PostgreSQL; the driver scans it into a float.
+func (p *Postgres) GetStudentStats(ctx context.Context, tenantID string) (types.StudentStats, error) {
+	var (
+		stats          types.StudentStats
+		avg            sql.NullFloat64
+		minAge, maxAge sql.NullInt64
+	)
+	err := p.Db.QueryRowContext(ctx, `
+		SELECT COUNT(*), AVG(NULLIF(age, 0)), MIN(NULLIF(age, 0)), MAX(NULLIF(age, 0))
+		FROM students
+		WHERE tenant_id = $1 AND deleted_at IS NULL`, tenantID).Scan(&stats.Total, &avg, &minAge, &maxAge)
+	if err != nil {
+		return types.StudentStats{}, fmt.Errorf("GetStudentStats: %w", err)
+	}
+
+	stats.AvgAge = avg.Float64
+	stats.MinAge = int(minAge.Int64)
+	stats.MaxAge = int(maxAge.Int64)
+	return stats, nil
+}
+
+// Ping checks that the database server can be reached.
+func (p *Postgres) Ping(ctx context.Context) error {
+	if err := p.Db.PingContext(ctx); err != nil {
+		return fmt.Errorf("Ping: %w", err)
+	}
+	return nil
+}
+
+// Close closes the connection pool, waiting for queries in progress.
+func (p *Postgres) Close() error {
+	if err := p.Db.Close(); err != nil {
+		return fmt.Errorf("Close: %w", err)
+	}
+	return nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Helpers
+// ─────────────────────────────────────────────────────────────────────────────
+
+// scanStudent reads one studentColumns row.
+func scanStudent(row interface{ Scan(...any) error }) (types.Student, error) {
+	var student types.Student
+	err := row.Scan(
+		&student.ID,
+		&student.Name,
+		&student.Email,
+		&student.Age,
+		&student.PhotoURL,
+		&student.ExternalID,
+		&student.Department,
+		&student.Phone,
+		&student.CreatedAt,
+		&student.UpdatedAt,
+		&student.Version,
+		&student.Status,
+		&student.TenantID,
+	)
+	// lib/pq returns times in the session's time zone.
+	student.CreatedAt, student.UpdatedAt = student.CreatedAt.UTC(), student.UpdatedAt.UTC()
+	return student, err
+}
+
+// scanStudents reads every row of a studentColumns query. It returns an
+// empty (non-nil) slice when there are none, so the JSON is [] not null.
+func scanStudents(rows *sql.Rows) ([]types.Student, error) {
+	students := make([]types.Student, 0)
+	for rows.Next() {
+		student, err := scanStudent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		students = append(students, student)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration: %w", err)
+	}
+	return students, nil
+}
+
+// sortColumns maps sort keys to columns, the only column names put into
+// an ORDER BY.
+var sortColumns = map[string]string{
+	"id":         "id",
+	"name":       "name",
+	"email":      "email",
+	"age":        "age",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// defaultOrderBy lists students newest first, as in SQLite.
+const defaultOrderBy = " ORDER BY created_at DESC, id DESC"
+
+// orderByClause builds " ORDER BY ..." for sort, always ending in id so
+// ties come back in a fixed order. An empty sort means defaultOrderBy.
+func orderByClause(sort types.SortOptions) (string, error) {
+	if len(sort.Fields) == 0 {
+		return defaultOrderBy, nil
+	}
+
+	terms := make([]string, 0, len(sort.Fields)+1)
+	hasID := false
+	for _, field := range sort.Fields {
+		column, ok := sortColumns[field.Key]
+		if !ok {
+			return "", fmt.Errorf("%w: can't sort by %q", storage.ErrUnsupportedFilter, field.Key)
+		}
+		direction := " ASC"
+		if field.Desc {
+			direction = " DESC"
+		}
+		terms = append(terms, column+direction)
+		hasID = hasID || column == "id"
+	}
+	if !hasID {
+		terms = append(terms, "id ASC")
+	}
+	return " ORDER BY " + strings.Join(terms, ", "), nil
+}
+
+// likeContains turns s into a pattern matching any value containing s,
+// with LIKE's wildcards in s escaped (the queries use ESCAPE '\').
+func likeContains(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+	return "%" + s + "%"
+}
+
+// isUniqueViolation reports whether err is PostgreSQL's unique_violation
+// (SQLSTATE 23505).
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// The methods below satisfy webhook.Store. They work like the SQLite
+// ones; see there for the details.
+
+// CreateWebhook stores a tenant's subscription and returns it with its
+// new ID and creation time.
+func (p *Postgres) CreateWebhook(ctx context.Context, tenantID string, sub types.WebhookSubscription) (types.WebhookSubscription, error) {
+	err := p.Db.QueryRowContext(ctx, `
+		INSERT INTO webhooks (tenant_id, url, secret) VALUES ($1, $2, $3)
+		RETURNING id, created_at`, tenantID, sub.URL, sub.Secret).
+		Scan(&sub.ID, &sub.CreatedAt)
+	if err != nil {
+		return types.WebhookSubscription{}, fmt.Errorf("CreateWebhook: %w", err)
+	}
+	sub.CreatedAt = sub.CreatedAt.UTC()
+	return sub, nil
+}
+
+// GetWebhooks returns every subscription of the tenant, secrets
+// included, in id order.
+func (p *Postgres) GetWebhooks(ctx context.Context, tenantID string) ([]types.WebhookSubscription, error) {
+	rows, err := p.Db.QueryContext(ctx,
+		"SELECT id, url, secret, created_at FROM webhooks WHERE tenant_id = $1 ORDER BY id", tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("GetWebhooks: query: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []types.WebhookSubscription{}
+	for rows.Next() {
+		var sub types.WebhookSubscription
+		if err := rows.Scan(&sub.ID, &sub.URL, &sub.Secret, &sub.CreatedAt); err != nil {
+			return nil, fmt.Errorf("GetWebhooks: scan: %w", err)
+		}
+		sub.CreatedAt = sub.CreatedAt.UTC()
+		subs = append(subs, sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetWebhooks: rows: %w", err)
+	}
+	return subs, nil
+}
+
+// DeleteWebhook removes a tenant's subscription, or returns
+// storage.ErrNotFound.
+func (p *Postgres) DeleteWebhook(ctx context.Context, tenantID string, id int64) error {
+	res, err := p.Db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1 AND tenant_id = $2", id, tenantID)
+	if err != nil {
+		return fmt.Errorf("DeleteWebhook: exec: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("DeleteWebhook: rows affected: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("DeleteWebhook: %w", storage.ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// InsertAuditEntry appends one entry to the audit_log table.
+// It satisfies audit.Writer.
+func (s *SQLite) InsertAuditEntry(ctx context.Context, entry types.AuditEntry) error {
+	_, err := s.Db.ExecContext(ctx, `
+		INSERT INTO audit_log (
+			action, student_id, timestamp, before, after, request_body,
+			response_status, client_ip, user_agent, request_id, duration_ms
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.Action,
+		entry.StudentID,
+		entry.Timestamp.Format(time.RFC3339Nano),
+		nullableJSON(entry.Before),
+		nullableJSON(entry.After),
+		nullableJSON(entry.RequestBody),
+		entry.ResponseStatus,
+		entry.ClientIP,
+		entry.UserAgent,
+		entry.RequestID,
+		entry.Duration.Milliseconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("InsertAuditEntry: exec: %w", err)
+	}
+
+	return nil
+}
+
+// GetAuditLog returns the audit entries for one student, oldest first.
+// audit_log has no tenant of its own: the join keeps only entries whose
+// student belongs to tenantID.
+func (s *SQLite) GetAuditLog(ctx context.Context, tenantID string, studentID int64) ([]types.AuditEntry, error) {
+	rows, err := s.Db.QueryContext(ctx, `
+		SELECT a.id, a.action, a.student_id, a.timestamp, a.before, a.after, a.request_body,
+		       a.response_status, a.client_ip, a.user_agent, a.request_id, a.duration_ms
+		FROM audit_log a
+		JOIN students s ON s.id = a.student_id
+		WHERE a.student_id = ? AND s.tenant_id = ?
+		ORDER BY a.id`, studentID, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("GetAuditLog: query: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []types.AuditEntry{}
+	for rows.Next() {
+		var (
+			entry                  types.AuditEntry
+			timestamp              string
+			before, after, reqBody sql.NullString
+			durationMs             int64
+		)
+		if err := rows.Scan(&entry.ID, &entry.Action, &entry.StudentID, &timestamp,
+			&before, &after, &reqBody, &entry.ResponseStatus, &entry.ClientIP,
+			&entry.UserAgent, &entry.RequestID, &durationMs); err != nil {
+			return nil, fmt.Errorf("GetAuditLog: scan: %w", err)
+		}
+
+		// Written by InsertAuditEntry, so always RFC 3339.
+		if entry.Timestamp, err = time.Parse(time.RFC3339Nano, timestamp); err != nil {
+			return nil, fmt.Errorf("GetAuditLog: parse timestamp: %w", err)
+		}
+		entry.Before = rawJSON(before)
+		entry.After = rawJSON(after)
+		entry.RequestBody = rawJSON(reqBody)
+		entry.Duration = time.Duration(durationMs) * time.Millisecond
+
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetAuditLog: rows: %w", err)
+	}
+
+	return entries, nil
+}
+
+// nullableJSON stores empty JSON as SQL NULL rather than an empty string.
+func nullableJSON(raw json.RawMessage) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
+
+// rawJSON is the reverse of nullableJSON: SQL NULL becomes empty JSON.
+func rawJSON(s sql.NullString) json.RawMessage {
+	if !s.Valid {
+		return nil
+	}
+	return json.RawMessage(s.String)
+}
@@ -0,0 +1,128 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/config"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// newStoreWith opens a fresh, migrated database in t.TempDir() with the
+// given SQLite and pool settings. It is closed when the test ends.
+func newStoreWith(t *testing.T, sqlite config.SQLite, database config.Database) *SQLite {
+	t.Helper()
+
+	database.AutoMigrate = true
+	db, err := New(&config.Config{
+		StoragePath: filepath.Join(t.TempDir(), "test.db"),
+		Database:    database,
+		SQLite:      sqlite,
+	})
+	if err != nil {
+		t.Fatalf("sqlite.New: %v", err)
+	}
+	t.Cleanup(func() { db.Db.Close() })
+	return db
+}
+
+// TestConcurrentInserts has 10 goroutines create a student at the same
+// time. None may fail with SQLITE_BUSY ("database is locked"), in either
+// journal mode.
+func TestConcurrentInserts(t *testing.T) {
+	const writers = 10
+
+	for _, mode := range []string{config.JournalModeWAL, config.JournalModeDelete} {
+		t.Run(mode, func(t *testing.T) {
+			db := newStoreWith(t, config.SQLite{JournalMode: mode, BusyTimeoutMs: 5000}, config.Database{})
+			ctx := context.Background()
+
+			// SQLite reports the mode in lower case.
+			if got := db.JournalMode(); !strings.EqualFold(got, mode) {
+				t.Fatalf("JournalMode = %q, want %s", got, mode)
+			}
+
+			// start releases every goroutine at once, so the inserts
+			// really overlap.
+			start := make(chan struct{})
+			errs := make(chan error, writers)
+			var wg sync.WaitGroup
+			for i := range writers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					<-start
+					_, err := db.CreateStudent(ctx, types.DefaultTenant,
+						fmt.Sprintf("Student %d", i), fmt.Sprintf("s%d@test.com", i), 20+i, "", "")
+					errs <- err
+				}()
+			}
+			close(start)
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				if err != nil {
+					t.Errorf("CreateStudent: %v", err)
+				}
+			}
+			if n, err := db.CountStudents(ctx, types.DefaultTenant); err != nil || n != writers {
+				t.Errorf("CountStudents = %d, %v; want %d", n, err, writers)
+			}
+		})
+	}
+}
+
+// TestMaxOpenConnsSerializes checks that with database.max_open_conns 1
+// two queries run one after the other: while one holds the only
+// connection, the other waits for it instead of opening a second.
+func TestMaxOpenConnsSerializes(t *testing.T) {
+	db := newStoreWith(t, config.SQLite{JournalMode: config.JournalModeWAL, BusyTimeoutMs: 5000},
+		config.Database{MaxOpenConns: 1})
+	ctx := context.Background()
+
+	if got := db.Db.Stats().MaxOpenConnections; got != 1 {
+		t.Fatalf("MaxOpenConnections = %d, want 1", got)
+	}
+
+	// The first query: it holds the pool's one connection until released.
+	conn, err := db.Db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn: %v", err)
+	}
+	if _, err := conn.ExecContext(ctx, "SELECT 1"); err != nil {
+		t.Fatalf("first query: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := db.CountStudents(ctx, types.DefaultTenant)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("second query finished (err %v) while the first held the only connection", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if waits := db.Db.Stats().WaitCount; waits == 0 {
+		t.Error("WaitCount = 0, want the second query to have waited for a connection")
+	}
+
+	if err := conn.Close(); err != nil {
+		t.Fatalf("releasing the connection: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("second query: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("second query still blocked after the connection was released")
+	}
+}
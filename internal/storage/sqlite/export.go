@@ -0,0 +1,142 @@
+package sqlite
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// exportFileName is the name of the CSV file inside the export archive.
+const exportFileName = "students.csv"
+
+// exportQuery lists the columns in the order they appear in the CSV.
+const exportQuery = "SELECT id, name, email, age, COALESCE(photo_url, ''), department, phone, created_at, updated_at FROM students WHERE tenant_id = ? AND deleted_at IS NULL ORDER BY id"
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ExportStudents writes every student of the tenant to w as a tar.gz archive holding a
+// single students.csv file.
+//
+// Rows are streamed straight from the cursor into the archive, so memory
+// use stays flat however big the table is. The catch is that a tar header
+// must state the file size BEFORE the contents. So the CSV is generated
+// twice inside one transaction:
+//
+//  1. into a byte counter, to learn the size;
+//  2. into the archive, for real.
+//
+// The transaction makes both passes see the same snapshot of the table,
+// so the size can't change in between. Nothing is written to w until the
+// first pass has succeeded, so callers can still report early failures
+// as a normal error response.
+// ─────────────────────────────────────────────────────────────────────────────
+func (s *SQLite) ExportStudents(ctx context.Context, tenantID string, w io.Writer) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ExportStudents: begin tx: %w", err)
+	}
+	// Nothing is written, so rolling back is just "end the transaction".
+	defer tx.Rollback()
+
+	// ── Pass 1: measure ───────────────────────────────────────────────────
+	var size countingWriter
+	if err := writeExportCSV(ctx, tx, tenantID, &size); err != nil {
+		return fmt.Errorf("ExportStudents: measure: %w", err)
+	}
+
+	// ── Pass 2: stream ────────────────────────────────────────────────────
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	err = tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     exportFileName,
+		Mode:     0o644,
+		Size:     int64(size),
+		ModTime:  time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("ExportStudents: write tar header: %w", err)
+	}
+
+	if err := writeExportCSV(ctx, tx, tenantID, tw); err != nil {
+		return fmt.Errorf("ExportStudents: write csv: %w", err)
+	}
+
+	// Closing flushes the tar footer and the gzip trailer — without them
+	// the archive is truncated.
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("ExportStudents: close tar: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("ExportStudents: close gzip: %w", err)
+	}
+
+	return nil
+}
+
+// writeExportCSV runs the export query in tx and writes the result to w.
+func writeExportCSV(ctx context.Context, tx *sql.Tx, tenantID string, w io.Writer) error {
+	rows, err := tx.QueryContext(ctx, exportQuery, tenantID)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	return StudentCSVWriter(rows, w)
+}
+
+// StudentCSVWriter writes rows as CSV with a header line. rows must have
+// the columns id, name, email, age, photo_url, department, phone,
+// created_at, updated_at, in that order. The timestamps are written in
+// RFC 3339, UTC.
+//
+// Each record goes to w as soon as it is scanned; nothing is collected
+// in memory. The caller still owns rows and must close it.
+func StudentCSVWriter(rows *sql.Rows, w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write([]string{"id", "name", "email", "age", "photo_url", "department", "phone", "created_at", "updated_at"}); err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		var (
+			id                                  int64
+			age                                 int
+			name, email, url, department, phone string
+			createdAt, updatedAt                time.Time
+		)
+		if err := rows.Scan(&id, &name, &email, &age, &url, &department, &phone,
+			timestamp{&createdAt}, timestamp{&updatedAt}); err != nil {
+			return fmt.Errorf("scan row: %w", err)
+		}
+
+		record := []string{strconv.FormatInt(id, 10), name, email, strconv.Itoa(age), url, department, phone,
+			createdAt.Format(time.RFC3339), updatedAt.Format(time.RFC3339)}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration: %w", err)
+	}
+
+	// csv.Writer buffers internally; Flush pushes the rest to w.
+	cw.Flush()
+	return cw.Error()
+}
+
+// countingWriter discards everything written to it but remembers how
+// many bytes that was.
+type countingWriter int64
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	*c += countingWriter(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,41 @@
+package sqlite
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/aanand-mishra/students-api/internal/storage"
+)
+
+// RegisterHook adds hook to the list notified after every successful
+// mutation. Safe to call while requests are being served.
+func (s *SQLite) RegisterHook(hook storage.Hook) {
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+
+	s.hooks = append(s.hooks, hook)
+}
+
+// notify calls fn once per registered hook, in registration order.
+//
+// Each call is isolated with recover(): the mutation has already been
+// committed, so a misbehaving hook must not turn a successful write into
+// a failed request or stop the hooks after it from running.
+func (s *SQLite) notify(fn func(storage.Hook)) {
+	s.hooksMu.RLock()
+	hooks := append([]storage.Hook(nil), s.hooks...)
+	s.hooksMu.RUnlock()
+
+	for _, hook := range hooks {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					slog.Error("storage hook panicked",
+						slog.String("hook", fmt.Sprintf("%T", hook)),
+						slog.Any("panic", r))
+				}
+			}()
+			fn(hook)
+		}()
+	}
+}
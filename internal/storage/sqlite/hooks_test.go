@@ -0,0 +1,125 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// recordingHook appends "<name>:<event>" to a shared log for every call.
+type recordingHook struct {
+	name string
+	mu   *sync.Mutex
+	log  *[]string
+}
+
+func (h recordingHook) record(event string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.log = append(*h.log, h.name+":"+event)
+}
+
+func (h recordingHook) OnCreate(ctx context.Context, s types.Student) {
+	h.record("create " + s.Email)
+}
+
+func (h recordingHook) OnUpdate(ctx context.Context, old, new types.Student) {
+	h.record(fmt.Sprintf("update %d->%d", old.Age, new.Age))
+}
+
+func (h recordingHook) OnDelete(ctx context.Context, tenantID string, id int64) {
+	h.record(fmt.Sprintf("delete %d", id))
+}
+
+// panickingHook panics on every call.
+type panickingHook struct{}
+
+func (panickingHook) OnCreate(context.Context, types.Student)                { panic("boom") }
+func (panickingHook) OnUpdate(context.Context, types.Student, types.Student) { panic("boom") }
+func (panickingHook) OnDelete(context.Context, string, int64)                { panic("boom") }
+
+func TestHooksFireInOrder(t *testing.T) {
+	db := newTestStore(t)
+	ctx := context.Background()
+
+	var (
+		mu  sync.Mutex
+		log []string
+	)
+	db.RegisterHook(recordingHook{"first", &mu, &log})
+	db.RegisterHook(recordingHook{"second", &mu, &log})
+
+	id := mustCreate(t, db, "Rakesh", "rakesh@test.com", 35)
+
+	s, err := db.GetStudentByID(ctx, types.DefaultTenant, id)
+	if err != nil {
+		t.Fatalf("GetStudentByID: %v", err)
+	}
+	s.Age = 36
+	if _, err := db.UpdateStudentByID(ctx, types.DefaultTenant, id, s); err != nil {
+		t.Fatalf("UpdateStudentByID: %v", err)
+	}
+	if err := db.DeleteStudentByID(ctx, types.DefaultTenant, id); err != nil {
+		t.Fatalf("DeleteStudentByID: %v", err)
+	}
+
+	want := []string{
+		"first:create rakesh@test.com", "second:create rakesh@test.com",
+		"first:update 35->36", "second:update 35->36",
+		fmt.Sprintf("first:delete %d", id), fmt.Sprintf("second:delete %d", id),
+	}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("hook calls =\n%q\nwant\n%q", log, want)
+	}
+}
+
+func TestHooksNotCalledOnFailure(t *testing.T) {
+	db := newTestStore(t)
+
+	var (
+		mu  sync.Mutex
+		log []string
+	)
+	mustCreate(t, db, "Rakesh", "rakesh@test.com", 35)
+	db.RegisterHook(recordingHook{"hook", &mu, &log})
+
+	// A duplicate email is refused, so nothing was created.
+	if _, err := db.CreateStudent(context.Background(), types.DefaultTenant, "Rakesh", "rakesh@test.com", 35, "", ""); err == nil {
+		t.Fatal("duplicate CreateStudent succeeded")
+	}
+	if err := db.DeleteStudentByID(context.Background(), types.DefaultTenant, 999); err == nil {
+		t.Fatal("DeleteStudentByID(999) succeeded")
+	}
+
+	if len(log) != 0 {
+		t.Errorf("hooks called for failed mutations: %q", log)
+	}
+}
+
+func TestPanickingHookDoesNotBreakMutation(t *testing.T) {
+	db := newTestStore(t)
+
+	var (
+		mu  sync.Mutex
+		log []string
+	)
+	db.RegisterHook(panickingHook{})
+	db.RegisterHook(recordingHook{"after", &mu, &log})
+
+	id, err := db.CreateStudent(context.Background(), types.DefaultTenant, "Rakesh", "rakesh@test.com", 35, "", "")
+	if err != nil {
+		t.Fatalf("CreateStudent with a panicking hook: %v", err)
+	}
+	if _, err := db.GetStudentByID(context.Background(), types.DefaultTenant, id); err != nil {
+		t.Errorf("student was not stored: %v", err)
+	}
+
+	// The hook registered after the panicking one still ran.
+	if want := []string{"after:create rakesh@test.com"}; !reflect.DeepEqual(log, want) {
+		t.Errorf("hook calls = %q, want %q", log, want)
+	}
+}
@@ -0,0 +1,102 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// The methods below satisfy middleware.IdempotencyStore.
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ClaimIdempotencyKey marks key as in flight until expiresAt, unless a
+// live entry for it exists already. It reports whether this call got
+// the key.
+//
+// Claiming is a single INSERT ... ON CONFLICT DO NOTHING, so of two
+// requests racing with the same key exactly one gets it. Expired entries
+// are cleared first, in the same transaction — they would block the
+// INSERT, and it keeps the table from growing forever.
+// ─────────────────────────────────────────────────────────────────────────────
+func (s *SQLite) ClaimIdempotencyKey(ctx context.Context, key string, expiresAt time.Time) (bool, error) {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("ClaimIdempotencyKey: begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM idempotency_cache WHERE expires_at <= ?", time.Now().UnixMilli()); err != nil {
+		return false, fmt.Errorf("ClaimIdempotencyKey: purge: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO idempotency_cache (key, expires_at) VALUES (?, ?)
+		ON CONFLICT(key) DO NOTHING`, key, expiresAt.UnixMilli())
+	if err != nil {
+		return false, fmt.Errorf("ClaimIdempotencyKey: insert: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ClaimIdempotencyKey: rows affected: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("ClaimIdempotencyKey: commit: %w", err)
+	}
+	return n == 1, nil
+}
+
+// GetIdempotencyResult returns the live entry for key: a stored
+// response, or one still in flight. Returns storage.ErrNotFound if there
+// is none.
+func (s *SQLite) GetIdempotencyResult(ctx context.Context, key string) (types.IdempotencyResult, error) {
+	result := types.IdempotencyResult{Key: key}
+	var expiresAt int64
+	err := s.Db.QueryRowContext(ctx, `
+		SELECT status_code, content_type, response_body, expires_at
+		FROM idempotency_cache
+		WHERE key = ? AND expires_at > ?`, key, time.Now().UnixMilli()).
+		Scan(&result.StatusCode, &result.ContentType, &result.Body, &expiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.IdempotencyResult{}, storage.ErrNotFound
+		}
+		return types.IdempotencyResult{}, fmt.Errorf("GetIdempotencyResult: %w", err)
+	}
+
+	result.ExpiresAt = time.UnixMilli(expiresAt)
+	return result, nil
+}
+
+// StoreIdempotencyResult records the response to replay for result.Key
+// until result.ExpiresAt, completing a claimed key.
+func (s *SQLite) StoreIdempotencyResult(ctx context.Context, result types.IdempotencyResult) error {
+	_, err := s.Db.ExecContext(ctx, `
+		INSERT INTO idempotency_cache (key, status_code, content_type, response_body, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET
+			status_code   = excluded.status_code,
+			content_type  = excluded.content_type,
+			response_body = excluded.response_body,
+			expires_at    = excluded.expires_at`,
+		result.Key, result.StatusCode, result.ContentType, result.Body, result.ExpiresAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("StoreIdempotencyResult: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey forgets key, so the next request with it is
+// handled afresh.
+func (s *SQLite) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	if _, err := s.Db.ExecContext(ctx, "DELETE FROM idempotency_cache WHERE key = ?", key); err != nil {
+		return fmt.Errorf("ReleaseIdempotencyKey: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package sqlite
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/types"
+	"github.com/aanand-mishra/students-api/internal/utils"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// GetStudentByExternalID fetches the tenant's student with the given external_id.
+// ─────────────────────────────────────────────────────────────────────────────
+func (s *SQLite) GetStudentByExternalID(ctx context.Context, tenantID string, externalID string) (types.Student, error) {
+	var student types.Student
+
+	err := s.Db.QueryRowContext(ctx,
+		"SELECT id, name, email, age, COALESCE(photo_url, ''), external_id, department, phone, created_at, updated_at, version, status, tenant_id FROM students WHERE tenant_id = ? AND external_id = ? AND deleted_at IS NULL",
+		tenantID, externalID,
+	).Scan(&student.ID, &student.Name, &student.Email, &student.Age, &student.PhotoURL, &student.ExternalID, &student.Department, &student.Phone,
+		timestamp{&student.CreatedAt}, timestamp{&student.UpdatedAt}, &student.Version, &student.Status, &student.TenantID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return types.Student{}, fmt.Errorf("%w with external_id: %q", storage.ErrNotFound, externalID)
+		}
+		return types.Student{}, fmt.Errorf("GetStudentByExternalID: scan: %w", err)
+	}
+
+	return student, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ImportStudents writes a batch of imported students in one transaction.
+//
+// Students WITH an external ID are upserted on it:
+//
+//	INSERT ... ON CONFLICT(tenant_id, external_id) DO UPDATE SET name = excluded.name, ...
+//
+// so importing the same file twice updates the rows the first run
+// created instead of duplicating them. Students WITHOUT one are plain
+// inserts — there is nothing to match them on.
+//
+// Like UpsertStudents, each external ID is looked up first to report
+// whether the row was created or updated, and hooks are only notified
+// once the transaction has committed.
+// ─────────────────────────────────────────────────────────────────────────────
+func (s *SQLite) ImportStudents(ctx context.Context, tenantID string, students []types.Student) ([]types.UpsertResult, error) {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ImportStudents: begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	lookup, err := tx.PrepareContext(ctx,
+		"SELECT id, name, email, age, COALESCE(photo_url, ''), external_id, department, phone, created_at, updated_at, version, status, tenant_id FROM students WHERE tenant_id = ? AND external_id = ? AND deleted_at IS NULL")
+	if err != nil {
+		return nil, fmt.Errorf("ImportStudents: prepare lookup: %w", err)
+	}
+	defer lookup.Close()
+
+	upsert, err := tx.PrepareContext(ctx, `
+		INSERT INTO students (tenant_id, name, email, age, department, phone, external_id) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(tenant_id, external_id) WHERE deleted_at IS NULL DO UPDATE
+			SET name = excluded.name, email = excluded.email, age = excluded.age,
+				department = excluded.department, phone = excluded.phone,
+				updated_at = datetime('now'), version = version + 1
+		RETURNING id, created_at, updated_at, version
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("ImportStudents: prepare upsert: %w", err)
+	}
+	defer upsert.Close()
+
+	insert, err := tx.PrepareContext(ctx,
+		"INSERT INTO students (tenant_id, name, email, age, department, phone) VALUES (?, ?, ?, ?, ?, ?) RETURNING id, created_at, updated_at, version")
+	if err != nil {
+		return nil, fmt.Errorf("ImportStudents: prepare insert: %w", err)
+	}
+	defer insert.Close()
+
+	results := make([]types.UpsertResult, 0, len(students))
+	var events []func(storage.Hook)
+
+	for _, student := range students {
+		email := utils.NormalizeEmail(student.Email)
+		action := types.UpsertActionCreated
+
+		var (
+			id                   int64
+			createdAt, updatedAt time.Time
+			version              int
+			old                  types.Student
+		)
+		if student.ExternalID != nil {
+			err := lookup.QueryRowContext(ctx, tenantID, *student.ExternalID).Scan(
+				&old.ID, &old.Name, &old.Email, &old.Age, &old.PhotoURL, &old.ExternalID, &old.Department, &old.Phone,
+				timestamp{&old.CreatedAt}, timestamp{&old.UpdatedAt}, &old.Version, &old.Status, &old.TenantID)
+			switch {
+			case err == nil:
+				action = types.UpsertActionUpdated
+			case err != sql.ErrNoRows:
+				return nil, fmt.Errorf("ImportStudents: lookup: %w", err)
+			}
+
+			err = upsert.QueryRowContext(ctx, tenantID, student.Name, email, student.Age, student.Department, student.Phone, *student.ExternalID).Scan(
+				&id, timestamp{&createdAt}, timestamp{&updatedAt}, &version)
+			if err != nil {
+				if isUniqueViolation(err) {
+					return nil, fmt.Errorf("%w: %s", storage.ErrDuplicateEmail, email)
+				}
+				return nil, fmt.Errorf("ImportStudents: upsert: %w", err)
+			}
+		} else {
+			err := insert.QueryRowContext(ctx, tenantID, student.Name, email, student.Age, student.Department, student.Phone).Scan(
+				&id, timestamp{&createdAt}, timestamp{&updatedAt}, &version)
+			if err != nil {
+				if isUniqueViolation(err) {
+					return nil, fmt.Errorf("%w: %s", storage.ErrDuplicateEmail, email)
+				}
+				return nil, fmt.Errorf("ImportStudents: insert: %w", err)
+			}
+		}
+
+		results = append(results, types.UpsertResult{StudentID: id, Email: email, Action: action})
+
+		current := types.Student{
+			ID: int(id), Name: student.Name, Email: email, Age: student.Age,
+			PhotoURL: old.PhotoURL, ExternalID: student.ExternalID, Department: student.Department, Phone: student.Phone,
+			CreatedAt: createdAt, UpdatedAt: updatedAt, Version: version,
+			Status: cmp.Or(old.Status, types.StudentStatusActive), TenantID: tenantID,
+		}
+		if action == types.UpsertActionCreated {
+			events = append(events, func(h storage.Hook) { h.OnCreate(ctx, current) })
+		} else {
+			events = append(events, func(h storage.Hook) { h.OnUpdate(ctx, old, current) })
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ImportStudents: commit: %w", err)
+	}
+
+	for _, event := range events {
+		s.notify(event)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,261 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/aanand-mishra/students-api/internal/storage/sqlite/migrations"
+)
+
+// migration is one numbered .sql file of the schema's history.
+type migration struct {
+	version int
+	name    string // the file name, e.g. "004_add_deleted_at.sql"
+	sql     string
+}
+
+// migrationFile matches a migration's file name: the version, then a
+// description.
+var migrationFile = regexp.MustCompile(`^(\d+)_[\w-]+\.sql$`)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Migrate applies the numbered .sql files in migrationsDir that db hasn't
+// run yet, in order, each in its own transaction. An empty migrationsDir
+// means the migrations compiled into the binary (see the migrations
+// package), which is what the server uses unless database.migrations_dir
+// says otherwise.
+//
+// Applied migrations are recorded in the schema_migrations table:
+//
+//	version | name                    | applied_at
+//	--------+-------------------------+--------------------
+//	      4 | 004_add_deleted_at.sql  | 2026-10-15 09:12:44
+//
+// If one fails, the ones before it stay applied and the database is left
+// at the last good version. A database that has run migrations this
+// build doesn't have is refused: it was upgraded by a newer release.
+// ─────────────────────────────────────────────────────────────────────────────
+func Migrate(db *sql.DB, migrationsDir string) error {
+	ctx := context.Background()
+
+	all, version, err := schemaState(ctx, db, migrationsDir)
+	if err != nil {
+		return fmt.Errorf("Migrate: %w", err)
+	}
+	if version > len(all) {
+		return fmt.Errorf("Migrate: %w", errNewerSchema(version, len(all)))
+	}
+
+	if err := applyMigrations(ctx, db, all[version:]); err != nil {
+		return fmt.Errorf("Migrate: %w", err)
+	}
+	return nil
+}
+
+// schemaState loads the migrations in migrationsDir and reads how many of
+// them db has run.
+func schemaState(ctx context.Context, db *sql.DB, migrationsDir string) ([]migration, int, error) {
+	var fsys fs.FS = migrations.FS
+	if migrationsDir != "" {
+		fsys = os.DirFS(migrationsDir)
+	}
+
+	all, err := loadMigrations(fsys)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	version, err := schemaVersion(ctx, db, all)
+	if err != nil {
+		return nil, 0, err
+	}
+	return all, version, nil
+}
+
+// errNewerSchema is the refusal to open a database a newer release has
+// upgraded; this one would misread it.
+func errNewerSchema(version, latest int) error {
+	return fmt.Errorf("database schema version %d is newer than this build supports (%d): "+
+		"run a newer release, or restore a backup taken before the upgrade", version, latest)
+}
+
+// loadMigrations reads the .sql files in fsys, in version order. Versions
+// must run 1, 2, 3… without gaps or repeats, so a missing or misnumbered
+// file fails at startup instead of leaving a hole in the schema.
+func loadMigrations(fsys fs.FS) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+
+	var all []migration
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		m := migrationFile.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue // not a migration, e.g. a README
+		}
+		version, err := strconv.Atoi(m[1])
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: bad version: %w", entry.Name(), err)
+		}
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s: %w", entry.Name(), err)
+		}
+		all = append(all, migration{version: version, name: entry.Name(), sql: string(body)})
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].version < all[j].version })
+	for i, m := range all {
+		if m.version != i+1 {
+			return nil, fmt.Errorf("migration %s: expected version %d", m.name, i+1)
+		}
+	}
+	return all, nil
+}
+
+// schemaVersion returns the highest version recorded in schema_migrations,
+// creating the table first if needed.
+//
+// Before the table existed the version was kept in PRAGMA user_version
+// (0 in a brand-new file). The first time round, rows for versions 1 to
+// user_version are filled in from it, named after all's files.
+func schemaVersion(ctx context.Context, db *sql.DB, all []migration) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("schema version: begin tx: %w", err)
+	}
+	defer tx.Rollback() // no-op after a successful Commit
+
+	_, err = tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    INTEGER  PRIMARY KEY,
+			name       TEXT     NOT NULL DEFAULT '',
+			applied_at DATETIME NOT NULL DEFAULT (datetime('now'))
+		)`)
+	if err != nil {
+		return 0, fmt.Errorf("schema version: create schema_migrations: %w", err)
+	}
+
+	var recorded sql.NullInt64
+	if err := tx.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_migrations").Scan(&recorded); err != nil {
+		return 0, fmt.Errorf("schema version: read schema_migrations: %w", err)
+	}
+	if recorded.Valid {
+		return int(recorded.Int64), nil
+	}
+
+	var userVersion int
+	if err := tx.QueryRowContext(ctx, "PRAGMA user_version").Scan(&userVersion); err != nil {
+		return 0, fmt.Errorf("schema version: read user_version: %w", err)
+	}
+	for v := 1; v <= userVersion; v++ {
+		name := ""
+		if v <= len(all) {
+			name = all[v-1].name
+		}
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO schema_migrations (version, name) VALUES (?, ?)", v, name); err != nil {
+			return 0, fmt.Errorf("schema version: record version %d: %w", v, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("schema version: commit: %w", err)
+	}
+	return userVersion, nil
+}
+
+// applyMigrations runs pending, in order, stopping at the first failure.
+func applyMigrations(ctx context.Context, db *sql.DB, pending []migration) error {
+	for _, m := range pending {
+		if err := applyMigration(ctx, db, m); err != nil {
+			return fmt.Errorf("migration %s: %w", m.name, err)
+		}
+		slog.Debug("applied migration", slog.String("migration", m.name))
+	}
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() // no-op after a successful Commit
+
+	if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+		return err
+	}
+
+	// Databases from before versioning may have a students table from
+	// before photos; CREATE TABLE IF NOT EXISTS leaves it as it is.
+	if m.version == 1 {
+		if err := addColumnIfMissing(ctx, tx, "students", "photo_url", "TEXT"); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO schema_migrations (version, name) VALUES (?, ?)", m.version, m.name); err != nil {
+		return fmt.Errorf("record migration: %w", err)
+	}
+
+	// user_version is kept in step, so older builds, which only know
+	// that, still see the version. PRAGMA values can't be bound as ?
+	// parameters; version is parsed from a file name, never user input.
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", m.version)); err != nil {
+		return fmt.Errorf("set user_version: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// addColumnIfMissing runs ALTER TABLE ... ADD COLUMN unless the column is
+// already present. PRAGMA table_info lists one row per existing column.
+func addColumnIfMissing(ctx context.Context, tx *sql.Tx, table, column, definition string) error {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("table info %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name, typ  string
+			notNull    int
+			defaultVal sql.NullString
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &defaultVal, &pk); err != nil {
+			return fmt.Errorf("table info %s: scan: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("table info %s: %w", table, err)
+	}
+	rows.Close() // release the cursor before altering the table
+
+	// Table and column names can't be bound as ? parameters; these come
+	// from constants in this package, never from user input.
+	_, err = tx.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	if err != nil {
+		return fmt.Errorf("add column %s.%s: %w", table, column, err)
+	}
+
+	return nil
+}
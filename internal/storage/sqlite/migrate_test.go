@@ -0,0 +1,329 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/aanand-mishra/students-api/internal/config"
+	"github.com/aanand-mishra/students-api/internal/storage/sqlite/migrations"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// latestVersion is the number of migrations compiled into the binary.
+func latestVersion(t *testing.T) int {
+	t.Helper()
+
+	all, err := loadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	return len(all)
+}
+
+// openAt opens the database file at path with New.
+func openAt(path string, autoMigrate bool) (*SQLite, error) {
+	return New(&config.Config{
+		StoragePath: path,
+		Database:    config.Database{AutoMigrate: autoMigrate},
+		SQLite:      config.SQLite{JournalMode: config.JournalModeWAL, BusyTimeoutMs: 5000},
+	})
+}
+
+// rawOpen opens the file at path without migrating it.
+func rawOpen(t *testing.T, path string) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// rawExec runs statements on the file at path without migrating it.
+func rawExec(t *testing.T, path string, statements ...string) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+}
+
+// recorded returns the names in schema_migrations, in version order.
+func recorded(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+
+	rows, err := db.Query("SELECT name FROM schema_migrations ORDER BY version")
+	if err != nil {
+		t.Fatalf("reading schema_migrations: %v", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// userVersion reads PRAGMA user_version.
+func userVersion(t *testing.T, db *sql.DB) int {
+	t.Helper()
+
+	var v int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// migrationsDir copies the first n built-in migrations, plus extra files,
+// into a new directory.
+func migrationsDir(t *testing.T, n int, extra map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	all, err := loadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range all[:n] {
+		if err := os.WriteFile(filepath.Join(dir, m.name), []byte(m.sql), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, body := range extra {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestMigrateNewDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	latest := latestVersion(t)
+
+	db, err := openAt(path, true)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	names := recorded(t, db.Db)
+	if len(names) != latest || names[0] != "001_initial.sql" {
+		t.Errorf("schema_migrations = %v, want all %d migrations by file name", names, latest)
+	}
+	if got := userVersion(t, db.Db); got != latest {
+		t.Errorf("user_version = %d, want it kept at %d for older builds", got, latest)
+	}
+	db.Db.Close()
+
+	// Opening it again has nothing left to upgrade.
+	db, err = openAt(path, false)
+	if err != nil {
+		t.Fatalf("reopening: %v", err)
+	}
+	db.Db.Close()
+}
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	// The schema from before versioning, at user_version 0, from before
+	// photos too.
+	rawExec(t, path,
+		"CREATE TABLE students (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, email TEXT NOT NULL, age INTEGER NOT NULL)",
+		"INSERT INTO students (name, email, age) VALUES ('Rakesh', 'rakesh@test.com', 35)",
+	)
+
+	if _, err := openAt(path, false); err == nil || !strings.Contains(err.Error(), "auto_migrate") {
+		t.Fatalf("New without auto_migrate = %v, want an error pointing at database.auto_migrate", err)
+	}
+
+	db, err := openAt(path, true)
+	if err != nil {
+		t.Fatalf("New with auto_migrate: %v", err)
+	}
+	t.Cleanup(func() { db.Db.Close() })
+
+	if got := len(recorded(t, db.Db)); got != latestVersion(t) {
+		t.Errorf("recorded %d migrations, want %d", got, latestVersion(t))
+	}
+
+	// The old row is kept, and filled in by the later migrations.
+	student, err := db.GetStudentByID(context.Background(), types.DefaultTenant, 1)
+	if err != nil {
+		t.Fatalf("GetStudentByID: %v", err)
+	}
+	if student.Name != "Rakesh" || student.Version != 1 || student.Status != types.StudentStatusActive {
+		t.Errorf("migrated student = %+v", student)
+	}
+	if err := db.SetStudentPhotoURL(context.Background(), types.DefaultTenant, 1, "/photos/1.jpg"); err != nil {
+		t.Errorf("photo_url wasn't added: %v", err)
+	}
+
+	// And the unique email index exists.
+	if _, err := db.CreateStudent(context.Background(), types.DefaultTenant, "Other", "RAKESH@test.com", 20, "", ""); err == nil {
+		t.Error("created a second student with the same email")
+	}
+}
+
+func TestMigrateRecordsUserVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := openAt(path, true)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	mustCreate(t, db, "Rakesh", "rakesh@test.com", 35)
+	db.Db.Close()
+
+	// A database migrated before schema_migrations existed only has
+	// user_version.
+	rawExec(t, path, "DROP TABLE schema_migrations")
+
+	db, err = openAt(path, false)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { db.Db.Close() })
+
+	names := recorded(t, db.Db)
+	if len(names) != latestVersion(t) || names[len(names)-1] == "" {
+		t.Errorf("schema_migrations = %v, want every version up to user_version, named", names)
+	}
+	if students, _ := db.GetStudents(context.Background(), types.DefaultTenant); len(students) != 1 {
+		t.Errorf("got %d students; the migrations must not run again", len(students))
+	}
+}
+
+func TestMigrateFromDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	latest := latestVersion(t)
+	db := rawOpen(t, path)
+
+	// Up to just before the table rebuild, with a student whose id must
+	// not be handed out again after it is hard-deleted.
+	if err := Migrate(db, migrationsDir(t, 7, nil)); err != nil {
+		t.Fatalf("Migrate to 7: %v", err)
+	}
+	if got := len(recorded(t, db)); got != 7 {
+		t.Fatalf("recorded %d migrations, want 7", got)
+	}
+	if _, err := db.Exec("INSERT INTO students (name, email, age) VALUES ('A', 'a@test.com', 20), ('B', 'b@test.com', 21)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("DELETE FROM students WHERE id = 2"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The rest, from the built-in files.
+	if err := Migrate(db, ""); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if got := len(recorded(t, db)); got != latest {
+		t.Errorf("recorded %d migrations, want %d", got, latest)
+	}
+
+	var id int64
+	if err := db.QueryRow("INSERT INTO students (name, email, age) VALUES ('C', 'c@test.com', 22) RETURNING id").Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	if id != 3 {
+		t.Errorf("new id = %d, want 3: the id sequence must survive the rebuild", id)
+	}
+
+	// Nothing left to do.
+	if err := Migrate(db, ""); err != nil {
+		t.Errorf("Migrate again: %v", err)
+	}
+}
+
+func TestMigrateFailure(t *testing.T) {
+	db := rawOpen(t, filepath.Join(t.TempDir(), "test.db"))
+	dir := migrationsDir(t, 1, map[string]string{
+		"002_broken.sql": "ALTER TABLE students ADD COLUMN nickname TEXT;\nSELECT * FROM no_such_table;",
+	})
+
+	err := Migrate(db, dir)
+	if err == nil || !strings.Contains(err.Error(), "002_broken.sql") {
+		t.Fatalf("Migrate = %v, want an error naming 002_broken.sql", err)
+	}
+
+	// 001 stays applied; 002 is rolled back whole.
+	if got := recorded(t, db); len(got) != 1 {
+		t.Errorf("schema_migrations = %v, want only 001", got)
+	}
+	if _, err := db.Exec("SELECT nickname FROM students"); err == nil {
+		t.Error("the failed migration's first statement was kept")
+	}
+}
+
+func TestMigrateNewerDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := openAt(path, true)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	db.Db.Close()
+
+	rawExec(t, path, "INSERT INTO schema_migrations (version, name) VALUES (999, '999_from_the_future.sql')")
+
+	if _, err := openAt(path, true); err == nil || !strings.Contains(err.Error(), "newer than this build supports") {
+		t.Errorf("New = %v, want a refusal to open a newer schema", err)
+	}
+	if err := Migrate(rawOpen(t, path), ""); err == nil || !strings.Contains(err.Error(), "newer than this build supports") {
+		t.Errorf("Migrate = %v, want a refusal to touch a newer schema", err)
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	file := func(body string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(body)} }
+
+	tests := []struct {
+		name    string
+		fsys    fs.FS
+		want    []string
+		wantErr bool
+	}{
+		{"in version order", fstest.MapFS{
+			"002_b.sql":  file("B"),
+			"001_a.sql":  file("A"),
+			"README.md":  file("not a migration"),
+			"003_c.sql~": file("an editor backup"),
+		}, []string{"001_a.sql", "002_b.sql"}, false},
+		{"gap", fstest.MapFS{"001_a.sql": file("A"), "003_c.sql": file("C")}, nil, true},
+		{"repeat", fstest.MapFS{"001_a.sql": file("A"), "001_b.sql": file("B")}, nil, true},
+		{"empty", fstest.MapFS{}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			all, err := loadMigrations(tt.fsys)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadMigrations error = %v, want error %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, m := range all {
+				got = append(got, m.name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("loaded %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
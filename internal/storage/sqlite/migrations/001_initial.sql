@@ -0,0 +1,40 @@
+-- The schema as it was before versioning was introduced. Databases from
+-- that time are still at version 0 but already have some or all of it,
+-- so everything here is IF NOT EXISTS. (Those that predate photo_url get
+-- the column from Migrate, as SQL alone can't add a column only when it
+-- is missing.)
+--
+--   id        — integer primary key, auto-incremented by SQLite
+--   name      — student's full name (TEXT = variable-length string)
+--   email     — student's email address
+--   age       — student's age in years
+--   photo_url — public URL of the uploaded photo (NULL if none)
+CREATE TABLE IF NOT EXISTS students (
+	id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	name      TEXT    NOT NULL,
+	email     TEXT    NOT NULL,
+	age       INTEGER NOT NULL,
+	photo_url TEXT
+);
+
+-- audit_log records every change to students (see internal/audit).
+-- Nothing else depends on it, so it has no foreign keys.
+CREATE TABLE IF NOT EXISTS audit_log (
+	id              INTEGER PRIMARY KEY AUTOINCREMENT,
+	action          TEXT    NOT NULL,
+	student_id      INTEGER NOT NULL,
+	timestamp       TEXT    NOT NULL,
+	before          TEXT,
+	after           TEXT,
+	request_body    TEXT,
+	response_status INTEGER,
+	client_ip       TEXT,
+	user_agent      TEXT,
+	request_id      TEXT,
+	duration_ms     INTEGER
+);
+
+-- Email identifies a student, so it must be unique — and unique
+-- regardless of case, hence the index on lower(email) rather than on the
+-- raw column.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_students_email ON students(lower(email));
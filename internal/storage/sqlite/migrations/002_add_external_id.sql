@@ -0,0 +1,7 @@
+-- A nullable external_id, for idempotent imports.
+--
+-- The unique index allows any number of NULLs (SQLite treats each NULL as
+-- distinct), so only students that have an external ID must differ.
+ALTER TABLE students ADD COLUMN external_id TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_students_external_id ON students(external_id);
@@ -0,0 +1,3 @@
+-- The student's department, '' when unknown. NOT NULL with a default so
+-- existing rows scan into a plain string.
+ALTER TABLE students ADD COLUMN department TEXT NOT NULL DEFAULT '';
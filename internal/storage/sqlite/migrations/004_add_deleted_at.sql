@@ -0,0 +1,13 @@
+-- Soft deletes. A deleted student keeps its row with deleted_at set, and
+-- every query skips those rows.
+--
+-- The unique indexes are rebuilt as partial indexes over the live rows
+-- only, so a deleted student's email and external ID can be used again.
+-- The upserts name the same WHERE in their ON CONFLICT clause, which
+-- SQLite requires to match a partial index.
+ALTER TABLE students ADD COLUMN deleted_at DATETIME;
+
+DROP INDEX IF EXISTS idx_students_email;
+CREATE UNIQUE INDEX idx_students_email ON students(lower(email)) WHERE deleted_at IS NULL;
+DROP INDEX IF EXISTS idx_students_external_id;
+CREATE UNIQUE INDEX idx_students_external_id ON students(external_id) WHERE deleted_at IS NULL;
@@ -0,0 +1,3 @@
+-- GetAuditLog reads one student's entries, which without an index means
+-- scanning the whole log.
+CREATE INDEX IF NOT EXISTS idx_audit_log_student_id ON audit_log(student_id);
@@ -0,0 +1,13 @@
+-- Responses kept for replay to requests that repeat an Idempotency-Key
+-- (see middleware.Idempotency). status_code 0 marks a key whose first
+-- request is still in flight. Times are Unix milliseconds, so expiry is a
+-- plain integer comparison.
+CREATE TABLE IF NOT EXISTS idempotency_cache (
+	key           TEXT    PRIMARY KEY,
+	status_code   INTEGER NOT NULL DEFAULT 0,
+	content_type  TEXT    NOT NULL DEFAULT '',
+	response_body BLOB,
+	expires_at    INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_cache_expires_at ON idempotency_cache(expires_at);
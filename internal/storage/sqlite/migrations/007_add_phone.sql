@@ -0,0 +1,3 @@
+-- The student's contact number, '' when unknown. NOT NULL with a default,
+-- like department, so it scans into a string.
+ALTER TABLE students ADD COLUMN phone TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,42 @@
+-- When each student was created and last updated, as UTC
+-- "YYYY-MM-DD HH:MM:SS" text filled in by the database.
+--
+-- ALTER TABLE ... ADD COLUMN can't take a default like datetime('now'),
+-- only a constant, so the table is rebuilt instead: create the new one,
+-- copy the rows over, drop the old one and rename. Students that already
+-- exist get the time of the migration for both columns — when they were
+-- really created was never recorded.
+--
+-- Dropping the table drops its indexes, so those are recreated, and its
+-- triggers: the FTS5 search index notices they are gone on the next start
+-- and rebuilds itself (see setupSearch).
+CREATE TABLE students_new (
+	id          INTEGER  PRIMARY KEY AUTOINCREMENT,
+	name        TEXT     NOT NULL,
+	email       TEXT     NOT NULL,
+	age         INTEGER  NOT NULL,
+	photo_url   TEXT,
+	external_id TEXT,
+	department  TEXT     NOT NULL DEFAULT '',
+	deleted_at  DATETIME,
+	phone       TEXT     NOT NULL DEFAULT '',
+	created_at  DATETIME NOT NULL DEFAULT (datetime('now')),
+	updated_at  DATETIME NOT NULL DEFAULT (datetime('now'))
+);
+
+INSERT INTO students_new (id, name, email, age, photo_url, external_id, department, deleted_at, phone)
+	SELECT id, name, email, age, photo_url, external_id, department, deleted_at, phone FROM students;
+
+-- The copy left students_new's sequence at the highest id copied (or none
+-- at all, if the table was empty). The old one is never lower, and keeping
+-- it means ids of hard-deleted students are not handed out again. RENAME
+-- carries the sequence over to the new name.
+DELETE FROM sqlite_sequence WHERE name = 'students_new';
+INSERT INTO sqlite_sequence (name, seq) SELECT 'students_new', seq FROM sqlite_sequence WHERE name = 'students';
+
+DROP TABLE students;
+ALTER TABLE students_new RENAME TO students;
+
+CREATE UNIQUE INDEX idx_students_email ON students(lower(email)) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_students_external_id ON students(external_id) WHERE deleted_at IS NULL;
+CREATE INDEX idx_students_created_at ON students(created_at);
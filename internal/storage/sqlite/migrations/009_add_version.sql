@@ -0,0 +1,4 @@
+-- A counter bumped by every change to a student, so an update can insist
+-- on the version it read (optimistic locking). Existing students start at
+-- 1, like new ones.
+ALTER TABLE students ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
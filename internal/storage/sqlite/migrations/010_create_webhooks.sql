@@ -0,0 +1,8 @@
+-- The URLs registered to be told about changes (POST /api/webhooks), with
+-- the secret that signs deliveries.
+CREATE TABLE IF NOT EXISTS webhooks (
+	id         INTEGER  PRIMARY KEY AUTOINCREMENT,
+	url        TEXT     NOT NULL,
+	secret     TEXT     NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT (datetime('now'))
+);
@@ -0,0 +1,3 @@
+-- Whether a student is active, inactive or suspended
+-- (PATCH /api/students/{id}/status). Existing students are active.
+ALTER TABLE students ADD COLUMN status TEXT NOT NULL DEFAULT 'active';
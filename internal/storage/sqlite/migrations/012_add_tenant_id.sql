@@ -0,0 +1,15 @@
+-- The tenant (school) each student and webhook belongs to. Everything
+-- stored so far belongs to the default tenant, like tokens without a
+-- tenant_id claim.
+--
+-- Emails and external IDs now only have to be unique within a tenant —
+-- two schools may well both have an alice@example.com — so the unique
+-- indexes are rebuilt with tenant_id first. The upserts' ON CONFLICT
+-- clauses name the same columns.
+ALTER TABLE students ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE webhooks ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+
+DROP INDEX IF EXISTS idx_students_email;
+CREATE UNIQUE INDEX idx_students_email ON students(tenant_id, lower(email)) WHERE deleted_at IS NULL;
+DROP INDEX IF EXISTS idx_students_external_id;
+CREATE UNIQUE INDEX idx_students_external_id ON students(tenant_id, external_id) WHERE deleted_at IS NULL;
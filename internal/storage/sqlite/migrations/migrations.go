@@ -0,0 +1,21 @@
+// Package migrations holds the SQLite schema's history as numbered SQL
+// files, compiled into the binary. sqlite.Migrate applies them.
+//
+// HOW IT WORKS
+// ────────────
+// Each file NNN_description.sql turns a version NNN-1 database into a
+// version NNN one. Migrate runs the ones a database hasn't had yet, in
+// order, each in its own transaction together with the row recording it
+// in the schema_migrations table. So a migration either happens
+// completely or not at all, and is never applied twice.
+//
+// To change the schema, add the next numbered file — never edit one that
+// has already shipped, since existing databases have already run it.
+package migrations
+
+import "embed"
+
+// FS holds the migration files.
+//
+//go:embed *.sql
+var FS embed.FS
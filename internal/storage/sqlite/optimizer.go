@@ -0,0 +1,71 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Optimizer keeps SQLite's query planner statistics up to date.
+//
+// The planner picks indexes using statistics gathered by ANALYZE, which
+// describe the table as it was when ANALYZE last ran. As the table grows
+// they drift further from reality and queries can get slower, so the
+// Optimizer refreshes them on a timer.
+type Optimizer struct {
+	db *sql.DB
+}
+
+// NewOptimizer returns an Optimizer for s's database.
+func NewOptimizer(s *SQLite) *Optimizer {
+	return &Optimizer{db: s.Db}
+}
+
+// Start runs Optimize every interval in a background goroutine until ctx
+// is cancelled. Failures are logged and retried on the next tick; stale
+// statistics only make queries slower, never wrong.
+func (o *Optimizer) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				start := time.Now()
+				if err := o.Optimize(ctx); err != nil {
+					if ctx.Err() == nil {
+						slog.Warn("database optimize failed", slog.String("error", err.Error()))
+					}
+					continue
+				}
+				slog.Debug("database optimized", slog.Duration("duration", time.Since(start)))
+			}
+		}
+	}()
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Optimize refreshes the planner statistics once.
+//
+// PRAGMA optimize (SQLite 3.18+) lets SQLite decide for itself what is
+// worth re-analysing, which is usually cheap. ANALYZE students then
+// refreshes the statistics for the one table that matters here anyway.
+// ─────────────────────────────────────────────────────────────────────────────
+func (o *Optimizer) Optimize(ctx context.Context) error {
+	if _, err := o.db.ExecContext(ctx, "PRAGMA optimize"); err != nil {
+		return fmt.Errorf("Optimize: pragma optimize: %w", err)
+	}
+	if _, err := o.db.ExecContext(ctx, "ANALYZE students"); err != nil {
+		return fmt.Errorf("Optimize: analyze: %w", err)
+	}
+	return nil
+}
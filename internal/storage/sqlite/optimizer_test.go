@@ -0,0 +1,99 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer the optimizer goroutine can log to while
+// the test reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestOptimize(t *testing.T) {
+	db := newTestStore(t)
+	for i := 0; i < 10; i++ {
+		mustCreate(t, db, "Student", fmt.Sprintf("s%d@test.com", i), 20)
+	}
+
+	if err := NewOptimizer(db).Optimize(context.Background()); err != nil {
+		t.Fatalf("Optimize: %v", err)
+	}
+
+	// ANALYZE leaves its statistics in sqlite_stat1.
+	var rows int
+	if err := db.Db.QueryRow("SELECT COUNT(*) FROM sqlite_stat1 WHERE tbl = 'students'").Scan(&rows); err != nil {
+		t.Fatalf("reading sqlite_stat1: %v", err)
+	}
+	if rows == 0 {
+		t.Error("no statistics for students after Optimize")
+	}
+}
+
+func TestOptimizerStart(t *testing.T) {
+	var logs syncBuffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	db := newTestStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	NewOptimizer(db).Start(ctx, 10*time.Millisecond)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for strings.Count(logs.String(), "database optimized") < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("the optimizer didn't run twice in 2s; logs:\n%s", logs.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !strings.Contains(logs.String(), "duration=") {
+		t.Errorf("the log line lacks the duration:\n%s", logs.String())
+	}
+
+	// Once cancelled, it stops.
+	cancel()
+	time.Sleep(30 * time.Millisecond)
+	runs := strings.Count(logs.String(), "database optimized")
+	time.Sleep(50 * time.Millisecond)
+	if got := strings.Count(logs.String(), "database optimized"); got != runs {
+		t.Errorf("ran %d more times after cancel", got-runs)
+	}
+}
+
+func TestOptimizerStartDisabled(t *testing.T) {
+	db := newTestStore(t)
+	db.Db.Close() // any run would fail and log
+
+	var logs syncBuffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	NewOptimizer(db).Start(context.Background(), 0)
+	time.Sleep(20 * time.Millisecond)
+	if logs.String() != "" {
+		t.Errorf("an interval of 0 still ran the optimizer:\n%s", logs.String())
+	}
+}
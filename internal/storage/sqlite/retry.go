@@ -0,0 +1,58 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/config"
+)
+
+// maxRetryDelay caps the wait between two attempts of NewWithRetry,
+// however many attempts have failed.
+const maxRetryDelay = 30 * time.Second
+
+// ─────────────────────────────────────────────────────────────────────────────
+// NewWithRetry is New, tried up to maxAttempts times. In a container the
+// storage volume may not be mounted yet when the process starts, and
+// failing at once just gets the container restarted; waiting a little
+// usually lets the same start succeed.
+//
+// The wait doubles after each failure, starting at baseDelay and capped
+// at maxRetryDelay, with random jitter (between half and all of it) so
+// replicas that start together don't retry in lockstep. Each failure is
+// logged at WARN.
+//
+// ctx bounds the whole loop: once it is done, NewWithRetry stops waiting
+// and returns the last error. maxAttempts < 1 is treated as 1.
+// ─────────────────────────────────────────────────────────────────────────────
+func NewWithRetry(ctx context.Context, cfg *config.Config, maxAttempts int, baseDelay time.Duration) (*SQLite, error) {
+	maxAttempts = max(maxAttempts, 1)
+	delay := baseDelay
+
+	for attempt := 1; ; attempt++ {
+		db, err := New(cfg)
+		if err == nil {
+			return db, nil
+		}
+		if attempt >= maxAttempts {
+			return nil, fmt.Errorf("sqlite.NewWithRetry: giving up after %d attempts: %w", attempt, err)
+		}
+
+		wait := delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		slog.Warn("cannot open database, retrying",
+			slog.Int("attempt", attempt),
+			slog.Int("max_attempts", maxAttempts),
+			slog.Duration("retry_in", wait),
+			slog.String("error", err.Error()))
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("sqlite.NewWithRetry: %w (last error: %w)", ctx.Err(), err)
+		case <-time.After(wait):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
@@ -0,0 +1,73 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// SearchStudents returns the tenant's live students whose name or email contains
+// query, ignoring case, newest first (see defaultOrderBy).
+//
+// How the matching rows are found depends on the build:
+//
+//	go build ./...                    → LIKE over the students table (search_like.go)
+//	go build -tags sqlite_fts5 ./...  → an FTS5 trigram index (search_fts5.go)
+//
+// Both give the same results; the index only makes them faster on a big
+// table.
+// ─────────────────────────────────────────────────────────────────────────────
+func (s *SQLite) SearchStudents(ctx context.Context, tenantID string, query string) ([]types.Student, error) {
+	rows, err := s.searchRows(ctx, tenantID, query)
+	if err != nil {
+		return nil, fmt.Errorf("SearchStudents: query: %w", err)
+	}
+	defer rows.Close()
+
+	students := make([]types.Student, 0)
+	for rows.Next() {
+		var student types.Student
+		if err := rows.Scan(
+			&student.ID,
+			&student.Name,
+			&student.Email,
+			&student.Age,
+			&student.PhotoURL,
+			&student.ExternalID,
+			&student.Department,
+			&student.Phone,
+			timestamp{&student.CreatedAt},
+			timestamp{&student.UpdatedAt},
+			&student.Version,
+			&student.Status,
+			&student.TenantID,
+		); err != nil {
+			return nil, fmt.Errorf("SearchStudents: scan row: %w", err)
+		}
+		students = append(students, student)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("SearchStudents: rows iteration: %w", err)
+	}
+
+	return students, nil
+}
+
+// searchLike finds the matches with LIKE. query is bound as a parameter
+// (with its own % and _ escaped by likeContains), never put in the SQL.
+// SQLite's LIKE already ignores case for ASCII letters.
+func (s *SQLite) searchLike(ctx context.Context, tenantID string, query string) (*sql.Rows, error) {
+	pattern := likeContains(query)
+	return s.Db.QueryContext(ctx, `
+		SELECT id, name, email, age, COALESCE(photo_url, ''), external_id, department, phone, created_at, updated_at, version, status, tenant_id
+		FROM students
+		WHERE tenant_id = ? AND deleted_at IS NULL
+		  AND (name LIKE ? ESCAPE '\' OR email LIKE ? ESCAPE '\')`+defaultOrderBy, tenantID, pattern, pattern)
+}
+
+// ftsTriggers are the triggers that keep the FTS5 index in step with the
+// students table (see search_fts5.go). Both builds need their names.
+var ftsTriggers = []string{"students_fts_ai", "students_fts_ad", "students_fts_au"}
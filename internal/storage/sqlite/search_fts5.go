@@ -0,0 +1,97 @@
+//go:build sqlite_fts5
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// minTrigramQuery is the shortest query the trigram index can answer.
+// Shorter ones fall back to LIKE.
+const minTrigramQuery = 3
+
+// searchRows looks the query up in students_fts. The trigram tokenizer
+// indexes every 3-character run of name and email, so a MATCH finds
+// substrings anywhere — the same results as LIKE '%query%', without
+// reading every row.
+//
+// The query is bound as one FTS5 phrase ("..." with inner quotes
+// doubled), so operators like OR or * in it are matched as text.
+func (s *SQLite) searchRows(ctx context.Context, tenantID string, query string) (*sql.Rows, error) {
+	if utf8.RuneCountInString(query) < minTrigramQuery {
+		return s.searchLike(ctx, tenantID, query)
+	}
+
+	phrase := `"` + strings.ReplaceAll(query, `"`, `""`) + `"`
+	return s.Db.QueryContext(ctx, `
+		SELECT s.id, s.name, s.email, s.age, COALESCE(s.photo_url, ''), s.external_id, s.department, s.phone, s.created_at, s.updated_at, s.version, s.status, s.tenant_id
+		FROM students_fts
+		JOIN students s ON s.id = students_fts.rowid
+		WHERE students_fts MATCH ? AND s.tenant_id = ? AND s.deleted_at IS NULL
+		ORDER BY s.created_at DESC, s.id DESC`, phrase, tenantID)
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// setupSearch creates the FTS5 index and the triggers that keep it in
+// step with the students table.
+//
+// students_fts is an external-content table: it stores only the index
+// and reads name and email from students, keyed by id. Soft-deleted
+// students stay indexed and are filtered out in searchRows, like
+// everywhere else.
+//
+// The index is (re)built from the table whenever the insert trigger is
+// missing — on first start, and after the database was used by a build
+// without FTS5, which drops the triggers (search_like.go).
+// ─────────────────────────────────────────────────────────────────────────────
+func setupSearch(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("setupSearch: begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	var triggers int
+	err = tx.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name = ?",
+		ftsTriggers[0]).Scan(&triggers)
+	if err != nil {
+		return fmt.Errorf("setupSearch: check triggers: %w", err)
+	}
+	if triggers > 0 {
+		return nil
+	}
+
+	statements := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS students_fts USING fts5(
+			name, email, content='students', content_rowid='id', tokenize='trigram'
+		)`,
+		`CREATE TRIGGER IF NOT EXISTS students_fts_ai AFTER INSERT ON students BEGIN
+			INSERT INTO students_fts(rowid, name, email) VALUES (new.id, new.name, new.email);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS students_fts_ad AFTER DELETE ON students BEGIN
+			INSERT INTO students_fts(students_fts, rowid, name, email)
+				VALUES ('delete', old.id, old.name, old.email);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS students_fts_au AFTER UPDATE OF name, email ON students BEGIN
+			INSERT INTO students_fts(students_fts, rowid, name, email)
+				VALUES ('delete', old.id, old.name, old.email);
+			INSERT INTO students_fts(rowid, name, email) VALUES (new.id, new.name, new.email);
+		END`,
+		`INSERT INTO students_fts(students_fts) VALUES ('rebuild')`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("setupSearch: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("setupSearch: commit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+//go:build !sqlite_fts5
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// searchRows scans the table with LIKE: without the sqlite_fts5 build tag
+// the driver has no FTS5 module.
+func (s *SQLite) searchRows(ctx context.Context, tenantID string, query string) (*sql.Rows, error) {
+	return s.searchLike(ctx, tenantID, query)
+}
+
+// setupSearch drops the triggers an FTS5 build leaves behind. They write
+// to students_fts, which this build can't open, so every insert or update
+// of a student would fail while they exist.
+//
+// The index table itself stays: dropping it needs the module too. An
+// FTS5 build rebuilds it when it finds the triggers gone.
+func setupSearch(ctx context.Context, db *sql.DB) error {
+	for _, trigger := range ftsTriggers {
+		if _, err := db.ExecContext(ctx, "DROP TRIGGER IF EXISTS "+trigger); err != nil {
+			return fmt.Errorf("setupSearch: drop %s: %w", trigger, err)
+		}
+	}
+	return nil
+}
@@ -7,22 +7,34 @@
 // network, no separate server process, and no installation beyond the
 // driver. It is fast enough for most projects and trivial to set up.
 //
-// The blank import below registers the sqlite3 driver with database/sql.
-// The driver's init() function does this automatically when the package
-// is loaded — we never call anything from it directly.
+// The go-sqlite3 import below registers the sqlite3 driver with
+// database/sql. The driver's init() function does this automatically when
+// the package is loaded.
 package sqlite
 
 import (
+	"cmp"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/aanand-mishra/students-api/internal/config"
+	"github.com/aanand-mishra/students-api/internal/storage"
 	"github.com/aanand-mishra/students-api/internal/types"
+	"github.com/aanand-mishra/students-api/internal/utils"
 
-	// Blank import: side-effect only (registers the "sqlite3" driver).
-	// Without this the sql.Open("sqlite3", ...) call would fail with
-	// "unknown driver".
-	_ "github.com/mattn/go-sqlite3"
+	// Importing the driver registers the "sqlite3" driver with
+	// database/sql as a side effect. Without it the sql.Open("sqlite3", ...)
+	// call would fail with "unknown driver". We also use its error type to
+	// recognise constraint violations.
+	"github.com/mattn/go-sqlite3"
 )
 
 // SQLite is the concrete implementation of storage.Storage.
@@ -30,11 +42,19 @@ import (
 // A single *sql.DB is safe for concurrent use by multiple goroutines.
 type SQLite struct {
 	Db *sql.DB
+
+	// journalMode is what PRAGMA journal_mode reported once the database
+	// was open, e.g. "wal". See JournalMode.
+	journalMode string
+
+	// hooks are notified after each successful mutation (see hooks.go).
+	hooksMu sync.RWMutex
+	hooks   []storage.Hook
 }
 
 // New opens the SQLite database at the path specified in cfg.StoragePath,
-// creates the students table if it does not already exist, and returns
-// a ready-to-use *SQLite.
+// migrates its schema to the current version (creating the tables in a
+// new file), and returns a ready-to-use *SQLite.
 //
 // Naming convention: New() acts as a constructor. Go has no constructors,
 // so the community convention is a package-level New() function that
@@ -43,32 +63,68 @@ func New(cfg *config.Config) (*SQLite, error) {
 	// sql.Open does NOT open a real connection yet — it just validates
 	// the driver name and data source name (DSN).
 	// The first actual connection happens on the first query.
-	db, err := sql.Open("sqlite3", cfg.StoragePath)
+	db, err := sql.Open("sqlite3", dsn(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("sqlite.New: open db: %w", err)
 	}
 
-	// CREATE TABLE IF NOT EXISTS is idempotent — safe to run on every
-	// startup. If the table already exists nothing happens.
+	// In WAL mode readers and the single writer work side by side. With
+	// the rollback journal ("DELETE") a write needs the whole file to
+	// itself, so more connections would only take turns failing with
+	// SQLITE_BUSY; one connection makes them queue in the pool instead.
 	//
-	// Schema:
-	//   id    — integer primary key, auto-incremented by SQLite
-	//   name  — student's full name (TEXT = variable-length string)
-	//   email — student's email address
-	//   age   — student's age in years
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS students (
-			id    INTEGER PRIMARY KEY AUTOINCREMENT,
-			name  TEXT    NOT NULL,
-			email TEXT    NOT NULL,
-			age   INTEGER NOT NULL
-		)
-	`)
-	if err != nil {
-		return nil, fmt.Errorf("sqlite.New: create table: %w", err)
+	// The warmed-up connections (see WarmUp) should stay open once they
+	// are handed back, so at least that many are kept idle.
+	maxOpen := cfg.Database.MaxOpenConns
+	if cfg.SQLite.JournalMode != config.JournalModeWAL {
+		maxOpen = 1
+	}
+	maxIdle := max(cfg.Database.MaxIdleConns, cfg.Database.WarmUpConns)
+	storage.ConfigurePool(db, config.StorageBackendSQLite, maxOpen, maxIdle, cfg.Database.ConnMaxLifetime)
+
+	// The PRAGMA answers with the mode actually in effect, which isn't
+	// always the one asked for (an in-memory database is "memory").
+	var journalMode string
+	if err := db.QueryRowContext(context.Background(), "PRAGMA journal_mode").Scan(&journalMode); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("sqlite.New: read journal mode: %w", err)
 	}
 
-	return &SQLite{Db: db}, nil
+	// Bring the schema up to date — or refuse to run against a database
+	// this build doesn't understand. See Migrate.
+	if err := migrate(context.Background(), db, cfg.Database.AutoMigrate, cfg.Database.MigrationsDir); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("sqlite.New: %w", err)
+	}
+
+	// The search index depends on the build, so it lives outside the
+	// versioned schema. See SearchStudents.
+	if err := setupSearch(context.Background(), db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("sqlite.New: %w", err)
+	}
+
+	return &SQLite{Db: db, journalMode: journalMode}, nil
+}
+
+// dsn adds the configured journal mode and busy timeout to the database
+// path as go-sqlite3 connection parameters. The driver runs the matching
+// PRAGMAs on every connection it opens, which matters for busy_timeout:
+// it is per connection, so running it once after sql.Open would only
+// reach whichever pooled connection happened to execute it.
+func dsn(cfg *config.Config) string {
+	sep := "?"
+	if strings.Contains(cfg.StoragePath, "?") {
+		sep = "&"
+	}
+	return fmt.Sprintf("%s%s_journal_mode=%s&_busy_timeout=%d",
+		cfg.StoragePath, sep, cfg.SQLite.JournalMode, cfg.SQLite.BusyTimeoutMs)
+}
+
+// JournalMode returns the journal mode the database is using, as SQLite
+// reported it when it was opened ("wal" or "delete").
+func (s *SQLite) JournalMode() string {
+	return s.journalMode
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
@@ -87,11 +143,17 @@ func New(cfg *config.Config) (*SQLite, error) {
 // the query and the values separately. The database engine treats the
 // values as pure data, never as SQL syntax.
 // ─────────────────────────────────────────────────────────────────────────────
-func (s *SQLite) CreateStudent(name, email string, age int) (int64, error) {
+func (s *SQLite) CreateStudent(ctx context.Context, tenantID, name, email string, age int, department, phone string) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "sqlite.CreateStudent")
+	defer func() { endSpan(span, err) }()
+
 	// Prepare compiles the SQL on the database side.
-	// The ? placeholders will be filled in when we call Exec.
-	stmt, err := s.Db.Prepare(
-		"INSERT INTO students (name, email, age) VALUES (?, ?, ?)",
+	// The ? placeholders will be filled in when we run the statement.
+	//
+	// RETURNING hands back what the database filled in itself: the
+	// auto-generated primary key and the two timestamps.
+	stmt, err := s.Db.PrepareContext(ctx,
+		"INSERT INTO students (tenant_id, name, email, age, department, phone) VALUES (?, ?, ?, ?, ?, ?) RETURNING id, created_at, updated_at, version",
 	)
 	if err != nil {
 		return 0, fmt.Errorf("CreateStudent: prepare: %w", err)
@@ -100,18 +162,23 @@ func (s *SQLite) CreateStudent(name, email string, age int) (int64, error) {
 	// even if we return early due to an error. Prevents resource leaks.
 	defer stmt.Close()
 
-	// Exec runs the prepared statement, substituting ? in the same order
-	// the arguments are listed here. Order matters!
-	result, err := stmt.Exec(name, email, age)
+	created := types.Student{Name: name, Email: utils.NormalizeEmail(email), Age: age, Department: department, Phone: phone, Status: types.StudentStatusActive, TenantID: tenantID}
+
+	// QueryRow runs the prepared statement, substituting ? in the same
+	// order the arguments are listed here. Order matters!
+	var lastID int64
+	err = stmt.QueryRowContext(ctx, tenantID, name, created.Email, age, department, phone).Scan(
+		&lastID, timestamp{&created.CreatedAt}, timestamp{&created.UpdatedAt}, &created.Version)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, storage.ErrDuplicateEmail
+		}
 		return 0, fmt.Errorf("CreateStudent: exec: %w", err)
 	}
+	span.SetAttributes(studentIDAttr(lastID))
 
-	// LastInsertId returns the auto-generated primary key of the new row.
-	lastID, err := result.LastInsertId()
-	if err != nil {
-		return 0, fmt.Errorf("CreateStudent: last insert id: %w", err)
-	}
+	created.ID = int(lastID)
+	s.notify(func(h storage.Hook) { h.OnCreate(ctx, created) })
 
 	return lastID, nil
 }
@@ -126,9 +193,12 @@ func (s *SQLite) CreateStudent(name, email string, age int) (int64, error) {
 // The order of variables in Scan must match the order of columns in SELECT.
 // We pass pointers (&student.ID) so Scan can write into those locations.
 // ─────────────────────────────────────────────────────────────────────────────
-func (s *SQLite) GetStudentByID(id int64) (types.Student, error) {
-	stmt, err := s.Db.Prepare(
-		"SELECT id, name, email, age FROM students WHERE id = ? LIMIT 1",
+func (s *SQLite) GetStudentByID(ctx context.Context, tenantID string, id int64) (_ types.Student, err error) {
+	ctx, span := startSpan(ctx, "sqlite.GetStudentByID", studentIDAttr(id))
+	defer func() { endSpan(span, err) }()
+
+	stmt, err := s.Db.PrepareContext(ctx,
+		"SELECT id, name, email, age, COALESCE(photo_url, ''), external_id, department, phone, created_at, updated_at, version, status, tenant_id FROM students WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL LIMIT 1",
 	)
 	if err != nil {
 		return types.Student{}, fmt.Errorf("GetStudentByID: prepare: %w", err)
@@ -139,18 +209,28 @@ func (s *SQLite) GetStudentByID(id int64) (types.Student, error) {
 
 	// QueryRow returns exactly one row. If the query finds no match it
 	// does NOT return nil — the error surfaces only when you call Scan.
-	err = stmt.QueryRow(id).Scan(
+	err = stmt.QueryRowContext(ctx, id, tenantID).Scan(
 		&student.ID,    // ← maps to SELECT column 1: id
 		&student.Name,  // ← maps to SELECT column 2: name
 		&student.Email, // ← maps to SELECT column 3: email
 		&student.Age,   // ← maps to SELECT column 4: age
+		&student.PhotoURL,
+		// A NULL external_id leaves the *string nil; database/sql
+		// allocates the string only when there is a value.
+		&student.ExternalID,
+		&student.Department,
+		&student.Phone,
+		timestamp{&student.CreatedAt},
+		timestamp{&student.UpdatedAt},
+		&student.Version, &student.Status, &student.TenantID,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			// sql.ErrNoRows is the sentinel error for "nothing matched".
 			// We return a human-readable message so the handler can surface
-			// it to the client without leaking internal DB details.
-			return types.Student{}, fmt.Errorf("no student found with id: %d", id)
+			// it to the client without leaking internal DB details, wrapping
+			// storage.ErrNotFound so callers can detect it with errors.Is.
+			return types.Student{}, fmt.Errorf("%w with id: %d", storage.ErrNotFound, id)
 		}
 		return types.Student{}, fmt.Errorf("GetStudentByID: scan: %w", err)
 	}
@@ -159,7 +239,42 @@ func (s *SQLite) GetStudentByID(id int64) (types.Student, error) {
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
-// GetStudents returns all student rows as a slice.
+// GetStudentByEmail fetches the student with the given email. Emails are
+// stored normalised (see utils.NormalizeEmail), and comparing on
+// lower(email) lets the query use the unique email index.
+// ─────────────────────────────────────────────────────────────────────────────
+func (s *SQLite) GetStudentByEmail(ctx context.Context, tenantID, email string) (_ types.Student, err error) {
+	ctx, span := startSpan(ctx, "sqlite.GetStudentByEmail")
+	defer func() { endSpan(span, err) }()
+
+	stmt, err := s.Db.PrepareContext(ctx,
+		"SELECT id, name, email, age, COALESCE(photo_url, ''), external_id, department, phone, created_at, updated_at, version, status, tenant_id FROM students WHERE tenant_id = ? AND lower(email) = ? AND deleted_at IS NULL LIMIT 1",
+	)
+	if err != nil {
+		return types.Student{}, fmt.Errorf("GetStudentByEmail: prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	var student types.Student
+	err = stmt.QueryRowContext(ctx, tenantID, utils.NormalizeEmail(email)).Scan(
+		&student.ID, &student.Name, &student.Email, &student.Age,
+		&student.PhotoURL, &student.ExternalID, &student.Department, &student.Phone,
+		timestamp{&student.CreatedAt}, timestamp{&student.UpdatedAt}, &student.Version, &student.Status, &student.TenantID,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// The email is not repeated in the message: errors end up in
+			// logs, and an email address is personal data.
+			return types.Student{}, fmt.Errorf("%w with that email", storage.ErrNotFound)
+		}
+		return types.Student{}, fmt.Errorf("GetStudentByEmail: scan: %w", err)
+	}
+
+	return student, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// GetStudents returns all student rows as a slice, newest first.
 //
 // HOW Query + rows.Next() WORK:
 // ──────────────────────────────
@@ -168,11 +283,15 @@ func (s *SQLite) GetStudentByID(id int64) (types.Student, error) {
 // when there are no more rows. We Scan each row inside the loop.
 // Always defer rows.Close() to release the database connection.
 // ─────────────────────────────────────────────────────────────────────────────
-func (s *SQLite) GetStudents() ([]types.Student, error) {
-	stmt, err := s.Db.Prepare(
+func (s *SQLite) GetStudents(ctx context.Context, tenantID string) (_ []types.Student, err error) {
+	ctx, span := startSpan(ctx, "sqlite.GetStudents")
+	defer func() { endSpan(span, err) }()
+
+	stmt, err := s.Db.PrepareContext(ctx,
 		// Explicitly list columns — never use SELECT * in production code.
 		// If a column is added later, SELECT * would break Scan's ordering.
-		"SELECT id, name, email, age FROM students",
+		"SELECT id, name, email, age, COALESCE(photo_url, ''), external_id, department, phone, created_at, updated_at, version, status, tenant_id FROM students WHERE tenant_id = ? AND deleted_at IS NULL"+
+			defaultOrderBy,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("GetStudents: prepare: %w", err)
@@ -180,7 +299,7 @@ func (s *SQLite) GetStudents() ([]types.Student, error) {
 	defer stmt.Close()
 
 	// Query returns a cursor (*sql.Rows) over the result set.
-	rows, err := stmt.Query()
+	rows, err := stmt.QueryContext(ctx, tenantID)
 	if err != nil {
 		return nil, fmt.Errorf("GetStudents: query: %w", err)
 	}
@@ -198,6 +317,13 @@ func (s *SQLite) GetStudents() ([]types.Student, error) {
 			&student.Name,
 			&student.Email,
 			&student.Age,
+			&student.PhotoURL,
+			&student.ExternalID,
+			&student.Department,
+			&student.Phone,
+			timestamp{&student.CreatedAt},
+			timestamp{&student.UpdatedAt},
+			&student.Version, &student.Status, &student.TenantID,
 		); err != nil {
 			return nil, fmt.Errorf("GetStudents: scan row: %w", err)
 		}
@@ -214,13 +340,206 @@ func (s *SQLite) GetStudents() ([]types.Student, error) {
 	return students, nil
 }
 
+// ─────────────────────────────────────────────────────────────────────────────
+// GetStudentsFiltered returns the students matching opts plus how many
+// match in total.
+//
+// The WHERE clause is assembled from fixed fragments, one per filter set,
+// and every value the client sent is bound as a ? placeholder:
+//
+//	WHERE name LIKE ? ESCAPE '\' AND age >= ?
+//
+// so nothing from the request ever becomes part of the SQL text. With no
+// filters only the tenant and "deleted_at IS NULL" are left, and every
+// live student of the tenant matches.
+//
+// The ORDER BY is built the same way from opts.Sort (see orderByClause).
+//
+// The COUNT and the page are read in the same transaction, so a student
+// created between the two queries can't make the total disagree with the
+// page. The COUNT is countStudents, the query behind CountStudents too,
+// so without filters the total is exactly the tenant's CountStudents.
+// A fixed ORDER BY keeps pages stable: without it SQLite may return
+// rows in any order, and a row could show up on two pages or none.
+// ─────────────────────────────────────────────────────────────────────────────
+func (s *SQLite) GetStudentsFiltered(ctx context.Context, tenantID string, opts types.FilterOptions) ([]types.Student, int64, error) {
+	where, args := filterWhere(tenantID, opts)
+
+	orderBy, err := orderByClause(opts.Sort)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("GetStudentsFiltered: begin: %w", err)
+	}
+	// Nothing is written, so rolling back is how this transaction ends.
+	defer tx.Rollback()
+
+	total, err := countStudents(ctx, tx, tenantID, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("GetStudentsFiltered: count: %w", err)
+	}
+
+	// A cursor narrows the page, after the total has been counted:
+	//   WHERE ... AND id > ? ORDER BY id ASC LIMIT ?
+	// Paging backwards reads the rows nearest the cursor first (DESC),
+	// and they are put back in ascending order below.
+	switch {
+	case opts.AfterID > 0:
+		where += " AND id > ?"
+		args = append(args, opts.AfterID)
+		orderBy = " ORDER BY id ASC"
+	case opts.BeforeID > 0:
+		where += " AND id < ?"
+		args = append(args, opts.BeforeID)
+		orderBy = " ORDER BY id DESC"
+	}
+
+	query := "SELECT id, name, email, age, COALESCE(photo_url, ''), external_id, department, phone, created_at, updated_at, version, status, tenant_id FROM students" +
+		where + orderBy
+	if opts.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, opts.Limit, opts.Offset)
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("GetStudentsFiltered: query: %w", err)
+	}
+	defer rows.Close()
+
+	students := make([]types.Student, 0, opts.Limit)
+	for rows.Next() {
+		var student types.Student
+		if err := rows.Scan(
+			&student.ID,
+			&student.Name,
+			&student.Email,
+			&student.Age,
+			&student.PhotoURL,
+			&student.ExternalID,
+			&student.Department,
+			&student.Phone,
+			timestamp{&student.CreatedAt},
+			timestamp{&student.UpdatedAt},
+			&student.Version, &student.Status, &student.TenantID,
+		); err != nil {
+			return nil, 0, fmt.Errorf("GetStudentsFiltered: scan row: %w", err)
+		}
+		students = append(students, student)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("GetStudentsFiltered: rows iteration: %w", err)
+	}
+	if opts.AfterID == 0 && opts.BeforeID > 0 {
+		slices.Reverse(students)
+	}
+
+	return students, total, nil
+}
+
+// sortColumns maps sort keys to columns. Like patchableColumns, it is
+// the only source of column names put into the SQL text.
+var sortColumns = map[string]string{
+	"id":         "id",
+	"name":       "name",
+	"email":      "email",
+	"age":        "age",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// defaultOrderBy lists students newest first. created_at only has
+// one-second resolution, so id breaks the ties.
+const defaultOrderBy = " ORDER BY created_at DESC, id DESC"
+
+// orderByClause builds " ORDER BY ..." for sort, or defaultOrderBy when
+// sort is empty. id is always the last column, so rows that tie on every
+// requested column still come back in a fixed order and pages never
+// overlap.
+func orderByClause(sort types.SortOptions) (string, error) {
+	if len(sort.Fields) == 0 {
+		return defaultOrderBy, nil
+	}
+
+	terms := make([]string, 0, len(sort.Fields)+1)
+	hasID := false
+	for _, field := range sort.Fields {
+		column, ok := sortColumns[field.Key]
+		if !ok {
+			return "", fmt.Errorf("%w: can't sort by %q", storage.ErrUnsupportedFilter, field.Key)
+		}
+		direction := " ASC"
+		if field.Desc {
+			direction = " DESC"
+		}
+		terms = append(terms, column+direction)
+		hasID = hasID || column == "id"
+	}
+	if !hasID {
+		terms = append(terms, "id ASC")
+	}
+	return " ORDER BY " + strings.Join(terms, ", "), nil
+}
+
+// likeContains turns s into a LIKE pattern matching any value containing
+// s. LIKE's own wildcards (% and _) in s are escaped so they match
+// literally; the queries declare \ as the escape character.
+func likeContains(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+	return "%" + s + "%"
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// GetRandomStudent picks one row at random.
+//
+// ORDER BY RANDOM() shuffles the whole table, which is fine at this scale;
+// a very large table would want a smarter strategy (e.g. random rowid).
+// ─────────────────────────────────────────────────────────────────────────────
+func (s *SQLite) GetRandomStudent(ctx context.Context, tenantID string) (types.Student, error) {
+	var student types.Student
+
+	err := s.Db.QueryRowContext(ctx,
+		"SELECT id, name, email, age, COALESCE(photo_url, ''), external_id, department, phone, created_at, updated_at, version, status, tenant_id FROM students WHERE tenant_id = ? AND deleted_at IS NULL ORDER BY RANDOM() LIMIT 1",
+		tenantID,
+	).Scan(&student.ID, &student.Name, &student.Email, &student.Age, &student.PhotoURL, &student.ExternalID, &student.Department, &student.Phone,
+		timestamp{&student.CreatedAt}, timestamp{&student.UpdatedAt}, &student.Version, &student.Status, &student.TenantID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return types.Student{}, storage.ErrNotFound
+		}
+		return types.Student{}, fmt.Errorf("GetRandomStudent: scan: %w", err)
+	}
+
+	return student, nil
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 // UpdateStudentByID replaces a student's data with the provided values.
 // Returns the updated student so the caller can echo it back to the client.
+//
+// The update only applies if the stored version is still student.Version:
+//
+//	UPDATE students SET ..., version = version + 1 WHERE id = ? AND version = ?
+//
+// If someone else updated the student in between, the version has moved
+// on, no row matches, and ErrVersionConflict is returned instead of
+// silently overwriting their change.
 // ─────────────────────────────────────────────────────────────────────────────
-func (s *SQLite) UpdateStudentByID(id int64, student types.Student) (types.Student, error) {
-	stmt, err := s.Db.Prepare(
-		"UPDATE students SET name = ?, email = ?, age = ? WHERE id = ?",
+func (s *SQLite) UpdateStudentByID(ctx context.Context, tenantID string, id int64, student types.Student) (_ types.Student, err error) {
+	ctx, span := startSpan(ctx, "sqlite.UpdateStudentByID", studentIDAttr(id))
+	defer func() { endSpan(span, err) }()
+
+	// Hooks receive the record as it was before the change.
+	old, err := s.GetStudentByID(ctx, tenantID, id)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	stmt, err := s.Db.PrepareContext(ctx,
+		"UPDATE students SET name = ?, email = ?, age = ?, department = ?, phone = ?, updated_at = datetime('now'), version = version + 1 WHERE id = ? AND tenant_id = ? AND version = ? AND deleted_at IS NULL",
 	)
 	if err != nil {
 		return types.Student{}, fmt.Errorf("UpdateStudentByID: prepare: %w", err)
@@ -228,30 +547,717 @@ func (s *SQLite) UpdateStudentByID(id int64, student types.Student) (types.Stude
 	defer stmt.Close()
 
 	// Note the argument order matches the ? order in the SQL:
-	//   name, email, age, id
-	_, err = stmt.Exec(student.Name, student.Email, student.Age, id)
+	//   name, email, age, department, phone, id, tenant, version
+	result, err := stmt.ExecContext(ctx, student.Name, utils.NormalizeEmail(student.Email), student.Age, student.Department, student.Phone, id, tenantID, student.Version)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return types.Student{}, storage.ErrDuplicateEmail
+		}
 		return types.Student{}, fmt.Errorf("UpdateStudentByID: exec: %w", err)
 	}
 
+	// The student exists (we just read it), so no matching row means
+	// the version check failed.
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return types.Student{}, fmt.Errorf("UpdateStudentByID: rows affected: %w", err)
+	}
+	if affected == 0 {
+		return types.Student{}, fmt.Errorf("%w: student %d is no longer at version %d", storage.ErrVersionConflict, id, student.Version)
+	}
+
 	// Re-fetch the record so we return exactly what is stored in the DB.
-	return s.GetStudentByID(id)
+	updated, err := s.GetStudentByID(ctx, tenantID, id)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	s.notify(func(h storage.Hook) { h.OnUpdate(ctx, old, updated) })
+
+	return updated, nil
+}
+
+// patchableColumns whitelists the fields PatchStudentByID may set, mapped
+// to their columns. Column names can't be ? placeholders, so only names
+// from this map — never from the request — are put into the SQL.
+var patchableColumns = map[string]string{
+	"name":       "name",
+	"email":      "email",
+	"age":        "age",
+	"department": "department",
+	"phone":      "phone",
+	"photo_url":  "photo_url",
+}
+
+// nullableColumns may be cleared with a nil value. department and phone
+// are NOT NULL in the schema, so clearing them stores "" instead.
+var nullableColumns = map[string]any{
+	"department": "",
+	"phone":      "",
+	"photo_url":  nil,
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
-// DeleteStudentByID removes a student row by primary key.
+// PatchStudentByID updates only the columns named in fields.
+//
+// The SET clause is built from the keys, e.g. {"age": 21, "name": "A"}
+// becomes
+//
+//	UPDATE students SET age = ?, name = ?, updated_at = datetime('now'), version = version + 1 WHERE id = ? AND tenant_id = ?
+//
+// Keys are sorted so the same patch always produces the same SQL. Values
+// still go through placeholders; only whitelisted column names are
+// written into the statement itself.
 // ─────────────────────────────────────────────────────────────────────────────
-func (s *SQLite) DeleteStudentByID(id int64) error {
-	stmt, err := s.Db.Prepare("DELETE FROM students WHERE id = ?")
+func (s *SQLite) PatchStudentByID(ctx context.Context, tenantID string, id int64, fields map[string]interface{}) (types.Student, error) {
+	// Hooks receive the record as it was before the change.
+	old, err := s.GetStudentByID(ctx, tenantID, id)
+	if err != nil {
+		return types.Student{}, err
+	}
+	if len(fields) == 0 {
+		return old, nil
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		if _, ok := patchableColumns[key]; !ok {
+			return types.Student{}, fmt.Errorf("PatchStudentByID: field %q cannot be patched", key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sets := make([]string, 0, len(keys))
+	args := make([]any, 0, len(keys)+1)
+	for _, key := range keys {
+		value := fields[key]
+		if value == nil {
+			cleared, ok := nullableColumns[key]
+			if !ok {
+				return types.Student{}, fmt.Errorf("PatchStudentByID: field %q cannot be null", key)
+			}
+			value = cleared
+		}
+		if email, ok := value.(string); ok && key == "email" {
+			value = utils.NormalizeEmail(email)
+		}
+
+		sets = append(sets, patchableColumns[key]+" = ?")
+		args = append(args, value)
+	}
+	sets = append(sets, "updated_at = datetime('now')", "version = version + 1")
+	args = append(args, id, tenantID)
+
+	query := "UPDATE students SET " + strings.Join(sets, ", ") + " WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL"
+	if _, err := s.Db.ExecContext(ctx, query, args...); err != nil {
+		if isUniqueViolation(err) {
+			return types.Student{}, storage.ErrDuplicateEmail
+		}
+		return types.Student{}, fmt.Errorf("PatchStudentByID: exec: %w", err)
+	}
+
+	updated, err := s.GetStudentByID(ctx, tenantID, id)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	s.notify(func(h storage.Hook) { h.OnUpdate(ctx, old, updated) })
+
+	return updated, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// DeleteStudentByID soft-deletes a student: the row stays, with deleted_at
+// set, so the audit trail still has something to point at and
+// RestoreStudentByID can bring it back. Every read skips such rows.
+// ─────────────────────────────────────────────────────────────────────────────
+func (s *SQLite) DeleteStudentByID(ctx context.Context, tenantID string, id int64) (err error) {
+	ctx, span := startSpan(ctx, "sqlite.DeleteStudentByID", studentIDAttr(id))
+	defer func() { endSpan(span, err) }()
+
+	stmt, err := s.Db.PrepareContext(ctx,
+		"UPDATE students SET deleted_at = datetime('now') WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL")
 	if err != nil {
 		return fmt.Errorf("DeleteStudentByID: prepare: %w", err)
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(id)
+	result, err := stmt.ExecContext(ctx, id, tenantID)
 	if err != nil {
 		return fmt.Errorf("DeleteStudentByID: exec: %w", err)
 	}
 
+	// No row changed: there is no such student, or it is already
+	// deleted. Either way there is nothing to tell the hooks.
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("DeleteStudentByID: rows affected: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w with id: %d", storage.ErrNotFound, id)
+	}
+
+	s.notify(func(h storage.Hook) { h.OnDelete(ctx, tenantID, id) })
+
+	return nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// AnonymizeStudentByID erases a student's personal data but keeps the
+// row, so records elsewhere that refer to the id (fees, grades…) still
+// point at something:
+//
+//	UPDATE students SET name = '[deleted]', email = '[deleted@example.com]', phone = '', ... WHERE id = ? AND tenant_id = ?
+//
+// The student is soft-deleted at the same time. Its audit log entries
+// keep what happened and when, but lose the before/after snapshots and
+// request bodies, which hold the very data being erased. Both updates
+// run in one transaction.
+//
+// The hooks are told the student was deleted — not updated, which would
+// write its old name and email straight back into the audit log.
+// ─────────────────────────────────────────────────────────────────────────────
+func (s *SQLite) AnonymizeStudentByID(ctx context.Context, tenantID string, id int64) (err error) {
+	ctx, span := startSpan(ctx, "sqlite.AnonymizeStudentByID", studentIDAttr(id))
+	defer func() { endSpan(span, err) }()
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("AnonymizeStudentByID: begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		UPDATE students
+		SET name = ?, email = ?, phone = '', photo_url = NULL,
+		    deleted_at = datetime('now'), updated_at = datetime('now'), version = version + 1
+		WHERE id = ? AND tenant_id = ?`)
+	if err != nil {
+		return fmt.Errorf("AnonymizeStudentByID: prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, storage.AnonymizedName, storage.AnonymizedEmail, id, tenantID)
+	if err != nil {
+		return fmt.Errorf("AnonymizeStudentByID: exec: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("AnonymizeStudentByID: rows affected: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w with id: %d", storage.ErrNotFound, id)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE audit_log SET before = NULL, after = NULL, request_body = NULL WHERE student_id = ?", id)
+	if err != nil {
+		return fmt.Errorf("AnonymizeStudentByID: audit log: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("AnonymizeStudentByID: commit: %w", err)
+	}
+
+	s.notify(func(h storage.Hook) { h.OnDelete(ctx, tenantID, id) })
+
+	return nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// BulkDeleteStudents soft-deletes many students with one statement:
+//
+//	UPDATE students SET deleted_at = ... WHERE tenant_id = ? AND id IN (?, ?, ?) AND deleted_at IS NULL RETURNING id
+//
+// IN needs one placeholder per ID; the IDs themselves are still bound as
+// arguments. RETURNING tells us which rows matched, so if any ID is
+// missing the transaction is rolled back and nothing is deleted.
+// ─────────────────────────────────────────────────────────────────────────────
+func (s *SQLite) BulkDeleteStudents(ctx context.Context, tenantID string, ids []int64) (int64, error) {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("BulkDeleteStudents: begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	args := make([]any, 0, len(ids)+1)
+	args = append(args, tenantID)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+
+	rows, err := tx.QueryContext(ctx,
+		"UPDATE students SET deleted_at = datetime('now') WHERE tenant_id = ? AND id IN ("+placeholders+") AND deleted_at IS NULL RETURNING id",
+		args...)
+	if err != nil {
+		return 0, fmt.Errorf("BulkDeleteStudents: update: %w", err)
+	}
+	defer rows.Close()
+
+	deleted := make(map[int64]bool, len(ids))
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return 0, fmt.Errorf("BulkDeleteStudents: scan id: %w", err)
+		}
+		deleted[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("BulkDeleteStudents: rows iteration: %w", err)
+	}
+	n := int64(len(deleted))
+
+	if err := storage.CheckFound(ids, deleted); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("BulkDeleteStudents: commit: %w", err)
+	}
+
+	for id := range deleted {
+		s.notify(func(h storage.Hook) { h.OnDelete(ctx, tenantID, id) })
+	}
+
+	return n, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// RestoreStudentByID undoes DeleteStudentByID by clearing deleted_at.
+//
+// It returns ErrNotFound if there is no DELETED student with that id, and
+// ErrDuplicateEmail if a live student has taken the email (or external
+// ID) since. To hooks the student reappears, so they get OnCreate.
+// ─────────────────────────────────────────────────────────────────────────────
+func (s *SQLite) RestoreStudentByID(ctx context.Context, tenantID string, id int64) error {
+	result, err := s.Db.ExecContext(ctx,
+		"UPDATE students SET deleted_at = NULL WHERE id = ? AND tenant_id = ? AND deleted_at IS NOT NULL", id, tenantID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return storage.ErrDuplicateEmail
+		}
+		return fmt.Errorf("RestoreStudentByID: exec: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("RestoreStudentByID: rows affected: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w with id %d among deleted students", storage.ErrNotFound, id)
+	}
+
+	restored, err := s.GetStudentByID(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	s.notify(func(h storage.Hook) { h.OnCreate(ctx, restored) })
+
+	return nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// SetStudentPhotoURL stores the public URL of a student's uploaded photo.
+// RowsAffected tells us whether the id matched anything.
+// ─────────────────────────────────────────────────────────────────────────────
+func (s *SQLite) SetStudentPhotoURL(ctx context.Context, tenantID string, id int64, url string) error {
+	old, err := s.GetStudentByID(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := s.Db.PrepareContext(ctx,
+		"UPDATE students SET photo_url = ?, updated_at = datetime('now'), version = version + 1 WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL")
+	if err != nil {
+		return fmt.Errorf("SetStudentPhotoURL: prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, url, id, tenantID)
+	if err != nil {
+		return fmt.Errorf("SetStudentPhotoURL: exec: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("SetStudentPhotoURL: rows affected: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w with id: %d", storage.ErrNotFound, id)
+	}
+
+	updated, err := s.GetStudentByID(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	s.notify(func(h storage.Hook) { h.OnUpdate(ctx, old, updated) })
+
+	return nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// SetStudentStatus marks a student active, inactive or suspended. The
+// caller checks status is one of types.StudentStatuses; reading the
+// student first both proves the id exists and gives the hooks (and so
+// the audit log) the status it had before.
+// ─────────────────────────────────────────────────────────────────────────────
+func (s *SQLite) SetStudentStatus(ctx context.Context, tenantID string, id int64, status string) error {
+	old, err := s.GetStudentByID(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := s.Db.PrepareContext(ctx,
+		"UPDATE students SET status = ?, updated_at = datetime('now'), version = version + 1 WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL")
+	if err != nil {
+		return fmt.Errorf("SetStudentStatus: prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, status, id, tenantID)
+	if err != nil {
+		return fmt.Errorf("SetStudentStatus: exec: %w", err)
+	}
+
+	// The student may have been deleted since we read it.
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("SetStudentStatus: rows affected: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w with id: %d", storage.ErrNotFound, id)
+	}
+
+	updated, err := s.GetStudentByID(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	s.notify(func(h storage.Hook) { h.OnUpdate(ctx, old, updated) })
+
+	return nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// UpsertStudents inserts each student, or updates the existing row with
+// the same email, inside a single transaction.
+//
+// The write itself is one atomic statement:
+//
+//	INSERT ... ON CONFLICT(lower(email)) DO UPDATE SET name = excluded.name, ...
+//
+// "excluded" is SQLite's name for the row we tried to insert. Because the
+// upsert alone cannot tell us whether it inserted or updated, we look the
+// email up first (inside the same transaction) to report the action.
+// ─────────────────────────────────────────────────────────────────────────────
+func (s *SQLite) UpsertStudents(ctx context.Context, tenantID string, students []types.Student) ([]types.UpsertResult, error) {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("UpsertStudents: begin: %w", err)
+	}
+	// Rollback after a successful Commit is a no-op, so this is safe to
+	// defer unconditionally — it only matters when we return early.
+	defer tx.Rollback()
+
+	lookup, err := tx.PrepareContext(ctx,
+		"SELECT id, name, email, age, COALESCE(photo_url, ''), external_id, department, phone, created_at, updated_at, version, status, tenant_id FROM students WHERE tenant_id = ? AND lower(email) = ? AND deleted_at IS NULL")
+	if err != nil {
+		return nil, fmt.Errorf("UpsertStudents: prepare lookup: %w", err)
+	}
+	defer lookup.Close()
+
+	upsert, err := tx.PrepareContext(ctx, `
+		INSERT INTO students (tenant_id, name, email, age, department, phone) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(tenant_id, lower(email)) WHERE deleted_at IS NULL DO UPDATE
+			SET name = excluded.name, age = excluded.age, department = excluded.department, phone = excluded.phone,
+				updated_at = datetime('now'), version = version + 1
+		RETURNING id, created_at, updated_at, version
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("UpsertStudents: prepare upsert: %w", err)
+	}
+	defer upsert.Close()
+
+	results := make([]types.UpsertResult, 0, len(students))
+
+	// Hook notifications are collected here and only sent after Commit,
+	// so observers never hear about a change that was rolled back.
+	var events []func(storage.Hook)
+
+	for _, student := range students {
+		action := types.UpsertActionUpdated
+		email := utils.NormalizeEmail(student.Email)
+
+		var old types.Student
+		err := lookup.QueryRowContext(ctx, tenantID, email).Scan(
+			&old.ID, &old.Name, &old.Email, &old.Age, &old.PhotoURL, &old.ExternalID, &old.Department, &old.Phone,
+			timestamp{&old.CreatedAt}, timestamp{&old.UpdatedAt}, &old.Version, &old.Status, &old.TenantID)
+		if err == sql.ErrNoRows {
+			action = types.UpsertActionCreated
+		} else if err != nil {
+			return nil, fmt.Errorf("UpsertStudents: lookup: %w", err)
+		}
+
+		var (
+			id                   int64
+			createdAt, updatedAt time.Time
+			version              int
+		)
+		err = upsert.QueryRowContext(ctx, tenantID, student.Name, email, student.Age, student.Department, student.Phone).Scan(
+			&id, timestamp{&createdAt}, timestamp{&updatedAt}, &version)
+		if err != nil {
+			return nil, fmt.Errorf("UpsertStudents: upsert: %w", err)
+		}
+
+		results = append(results, types.UpsertResult{
+			StudentID: id,
+			Email:     email,
+			Action:    action,
+		})
+
+		current := types.Student{
+			ID: int(id), Name: student.Name, Email: email, Age: student.Age,
+			PhotoURL: old.PhotoURL, ExternalID: old.ExternalID, Department: student.Department, Phone: student.Phone,
+			CreatedAt: createdAt, UpdatedAt: updatedAt, Version: version,
+			Status: cmp.Or(old.Status, types.StudentStatusActive), TenantID: tenantID,
+		}
+		if action == types.UpsertActionCreated {
+			events = append(events, func(h storage.Hook) { h.OnCreate(ctx, current) })
+		} else {
+			events = append(events, func(h storage.Hook) { h.OnUpdate(ctx, old, current) })
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("UpsertStudents: commit: %w", err)
+	}
+
+	for _, event := range events {
+		s.notify(event)
+	}
+
+	return results, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// BulkCreateStudents inserts a batch of students in one transaction, with
+// one prepared INSERT executed per student.
+//
+// One transaction means one fsync at Commit instead of one per student,
+// which is most of the cost of an insert. A row that breaks the unique
+// email index only fails its own statement — SQLite undoes that one
+// statement and the transaction carries on — so it is recorded in the
+// BulkError and the rest of the batch is still committed.
+// ─────────────────────────────────────────────────────────────────────────────
+func (s *SQLite) BulkCreateStudents(ctx context.Context, tenantID string, students []types.Student) ([]int64, error) {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("BulkCreateStudents: begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx,
+		"INSERT INTO students (tenant_id, name, email, age, department, phone) VALUES (?, ?, ?, ?, ?, ?) RETURNING id, created_at, updated_at, version")
+	if err != nil {
+		return nil, fmt.Errorf("BulkCreateStudents: prepare: %w", err)
+	}
+	defer stmt.Close()
+
+	ids := make([]int64, len(students))
+	failed := make(map[int]error)
+	var events []func(storage.Hook)
+
+	for i, student := range students {
+		email := utils.NormalizeEmail(student.Email)
+
+		created := types.Student{Name: student.Name, Email: email, Age: student.Age, Department: student.Department, Phone: student.Phone, Status: types.StudentStatusActive, TenantID: tenantID}
+		err := stmt.QueryRowContext(ctx, tenantID, student.Name, email, student.Age, student.Department, student.Phone).Scan(
+			&ids[i], timestamp{&created.CreatedAt}, timestamp{&created.UpdatedAt}, &created.Version)
+		if err != nil {
+			if isUniqueViolation(err) {
+				failed[i] = storage.ErrDuplicateEmail
+				continue
+			}
+			return nil, fmt.Errorf("BulkCreateStudents: exec row %d: %w", i, err)
+		}
+
+		created.ID = int(ids[i])
+		events = append(events, func(h storage.Hook) { h.OnCreate(ctx, created) })
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("BulkCreateStudents: commit: %w", err)
+	}
+
+	for _, event := range events {
+		s.notify(event)
+	}
+
+	if len(failed) > 0 {
+		return ids, &storage.BulkError{Rows: failed}
+	}
+	return ids, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Backup writes a consistent snapshot of the whole database to destPath.
+//
+// Copying the .db file directly could capture a half-finished write.
+// VACUUM INTO builds a fresh, compacted copy from inside a read
+// transaction, so the result is always a valid point-in-time database.
+// destPath must not already exist.
+// ─────────────────────────────────────────────────────────────────────────────
+func (s *SQLite) Backup(ctx context.Context, destPath string) error {
+	if _, err := s.Db.ExecContext(ctx, "VACUUM INTO ?", destPath); err != nil {
+		return fmt.Errorf("Backup: vacuum into: %w", err)
+	}
+	return nil
+}
+
+// CountStudents returns the number of live (not deleted) students of a tenant.
+func (s *SQLite) CountStudents(ctx context.Context, tenantID string) (int64, error) {
+	n, err := countStudents(ctx, s.Db, tenantID, types.FilterOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("CountStudents: %w", err)
+	}
+	return n, nil
+}
+
+// rowQuerier is what countStudents needs: a *sql.DB, or a *sql.Tx for a
+// count that must see the same snapshot as the queries around it.
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// countStudents counts the tenant's live students matching opts' filters
+// (paging and sort are ignored). CountStudents and GetStudentsFiltered
+// both count through it, so the two always agree.
+func countStudents(ctx context.Context, q rowQuerier, tenantID string, opts types.FilterOptions) (int64, error) {
+	where, args := filterWhere(tenantID, opts)
+	var n int64
+	err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM students"+where, args...).Scan(&n)
+	return n, err
+}
+
+// filterWhere builds the WHERE clause selecting the tenant's live
+// students that match opts' filters, and the values for its
+// placeholders. See GetStudentsFiltered.
+func filterWhere(tenantID string, opts types.FilterOptions) (string, []any) {
+	var (
+		conds = []string{"tenant_id = ?", "deleted_at IS NULL"}
+		args  = []any{tenantID}
+	)
+	if opts.Name != "" {
+		conds = append(conds, `name LIKE ? ESCAPE '\'`)
+		args = append(args, likeContains(opts.Name))
+	}
+	if opts.Email != "" {
+		conds = append(conds, `email LIKE ? ESCAPE '\'`)
+		args = append(args, likeContains(opts.Email))
+	}
+	if opts.AgeMin != nil {
+		conds = append(conds, "age >= ?")
+		args = append(args, *opts.AgeMin)
+	}
+	if opts.AgeMax != nil {
+		conds = append(conds, "age <= ?")
+		args = append(args, *opts.AgeMax)
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// CountAllStudents returns the number of live students across every
+// tenant. It is not part of storage.Storage — tenant code never needs it —
+// and only feeds the students_total metric.
+func (s *SQLite) CountAllStudents(ctx context.Context) (int64, error) {
+	var n int64
+	if err := s.Db.QueryRowContext(ctx, "SELECT COUNT(*) FROM students WHERE deleted_at IS NULL").Scan(&n); err != nil {
+		return 0, fmt.Errorf("CountAllStudents: %w", err)
+	}
+	return n, nil
+}
+
+// GetStudentStats computes every figure in one query, so they all
+// describe the same rows. An age of 0 means "not given" (age can be made
+// optional), so NULLIF keeps those out of the age aggregates; over no
+// ages at all, AVG, MIN and MAX are NULL.
+func (s *SQLite) GetStudentStats(ctx context.Context, tenantID string) (types.StudentStats, error) {
+	var (
+		stats          types.StudentStats
+		avg            sql.NullFloat64
+		minAge, maxAge sql.NullInt64
+	)
+	err := s.Db.QueryRowContext(ctx, `
+		SELECT COUNT(*), AVG(NULLIF(age, 0)), MIN(NULLIF(age, 0)), MAX(NULLIF(age, 0))
+		FROM students
+		WHERE tenant_id = ? AND deleted_at IS NULL`, tenantID).Scan(&stats.Total, &avg, &minAge, &maxAge)
+	if err != nil {
+		return types.StudentStats{}, fmt.Errorf("GetStudentStats: %w", err)
+	}
+
+	stats.AvgAge = avg.Float64
+	stats.MinAge = int(minAge.Int64)
+	stats.MaxAge = int(maxAge.Int64)
+	return stats, nil
+}
+
+// Ping checks that the database file can still be opened and queried.
+func (s *SQLite) Ping(ctx context.Context) error {
+	if err := s.Db.PingContext(ctx); err != nil {
+		return fmt.Errorf("Ping: %w", err)
+	}
+	return nil
+}
+
+// Close closes the connection pool, waiting for queries in progress.
+func (s *SQLite) Close() error {
+	if err := s.Db.Close(); err != nil {
+		return fmt.Errorf("Close: %w", err)
+	}
+	return nil
+}
+
+// isUniqueViolation reports whether err is SQLite rejecting a write
+// because it would break a UNIQUE index (in practice: a duplicate email).
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) &&
+		sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
+
+// migrate compares the database's schema version with the migrations in
+// migrationsDir (see Migrate) and, when allowed, upgrades it.
+//
+//	older  → run the migrations (autoMigrate) or fail with instructions
+//	newer  → always fail: the file was upgraded by a newer release, and
+//	         this one would misread it
+func migrate(ctx context.Context, db *sql.DB, autoMigrate bool, migrationsDir string) error {
+	all, version, err := schemaState(ctx, db, migrationsDir)
+	if err != nil {
+		return err
+	}
+	latest := len(all)
+
+	switch {
+	case version > latest:
+		return errNewerSchema(version, latest)
+
+	case version < latest:
+		if !autoMigrate {
+			return fmt.Errorf("database schema version %d is older than required (%d): "+
+				"set database.auto_migrate: true (DB_AUTO_MIGRATE=true) to upgrade it",
+				version, latest)
+		}
+
+		slog.Info("migrating database schema",
+			slog.Int("from_version", version),
+			slog.Int("to_version", latest))
+		if err := applyMigrations(ctx, db, all[version:]); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
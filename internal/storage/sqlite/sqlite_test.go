@@ -0,0 +1,335 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aanand-mishra/students-api/internal/config"
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// newTestStore opens a fresh, migrated database in t.TempDir(). It is
+// closed when the test ends.
+func newTestStore(t *testing.T) *SQLite {
+	t.Helper()
+
+	db, err := New(&config.Config{
+		StoragePath: filepath.Join(t.TempDir(), "test.db"),
+		Database:    config.Database{AutoMigrate: true},
+		SQLite:      config.SQLite{JournalMode: config.JournalModeWAL, BusyTimeoutMs: 5000},
+	})
+	if err != nil {
+		t.Fatalf("sqlite.New: %v", err)
+	}
+	t.Cleanup(func() { db.Db.Close() })
+	return db
+}
+
+// mustCreate inserts a student in the default tenant and returns its ID.
+func mustCreate(t *testing.T, db *SQLite, name, email string, age int) int64 {
+	t.Helper()
+
+	id, err := db.CreateStudent(context.Background(), types.DefaultTenant, name, email, age, "", "")
+	if err != nil {
+		t.Fatalf("CreateStudent(%s): %v", email, err)
+	}
+	return id
+}
+
+func TestUpsertStudents(t *testing.T) {
+	tests := []struct {
+		name        string
+		existing    []string // emails created before the upsert
+		upsert      []string // emails in the upsert batch
+		wantActions []string
+	}{
+		{
+			name:        "all create",
+			upsert:      []string{"a@test.com", "b@test.com"},
+			wantActions: []string{types.UpsertActionCreated, types.UpsertActionCreated},
+		},
+		{
+			name:        "all update",
+			existing:    []string{"a@test.com", "b@test.com"},
+			upsert:      []string{"a@test.com", "b@test.com"},
+			wantActions: []string{types.UpsertActionUpdated, types.UpsertActionUpdated},
+		},
+		{
+			name:        "mixed",
+			existing:    []string{"a@test.com"},
+			upsert:      []string{"b@test.com", "A@Test.com"},
+			wantActions: []string{types.UpsertActionCreated, types.UpsertActionUpdated},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestStore(t)
+			ctx := context.Background()
+
+			ids := map[string]int64{}
+			for _, email := range tt.existing {
+				ids[email] = mustCreate(t, db, "Old", email, 20)
+			}
+
+			batch := make([]types.Student, len(tt.upsert))
+			for i, email := range tt.upsert {
+				batch[i] = types.Student{Name: "New", Email: email, Age: 30}
+			}
+
+			results, err := db.UpsertStudents(ctx, types.DefaultTenant, batch)
+			if err != nil {
+				t.Fatalf("UpsertStudents: %v", err)
+			}
+
+			var actions []string
+			for _, r := range results {
+				actions = append(actions, r.Action)
+			}
+			if !reflect.DeepEqual(actions, tt.wantActions) {
+				t.Fatalf("actions = %v, want %v", actions, tt.wantActions)
+			}
+
+			for _, r := range results {
+				// An update keeps the student's ID.
+				if id, ok := ids[r.Email]; ok && r.StudentID != id {
+					t.Errorf("%s: id = %d, want existing id %d", r.Email, r.StudentID, id)
+				}
+
+				got, err := db.GetStudentByID(ctx, types.DefaultTenant, r.StudentID)
+				if err != nil {
+					t.Fatalf("GetStudentByID(%d): %v", r.StudentID, err)
+				}
+				if got.Name != "New" || got.Age != 30 {
+					t.Errorf("%s: stored %q aged %d, want \"New\" aged 30", r.Email, got.Name, got.Age)
+				}
+			}
+
+			total, err := db.CountStudents(ctx, types.DefaultTenant)
+			if err != nil {
+				t.Fatalf("CountStudents: %v", err)
+			}
+			if want := int64(len(ids) + countCreated(results)); total != want {
+				t.Errorf("CountStudents = %d, want %d", total, want)
+			}
+		})
+	}
+}
+
+// countCreated returns how many results created a student.
+func countCreated(results []types.UpsertResult) int {
+	n := 0
+	for _, r := range results {
+		if r.Action == types.UpsertActionCreated {
+			n++
+		}
+	}
+	return n
+}
+
+func TestEmailIsCaseInsensitive(t *testing.T) {
+	db := newTestStore(t)
+	ctx := context.Background()
+
+	id := mustCreate(t, db, "Rakesh", "rakesh@test.com", 35)
+
+	if _, err := db.CreateStudent(ctx, types.DefaultTenant, "Rakesh", "RAKESH@TEST.COM", 35, "", ""); !errors.Is(err, storage.ErrDuplicateEmail) {
+		t.Errorf("CreateStudent(RAKESH@TEST.COM) error = %v, want ErrDuplicateEmail", err)
+	}
+
+	for _, email := range []string{"rakesh@test.com", "RAKESH@TEST.COM", "Rakesh@Test.com"} {
+		got, err := db.GetStudentByEmail(ctx, types.DefaultTenant, email)
+		if err != nil {
+			t.Errorf("GetStudentByEmail(%s): %v", email, err)
+			continue
+		}
+		if int64(got.ID) != id || got.Email != "rakesh@test.com" {
+			t.Errorf("GetStudentByEmail(%s) = id %d %s, want id %d rakesh@test.com", email, got.ID, got.Email, id)
+		}
+	}
+}
+
+func TestDuplicateEmail(t *testing.T) {
+	db := newTestStore(t)
+	ctx := context.Background()
+
+	rakesh := mustCreate(t, db, "Rakesh", "rakesh@test.com", 35)
+	priya := mustCreate(t, db, "Priya", "priya@test.com", 20)
+
+	if _, err := db.CreateStudent(ctx, types.DefaultTenant, "Other", "rakesh@test.com", 20, "", ""); !errors.Is(err, storage.ErrDuplicateEmail) {
+		t.Errorf("CreateStudent with a taken email = %v, want ErrDuplicateEmail", err)
+	}
+
+	student, err := db.GetStudentByID(ctx, types.DefaultTenant, priya)
+	if err != nil {
+		t.Fatal(err)
+	}
+	student.Email = "rakesh@test.com"
+	if _, err := db.UpdateStudentByID(ctx, types.DefaultTenant, priya, student); !errors.Is(err, storage.ErrDuplicateEmail) {
+		t.Errorf("UpdateStudentByID to a taken email = %v, want ErrDuplicateEmail", err)
+	}
+
+	// Emails are unique per tenant, among students that aren't deleted.
+	if _, err := db.CreateStudent(ctx, "other", "Other", "rakesh@test.com", 20, "", ""); err != nil {
+		t.Errorf("the same email in another tenant: %v", err)
+	}
+	if err := db.DeleteStudentByID(ctx, types.DefaultTenant, rakesh); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.CreateStudent(ctx, types.DefaultTenant, "New Rakesh", "rakesh@test.com", 20, "", ""); err != nil {
+		t.Errorf("reusing a deleted student's email: %v", err)
+	}
+}
+
+func TestGetRandomStudent(t *testing.T) {
+	db := newTestStore(t)
+	ctx := context.Background()
+
+	if _, err := db.GetRandomStudent(ctx, types.DefaultTenant); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("empty table: err = %v, want ErrNotFound", err)
+	}
+
+	deleted := mustCreate(t, db, "Gone", "gone@test.com", 30)
+	kept := mustCreate(t, db, "Kept", "kept@test.com", 31)
+	if err := db.DeleteStudentByID(ctx, types.DefaultTenant, deleted); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.CreateStudent(ctx, "other-school", "Other", "other@test.com", 32, "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only one student is live in this tenant, so every draw is it.
+	for range 20 {
+		s, err := db.GetRandomStudent(ctx, types.DefaultTenant)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int64(s.ID) != kept {
+			t.Fatalf("drew student %d (%s), want only %d", s.ID, s.Name, kept)
+		}
+	}
+}
+
+func TestImportStudentsByExternalID(t *testing.T) {
+	db := newTestStore(t)
+	ctx := context.Background()
+	sis1 := "SIS-1"
+
+	results, err := db.ImportStudents(ctx, types.DefaultTenant, []types.Student{
+		{Name: "Priya", Email: "priya@test.com", Age: 22, ExternalID: &sis1},
+		{Name: "Amit", Email: "amit@test.com", Age: 19},
+	})
+	if err != nil {
+		t.Fatalf("ImportStudents: %v", err)
+	}
+	if results[0].Action != types.UpsertActionCreated || results[1].Action != types.UpsertActionCreated {
+		t.Errorf("first import results = %+v, want both created", results)
+	}
+
+	// Another tenant's SIS-1 is a different student.
+	if _, err := db.ImportStudents(ctx, "other", []types.Student{
+		{Name: "Neha", Email: "neha@test.com", Age: 30, ExternalID: &sis1},
+	}); err != nil {
+		t.Fatalf("ImportStudents in another tenant: %v", err)
+	}
+
+	results, err = db.ImportStudents(ctx, types.DefaultTenant, []types.Student{
+		{Name: "Priya S", Email: "priya@test.com", Age: 23, ExternalID: &sis1},
+	})
+	if err != nil {
+		t.Fatalf("re-import: %v", err)
+	}
+	if results[0].Action != types.UpsertActionUpdated {
+		t.Errorf("re-import action = %q, want SIS-1 updated", results[0].Action)
+	}
+
+	priya, err := db.GetStudentByExternalID(ctx, types.DefaultTenant, sis1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if priya.Name != "Priya S" || priya.Age != 23 {
+		t.Errorf("SIS-1 = %+v, want it updated", priya)
+	}
+	neha, err := db.GetStudentByExternalID(ctx, "other", sis1)
+	if err != nil || neha.Name != "Neha" {
+		t.Errorf("the other tenant's SIS-1 = %+v, %v; want it untouched", neha, err)
+	}
+
+	// Without an external ID there is nothing to match on: the same
+	// student imported again is a duplicate.
+	if _, err := db.ImportStudents(ctx, types.DefaultTenant, []types.Student{
+		{Name: "Amit", Email: "amit@test.com", Age: 19},
+	}); !errors.Is(err, storage.ErrDuplicateEmail) {
+		t.Errorf("re-importing without external_id = %v, want ErrDuplicateEmail", err)
+	}
+}
+
+func TestCountStudents(t *testing.T) {
+	db := newTestStore(t)
+	ctx := context.Background()
+
+	var ids []int64
+	for i := 1; i <= 5; i++ {
+		ids = append(ids, mustCreate(t, db, fmt.Sprintf("Student %d", i), fmt.Sprintf("s%d@test.com", i), 20+i))
+	}
+	// Another tenant's students are not counted.
+	if _, err := db.CreateStudent(ctx, "other", "Priya", "priya@test.com", 22, "", ""); err != nil {
+		t.Fatalf("CreateStudent in another tenant: %v", err)
+	}
+
+	if n, err := db.CountStudents(ctx, types.DefaultTenant); err != nil || n != 5 {
+		t.Errorf("CountStudents = %d, %v; want 5", n, err)
+	}
+
+	if err := db.DeleteStudentByID(ctx, types.DefaultTenant, ids[2]); err != nil {
+		t.Fatalf("DeleteStudentByID: %v", err)
+	}
+	if n, err := db.CountStudents(ctx, types.DefaultTenant); err != nil || n != 4 {
+		t.Errorf("CountStudents after a soft delete = %d, %v; want 4", n, err)
+	}
+
+	// An unfiltered list page counts the same students.
+	if _, total, err := db.GetStudentsFiltered(ctx, types.DefaultTenant, types.FilterOptions{Limit: 2}); err != nil || total != 4 {
+		t.Errorf("GetStudentsFiltered total = %d, %v; want 4", total, err)
+	}
+}
+
+func TestPing(t *testing.T) {
+	db := newTestStore(t)
+	ctx := context.Background()
+
+	if err := db.Ping(ctx); err != nil {
+		t.Fatalf("Ping on an open database: %v", err)
+	}
+
+	// Closing the pool closes the file underneath; a readiness probe must
+	// notice.
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := db.Ping(ctx); err == nil {
+		t.Error("Ping on a closed database = nil, want an error")
+	}
+}
+
+func TestClose(t *testing.T) {
+	db := newTestStore(t)
+	ctx := context.Background()
+	mustCreate(t, db, "Rakesh", "rakesh@test.com", 35)
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if _, err := db.GetStudents(ctx, types.DefaultTenant); err == nil {
+		t.Error("GetStudents after Close = nil error")
+	}
+	if _, err := db.CreateStudent(ctx, types.DefaultTenant, "Priya", "priya@test.com", 22, "", ""); err == nil {
+		t.Error("CreateStudent after Close = nil error")
+	}
+}
@@ -0,0 +1,53 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+)
+
+// SqliteStats describes the database file and its contents, for
+// operators (see GET /admin/db/stats).
+type SqliteStats struct {
+	PageCount     int64 `json:"page_count"`     // pages in the database file
+	PageSize      int64 `json:"page_size"`      // bytes per page
+	FreelistCount int64 `json:"freelist_count"` // unused pages (reclaimable by VACUUM)
+	SizeBytes     int64 `json:"size_bytes"`     // page_count × page_size
+
+	StudentCount int64 `json:"student_count"`
+	// StudentDataBytes is the total length of all names and emails — a
+	// rough measure of how much of the file is actual student data.
+	StudentDataBytes int64 `json:"student_data_bytes"`
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// DBStats gathers SqliteStats using SQLite's PRAGMAs and two aggregate
+// queries over the students table.
+//
+// PRAGMA page_count etc. are read-only queries that return one integer
+// each, so they are scanned just like SELECT results.
+// ─────────────────────────────────────────────────────────────────────────────
+func (s *SQLite) DBStats(ctx context.Context) (SqliteStats, error) {
+	var stats SqliteStats
+
+	queries := []struct {
+		sql  string
+		dest *int64
+	}{
+		{"PRAGMA page_count", &stats.PageCount},
+		{"PRAGMA page_size", &stats.PageSize},
+		{"PRAGMA freelist_count", &stats.FreelistCount},
+		{"SELECT COUNT(*) FROM students", &stats.StudentCount},
+		// SUM over zero rows is NULL, hence the COALESCE.
+		{"SELECT COALESCE(SUM(length(name) + length(email)), 0) FROM students", &stats.StudentDataBytes},
+	}
+
+	for _, q := range queries {
+		if err := s.Db.QueryRowContext(ctx, q.sql).Scan(q.dest); err != nil {
+			return SqliteStats{}, fmt.Errorf("DBStats: %s: %w", q.sql, err)
+		}
+	}
+
+	stats.SizeBytes = stats.PageCount * stats.PageSize
+
+	return stats, nil
+}
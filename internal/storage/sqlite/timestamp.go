@@ -0,0 +1,46 @@
+package sqlite
+
+import (
+	"fmt"
+	"time"
+)
+
+// sqliteDateTime is the text format of datetime('now'), always UTC.
+const sqliteDateTime = "2006-01-02 15:04:05"
+
+// timestamp scans a DATETIME column into a time.Time in UTC.
+//
+// SQLite has no date type: datetime('now') stores text such as
+// "2024-05-01 09:30:00", with no zone. The driver turns columns declared
+// DATETIME into a time.Time itself, but an expression loses the declared
+// type and arrives as a string — timestamp accepts either.
+type timestamp struct {
+	t *time.Time
+}
+
+// Scan implements sql.Scanner.
+func (ts timestamp) Scan(src any) error {
+	switch v := src.(type) {
+	case time.Time:
+		*ts.t = v.UTC()
+		return nil
+	case string:
+		return ts.parse(v)
+	case []byte:
+		return ts.parse(string(v))
+	default:
+		return fmt.Errorf("timestamp: cannot scan %T", src)
+	}
+}
+
+func (ts timestamp) parse(s string) error {
+	t, err := time.ParseInLocation(sqliteDateTime, s, time.UTC)
+	if err != nil {
+		// Also accept RFC 3339, in case the value was written by Go.
+		if t, err = time.Parse(time.RFC3339Nano, s); err != nil {
+			return fmt.Errorf("timestamp: %w", err)
+		}
+	}
+	*ts.t = t.UTC()
+	return nil
+}
@@ -0,0 +1,47 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/aanand-mishra/students-api/internal/storage"
+)
+
+// tracer creates the storage spans. It comes from the global provider,
+// which tracing.Setup replaces at startup; until then (and in tools that
+// never call it) spans cost next to nothing and go nowhere.
+var tracer = otel.Tracer("github.com/aanand-mishra/students-api/internal/storage/sqlite")
+
+// startSpan starts a child span of whatever span ctx carries (normally
+// the HTTP request's). Pass the returned context on, so queries made
+// inside — like UpdateStudentByID's GetStudentByID — nest under it.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(append(attrs, semconv.DBSystemSqlite)...))
+}
+
+// endSpan ends span, marking it failed if err is a real failure. Not
+// found, duplicate email and version conflicts are answers the caller
+// asked for, not database errors, so they leave the span OK.
+func endSpan(span trace.Span, err error) {
+	if err != nil &&
+		!errors.Is(err, storage.ErrNotFound) &&
+		!errors.Is(err, storage.ErrDuplicateEmail) &&
+		!errors.Is(err, storage.ErrVersionConflict) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// studentIDAttr tags a span with the student it is about.
+func studentIDAttr(id int64) attribute.KeyValue {
+	return attribute.Int64("student.id", id)
+}
@@ -0,0 +1,50 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// WarmUp opens n connections up front so the first requests after startup
+// don't pay for opening them.
+//
+// database/sql creates connections lazily, and Ping alone isn't enough:
+// pinging n times in a row reuses the same idle connection. So all n are
+// checked out at once (forcing the pool to open each one), pinged, and
+// then returned together.
+//
+// New keeps at least database.warm_up_conns connections idle, so they
+// stay open once they come back.
+// ─────────────────────────────────────────────────────────────────────────────
+func (s *SQLite) WarmUp(ctx context.Context, n int) error {
+	// Asking for more connections than the pool may open would wait for
+	// one to come back, and none will until WarmUp returns.
+	if max := s.Db.Stats().MaxOpenConnections; max > 0 && n > max {
+		n = max
+	}
+	if n <= 0 {
+		return nil
+	}
+	conns := make([]*sql.Conn, 0, n)
+	defer func() {
+		for _, c := range conns {
+			c.Close() // returns it to the pool, still open
+		}
+	}()
+
+	for i := 0; i < n; i++ {
+		c, err := s.Db.Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("WarmUp: connection %d: %w", i+1, err)
+		}
+		conns = append(conns, c)
+
+		if err := c.PingContext(ctx); err != nil {
+			return fmt.Errorf("WarmUp: ping connection %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/aanand-mishra/students-api/internal/config"
+)
+
+// openWarm opens a fresh database configured to keep warmUp connections.
+func openWarm(tb testing.TB, journalMode string, warmUp int) *SQLite {
+	tb.Helper()
+
+	db, err := New(&config.Config{
+		StoragePath: filepath.Join(tb.TempDir(), "test.db"),
+		Database:    config.Database{AutoMigrate: true, WarmUpConns: warmUp},
+		SQLite:      config.SQLite{JournalMode: journalMode, BusyTimeoutMs: 5000},
+	})
+	if err != nil {
+		tb.Fatalf("sqlite.New: %v", err)
+	}
+	tb.Cleanup(func() { db.Db.Close() })
+	return db
+}
+
+func TestWarmUp(t *testing.T) {
+	db := openWarm(t, config.JournalModeWAL, 4)
+
+	if err := db.WarmUp(context.Background(), 4); err != nil {
+		t.Fatalf("WarmUp: %v", err)
+	}
+	stats := db.Db.Stats()
+	if stats.OpenConnections < 4 || stats.Idle < 4 {
+		t.Errorf("open = %d, idle = %d after warming up 4; want 4 kept open and idle", stats.OpenConnections, stats.Idle)
+	}
+	if stats.InUse != 0 {
+		t.Errorf("%d connections still checked out", stats.InUse)
+	}
+}
+
+func TestWarmUpCappedByPool(t *testing.T) {
+	// The rollback journal limits the pool to one connection; asking for
+	// more must not wait forever for a second one.
+	db := openWarm(t, config.JournalModeDelete, 3)
+
+	if err := db.WarmUp(context.Background(), 3); err != nil {
+		t.Fatalf("WarmUp: %v", err)
+	}
+	if open := db.Db.Stats().OpenConnections; open != 1 {
+		t.Errorf("open = %d, want the pool's maximum of 1", open)
+	}
+}
+
+func TestWarmUpNothing(t *testing.T) {
+	db := openWarm(t, config.JournalModeWAL, 0)
+	if err := db.WarmUp(context.Background(), 0); err != nil {
+		t.Errorf("WarmUp(0) = %v", err)
+	}
+}
+
+func TestWarmUpCanceled(t *testing.T) {
+	db := openWarm(t, config.JournalModeWAL, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := db.WarmUp(ctx, 2); err == nil {
+		t.Error("WarmUp succeeded with a canceled context")
+	}
+}
+
+// BenchmarkFirstRequests measures a burst of concurrent queries against a
+// freshly opened database, with and without warming up its connections
+// first — what the first requests after a deploy see.
+func BenchmarkFirstRequests(b *testing.B) {
+	const concurrent = 8
+
+	for _, warm := range []bool{false, true} {
+		name := "cold"
+		if warm {
+			name = "warm"
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				db := openWarm(b, config.JournalModeWAL, concurrent)
+				if warm {
+					if err := db.WarmUp(context.Background(), concurrent); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.StartTimer()
+
+				var wg sync.WaitGroup
+				for j := 0; j < concurrent; j++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						if _, err := db.GetStudentStats(context.Background(), "default"); err != nil {
+							b.Error(err)
+						}
+					}()
+				}
+				wg.Wait()
+
+				b.StopTimer()
+				db.Db.Close()
+				b.StartTimer()
+			}
+		})
+	}
+}
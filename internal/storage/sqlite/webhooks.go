@@ -0,0 +1,66 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// The methods below satisfy webhook.Store.
+
+// CreateWebhook stores a tenant's subscription and returns it with its
+// new ID and creation time.
+func (s *SQLite) CreateWebhook(ctx context.Context, tenantID string, sub types.WebhookSubscription) (types.WebhookSubscription, error) {
+	err := s.Db.QueryRowContext(ctx, `
+		INSERT INTO webhooks (tenant_id, url, secret) VALUES (?, ?, ?)
+		RETURNING id, created_at`, tenantID, sub.URL, sub.Secret).
+		Scan(&sub.ID, timestamp{&sub.CreatedAt})
+	if err != nil {
+		return types.WebhookSubscription{}, fmt.Errorf("CreateWebhook: %w", err)
+	}
+	return sub, nil
+}
+
+// GetWebhooks returns every subscription of the tenant, secrets
+// included, in id order. Returns an empty slice (not nil) if there are
+// none.
+func (s *SQLite) GetWebhooks(ctx context.Context, tenantID string) ([]types.WebhookSubscription, error) {
+	rows, err := s.Db.QueryContext(ctx,
+		"SELECT id, url, secret, created_at FROM webhooks WHERE tenant_id = ? ORDER BY id", tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("GetWebhooks: query: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []types.WebhookSubscription{}
+	for rows.Next() {
+		var sub types.WebhookSubscription
+		if err := rows.Scan(&sub.ID, &sub.URL, &sub.Secret, timestamp{&sub.CreatedAt}); err != nil {
+			return nil, fmt.Errorf("GetWebhooks: scan: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetWebhooks: rows: %w", err)
+	}
+	return subs, nil
+}
+
+// DeleteWebhook removes a tenant's subscription. Returns
+// storage.ErrNotFound if the tenant has none with the given id.
+func (s *SQLite) DeleteWebhook(ctx context.Context, tenantID string, id int64) error {
+	res, err := s.Db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = ? AND tenant_id = ?", id, tenantID)
+	if err != nil {
+		return fmt.Errorf("DeleteWebhook: exec: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("DeleteWebhook: rows affected: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("DeleteWebhook: %w", storage.ErrNotFound)
+	}
+	return nil
+}
@@ -15,29 +15,262 @@
 // This is the Dependency Inversion Principle in practice.
 package storage
 
-import "github.com/aanand-mishra/students-api/internal/types"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// ErrDuplicateEmail is returned by write methods when another student
+// already uses the given email (compared case-insensitively).
+// Handlers check for it with errors.Is and respond 409 Conflict.
+var ErrDuplicateEmail = errors.New("a student with this email already exists")
+
+// ErrNotFound is returned when a lookup matches no student.
+// Handlers check for it with errors.Is and respond 404 Not Found.
+var ErrNotFound = errors.New("no student found")
+
+// ErrVersionConflict is returned by UpdateStudentByID when the student
+// has changed since the client read it: the version sent with the update
+// is no longer the stored one. Handlers respond 409 Conflict; the client
+// should fetch the student again and reapply its change.
+var ErrVersionConflict = errors.New("student was changed by someone else")
+
+// ErrCapacityExceeded is returned when creating students would take the
+// total over the configured maximum (see the capacity package).
+// Handlers respond 403 Forbidden.
+var ErrCapacityExceeded = errors.New("the maximum number of students has been reached")
+
+// ErrUnavailable is returned by decorators such as CachingStorage when a
+// write fails because the database itself could not be reached.
+// Handlers respond 503 Service Unavailable.
+var ErrUnavailable = errors.New("database is unavailable")
+
+// ErrStale accompanies a VALID result that was served from a cache
+// because the database failed. Callers should use the returned value and
+// may tell the client it could be out of date.
+var ErrStale = errors.New("result served from cache: database is unavailable")
+
+// ErrUnsupportedFilter is returned when a storage can't apply a requested
+// filter — for example, sorting by a field it doesn't know.
+// Handlers respond 400 Bad Request.
+var ErrUnsupportedFilter = errors.New("filter is not supported")
+
+// What AnonymizeStudentByID leaves in place of an erased student's name
+// and email.
+const (
+	AnonymizedName  = "[deleted]"
+	AnonymizedEmail = "[deleted@example.com]"
+)
+
+// BulkError is returned by BulkCreateStudents when some of the students
+// could not be created. The others were still created.
+type BulkError struct {
+	// Rows maps the index of each student that was not created to why,
+	// e.g. ErrDuplicateEmail.
+	Rows map[int]error
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("%d of the students could not be created", len(e.Rows))
+}
+
+// Unwrap exposes the row errors, so errors.Is(err, ErrDuplicateEmail)
+// works on a BulkError too. They are returned in row order.
+func (e *BulkError) Unwrap() []error {
+	indexes := make([]int, 0, len(e.Rows))
+	for i := range e.Rows {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	errs := make([]error, len(indexes))
+	for n, i := range indexes {
+		errs[n] = e.Rows[i]
+	}
+	return errs
+}
+
+// MissingIDsError is returned by BulkDeleteStudents when some of the IDs
+// match no student. It wraps ErrNotFound.
+type MissingIDsError struct {
+	IDs []int64 // in the order they were asked for
+}
+
+func (e *MissingIDsError) Error() string {
+	return fmt.Sprintf("%s with ids: %v", ErrNotFound, e.IDs)
+}
+
+func (e *MissingIDsError) Unwrap() error {
+	return ErrNotFound
+}
+
+// CheckFound returns a *MissingIDsError listing the ids that are not in
+// found, or nil if all of them are. Storage implementations use it to
+// report on a batch.
+func CheckFound(ids []int64, found map[int64]bool) error {
+	var missing []int64
+	listed := make(map[int64]bool)
+	for _, id := range ids {
+		// Listed once, even if asked for twice.
+		if !found[id] && !listed[id] {
+			missing = append(missing, id)
+			listed[id] = true
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingIDsError{IDs: missing}
+	}
+	return nil
+}
 
 // Storage is the database contract.
+//
+// Every method takes the request's context.Context first. It carries
+// cancellation (a client that disconnects stops its query) and
+// request-scoped values such as audit details down to the storage layer
+// and its hooks.
+//
+// The student methods then take the tenant (the school) to work on, and
+// only ever see that tenant's students: a student of another tenant is
+// simply not found. It is a parameter rather than a context value so
+// that no call can forget it — leaving it out doesn't compile. Handlers
+// get it from middleware.TenantFromContext.
+//
 // Any concrete type that implements ALL of these methods automatically
 // satisfies this interface — Go does this implicitly (no "implements"
 // keyword required).
 type Storage interface {
 	// CreateStudent inserts a new student record and returns the auto-
-	// generated primary-key ID. Returns an error on failure.
-	CreateStudent(name string, email string, age int) (int64, error)
+	// generated primary-key ID. Returns ErrDuplicateEmail if the email is
+	// already taken, or another error on failure.
+	CreateStudent(ctx context.Context, tenantID string, name string, email string, age int, department string, phone string) (int64, error)
 
 	// GetStudentByID fetches a single student by their primary key.
 	// Returns an error (with a descriptive message) if not found.
-	GetStudentByID(id int64) (types.Student, error)
+	GetStudentByID(ctx context.Context, tenantID string, id int64) (types.Student, error)
 
-	// GetStudents returns every student in the database.
+	// GetStudentByEmail fetches the student with the given email, compared
+	// case-insensitively. Returns ErrNotFound if there is none.
+	GetStudentByEmail(ctx context.Context, tenantID string, email string) (types.Student, error)
+
+	// GetStudents returns every student of the tenant.
 	// Returns an empty slice (not nil) if there are no students.
-	GetStudents() ([]types.Student, error)
+	GetStudents(ctx context.Context, tenantID string) ([]types.Student, error)
+
+	// GetStudentsFiltered returns the students matching opts, in
+	// opts.Sort order or else newest first (created_at DESC, id DESC),
+	// one page of them when opts.Limit is set, together with the total
+	// number of matches. Both come from one consistent snapshot of
+	// the table. Zero-value options match every student.
+	GetStudentsFiltered(ctx context.Context, tenantID string, opts types.FilterOptions) ([]types.Student, int64, error)
+
+	// SearchStudents returns the students whose name or email contains
+	// query, ignoring case, newest first (created_at DESC, id DESC).
+	// Returns an empty slice (not nil) if none match.
+	SearchStudents(ctx context.Context, tenantID string, query string) ([]types.Student, error)
+
+	// GetRandomStudent returns one student chosen at random.
+	// Returns ErrNotFound if there are no students.
+	GetRandomStudent(ctx context.Context, tenantID string) (types.Student, error)
 
 	// UpdateStudentByID replaces the fields of an existing student.
-	// Returns the updated student record or an error.
-	UpdateStudentByID(id int64, student types.Student) (types.Student, error)
+	// student.Version must be the student's current version — the one the
+	// caller read — and the update bumps it by one. Returns the updated
+	// student record, ErrVersionConflict if the student is at another
+	// version, ErrDuplicateEmail if the new email belongs to another
+	// student, or another error.
+	UpdateStudentByID(ctx context.Context, tenantID string, id int64, student types.Student) (types.Student, error)
+
+	// PatchStudentByID sets only the given fields of a student, keyed by
+	// their JSON names ("name", "email", "age", "department", "phone",
+	// "photo_url"); every other field keeps its stored value. A nil value
+	// clears an optional field. Returns the updated student, ErrNotFound,
+	// or ErrDuplicateEmail. Unknown field names are an error.
+	PatchStudentByID(ctx context.Context, tenantID string, id int64, fields map[string]interface{}) (types.Student, error)
+
+	// DeleteStudentByID soft-deletes a student: the record is kept but
+	// hidden from every other method until it is restored. Returns
+	// ErrNotFound if there is no such student or it is already deleted.
+	DeleteStudentByID(ctx context.Context, tenantID string, id int64) error
+
+	// AnonymizeStudentByID erases a student's personal data (the GDPR
+	// right to erasure) while keeping the row for the records that refer
+	// to it: name and email are replaced with placeholders, the phone is
+	// cleared, the student is soft-deleted and the personal data in its
+	// audit log entries is dropped. It works on deleted students too.
+	// Returns ErrNotFound if no student, deleted or not, has the id.
+	AnonymizeStudentByID(ctx context.Context, tenantID string, id int64) error
+
+	// BulkDeleteStudents soft-deletes the given students in a single
+	// transaction and returns how many were deleted. It is all or
+	// nothing: if any ID matches no student (or an already deleted one),
+	// nothing is deleted and a *MissingIDsError lists those IDs.
+	BulkDeleteStudents(ctx context.Context, tenantID string, ids []int64) (int64, error)
+
+	// RestoreStudentByID brings back a soft-deleted student. Returns
+	// ErrNotFound if no deleted student has the given id, or
+	// ErrDuplicateEmail if its email has been taken in the meantime.
+	RestoreStudentByID(ctx context.Context, tenantID string, id int64) error
+
+	// SetStudentPhotoURL records the public URL of a student's photo.
+	// Returns an error if no student has the given id.
+	SetStudentPhotoURL(ctx context.Context, tenantID string, id int64, url string) error
+
+	// SetStudentStatus sets a student's status to one of
+	// types.StudentStatuses. Returns ErrNotFound if no student has the
+	// given id.
+	SetStudentStatus(ctx context.Context, tenantID string, id int64, status string) error
+
+	// UpsertStudents inserts or updates each student, matched by email.
+	// The whole batch is applied atomically: either every student is
+	// written or none are. Results are returned in input order.
+	UpsertStudents(ctx context.Context, tenantID string, students []types.Student) ([]types.UpsertResult, error)
+
+	// BulkCreateStudents inserts many students in a single transaction
+	// and returns their new IDs in input order. A student whose email is
+	// already taken — by an existing student or an earlier one in the
+	// batch — is skipped: its ID is 0 and the returned *BulkError says
+	// why. Any other error rolls the whole batch back.
+	BulkCreateStudents(ctx context.Context, tenantID string, students []types.Student) ([]int64, error)
+
+	// GetStudentByExternalID fetches the student imported with the given
+	// external ID. Returns ErrNotFound if there is none.
+	GetStudentByExternalID(ctx context.Context, tenantID string, externalID string) (types.Student, error)
+
+	// ImportStudents writes a batch of imported students atomically.
+	// Students with an ExternalID update the row with that external ID if
+	// there is one; all others are inserted as new students. Returns
+	// ErrDuplicateEmail if an email is already used by another student.
+	// Results are returned in input order.
+	ImportStudents(ctx context.Context, tenantID string, students []types.Student) ([]types.UpsertResult, error)
+
+	// CountStudents returns how many students the tenant has, not
+	// counting soft-deleted ones.
+	CountStudents(ctx context.Context, tenantID string) (int64, error)
+
+	// GetStudentStats returns the number of students and their average,
+	// youngest and oldest age, not counting soft-deleted ones.
+	GetStudentStats(ctx context.Context, tenantID string) (types.StudentStats, error)
+
+	// GetAuditLog returns every audit entry recorded for a student of the
+	// tenant, oldest first — including those of a deleted student.
+	// Returns an empty slice (not nil) if there are none, or if the
+	// student belongs to another tenant.
+	GetAuditLog(ctx context.Context, tenantID string, studentID int64) ([]types.AuditEntry, error)
+
+	// Ping checks that the database can be reached, for readiness probes.
+	Ping(ctx context.Context) error
+
+	// Close releases the database connections. It is called once, at
+	// shutdown, after the last request has finished; every method fails
+	// after it.
+	Close() error
 
-	// DeleteStudentByID removes a student record permanently.
-	DeleteStudentByID(id int64) error
+	// RegisterHook adds a Hook to be notified of every successful
+	// create, update and delete. See Hook for the calling contract.
+	RegisterHook(hook Hook)
 }
@@ -0,0 +1,65 @@
+package testing
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// goldenDir is where golden files live, relative to the package under
+// test (go test runs each package in its own directory).
+const goldenDir = "testdata/golden"
+
+// UpdateGoldenEnv is the environment variable that makes AssertGolden
+// (re)write golden files instead of comparing against them:
+//
+//	UPDATE_GOLDEN=true go test ./...
+const UpdateGoldenEnv = "UPDATE_GOLDEN"
+
+// AssertGolden compares actual with testdata/golden/<name>.json and fails
+// the test if they differ — catching response shapes that change by
+// accident (a renamed field, a new one, a different error format).
+//
+// With UPDATE_GOLDEN=true the file is written instead, for the first run
+// or after an intended change; review the diff before committing it.
+//
+// JSON is re-indented before comparing or writing so golden files are
+// readable and the comparison ignores whitespace differences.
+func AssertGolden(t *testing.T, name string, actual []byte) {
+	t.Helper()
+
+	actual = normalizeJSON(actual)
+	path := filepath.Join(goldenDir, name+".json")
+
+	if os.Getenv(UpdateGoldenEnv) == "true" {
+		if err := os.MkdirAll(goldenDir, 0o755); err != nil {
+			t.Fatalf("AssertGolden: create %s: %v", goldenDir, err)
+		}
+		if err := os.WriteFile(path, actual, 0o644); err != nil {
+			t.Fatalf("AssertGolden: write %s: %v", path, err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("AssertGolden: read %s: %v (run with %s=true to create it)", path, err, UpdateGoldenEnv)
+	}
+
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("AssertGolden: %s differs from golden file\n got: %s\nwant: %s", name, actual, expected)
+	}
+}
+
+// normalizeJSON indents valid JSON consistently and ends it with a
+// newline. Anything that isn't JSON is returned unchanged.
+func normalizeJSON(data []byte) []byte {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, bytes.TrimSpace(data), "", "  "); err != nil {
+		return data
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
@@ -0,0 +1,316 @@
+// Package testing provides helpers that cut the boilerplate out of
+// handler tests. Its name clashes with the standard library's, so import
+// it under an alias:
+//
+//	import apitest "github.com/aanand-mishra/students-api/internal/testing"
+//
+//	func TestGetStudent(t *testing.T) {
+//		ts := apitest.NewTestServer(t)
+//		id := ts.CreateStudent("Rakesh", "rakesh@test.com", 20)
+//		apitest.AssertJSON(t, ts.GET(fmt.Sprintf("/api/students/%d", id)), map[string]any{...})
+//	}
+//
+// Nothing in this package is compiled into the server binary; only test
+// files import it.
+package testing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/audit"
+	"github.com/aanand-mishra/students-api/internal/config"
+	"github.com/aanand-mishra/students-api/internal/http/handlers/health"
+	"github.com/aanand-mishra/students-api/internal/http/handlers/history"
+	"github.com/aanand-mishra/students-api/internal/http/handlers/student"
+	"github.com/aanand-mishra/students-api/internal/storage/sqlite"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// TestServer is a running HTTP server with the students routes, backed by
+// a throwaway database. Everything is torn down when the test ends.
+type TestServer struct {
+	// Server is the underlying httptest server; Server.URL is its base URL.
+	Server *httptest.Server
+
+	// Storage is the database behind the server, for seeding data or
+	// checking what a request changed.
+	Storage *sqlite.SQLite
+
+	t      *testing.T
+	routes map[string]http.Handler
+}
+
+// TestServerOption customises a TestServer before it starts.
+type TestServerOption func(*TestServer)
+
+// WithRoute registers an extra handler, using http.ServeMux patterns
+// (e.g. "GET /health"). It can also replace one of the default routes.
+func WithRoute(pattern string, handler http.Handler) TestServerOption {
+	return func(ts *TestServer) {
+		ts.routes[pattern] = handler
+	}
+}
+
+// NewTestServer starts a server with the same student routes as main.go.
+//
+// The database is a fresh SQLite file in t.TempDir(), so each test gets
+// an empty, isolated store without any cleanup of its own.
+func NewTestServer(t *testing.T, opts ...TestServerOption) *TestServer {
+	t.Helper()
+
+	db, err := sqlite.New(&config.Config{
+		StoragePath: filepath.Join(t.TempDir(), "test.db"),
+		Database:    config.Database{AutoMigrate: true},
+		SQLite:      config.SQLite{JournalMode: config.JournalModeWAL, BusyTimeoutMs: 5000},
+	})
+	if err != nil {
+		t.Fatalf("NewTestServer: open storage: %v", err)
+	}
+	t.Cleanup(func() { db.Db.Close() })
+	db.RegisterHook(audit.New(db))
+
+	ts := &TestServer{Storage: db, t: t, routes: map[string]http.Handler{
+		"POST /api/students":                       student.New(db),
+		"GET /api/students":                        student.GetList(db, 100),
+		"GET /api/students/random":                 student.GetRandom(db),
+		"GET /api/students/search":                 student.Search(db),
+		"GET /api/students/stats":                  student.Stats(db),
+		"GET /api/students/export":                 student.ExportCSV(db),
+		"GET /api/students/{id}":                   student.GetByID(db),
+		"POST /api/students/import":                student.Import(db, 10<<20),
+		"GET /api/students/external/{external_id}": student.GetByExternalID(db),
+		"GET /api/students/by-email":               student.GetByEmail(db),
+		"PUT /api/students/{id}":                   student.Update(db),
+		"PATCH /api/students/{id}":                 student.Patch(db),
+		"DELETE /api/students/{id}":                student.Delete(db),
+		"POST /api/students/{id}/restore":          student.Restore(db),
+		history.Pattern:                            history.Get(db),
+		"POST /api/students/batch":                 student.BatchCreate(db),
+		"DELETE /api/students/batch":               student.BatchDelete(db),
+		"PUT /api/students/batch/upsert":           student.Upsert(db),
+		"GET /health":                              health.Health(&config.DriftStatus{}),
+		"GET /ready":                               health.Readiness(db, &health.Drain{}),
+	}}
+	for _, opt := range opts {
+		opt(ts)
+	}
+
+	router := http.NewServeMux()
+	for pattern, handler := range ts.routes {
+		router.Handle(pattern, handler)
+	}
+
+	ts.Server = httptest.NewServer(router)
+	t.Cleanup(ts.Server.Close)
+
+	return ts
+}
+
+// CreateStudent inserts a student straight into storage and returns its ID.
+// The routes are served without JWTMiddleware, so every request works on
+// types.DefaultTenant; the student is created there too.
+func (ts *TestServer) CreateStudent(name, email string, age int) int64 {
+	ts.t.Helper()
+
+	id, err := ts.Storage.CreateStudent(context.Background(), types.DefaultTenant, name, email, age, "", "")
+	if err != nil {
+		ts.t.Fatalf("CreateStudent: %v", err)
+	}
+	return id
+}
+
+// GET sends a GET request to path (e.g. "/api/students/1").
+func (ts *TestServer) GET(path string) *http.Response {
+	ts.t.Helper()
+	return ts.do(http.MethodGet, path, nil)
+}
+
+// POST sends body, encoded as JSON, to path.
+func (ts *TestServer) POST(path string, body any) *http.Response {
+	ts.t.Helper()
+	return ts.do(http.MethodPost, path, body)
+}
+
+// PUT sends body, encoded as JSON, to path.
+func (ts *TestServer) PUT(path string, body any) *http.Response {
+	ts.t.Helper()
+	return ts.do(http.MethodPut, path, body)
+}
+
+// DELETE sends a DELETE request to path.
+func (ts *TestServer) DELETE(path string) *http.Response {
+	ts.t.Helper()
+	return ts.do(http.MethodDelete, path, nil)
+}
+
+// GETWithRetry is GET for a server that may be briefly unavailable (e.g.
+// mid-restart): it retries while the response is 503 or 429, up to
+// maxAttempts requests in total, and returns the last response.
+//
+// Between attempts it waits as long as the response's Retry-After header
+// asks, or delay when there is none.
+func (ts *TestServer) GETWithRetry(path string, maxAttempts int, delay time.Duration) *http.Response {
+	ts.t.Helper()
+	return ts.doWithRetry(http.MethodGet, path, maxAttempts, delay)
+}
+
+// WaitForReady polls GET /health until it answers 200 OK, or returns an
+// error once timeout has passed.
+func (ts *TestServer) WaitForReady(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		resp, err := ts.Server.Client().Get(ts.Server.URL + "/health")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			if err != nil {
+				return fmt.Errorf("WaitForReady: not ready after %s: %w", timeout, err)
+			}
+			return fmt.Errorf("WaitForReady: not ready after %s: status %d", timeout, resp.StatusCode)
+		}
+		time.Sleep(readyPollInterval)
+	}
+}
+
+// readyPollInterval is how often WaitForReady polls /health.
+const readyPollInterval = 50 * time.Millisecond
+
+// doWithRetry sends a bodyless request, retrying on 503 and 429. Only
+// idempotent methods are retried — repeating a POST could create twice.
+func (ts *TestServer) doWithRetry(method, path string, maxAttempts int, delay time.Duration) *http.Response {
+	ts.t.Helper()
+
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		ts.t.Fatalf("doWithRetry: %s is not safe to retry", method)
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp := ts.do(method, path, nil)
+
+		retryable := resp.StatusCode == http.StatusServiceUnavailable ||
+			resp.StatusCode == http.StatusTooManyRequests
+		if !retryable || attempt >= maxAttempts {
+			return resp
+		}
+
+		time.Sleep(RetryAfter(resp, delay))
+	}
+}
+
+// RetryAfter returns how long resp asks the client to wait before trying
+// again, from its Retry-After header: either a number of seconds or an
+// HTTP date. It returns fallback when the header is missing or invalid.
+func RetryAfter(resp *http.Response, fallback time.Duration) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return fallback
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(when); wait > 0 {
+			return wait
+		}
+		return 0
+	}
+
+	return fallback
+}
+
+// do sends a request and fails the test if it can't be sent at all.
+// The response body is closed automatically at the end of the test.
+//
+// A body that is already a string or []byte is sent as is, so tests can
+// send deliberately malformed JSON.
+func (ts *TestServer) do(method, path string, body any) *http.Response {
+	ts.t.Helper()
+
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = bytes.NewReader([]byte(b))
+	case []byte:
+		reader = bytes.NewReader(b)
+	default:
+		encoded, err := json.Marshal(b)
+		if err != nil {
+			ts.t.Fatalf("%s %s: encode body: %v", method, path, err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, ts.Server.URL+path, reader)
+	if err != nil {
+		ts.t.Fatalf("%s %s: build request: %v", method, path, err)
+	}
+	if reader != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := ts.Server.Client().Do(req)
+	if err != nil {
+		ts.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	ts.t.Cleanup(func() { resp.Body.Close() })
+
+	return resp
+}
+
+// AssertJSON fails the test unless resp's body is JSON equal to expected.
+//
+// Both sides are decoded before comparing, so key order and whitespace
+// don't matter. expected may be a JSON string or []byte, or any value
+// that encodes to the expected JSON (a map, a struct, a slice…).
+func AssertJSON(t *testing.T, resp *http.Response, expected any) {
+	t.Helper()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("AssertJSON: read body: %v", err)
+	}
+
+	var want []byte
+	switch e := expected.(type) {
+	case string:
+		want = []byte(e)
+	case []byte:
+		want = e
+	default:
+		if want, err = json.Marshal(e); err != nil {
+			t.Fatalf("AssertJSON: encode expected: %v", err)
+		}
+	}
+
+	var gotValue, wantValue any
+	if err := json.Unmarshal(body, &gotValue); err != nil {
+		t.Fatalf("AssertJSON: response is not JSON: %v\nbody: %s", err, body)
+	}
+	if err := json.Unmarshal(want, &wantValue); err != nil {
+		t.Fatalf("AssertJSON: expected value is not JSON: %v", err)
+	}
+
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("AssertJSON: body mismatch\n got: %s\nwant: %s", body, want)
+	}
+}
@@ -0,0 +1,208 @@
+package testing_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apitest "github.com/aanand-mishra/students-api/internal/testing"
+)
+
+func TestTestServer(t *testing.T) {
+	ts := apitest.NewTestServer(t)
+
+	id := ts.CreateStudent("Rakesh", "rakesh@test.com", 20)
+	path := fmt.Sprintf("/api/students/%d", id)
+
+	resp := ts.GET(path)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s = %d, want %d", path, resp.StatusCode, http.StatusOK)
+	}
+	var got map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("GET %s: body is not JSON: %v", path, err)
+	}
+	if got["id"] != float64(id) || got["name"] != "Rakesh" || got["email"] != "rakesh@test.com" || got["age"] != float64(20) {
+		t.Errorf("GET %s = %v, want the created student", path, got)
+	}
+
+	resp = ts.POST("/api/students", map[string]any{"name": "Priya", "email": "priya@test.com", "age": 22})
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("POST = %d, want %d", resp.StatusCode, http.StatusCreated)
+	}
+
+	resp = ts.PUT(path, map[string]any{"name": "Rakesh K", "email": "rakesh@test.com", "age": 21, "version": 1})
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("PUT = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	resp = ts.DELETE(path)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		t.Errorf("DELETE = %d, want success", resp.StatusCode)
+	}
+	if resp := ts.GET(path); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET after DELETE = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+
+	// A string body is sent as is, malformed or not.
+	if resp := ts.POST("/api/students", `{"name":`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("POST of broken JSON = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestTestServersAreIsolated(t *testing.T) {
+	first := apitest.NewTestServer(t)
+	second := apitest.NewTestServer(t)
+
+	first.CreateStudent("Rakesh", "rakesh@test.com", 20)
+
+	if resp := second.GET("/api/students/1"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("the second server sees the first one's student: %d", resp.StatusCode)
+	}
+}
+
+func TestWithRoute(t *testing.T) {
+	ts := apitest.NewTestServer(t,
+		apitest.WithRoute("GET /ping", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"pong": true}`))
+		})),
+		apitest.WithRoute("GET /health", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		})),
+	)
+
+	apitest.AssertJSON(t, ts.GET("/ping"), `{"pong":true}`)
+	if resp := ts.GET("/health"); resp.StatusCode != http.StatusTeapot {
+		t.Errorf("GET /health = %d; WithRoute should replace the default route", resp.StatusCode)
+	}
+}
+
+func TestAssertJSON(t *testing.T) {
+	ts := apitest.NewTestServer(t, apitest.WithRoute("GET /doc", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"b": [1, 2], "a": "x"}`))
+	})))
+
+	tests := []struct {
+		name     string
+		expected any
+	}{
+		{"string, other key order", `{"a":"x","b":[1,2]}`},
+		{"bytes", []byte(`{ "a": "x", "b": [ 1, 2 ] }`)},
+		{"map", map[string]any{"a": "x", "b": []int{1, 2}}},
+		{"struct", struct {
+			A string `json:"a"`
+			B []int  `json:"b"`
+		}{"x", []int{1, 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apitest.AssertJSON(t, ts.GET("/doc"), tt.expected)
+		})
+	}
+}
+
+// flaky answers with status the first failures times it is called, then
+// 200 OK. calls counts every request.
+func flaky(status, failures int, retryAfter string, calls *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if int(calls.Add(1)) <= failures {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestGETWithRetry(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		failures    int
+		maxAttempts int
+		wantStatus  int
+		wantCalls   int32
+	}{
+		{"recovers from 503", http.StatusServiceUnavailable, 2, 5, http.StatusOK, 3},
+		{"recovers from 429", http.StatusTooManyRequests, 1, 5, http.StatusOK, 2},
+		{"gives up after maxAttempts", http.StatusServiceUnavailable, 10, 3, http.StatusServiceUnavailable, 3},
+		{"other errors aren't retried", http.StatusInternalServerError, 10, 5, http.StatusInternalServerError, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			// Retry-After: 0 keeps the test fast and proves the header wins over delay.
+			ts := apitest.NewTestServer(t, apitest.WithRoute("GET /flaky", flaky(tt.status, tt.failures, "0", &calls)))
+
+			resp := ts.GETWithRetry("/flaky", tt.maxAttempts, time.Hour)
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("made %d requests, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestWaitForReady(t *testing.T) {
+	t.Run("becomes ready", func(t *testing.T) {
+		var calls atomic.Int32
+		ts := apitest.NewTestServer(t, apitest.WithRoute("GET /health", flaky(http.StatusServiceUnavailable, 2, "", &calls)))
+
+		if err := ts.WaitForReady(5 * time.Second); err != nil {
+			t.Fatalf("WaitForReady: %v", err)
+		}
+		if got := calls.Load(); got != 3 {
+			t.Errorf("polled %d times, want 3", got)
+		}
+	})
+
+	t.Run("never ready", func(t *testing.T) {
+		var calls atomic.Int32
+		ts := apitest.NewTestServer(t, apitest.WithRoute("GET /health", flaky(http.StatusServiceUnavailable, 1000, "", &calls)))
+
+		err := ts.WaitForReady(120 * time.Millisecond)
+		if err == nil || !strings.Contains(err.Error(), "status 503") {
+			t.Errorf("WaitForReady = %v, want a not-ready error with the status", err)
+		}
+	})
+}
+
+func TestRetryAfter(t *testing.T) {
+	const fallback = 7 * time.Second
+
+	tests := []struct {
+		name   string
+		header string
+		want   func(time.Duration) bool
+	}{
+		{"missing", "", func(d time.Duration) bool { return d == fallback }},
+		{"seconds", "3", func(d time.Duration) bool { return d == 3*time.Second }},
+		{"zero", "0", func(d time.Duration) bool { return d == 0 }},
+		{"negative", "-1", func(d time.Duration) bool { return d == fallback }},
+		{"garbage", "soon", func(d time.Duration) bool { return d == fallback }},
+		{"date in the past", "Mon, 02 Jan 2006 15:04:05 GMT", func(d time.Duration) bool { return d == 0 }},
+		{"date in the future", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), func(d time.Duration) bool {
+			return d > 55*time.Second && d <= time.Minute
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tt.header != "" {
+				resp.Header.Set("Retry-After", tt.header)
+			}
+			if got := apitest.RetryAfter(resp, fallback); !tt.want(got) {
+				t.Errorf("RetryAfter(%q) = %s", tt.header, got)
+			}
+		})
+	}
+}
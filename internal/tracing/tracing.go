@@ -0,0 +1,73 @@
+// Package tracing sets up OpenTelemetry distributed tracing.
+//
+// A trace follows one request through the server: an otelhttp span for
+// the HTTP request, with a child span for each storage call it makes.
+// Spans are sent to an OpenTelemetry collector over OTLP/HTTP, from where
+// Jaeger, Tempo, Honeycomb etc. can show them.
+//
+// Code that creates spans gets its tracer from the global provider
+// (otel.Tracer), which Setup installs — so the storage packages don't
+// need to be handed anything.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"github.com/aanand-mishra/students-api/internal/build"
+	"github.com/aanand-mishra/students-api/internal/config"
+)
+
+// ServiceName identifies this server's spans in the tracing backend.
+const ServiceName = "students-api"
+
+// Setup creates the tracer provider and installs it as the global one,
+// together with the W3C traceparent/baggage propagators, so a trace
+// started by the caller continues here.
+//
+// With cfg.OTLPEndpoint set, spans are batched and exported to it.
+// Without, they are created but dropped — trace IDs still propagate.
+//
+// The caller must Shutdown the returned provider before exiting, or the
+// last batch of spans is lost.
+func Setup(cfg *config.Config) (*sdktrace.TracerProvider, error) {
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(ServiceName),
+		semconv.ServiceVersion(build.Version),
+		semconv.DeploymentEnvironment(cfg.Env),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("tracing.Setup: resource: %w", err)
+	}
+
+	opts := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+
+	if cfg.OTLPEndpoint != "" {
+		// New doesn't connect yet; an unreachable collector only shows up
+		// as export errors later, never as a failed start.
+		exporter, err := otlptracehttp.New(context.Background(),
+			otlptracehttp.WithEndpointURL(cfg.OTLPEndpoint))
+		if err != nil {
+			return nil, fmt.Errorf("tracing.Setup: exporter: %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+
+	tp := sdktrace.NewTracerProvider(opts...)
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return tp, nil
+}
@@ -0,0 +1,90 @@
+package types
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// AuditEntry is one row of the audit log: a single change to a student,
+// plus details of the HTTP request that caused it.
+type AuditEntry struct {
+	ID        int64     `json:"id"`
+	Action    string    `json:"action"` // AuditActionCreate, ...Update or ...Delete
+	StudentID int64     `json:"student_id"`
+	Timestamp time.Time `json:"timestamp"`
+
+	// Before and After are the student as JSON, before and after the
+	// change. Before is empty for creates, After is empty for deletes.
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+
+	// Request context — empty when the change did not come from an HTTP
+	// request (e.g. the startup self-test).
+	RequestBody    json.RawMessage `json:"request_body,omitempty"` // sensitive fields redacted
+	ResponseStatus int             `json:"response_status,omitempty"`
+	ClientIP       string          `json:"client_ip,omitempty"`
+	UserAgent      string          `json:"user_agent,omitempty"`
+	RequestID      string          `json:"request_id,omitempty"`
+	Duration       time.Duration   `json:"duration,omitempty"`
+}
+
+// Values for AuditEntry.Action.
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// AuditContext carries request details from the HTTP layer to the audit
+// log through a context.Context.
+//
+// The logging middleware creates one per request with WithAuditContext.
+// Because the response status and duration are only known after the
+// handler returns, audit writers register a callback with OnFinish and the
+// middleware calls Finish once the response is complete.
+type AuditContext struct {
+	RequestBody json.RawMessage
+	ClientIP    string
+	UserAgent   string
+	RequestID   string
+
+	mu       sync.Mutex
+	onFinish []func(status int, duration time.Duration)
+}
+
+// OnFinish registers fn to run when the request completes.
+func (a *AuditContext) OnFinish(fn func(status int, duration time.Duration)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.onFinish = append(a.onFinish, fn)
+}
+
+// Finish runs the registered callbacks with the final response details.
+func (a *AuditContext) Finish(status int, duration time.Duration) {
+	a.mu.Lock()
+	callbacks := a.onFinish
+	a.onFinish = nil
+	a.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn(status, duration)
+	}
+}
+
+// auditContextKey is the context key type for *AuditContext. Being an
+// unexported type, no other package can collide with it.
+type auditContextKey struct{}
+
+// WithAuditContext returns a copy of ctx carrying ac.
+func WithAuditContext(ctx context.Context, ac *AuditContext) context.Context {
+	return context.WithValue(ctx, auditContextKey{}, ac)
+}
+
+// AuditContextFrom returns the *AuditContext stored in ctx, or nil.
+func AuditContextFrom(ctx context.Context) *AuditContext {
+	ac, _ := ctx.Value(auditContextKey{}).(*AuditContext)
+	return ac
+}
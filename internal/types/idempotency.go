@@ -0,0 +1,24 @@
+package types
+
+import "time"
+
+// IdempotencyResult is the stored outcome of a request sent with an
+// Idempotency-Key header, replayed if the same key comes again.
+type IdempotencyResult struct {
+	Key string
+
+	// StatusCode is 0 while the first request with the key is still
+	// being handled.
+	StatusCode  int
+	ContentType string
+	Body        []byte
+
+	// ExpiresAt is when the key may be used for a new request.
+	ExpiresAt time.Time
+}
+
+// InFlight reports whether the first request with the key hasn't
+// finished yet.
+func (r IdempotencyResult) InFlight() bool {
+	return r.StatusCode == 0
+}
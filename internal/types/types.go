@@ -4,6 +4,8 @@
 // on each other.
 package types
 
+import "time"
+
 // Student represents a student record in our system.
 //
 // Struct tags serve two purposes:
@@ -13,10 +15,228 @@ package types
 //     Without this tag Go uses the exported field name, e.g. "Name".
 //
 //  2. validate:"..." — rules checked by the go-playground/validator
-//     package. "required" means the field must be non-zero / non-empty.
+//     package. Which fields are REQUIRED is not in the tags: it is
+//     configured per deployment (validation.required_fields) and
+//     enforced by the validation package. By default name, email and age.
+//
+// The validation package also applies one cross-field rule,
+// "departmentemailmatch": when Department has a configured email domain
+// (validation.department_email_domains), Email must be at that domain.
+// It spans two fields, so it is a struct-level rule rather than a tag.
 type Student struct {
 	ID    int    `json:"id"`
-	Name  string `json:"name"  validate:"required"`
-	Email string `json:"email" validate:"required"`
-	Age   int    `json:"age"   validate:"required"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	// Age must be 1 to 150. It has no "required" in its tag — like the
+	// other fields, that is configured — so omitempty leaves an unset
+	// (zero) age to the required check instead of reporting it twice.
+	Age int `json:"age" validate:"omitempty,min=1,max=150"`
+
+	// Department is the academic department the student belongs to, e.g.
+	// "CS". Optional. When validation.department_email_domains maps it to
+	// a domain, the student's email must be at that domain.
+	Department string `json:"department,omitempty"`
+
+	// Phone is a contact number for emergencies, in E.164 format: a "+",
+	// the country code and the number, digits only, e.g. "+14155552671".
+	// Optional.
+	Phone string `json:"phone,omitempty" validate:"omitempty,e164"`
+
+	// PhotoURL is the public path of the student's photo, e.g.
+	// "/photos/1.jpg". It is set only by the photo upload endpoint and is
+	// omitted from JSON when the student has no photo.
+	PhotoURL string `json:"photo_url,omitempty"`
+
+	// ExternalID is the student's ID in another system (e.g. the school's
+	// records), set by CSV imports. It is unique when present, which lets
+	// an import be re-run without creating duplicates. nil when unset;
+	// create and update requests leave it untouched.
+	ExternalID *string `json:"external_id,omitempty"`
+
+	// CreatedAt and UpdatedAt are set by the database, in UTC: when the
+	// student was created and when it last changed. Values sent in a
+	// request are ignored.
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Version counts the changes to the student: 1 when created, plus
+	// one for every update. A PUT must send back the version it read;
+	// if the student has changed since, the update is refused with
+	// 409 Conflict instead of overwriting that change (optimistic
+	// locking).
+	Version int `json:"version"`
+
+	// Status is one of StudentStatuses: "active" for new students, or
+	// "inactive" or "suspended" once an administrator changes it with
+	// PATCH /api/students/{id}/status. Values sent elsewhere are ignored.
+	Status string `json:"status"`
+
+	// TenantID is the school (tenant) the student belongs to, taken from
+	// the tenant_id claim of the token that created it. Every storage
+	// call is scoped to one tenant, so it is never sent to clients: a
+	// client only ever sees its own tenant's students.
+	TenantID string `json:"-"`
+}
+
+// DefaultTenant is the tenant of tokens without a tenant_id claim, and
+// of every student stored before tenants existed. A deployment serving a
+// single school never needs any other.
+const DefaultTenant = "default"
+
+// Values for Student.Status.
+const (
+	StudentStatusActive    = "active"
+	StudentStatusInactive  = "inactive"
+	StudentStatusSuspended = "suspended"
+)
+
+// StudentStatuses are the values Student.Status can take.
+var StudentStatuses = []string{StudentStatusActive, StudentStatusInactive, StudentStatusSuspended}
+
+// FilterOptions selects which students GET /api/students returns, and in
+// what order. The zero value selects all of them, newest first.
+type FilterOptions struct {
+	Name   string // substring of the name, case-insensitive
+	Email  string // substring of the email, case-insensitive
+	AgeMin *int   // inclusive; nil for no lower bound
+	AgeMax *int   // inclusive; nil for no upper bound
+
+	// Offset and Limit pick one page of the matches. Limit 0 returns
+	// all of them.
+	Offset int
+	Limit  int
+
+	// AfterID and BeforeID page by cursor instead: the first Limit
+	// matches with an id above AfterID, or the last Limit with an id
+	// below BeforeID. Either one orders the page by id, whatever Sort
+	// says, and neither changes the total. 0 means unset.
+	AfterID  int64
+	BeforeID int64
+
+	// Sort orders the results; ties fall back to id order. The empty
+	// SortOptions means newest first: created_at, then id, descending.
+	Sort SortOptions
+}
+
+// SortOptions is a multi-column sort, most significant column first.
+type SortOptions struct {
+	Fields []SortField
+}
+
+// SortField is one column of a SortOptions.
+type SortField struct {
+	Key  string // one of SortKeys
+	Desc bool
+}
+
+// SortKeys are the fields students can be sorted by (?sort=...).
+var SortKeys = []string{"id", "name", "email", "age", "created_at", "updated_at"}
+
+// HasFilters reports whether any filter (not just paging) is set.
+func (o FilterOptions) HasFilters() bool {
+	return o.Name != "" || o.Email != "" || o.AgeMin != nil || o.AgeMax != nil
+}
+
+// StudentPage is one page of the student list (GET /api/students).
+type StudentPage struct {
+	Data    []Student `json:"data"`
+	Total   int64     `json:"total"`          // students across all pages
+	Page    int       `json:"page,omitempty"` // 1-based; unset when paging by cursor
+	PerPage int       `json:"per_page"`
+
+	// NextCursor and PrevCursor fetch the neighbouring pages, passed as
+	// ?after= and ?before= respectively. Each is unset when there is no
+	// such page (or the list is sorted by something other than id).
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// StudentStats summarises the student table (GET /api/students/stats).
+// The age figures only count students whose age is known; with none,
+// they are all 0.
+type StudentStats struct {
+	Total  int64   `json:"total"`
+	AvgAge float64 `json:"avg_age"`
+	MinAge int     `json:"min_age"`
+	MaxAge int     `json:"max_age"`
+}
+
+// UpsertResult reports what happened to one student in a batch upsert.
+// Results are returned in the same order as the submitted students.
+type UpsertResult struct {
+	StudentID int64  `json:"id"`
+	Email     string `json:"email"`
+	Action    string `json:"action"` // UpsertActionCreated or UpsertActionUpdated
+}
+
+// Values for UpsertResult.Action.
+const (
+	UpsertActionCreated = "created"
+	UpsertActionUpdated = "updated"
+)
+
+// BatchCreateResult reports what happened to one student in a batch
+// create. Status is the HTTP status creating it alone would have had:
+// 201 with the new ID, or 422/409 with the error.
+type BatchCreateResult struct {
+	Index     int    `json:"index"` // position in the submitted array
+	Status    int    `json:"status"`
+	ID        int64  `json:"id,omitempty"`
+	Error     string `json:"error,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+// ImportSummary is the response to a CSV import: how many rows created a
+// new student and how many updated an existing one.
+type ImportSummary struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+}
+
+// CSVImportResult is the response to an uploaded CSV import
+// (multipart/form-data): how many rows became students, and why the
+// others didn't.
+type CSVImportResult struct {
+	Imported int              `json:"imported"`
+	Failed   int              `json:"failed"`
+	Errors   []ImportRowError `json:"errors"`
+}
+
+// ImportRowError is one row of a CSV import that was not imported. Row
+// is the line in the file, counting the header as line 1.
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// WebhookSubscription is a URL registered with POST /api/webhooks to be
+// told about every change to a student.
+type WebhookSubscription struct {
+	ID  int64  `json:"id"`
+	URL string `json:"url"`
+
+	// Secret signs each delivery (see WebhookEvent). It is returned only
+	// when the subscription is created; listings omit it.
+	Secret string `json:"secret,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
 }
+
+// WebhookEvent is the JSON body POSTed to every subscription when a
+// student changes. Student is the record after the change; for
+// WebhookEventDeleted only its id is known. TenantID picks the
+// subscriptions it goes to and is not sent.
+type WebhookEvent struct {
+	Type      string    `json:"type"` // WebhookEventCreated, ...Updated or ...Deleted
+	StudentID int64     `json:"student_id"`
+	Student   *Student  `json:"student,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	TenantID  string    `json:"-"`
+}
+
+// Values for WebhookEvent.Type.
+const (
+	WebhookEventCreated = "student.created"
+	WebhookEventUpdated = "student.updated"
+	WebhookEventDeleted = "student.deleted"
+)
@@ -0,0 +1,16 @@
+// Package utils holds small, dependency-free helpers shared by the HTTP
+// and storage layers.
+package utils
+
+import "strings"
+
+// NormalizeEmail returns the canonical form of an email address used for
+// storage and lookups: surrounding whitespace trimmed and lower-cased.
+//
+// "  Rakesh@TEST.com " and "rakesh@test.com" therefore refer to the same
+// student. Both the handlers (before validation) and the storage layer
+// (before any SQL touching email) call this, so neither has to trust the
+// other to have done it.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,20 @@
+package utils
+
+import "testing"
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"rakesh@test.com", "rakesh@test.com"},
+		{"Rakesh@TEST.COM", "rakesh@test.com"},
+		{"  rakesh@test.com\n", "rakesh@test.com"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeEmail(tt.in); got != tt.want {
+			t.Errorf("NormalizeEmail(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
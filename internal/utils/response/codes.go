@@ -0,0 +1,28 @@
+package response
+
+// Machine-readable error codes sent in the "error_code" field of error
+// responses. Clients should switch on these rather than parsing the
+// human-readable "error" string, which may be reworded at any time.
+const (
+	ErrCodeValidation   = "VALIDATION_ERROR" // request body failed validation
+	ErrCodeBadRequest   = "BAD_REQUEST"      // malformed request (bad JSON, bad id…)
+	ErrCodeNotFound     = "NOT_FOUND"        // the requested record does not exist
+	ErrCodeDuplicate    = "DUPLICATE_ENTRY"  // a unique constraint would be violated
+	ErrCodeInternal     = "INTERNAL_ERROR"   // unexpected server-side failure
+	ErrCodeUnauthorized = "UNAUTHORIZED"     // missing or invalid credentials
+	ErrCodeForbidden    = "FORBIDDEN"        // client is not allowed to do this
+	ErrCodeRateLimit    = "RATE_LIMITED"     // client exceeded its request quota
+	ErrCodeTimeout      = "TIMEOUT"          // the request took too long to handle
+
+	ErrCodeUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE" // wrong Content-Type for the endpoint
+	ErrCodeIdempotencyInFlight  = "IDEMPOTENCY_IN_FLIGHT"  // an earlier request with the same Idempotency-Key is still running
+	ErrCodeVersionConflict      = "VERSION_CONFLICT"       // the record changed since the client read it
+	ErrCodeCapacityExceeded     = "CAPACITY_EXCEEDED"      // the deployment's maximum number of students is reached
+	ErrCodePayloadTooLarge      = "PAYLOAD_TOO_LARGE"      // the request body, or a file in it, is over the size limit
+
+	// ErrCodeDuplicateEmail is sent when another student already uses the
+	// email. It shares DUPLICATE_ENTRY's value so clients that already
+	// switch on that code keep working; email is the only unique field a
+	// client can collide on today.
+	ErrCodeDuplicateEmail = ErrCodeDuplicate
+)
@@ -0,0 +1,88 @@
+package response
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ETag returns an entity tag for data: the SHA-256 of its JSON encoding.
+// Any change to the value changes the tag.
+//
+// The tag is weak (W/"..."): WriteJSON may send the same value compact or
+// pretty-printed, so the bytes can differ while the data is the same.
+// ─────────────────────────────────────────────────────────────────────────────
+func ETag(data any) (string, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(sum[:]) + `"`, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// CheckNotModified sets the ETag and Last-Modified headers and answers a
+// conditional GET. If the client's copy is still current it writes
+// 304 Not Modified, with no body, and returns true; the handler then has
+// nothing left to do:
+//
+//	if response.CheckNotModified(w, r, etag, student.UpdatedAt) {
+//	    return
+//	}
+//	response.WriteJSON(w, http.StatusOK, student)
+//
+// The client's copy is current when If-None-Match lists etag (or is
+// "*"). Only when there is no If-None-Match is If-Modified-Since looked
+// at: lastModified must not be after it. HTTP dates have whole seconds,
+// so lastModified is compared at that precision. A zero lastModified
+// sends no Last-Modified header and never matches.
+//
+// Either header may be "" to leave it out.
+// ─────────────────────────────────────────────────────────────────────────────
+func CheckNotModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if etag == "" || !etagListMatches(inm, etag) {
+			return false
+		}
+	} else {
+		ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+		if err != nil || lastModified.IsZero() || lastModified.Truncate(time.Second).After(ims) {
+			return false
+		}
+	}
+
+	// A 304 describes the stored response; it has no body to type.
+	w.Header().Del("Content-Type")
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagListMatches reports whether the If-None-Match value list names
+// etag. The comparison is weak, as RFC 9110 requires for If-None-Match:
+// W/"x" and "x" are the same tag.
+func etagListMatches(list, etag string) bool {
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package response
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PaginationMeta describes the page a list response holds, for the
+// headers WritePaginatedJSON adds.
+type PaginationMeta struct {
+	// URL is the request's URL. The links keep its path and every query
+	// parameter except the paging ones (page, after, before).
+	URL *url.URL
+
+	Total   int64
+	PerPage int
+
+	// Page is the 1-based page number of an offset page, or 0 for a
+	// page fetched by cursor.
+	Page int
+
+	// For a cursor page: the cursors of the neighbouring pages ("" when
+	// there is none), and one that fetches the last page.
+	NextCursor string
+	PrevCursor string
+	LastCursor string
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// WritePaginatedJSON is WriteJSON for one page of a list. It also puts the
+// paging details in headers, for clients (data grids and the like) that
+// read them there rather than from the body:
+//
+//	X-Total-Count: 243
+//	Link: </api/students?page=1&per_page=20>; rel="first",
+//	      </api/students?page=3&per_page=20>; rel="next", ...
+//
+// Link (RFC 8288, formerly 5988) has first, prev, next and last, minus
+// any that don't exist — no prev on the first page, no next on the last.
+// Offset pages link by page number. Cursor pages link by cursor, and
+// "first" is the first offset page, which needs none.
+// ─────────────────────────────────────────────────────────────────────────────
+func WritePaginatedJSON(w http.ResponseWriter, status int, data any, meta PaginationMeta) error {
+	w.Header().Set("X-Total-Count", strconv.FormatInt(meta.Total, 10))
+	if link := meta.linkHeader(); link != "" {
+		w.Header().Set("Link", link)
+	}
+	return WriteJSON(w, status, data)
+}
+
+// linkHeader builds the Link header value, or "" without a URL.
+func (m PaginationMeta) linkHeader() string {
+	if m.URL == nil {
+		return ""
+	}
+
+	var links []string
+	add := func(rel, param, value string) {
+		links = append(links, fmt.Sprintf("<%s>; rel=%q", m.pageURL(param, value), rel))
+	}
+
+	add("first", "page", "1")
+
+	if m.Page == 0 {
+		if m.PrevCursor != "" {
+			add("prev", "before", m.PrevCursor)
+		}
+		if m.NextCursor != "" {
+			add("next", "after", m.NextCursor)
+		}
+		if m.LastCursor != "" {
+			add("last", "before", m.LastCursor)
+		}
+		return strings.Join(links, ", ")
+	}
+
+	lastPage := 1
+	if m.PerPage > 0 && m.Total > 0 {
+		lastPage = int((m.Total + int64(m.PerPage) - 1) / int64(m.PerPage))
+	}
+	if m.Page > 1 {
+		add("prev", "page", strconv.Itoa(min(m.Page-1, lastPage)))
+	}
+	if m.Page < lastPage {
+		add("next", "page", strconv.Itoa(m.Page+1))
+	}
+	add("last", "page", strconv.Itoa(lastPage))
+
+	return strings.Join(links, ", ")
+}
+
+// pageURL is the request's path and query with the paging parameters
+// replaced by param=value.
+func (m PaginationMeta) pageURL(param, value string) string {
+	query := m.URL.Query()
+	query.Del("page")
+	query.Del("after")
+	query.Del("before")
+	query.Set(param, value)
+	return m.URL.Path + "?" + query.Encode()
+}
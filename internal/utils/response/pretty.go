@@ -0,0 +1,77 @@
+package response
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// WriteOption customises a single WriteJSON call.
+type WriteOption func(*writeOptions)
+
+type writeOptions struct {
+	pretty bool
+}
+
+// WithPrettyPrint makes WriteJSON indent its output with two spaces.
+func WithPrettyPrint() WriteOption {
+	return func(o *writeOptions) { o.pretty = true }
+}
+
+// prettyDefault makes every response pretty-printed (set in dev).
+// atomic.Bool because handlers read it concurrently.
+var prettyDefault atomic.Bool
+
+// SetPrettyDefault turns pretty-printing on or off for all responses.
+// main.go enables it in the "dev" environment.
+func SetPrettyDefault(enabled bool) {
+	prettyDefault.Store(enabled)
+}
+
+// WriteJSONPretty is WriteJSON with WithPrettyPrint always applied.
+func WriteJSONPretty(w http.ResponseWriter, status int, data any) error {
+	return WriteJSON(w, status, data, WithPrettyPrint())
+}
+
+// PrettyWriter marks a ResponseWriter whose JSON responses should be
+// pretty-printed. Middleware wraps the writer in it (e.g. for ?pretty=true)
+// because WriteJSON only ever sees the writer, never the request.
+type PrettyWriter struct {
+	http.ResponseWriter
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (p PrettyWriter) Unwrap() http.ResponseWriter {
+	return p.ResponseWriter
+}
+
+// Flush passes through to the underlying writer when it supports it.
+func (p PrettyWriter) Flush() {
+	if f, ok := p.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// wantsPretty combines the three ways pretty-printing can be requested.
+func wantsPretty(w http.ResponseWriter, opts []WriteOption) bool {
+	var o writeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o.pretty || isPrettyWriter(w) || prettyDefault.Load()
+}
+
+// isPrettyWriter reports whether w, or any writer it wraps (found through
+// Unwrap, as other middleware may have wrapped it again), is a PrettyWriter.
+func isPrettyWriter(w http.ResponseWriter) bool {
+	for {
+		if _, ok := w.(PrettyWriter); ok {
+			return true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return false
+		}
+		w = u.Unwrap()
+	}
+}
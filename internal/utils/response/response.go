@@ -10,10 +10,15 @@ package response
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"reflect"
 	"strings"
+	"sync/atomic"
 
+	apperrors "github.com/aanand-mishra/students-api/internal/errors"
+	"github.com/aanand-mishra/students-api/internal/types"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -23,14 +28,26 @@ import (
 // Success responses may return any JSON shape (a student, a list, an id…).
 // Error responses always look like:
 //
-//	{ "status": "error", "error": "field Name is required" }
+//	{ "status": "error", "error": "field Name is required", "error_code": "VALIDATION_ERROR" }
+//
+// error_code is one of the ErrCode* constants in codes.go.
 //
 // The json:"..." struct tags control the JSON key names.
 // Without them Go would use capitalised field names ("Status", "Error").
 // ─────────────────────────────────────────────────────────────────────────────
 type Response struct {
-	Status string `json:"status"` // "ok" or "error"
-	Error  string `json:"error"`  // human-readable error detail
+	Status    string `json:"status"`               // "ok" or "error"
+	Error     string `json:"error"`                // human-readable error detail
+	ErrorCode string `json:"error_code,omitempty"` // machine-readable ErrCode* value
+
+	// Errors lists each failed field of a validation error (see
+	// ValidationError). Omitted for every other error.
+	Errors []FieldError `json:"errors,omitempty"`
+
+	// Stack is where an internal error came from, one frame per entry.
+	// Only sent in debug mode (see SetDebug) — never in production, where
+	// it would reveal the code's internals.
+	Stack []string `json:"stack,omitempty"`
 }
 
 // Status string constants — use these instead of raw string literals so
@@ -48,6 +65,11 @@ const (
 //	w      — the http.ResponseWriter provided to every handler
 //	status — HTTP status code (e.g. http.StatusOK = 200)
 //	data   — any Go value; will be JSON-encoded and written to the body
+//	opts   — optional tweaks, e.g. WithPrettyPrint()
+//
+// Output is compact unless pretty-printing was asked for by an option,
+// by the PrettyWriter wrapper (?pretty=true), or globally via
+// SetPrettyDefault (dev environment). Both forms decode to the same data.
 //
 // The "any" type (alias for interface{}) means data can be a struct, map,
 // slice, or primitive — WriteJSON doesn't care.
@@ -55,10 +77,24 @@ const (
 // IMPORTANT ORDER: Header() → WriteHeader() → body writes.
 // Once WriteHeader is called (or the first Write), headers are locked.
 // ─────────────────────────────────────────────────────────────────────────────
-func WriteJSON(w http.ResponseWriter, status int, data any) error {
+func WriteJSON(w http.ResponseWriter, status int, data any, opts ...WriteOption) error {
 	// Tell the client the body is JSON, not HTML or plain text.
 	w.Header().Set("Content-Type", "application/json")
 
+	if wantsPretty(w, opts) {
+		// MarshalIndent needs the whole value in memory, but the streaming
+		// encoder can't indent after the fact, and pretty output is only
+		// for humans reading small responses anyway.
+		body, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return err
+		}
+		w.WriteHeader(status)
+		_, err = w.Write(append(body, '\n'))
+		return err
+	}
+
 	// Write the HTTP status line (e.g. "HTTP/1.1 201 Created").
 	// This must happen before any body bytes are written.
 	w.WriteHeader(status)
@@ -69,9 +105,40 @@ func WriteJSON(w http.ResponseWriter, status int, data any) error {
 	return json.NewEncoder(w).Encode(data)
 }
 
+// ─────────────────────────────────────────────────────────────────────────────
+// WriteJSONL writes students as newline-delimited JSON (JSONL / NDJSON)
+// with a 200 OK status.
+//
+// Each student is encoded as one compact JSON object on its own line:
+//
+//	{"id":1,"name":"Rakesh","email":"rakesh@test.com","age":35}
+//	{"id":2,"name":"Priya","email":"priya@test.com","age":22}
+//
+// Every line parses on its own, so clients can stream the body line by
+// line (jq -c '.', wc -l) instead of waiting for a complete JSON array.
+// An empty slice produces an empty body.
+// ─────────────────────────────────────────────────────────────────────────────
+func WriteJSONL(w http.ResponseWriter, students []types.Student) error {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	// Encode() already terminates each value with "\n", which is exactly
+	// the JSONL record separator.
+	enc := json.NewEncoder(w)
+	for _, student := range students {
+		if err := enc.Encode(student); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 // GeneralError wraps any Go error into our standard Response shape.
-// Use this for unexpected errors (DB failures, decode errors, etc.)
+// Use this for unexpected errors (DB failures, etc.). The error code is
+// always INTERNAL_ERROR — use one of the typed helpers below when the
+// failure has a more specific cause.
 //
 // Example usage:
 //
@@ -80,49 +147,195 @@ func WriteJSON(w http.ResponseWriter, status int, data any) error {
 //
 // ─────────────────────────────────────────────────────────────────────────────
 func GeneralError(err error) Response {
+	resp := Error(ErrCodeInternal, err)
+
+	if debug.Load() {
+		stack := apperrors.StackTrace(err)
+		if stack == "" {
+			// The error wasn't wrapped where it happened; the stack from
+			// here at least shows which handler reported it.
+			stack = apperrors.StackTrace(apperrors.Wrap(err))
+		}
+		resp.Stack = strings.Split(stack, "\n")
+	}
+
+	return resp
+}
+
+// debug adds stack traces to GeneralError responses. atomic.Bool because
+// handlers read it concurrently.
+var debug atomic.Bool
+
+// SetDebug turns stack traces in internal error responses on or off.
+// main.go enables it in the "dev" environment only.
+func SetDebug(enabled bool) {
+	debug.Store(enabled)
+}
+
+// Error builds an error Response with an explicit error code.
+// The typed helpers below are thin wrappers around it.
+func Error(code string, err error) Response {
 	return Response{
-		Status: StatusError,
-		Error:  err.Error(), // .Error() returns the error message string
+		Status:    StatusError,
+		Error:     err.Error(), // .Error() returns the error message string
+		ErrorCode: code,
 	}
 }
 
+// BadRequestError is for malformed requests: empty or invalid JSON,
+// non-numeric ids, bad query parameters.
+func BadRequestError(err error) Response {
+	return Error(ErrCodeBadRequest, err)
+}
+
+// NotFoundError is for lookups that matched no record.
+func NotFoundError(err error) Response {
+	return Error(ErrCodeNotFound, err)
+}
+
+// NotFound writes a 404 Not Found with message, e.g. the text of a
+// storage.ErrNotFound:
+//
+//	{ "status": "error", "error": "no student found with id: 7", "error_code": "NOT_FOUND" }
+func NotFound(w http.ResponseWriter, message string) {
+	WriteJSON(w, http.StatusNotFound, NotFoundError(errors.New(message)))
+}
+
+// DuplicateError is for writes that would violate a unique constraint.
+func DuplicateError(err error) Response {
+	return Error(ErrCodeDuplicate, err)
+}
+
+// DuplicateEmailError is for writes whose email another student
+// already uses (storage.ErrDuplicateEmail).
+func DuplicateEmailError(err error) Response {
+	return Error(ErrCodeDuplicateEmail, err)
+}
+
+// UnauthorizedError is for requests with missing or invalid credentials.
+func UnauthorizedError(err error) Response {
+	return Error(ErrCodeUnauthorized, err)
+}
+
+// RateLimitError is for clients that have exceeded their request quota.
+func RateLimitError(err error) Response {
+	return Error(ErrCodeRateLimit, err)
+}
+
+// PayloadTooLargeError is for request bodies, or files uploaded in them,
+// over the size limit. It goes with 413 Request Entity Too Large.
+func PayloadTooLargeError(err error) Response {
+	return Error(ErrCodePayloadTooLarge, err)
+}
+
+// TimeoutError is for requests the server gave up on before the handler
+// finished.
+func TimeoutError(err error) Response {
+	return Error(ErrCodeTimeout, err)
+}
+
+// FieldError is one failed validation rule, so clients can show each
+// message next to the form field it belongs to.
+type FieldError struct {
+	Field   string `json:"field"`   // Go field name, e.g. "Age"
+	Message string `json:"message"` // e.g. "field Age must be at least 1"
+}
+
+// joinValidationErrors keeps Response.Error a readable list of every
+// validation failure, as it was before Errors existed. On by default
+// while clients move over to Errors; see SetJoinValidationErrors.
+var joinValidationErrors atomic.Bool
+
+func init() {
+	joinValidationErrors.Store(true)
+}
+
+// SetJoinValidationErrors chooses what Error says in ValidationError
+// responses: every message joined with ", " (true, the old format) or
+// just "validation failed" (false), leaving the details to Errors.
+// main.go turns it off when validation.errors_only is set.
+func SetJoinValidationErrors(enabled bool) {
+	joinValidationErrors.Store(enabled)
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 // ValidationError converts a slice of validator.FieldError values into
-// a single human-readable Response.
+// a Response listing each failure in Errors. Handlers send it with 422
+// Unprocessable Entity: the body was well-formed but its values are not
+// acceptable.
 //
 // The go-playground/validator package returns one FieldError per failing
-// struct field. We convert each to a plain English sentence and join them
-// with ", " so the client sees a single descriptive error string.
+// struct field. We convert each to a plain English sentence:
 //
-// Example output:
-//
-//	{ "status": "error", "error": "field Name is required, field Age is required" }
+//	{
+//	  "status": "error",
+//	  "error": "field Name is required, field Age must be at least 1",
+//	  "error_code": "VALIDATION_ERROR",
+//	  "errors": [
+//	    { "field": "Name", "message": "field Name is required" },
+//	    { "field": "Age",  "message": "field Age must be at least 1" }
+//	  ]
+//	}
 //
+// "error" joins the messages only while SetJoinValidationErrors is on.
 // ─────────────────────────────────────────────────────────────────────────────
 func ValidationError(errs validator.ValidationErrors) Response {
-	var errMessages []string
+	fieldErrs := make([]FieldError, 0, len(errs))
+	messages := make([]string, 0, len(errs))
 
 	for _, e := range errs {
-		switch e.ActualTag() {
-		// "required" tag — field was missing or zero-valued
-		case "required":
-			errMessages = append(errMessages,
-				fmt.Sprintf("field %s is required", e.Field()))
-		// "email" tag — field did not match email format
-		case "email":
-			errMessages = append(errMessages,
-				fmt.Sprintf("field %s must be a valid email address", e.Field()))
-		// Catch-all for any other validation tag (min, max, len, etc.)
-		default:
-			errMessages = append(errMessages,
-				fmt.Sprintf("field %s is invalid", e.Field()))
-		}
+		message := fieldMessage(e)
+		fieldErrs = append(fieldErrs, FieldError{Field: e.Field(), Message: message})
+		messages = append(messages, message)
 	}
 
-	return Response{
-		Status: StatusError,
+	summary := "validation failed"
+	if joinValidationErrors.Load() {
 		// strings.Join(slice, sep) concatenates a slice of strings
 		// with the given separator between each element.
-		Error: strings.Join(errMessages, ", "),
+		summary = strings.Join(messages, ", ")
+	}
+
+	return Response{
+		Status:    StatusError,
+		Error:     summary,
+		ErrorCode: ErrCodeValidation,
+		Errors:    fieldErrs,
+	}
+}
+
+// fieldMessage describes one failed rule as a sentence.
+func fieldMessage(e validator.FieldError) string {
+	switch e.ActualTag() {
+	// "required" tag — field was missing or zero-valued
+	case "required":
+		return fmt.Sprintf("field %s is required", e.Field())
+	// "email" tag — field did not match email format
+	case "email":
+		return fmt.Sprintf("field %s must be a valid email address", e.Field())
+	// "departmentemailmatch" — email isn't at the department's domain
+	case "departmentemailmatch":
+		return fmt.Sprintf("field %s must be an @%s address", e.Field(), e.Param())
+	// "e164" — a phone number not in international format
+	case "e164":
+		return fmt.Sprintf("field %s must be an E.164 phone number, e.g. +14155552671", e.Field())
+	// "min" / "max" — a number out of range, or a string of the
+	// wrong length (validator uses the same tags for both)
+	case "min":
+		return boundMessage(e, "at least")
+	case "max":
+		return boundMessage(e, "at most")
+	// Catch-all for any other validation tag (len, oneof, etc.)
+	default:
+		return fmt.Sprintf("field %s is invalid", e.Field())
+	}
+}
+
+// boundMessage describes a failed min or max rule, e.g. "field Age must
+// be at least 1" or "field Name must be at most 100 characters long".
+func boundMessage(e validator.FieldError, bound string) string {
+	if e.Kind() == reflect.String {
+		return fmt.Sprintf("field %s must be %s %s characters long", e.Field(), bound, e.Param())
 	}
+	return fmt.Sprintf("field %s must be %s %s", e.Field(), bound, e.Param())
 }
@@ -0,0 +1,427 @@
+package response
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	apperrors "github.com/aanand-mishra/students-api/internal/errors"
+	"github.com/aanand-mishra/students-api/internal/types"
+	"github.com/go-playground/validator/v10"
+)
+
+func TestWriteJSONL(t *testing.T) {
+	tests := []struct {
+		name  string
+		count int
+	}{
+		{"empty", 0},
+		{"one", 1},
+		{"ten", 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			students := make([]types.Student, tt.count)
+			for i := range students {
+				students[i] = types.Student{
+					ID:    i + 1,
+					Name:  fmt.Sprintf("Student %d", i+1),
+					Email: fmt.Sprintf("s%d@test.com", i+1),
+					Age:   20 + i,
+				}
+			}
+
+			rec := httptest.NewRecorder()
+			if err := WriteJSONL(rec, students); err != nil {
+				t.Fatalf("WriteJSONL: %v", err)
+			}
+
+			if rec.Code != 200 {
+				t.Errorf("status = %d, want 200", rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+				t.Errorf("Content-Type = %q, want application/x-ndjson", ct)
+			}
+
+			body := rec.Body.String()
+			if got := strings.Count(body, "\n"); got != tt.count {
+				t.Errorf("body has %d newlines, want %d", got, tt.count)
+			}
+
+			// Every line must parse on its own, like `jq -c '.'` reads it.
+			scanner := bufio.NewScanner(strings.NewReader(body))
+			lines := 0
+			for scanner.Scan() {
+				var s types.Student
+				if err := json.Unmarshal(scanner.Bytes(), &s); err != nil {
+					t.Fatalf("line %d is not a JSON object: %v\n%s", lines+1, err, scanner.Text())
+				}
+				if s.ID != students[lines].ID || s.Email != students[lines].Email {
+					t.Errorf("line %d = %+v, want student %d", lines+1, s, students[lines].ID)
+				}
+				lines++
+			}
+			if lines != tt.count {
+				t.Errorf("read %d lines, want %d", lines, tt.count)
+			}
+		})
+	}
+}
+
+func TestErrorHelpers(t *testing.T) {
+	err := errors.New("something went wrong")
+
+	tests := []struct {
+		name string
+		resp Response
+		code string
+	}{
+		{"GeneralError", GeneralError(err), ErrCodeInternal},
+		{"BadRequestError", BadRequestError(err), ErrCodeBadRequest},
+		{"NotFoundError", NotFoundError(err), ErrCodeNotFound},
+		{"DuplicateError", DuplicateError(err), ErrCodeDuplicate},
+		{"DuplicateEmailError", DuplicateEmailError(err), ErrCodeDuplicateEmail},
+		{"UnauthorizedError", UnauthorizedError(err), ErrCodeUnauthorized},
+		{"RateLimitError", RateLimitError(err), ErrCodeRateLimit},
+		{"TimeoutError", TimeoutError(err), ErrCodeTimeout},
+		{"PayloadTooLargeError", PayloadTooLargeError(err), ErrCodePayloadTooLarge},
+		{"Error", Error(ErrCodeForbidden, err), ErrCodeForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := Response{Status: StatusError, Error: "something went wrong", ErrorCode: tt.code}
+			if !reflect.DeepEqual(tt.resp, want) {
+				t.Errorf("got %+v, want %+v", tt.resp, want)
+			}
+		})
+	}
+}
+
+func TestGeneralErrorStackInDebug(t *testing.T) {
+	SetDebug(true)
+	t.Cleanup(func() { SetDebug(false) })
+
+	resp := GeneralError(errors.New("database is on fire"))
+	if len(resp.Stack) == 0 {
+		t.Fatal("no stack in debug mode")
+	}
+	if !strings.Contains(strings.Join(resp.Stack, "\n"), "TestGeneralErrorStackInDebug") {
+		t.Errorf("stack doesn't show the caller:\n%s", strings.Join(resp.Stack, "\n"))
+	}
+
+	SetDebug(false)
+	if resp := GeneralError(errors.New("database is on fire")); resp.Stack != nil {
+		t.Errorf("stack = %v outside debug mode, want none", resp.Stack)
+	}
+}
+
+// failDeep returns an error wrapped with its stack where it happens.
+func failDeep() error { return apperrors.Wrap(errors.New("disk full")) }
+
+func TestGeneralErrorUsesWrappedStack(t *testing.T) {
+	SetDebug(true)
+	t.Cleanup(func() { SetDebug(false) })
+
+	resp := GeneralError(fmt.Errorf("CreateStudent: %w", failDeep()))
+	if len(resp.Stack) == 0 || !strings.Contains(resp.Stack[0], "failDeep") {
+		t.Errorf("stack = %v, want it to start where the error was wrapped", resp.Stack)
+	}
+	if resp.Error != "CreateStudent: disk full" {
+		t.Errorf("error = %q, want the full message", resp.Error)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NotFound(rec, "no student found with id: 7")
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	want := `{"status":"error","error":"no student found with id: 7","error_code":"NOT_FOUND"}` + "\n"
+	if rec.Body.String() != want {
+		t.Errorf("body = %s, want %s", rec.Body, want)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	data := map[string]any{"id": 1, "name": "Rakesh"}
+	const compact = `{"id":1,"name":"Rakesh"}` + "\n"
+	const pretty = "{\n  \"id\": 1,\n  \"name\": \"Rakesh\"\n}\n"
+
+	tests := []struct {
+		name    string
+		writer  func(http.ResponseWriter) http.ResponseWriter
+		opts    []WriteOption
+		global  bool
+		want    string
+		writeFn func(http.ResponseWriter) error
+	}{
+		{name: "compact", want: compact},
+		{name: "WithPrettyPrint", opts: []WriteOption{WithPrettyPrint()}, want: pretty},
+		{name: "PrettyWriter", writer: func(w http.ResponseWriter) http.ResponseWriter { return PrettyWriter{w} }, want: pretty},
+		{name: "wrapped PrettyWriter", writer: func(w http.ResponseWriter) http.ResponseWriter { return unwrapper{PrettyWriter{w}} }, want: pretty},
+		{name: "SetPrettyDefault", global: true, want: pretty},
+		{name: "WriteJSONPretty", want: pretty, writeFn: func(w http.ResponseWriter) error { return WriteJSONPretty(w, http.StatusCreated, data) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetPrettyDefault(tt.global)
+			t.Cleanup(func() { SetPrettyDefault(false) })
+
+			rec := httptest.NewRecorder()
+			var w http.ResponseWriter = rec
+			if tt.writer != nil {
+				w = tt.writer(rec)
+			}
+
+			var err error
+			if tt.writeFn != nil {
+				err = tt.writeFn(w)
+			} else {
+				err = WriteJSON(w, http.StatusCreated, data, tt.opts...)
+			}
+			if err != nil {
+				t.Fatalf("WriteJSON: %v", err)
+			}
+
+			if rec.Code != http.StatusCreated {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusCreated)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+			if rec.Body.String() != tt.want {
+				t.Errorf("body = %q, want %q", rec.Body, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteJSONUnencodable(t *testing.T) {
+	for _, opts := range [][]WriteOption{nil, {WithPrettyPrint()}} {
+		rec := httptest.NewRecorder()
+		if err := WriteJSON(rec, http.StatusOK, func() {}, opts...); err == nil {
+			t.Errorf("WriteJSON(func, pretty=%v) = nil, want an error", opts != nil)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	WriteJSON(rec, http.StatusOK, func() {}, WithPrettyPrint())
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("pretty status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestPrettyWriterPassesThrough(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := PrettyWriter{rec}
+
+	if w.Unwrap() != rec {
+		t.Error("Unwrap doesn't return the wrapped writer")
+	}
+	w.Flush()
+	if !rec.Flushed {
+		t.Error("Flush didn't reach the wrapped writer")
+	}
+	// A writer that can't flush is left alone.
+	PrettyWriter{nonFlusher{rec}}.Flush()
+}
+
+// unwrapper is middleware's writer wrapped around another one.
+type unwrapper struct{ http.ResponseWriter }
+
+func (u unwrapper) Unwrap() http.ResponseWriter { return u.ResponseWriter }
+
+// nonFlusher hides the recorder's Flush method.
+type nonFlusher struct{ http.ResponseWriter }
+
+// validated has one field per message ValidationError knows.
+type validated struct {
+	Name    string `validate:"required"`
+	Email   string `validate:"email"`
+	Phone   string `validate:"e164"`
+	Age     int    `validate:"min=1"`
+	Score   int    `validate:"max=10"`
+	Code    string `validate:"min=3"`
+	Comment string `validate:"max=2"`
+	Status  string `validate:"oneof=a b"`
+}
+
+func TestValidationError(t *testing.T) {
+	err := validator.New().Struct(validated{
+		Email: "nope", Phone: "123", Score: 11, Code: "ab", Comment: "long", Status: "c",
+	})
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("validator returned %v", err)
+	}
+
+	want := []FieldError{
+		{"Name", "field Name is required"},
+		{"Email", "field Email must be a valid email address"},
+		{"Phone", "field Phone must be an E.164 phone number, e.g. +14155552671"},
+		{"Age", "field Age must be at least 1"},
+		{"Score", "field Score must be at most 10"},
+		{"Code", "field Code must be at least 3 characters long"},
+		{"Comment", "field Comment must be at most 2 characters long"},
+		{"Status", "field Status is invalid"},
+	}
+
+	resp := ValidationError(errs)
+	if resp.Status != StatusError || resp.ErrorCode != ErrCodeValidation {
+		t.Errorf("status %q, code %q; want error, %s", resp.Status, resp.ErrorCode, ErrCodeValidation)
+	}
+	if !reflect.DeepEqual(resp.Errors, want) {
+		t.Errorf("errors =\n%+v\nwant\n%+v", resp.Errors, want)
+	}
+	if !strings.HasPrefix(resp.Error, "field Name is required, field Email must be") {
+		t.Errorf("error = %q, want the messages joined", resp.Error)
+	}
+
+	SetJoinValidationErrors(false)
+	t.Cleanup(func() { SetJoinValidationErrors(true) })
+	if resp := ValidationError(errs); resp.Error != "validation failed" || len(resp.Errors) != len(want) {
+		t.Errorf("errors only: error = %q with %d errors", resp.Error, len(resp.Errors))
+	}
+}
+
+func TestETag(t *testing.T) {
+	a, err := ETag(types.Student{ID: 1, Name: "Rakesh"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ETag(types.Student{ID: 1, Name: "Rakesh"})
+	c, _ := ETag(types.Student{ID: 1, Name: "Rakesh K"})
+
+	if !strings.HasPrefix(a, `W/"`) || a != b {
+		t.Errorf("ETag = %s and %s for the same value, want one weak tag", a, b)
+	}
+	if a == c {
+		t.Error("ETag didn't change with the value")
+	}
+	if _, err := ETag(func() {}); err == nil {
+		t.Error("ETag(func) = nil error")
+	}
+}
+
+func TestCheckNotModified(t *testing.T) {
+	const etag = `W/"abc"`
+	modified := time.Date(2024, 5, 1, 9, 30, 0, 500, time.UTC)
+
+	tests := []struct {
+		name         string
+		method       string
+		headers      map[string]string
+		etag         string
+		lastModified time.Time
+		want         bool
+	}{
+		{"no conditions", http.MethodGet, nil, etag, modified, false},
+		{"etag matches", http.MethodGet, map[string]string{"If-None-Match": etag}, etag, modified, true},
+		{"strong form matches", http.MethodGet, map[string]string{"If-None-Match": `"abc"`}, etag, modified, true},
+		{"one of a list", http.MethodGet, map[string]string{"If-None-Match": `"x", W/"abc"`}, etag, modified, true},
+		{"star", http.MethodHead, map[string]string{"If-None-Match": "*"}, etag, modified, true},
+		{"etag differs", http.MethodGet, map[string]string{"If-None-Match": `"other"`}, etag, modified, false},
+		{"no etag to match", http.MethodGet, map[string]string{"If-None-Match": "*"}, "", modified, false},
+		{"etag wins over date", http.MethodGet, map[string]string{
+			"If-None-Match": `"other"`, "If-Modified-Since": modified.Format(http.TimeFormat),
+		}, etag, modified, false},
+		{"not modified since", http.MethodGet, map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, etag, modified, true},
+		{"modified since", http.MethodGet, map[string]string{"If-Modified-Since": modified.Add(-time.Minute).Format(http.TimeFormat)}, etag, modified, false},
+		{"bad date", http.MethodGet, map[string]string{"If-Modified-Since": "yesterday"}, etag, modified, false},
+		{"no last modified", http.MethodGet, map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, etag, time.Time{}, false},
+		{"not a GET", http.MethodPut, map[string]string{"If-None-Match": etag}, etag, modified, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/api/students/1", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			rec.Header().Set("Content-Type", "application/json")
+
+			if got := CheckNotModified(rec, r, tt.etag, tt.lastModified); got != tt.want {
+				t.Fatalf("CheckNotModified = %v, want %v", got, tt.want)
+			}
+
+			if rec.Header().Get("ETag") != tt.etag {
+				t.Errorf("ETag = %q, want %q", rec.Header().Get("ETag"), tt.etag)
+			}
+			wantLM := ""
+			if !tt.lastModified.IsZero() {
+				wantLM = "Wed, 01 May 2024 09:30:00 GMT"
+			}
+			if got := rec.Header().Get("Last-Modified"); got != wantLM {
+				t.Errorf("Last-Modified = %q, want %q", got, wantLM)
+			}
+			if tt.want && (rec.Code != http.StatusNotModified || rec.Header().Get("Content-Type") != "") {
+				t.Errorf("status = %d, Content-Type = %q; want 304 without one", rec.Code, rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestWritePaginatedJSON(t *testing.T) {
+	u, _ := url.Parse("/api/students?name=ra&page=2&per_page=10")
+
+	tests := []struct {
+		name string
+		meta PaginationMeta
+		want string
+	}{
+		{"no URL", PaginationMeta{Total: 5, PerPage: 10, Page: 1}, ""},
+		{"first page", PaginationMeta{URL: u, Total: 25, PerPage: 10, Page: 1},
+			`</api/students?name=ra&page=1&per_page=10>; rel="first", ` +
+				`</api/students?name=ra&page=2&per_page=10>; rel="next", ` +
+				`</api/students?name=ra&page=3&per_page=10>; rel="last"`},
+		{"middle page", PaginationMeta{URL: u, Total: 25, PerPage: 10, Page: 2},
+			`</api/students?name=ra&page=1&per_page=10>; rel="first", ` +
+				`</api/students?name=ra&page=1&per_page=10>; rel="prev", ` +
+				`</api/students?name=ra&page=3&per_page=10>; rel="next", ` +
+				`</api/students?name=ra&page=3&per_page=10>; rel="last"`},
+		{"past the end", PaginationMeta{URL: u, Total: 25, PerPage: 10, Page: 9},
+			`</api/students?name=ra&page=1&per_page=10>; rel="first", ` +
+				`</api/students?name=ra&page=3&per_page=10>; rel="prev", ` +
+				`</api/students?name=ra&page=3&per_page=10>; rel="last"`},
+		{"empty list", PaginationMeta{URL: u, Total: 0, PerPage: 10, Page: 1},
+			`</api/students?name=ra&page=1&per_page=10>; rel="first", ` +
+				`</api/students?name=ra&page=1&per_page=10>; rel="last"`},
+		{"cursor page", PaginationMeta{URL: u, Total: 25, PerPage: 10, NextCursor: "n", PrevCursor: "p", LastCursor: "l"},
+			`</api/students?name=ra&page=1&per_page=10>; rel="first", ` +
+				`</api/students?before=p&name=ra&per_page=10>; rel="prev", ` +
+				`</api/students?after=n&name=ra&per_page=10>; rel="next", ` +
+				`</api/students?before=l&name=ra&per_page=10>; rel="last"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			if err := WritePaginatedJSON(rec, http.StatusOK, []int{}, tt.meta); err != nil {
+				t.Fatal(err)
+			}
+			if got := rec.Header().Get("X-Total-Count"); got != fmt.Sprint(tt.meta.Total) {
+				t.Errorf("X-Total-Count = %q, want %d", got, tt.meta.Total)
+			}
+			if got := rec.Header().Get("Link"); got != tt.want {
+				t.Errorf("Link =\n%s\nwant\n%s", got, tt.want)
+			}
+			if rec.Body.String() != "[]\n" {
+				t.Errorf("body = %q", rec.Body)
+			}
+		})
+	}
+}
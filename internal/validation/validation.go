@@ -0,0 +1,169 @@
+// Package validation holds the application's single validator instance.
+//
+// go-playground/validator caches what it learns about each struct type,
+// so one shared *validator.Validate is both faster than calling
+// validator.New() per request and the only place custom rules need to be
+// registered. It is safe for concurrent use once configured.
+//
+// Which Student fields are required is a per-deployment setting
+// (validation.required_fields in the config), so instead of
+// validate:"required" struct tags, Student is checked by a struct-level
+// rule built from that list — see SetRequiredFields. The same struct-level
+// rule also checks that a student's email is at their department's domain
+// when one is configured — see SetDepartmentEmailDomains.
+//
+// Custom field tags are registered here too: "e164", for phone numbers.
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/aanand-mishra/students-api/internal/types"
+	"github.com/go-playground/validator/v10"
+)
+
+// DefaultRequiredFields are the Student fields required when the config
+// doesn't say otherwise — the ones the API has always required.
+var DefaultRequiredFields = []string{"name", "email", "age"}
+
+var validate = validator.New()
+
+// E164Tag validates a phone number in E.164 format.
+const E164Tag = "e164"
+
+// e164Pattern is E.164: "+", a country code that doesn't start with 0,
+// and at most 15 digits in all.
+var e164Pattern = regexp.MustCompile(`^\+[1-9]\d{1,14}$`)
+
+// DepartmentEmailMatchTag is the tag reported on the email field when it
+// isn't at the domain configured for the student's department.
+const DepartmentEmailMatchTag = "departmentemailmatch"
+
+var (
+	// requiredFields is the list last passed to SetRequiredFields, and
+	// required the struct fields it names.
+	requiredFields []string
+	required       []reflect.StructField
+
+	// departmentDomains maps lower-cased department names to the email
+	// domain their students must use (see SetDepartmentEmailDomains).
+	departmentDomains map[string]string
+)
+
+func init() {
+	// A type can only have one struct-level rule, so studentRules checks
+	// everything that isn't expressed as a field tag.
+	validate.RegisterStructValidation(studentRules, types.Student{})
+
+	// validator has an e164 tag of its own, but it wants at least 8
+	// digits, which rejects valid short numbers. Registering the tag
+	// replaces it.
+	if err := validate.RegisterValidation(E164Tag, isE164); err != nil {
+		panic(err)
+	}
+
+	if err := SetRequiredFields(DefaultRequiredFields); err != nil {
+		panic(err) // the defaults are constants; this is a programming error
+	}
+}
+
+// Validator returns the shared validator.
+func Validator() *validator.Validate {
+	return validate
+}
+
+// SetRequiredFields makes exactly the listed Student fields required; all
+// others become optional. Fields are named as in JSON ("name", "email"…).
+// An unknown name is an error, so a typo in the config fails at startup
+// rather than silently requiring nothing.
+//
+// Call it once at startup, before requests are served.
+func SetRequiredFields(fields []string) error {
+	resolved, err := resolveFields(reflect.TypeOf(types.Student{}), fields)
+	if err != nil {
+		return err
+	}
+
+	requiredFields = append([]string(nil), fields...)
+	required = resolved
+
+	return nil
+}
+
+// SetDepartmentEmailDomains makes students in the listed departments use
+// an email at that department's domain, e.g. {"CS": "cs.university.edu"}.
+// Department names match case-insensitively; students in departments not
+// listed, or with no department, are not checked. An empty map turns the
+// check off.
+//
+// Call it once at startup, before requests are served.
+func SetDepartmentEmailDomains(domains map[string]string) {
+	departmentDomains = make(map[string]string, len(domains))
+	for department, domain := range domains {
+		domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+		departmentDomains[strings.ToLower(strings.TrimSpace(department))] = domain
+	}
+}
+
+// studentRules is the struct-level rule for types.Student: the configured
+// required fields, then departmentemailmatch.
+func studentRules(sl validator.StructLevel) {
+	student := sl.Current()
+	for _, f := range required {
+		if student.FieldByIndex(f.Index).IsZero() {
+			// Reported under the Go field name, like a "required" tag
+			// would be, so error messages read the same as before.
+			sl.ReportError(student.FieldByIndex(f.Index).Interface(),
+				f.Name, f.Name, "required", "")
+		}
+	}
+
+	s := sl.Current().Interface().(types.Student)
+	if s.Department == "" || s.Email == "" {
+		return
+	}
+	domain, ok := departmentDomains[strings.ToLower(strings.TrimSpace(s.Department))]
+	if !ok {
+		return
+	}
+	if !strings.HasSuffix(strings.ToLower(strings.TrimSpace(s.Email)), "@"+domain) {
+		sl.ReportError(s.Email, "Email", "Email", DepartmentEmailMatchTag, domain)
+	}
+}
+
+// isE164 checks a string field against e164Pattern.
+func isE164(fl validator.FieldLevel) bool {
+	return e164Pattern.MatchString(fl.Field().String())
+}
+
+// RequiredFields returns the Student fields (JSON names) currently
+// required, as set by SetRequiredFields.
+func RequiredFields() []string {
+	return append([]string(nil), requiredFields...)
+}
+
+// resolveFields maps JSON field names to struct fields of t.
+func resolveFields(t reflect.Type, names []string) ([]reflect.StructField, error) {
+	byJSONName := make(map[string]reflect.StructField, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		byJSONName[name] = field
+	}
+
+	fields := make([]reflect.StructField, 0, len(names))
+	for _, name := range names {
+		field, ok := byJSONName[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("validation: unknown student field %q in required fields", name)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
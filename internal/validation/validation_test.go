@@ -0,0 +1,170 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/aanand-mishra/students-api/internal/types"
+	"github.com/go-playground/validator/v10"
+)
+
+// failures validates s and returns "Field:tag" for every failure, sorted.
+func failures(t *testing.T, s types.Student) []string {
+	t.Helper()
+
+	err := Validator().Struct(s)
+	if err == nil {
+		return nil
+	}
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("Struct() = %v, want validator.ValidationErrors", err)
+	}
+	var got []string
+	for _, fe := range verrs {
+		got = append(got, fe.Field()+":"+fe.Tag())
+	}
+	sort.Strings(got)
+	return got
+}
+
+func TestSetRequiredFields(t *testing.T) {
+	t.Cleanup(func() { SetRequiredFields(DefaultRequiredFields) })
+
+	tests := []struct {
+		name     string
+		required []string
+		student  types.Student
+		want     []string
+	}{
+		{"defaults, complete", DefaultRequiredFields, types.Student{Name: "Rakesh", Email: "r@test.com", Age: 35}, nil},
+		{"defaults, empty", DefaultRequiredFields, types.Student{}, []string{"Age:required", "Email:required", "Name:required"}},
+		{"age optional", []string{"name", "email"}, types.Student{Name: "Rakesh", Email: "r@test.com"}, nil},
+		{"phone required", []string{"name", "phone"}, types.Student{Name: "Rakesh"}, []string{"Phone:required"}},
+		{"nothing required", nil, types.Student{}, nil},
+		{"tags still apply", nil, types.Student{Age: 200, Phone: "12345"}, []string{"Age:max", "Phone:e164"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetRequiredFields(tt.required); err != nil {
+				t.Fatalf("SetRequiredFields(%v): %v", tt.required, err)
+			}
+			if got := failures(t, tt.student); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("failures = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetRequiredFieldsUnknown(t *testing.T) {
+	t.Cleanup(func() { SetRequiredFields(DefaultRequiredFields) })
+
+	if err := SetRequiredFields([]string{"name", "Email"}); err == nil {
+		t.Error("SetRequiredFields accepted the Go field name Email; want JSON names only")
+	}
+	if err := SetRequiredFields([]string{"name", "shoe_size"}); err == nil {
+		t.Error("SetRequiredFields accepted an unknown field")
+	}
+	// A rejected list leaves the previous one in force.
+	if got := RequiredFields(); !reflect.DeepEqual(got, DefaultRequiredFields) {
+		t.Errorf("RequiredFields() = %v after a rejected list, want %v", got, DefaultRequiredFields)
+	}
+}
+
+func TestRequiredFieldsIsACopy(t *testing.T) {
+	t.Cleanup(func() { SetRequiredFields(DefaultRequiredFields) })
+
+	fields := []string{"name", "email"}
+	if err := SetRequiredFields(fields); err != nil {
+		t.Fatal(err)
+	}
+	fields[0] = "age"
+	got := RequiredFields()
+	got[1] = "phone"
+
+	if want := []string{"name", "email"}; !reflect.DeepEqual(RequiredFields(), want) {
+		t.Errorf("RequiredFields() = %v, want %v unaffected by callers' slices", RequiredFields(), want)
+	}
+}
+
+func TestDepartmentEmailMatch(t *testing.T) {
+	t.Cleanup(func() { SetDepartmentEmailDomains(nil) })
+
+	student := func(department, email string) types.Student {
+		return types.Student{Name: "Priya", Email: email, Age: 20, Department: department}
+	}
+	csDomain := map[string]string{"CS": "@cs.university.edu"}
+
+	tests := []struct {
+		name    string
+		domains map[string]string
+		student types.Student
+		want    []string
+	}{
+		{"correct domain", csDomain, student("CS", "priya@cs.university.edu"), nil},
+		{"case-insensitive", csDomain, student(" cs ", "Priya@CS.University.edu"), nil},
+		{"wrong domain", csDomain, student("CS", "priya@gmail.com"), []string{"Email:" + DepartmentEmailMatchTag}},
+		{"subdomain lookalike", csDomain, student("CS", "priya@evilcs.university.edu"), []string{"Email:" + DepartmentEmailMatchTag}},
+		{"unknown department", csDomain, student("Physics", "priya@gmail.com"), nil},
+		{"no department", csDomain, student("", "priya@gmail.com"), nil},
+		{"empty domain map", map[string]string{}, student("CS", "priya@gmail.com"), nil},
+		{"nil domain map", nil, student("CS", "priya@gmail.com"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetDepartmentEmailDomains(tt.domains)
+			if got := failures(t, tt.student); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("failures = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDepartmentEmailMatchParam(t *testing.T) {
+	t.Cleanup(func() { SetDepartmentEmailDomains(nil) })
+	SetDepartmentEmailDomains(map[string]string{"CS": "cs.university.edu"})
+
+	err := Validator().Struct(types.Student{Name: "Priya", Email: "priya@gmail.com", Age: 20, Department: "CS"})
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs) != 1 {
+		t.Fatalf("Struct() = %v, want one validation error", err)
+	}
+	// The expected domain rides along, for the error message.
+	if verrs[0].Param() != "cs.university.edu" {
+		t.Errorf("Param() = %q, want the department's domain", verrs[0].Param())
+	}
+}
+
+func TestValidatorIsShared(t *testing.T) {
+	if Validator() != Validator() {
+		t.Error("Validator() returned a new instance")
+	}
+}
+
+// BenchmarkValidate compares building a validator per request, as the
+// handlers once did, with the shared one. Run with -benchmem: the shared
+// validator has already cached Student's struct metadata, so it allocates
+// far less per call.
+func BenchmarkValidate(b *testing.B) {
+	s := types.Student{Name: "Rakesh", Email: "rakesh@test.com", Age: 35, Phone: "+14155552671"}
+
+	b.Run("new per call", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := validator.New().Struct(s); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("shared", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := Validator().Struct(s); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
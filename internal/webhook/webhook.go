@@ -0,0 +1,327 @@
+// Package webhook notifies other systems (an LMS, billing…) of changes to
+// students, by POSTing a types.WebhookEvent to every URL the student's
+// tenant registered with POST /api/webhooks.
+//
+// Dispatcher implements storage.Hook, so it sees every create, update and
+// delete however it was made — REST, gRPC or a CSV import. The hook only
+// queues the event; a worker goroutine delivers it, so a slow subscriber
+// never slows down the request that made the change.
+//
+// Each delivery is signed: the X-Webhook-Signature header is
+// "sha256=" and the hex HMAC-SHA256 of the body, keyed with the
+// subscription's secret. Receivers should compute it themselves (see
+// Sign) and ignore requests where it doesn't match.
+//
+// Deliveries only go to public addresses. Otherwise any client allowed
+// to register a webhook could make the server POST to its own loopback
+// interface, the private network around it or a cloud metadata service
+// (169.254.169.254). CheckURL refuses such URLs when they are
+// registered, and every connection is checked again once the host name
+// has been resolved, since DNS can point a public-looking name anywhere.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// Headers sent with every delivery.
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+)
+
+// maxAttempts is how many times a delivery is tried: once, then three
+// retries, waiting firstRetryDelay and doubling it each time (1s, 2s, 4s).
+const (
+	maxAttempts     = 4
+	firstRetryDelay = time.Second
+)
+
+// Store persists webhook subscriptions (implemented by *sqlite.SQLite
+// and *postgres.Postgres). Each belongs to a tenant and only hears about
+// that tenant's students.
+type Store interface {
+	// CreateWebhook stores sub for the tenant and returns it with its ID
+	// and creation time set.
+	CreateWebhook(ctx context.Context, tenantID string, sub types.WebhookSubscription) (types.WebhookSubscription, error)
+
+	// GetWebhooks returns every subscription of the tenant, secrets
+	// included.
+	GetWebhooks(ctx context.Context, tenantID string) ([]types.WebhookSubscription, error)
+
+	// DeleteWebhook removes a subscription of the tenant. Returns
+	// storage.ErrNotFound if it has none with the given id.
+	DeleteWebhook(ctx context.Context, tenantID string, id int64) error
+}
+
+// Dispatcher is a storage.Hook that delivers webhook events.
+type Dispatcher struct {
+	store      Store
+	client     *http.Client
+	events     chan types.WebhookEvent
+	retryDelay time.Duration // before the first retry; doubled for each one after
+}
+
+// New returns a Dispatcher that reads subscriptions from store. Up to
+// queueSize events wait for delivery; each attempt may take up to
+// timeout. Nothing is delivered until Start is called.
+func New(store Store, queueSize int, timeout time.Duration) *Dispatcher {
+	return &Dispatcher{
+		store:      store,
+		client:     newClient(timeout),
+		events:     make(chan types.WebhookEvent, queueSize),
+		retryDelay: firstRetryDelay,
+	}
+}
+
+// newClient returns the client deliveries are made with. Its dialer
+// refuses forbidden addresses (see forbidden) after DNS resolution, for
+// redirects too, since each of them dials again. It ignores the
+// HTTP_PROXY environment: through a proxy, the only address dialed
+// would be the proxy's.
+func newClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: refuseForbidden}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// Start runs the delivery worker until ctx is cancelled. Events still
+// queued then are not delivered.
+func (d *Dispatcher) Start(ctx context.Context) {
+	go d.run(ctx)
+}
+
+// OnCreate implements storage.Hook.
+func (d *Dispatcher) OnCreate(ctx context.Context, student types.Student) {
+	d.enqueue(types.WebhookEvent{
+		Type:      types.WebhookEventCreated,
+		StudentID: int64(student.ID),
+		Student:   &student,
+		TenantID:  student.TenantID,
+	})
+}
+
+// OnUpdate implements storage.Hook.
+func (d *Dispatcher) OnUpdate(ctx context.Context, old, new types.Student) {
+	d.enqueue(types.WebhookEvent{
+		Type:      types.WebhookEventUpdated,
+		StudentID: int64(new.ID),
+		Student:   &new,
+		TenantID:  new.TenantID,
+	})
+}
+
+// OnDelete implements storage.Hook.
+func (d *Dispatcher) OnDelete(ctx context.Context, tenantID string, studentID int64) {
+	d.enqueue(types.WebhookEvent{
+		Type:      types.WebhookEventDeleted,
+		StudentID: studentID,
+		TenantID:  tenantID,
+	})
+}
+
+// enqueue hands event to the worker without blocking: hooks run inside
+// the request, and a backlog of deliveries must not hold it up.
+func (d *Dispatcher) enqueue(event types.WebhookEvent) {
+	event.Timestamp = time.Now().UTC()
+
+	select {
+	case d.events <- event:
+	default:
+		slog.Warn("webhook queue is full, event dropped",
+			slog.String("type", event.Type),
+			slog.Int64("student_id", event.StudentID))
+	}
+}
+
+// run delivers queued events one at a time, so every subscriber receives
+// them in the order the changes were made.
+func (d *Dispatcher) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-d.events:
+			d.deliverAll(ctx, event)
+		}
+	}
+}
+
+// deliverAll sends event to every subscription of its tenant at once, and
+// returns when each has received it or run out of retries.
+func (d *Dispatcher) deliverAll(ctx context.Context, event types.WebhookEvent) {
+	subs, err := d.store.GetWebhooks(ctx, event.TenantID)
+	if err != nil {
+		slog.Error("failed to load webhooks, event dropped",
+			slog.String("type", event.Type),
+			slog.Int64("student_id", event.StudentID),
+			slog.String("error", err.Error()))
+		return
+	}
+	if len(subs) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		slog.Error("failed to encode webhook event", slog.String("error", err.Error()))
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, sub := range subs {
+		wg.Add(1)
+		go func(sub types.WebhookSubscription) {
+			defer wg.Done()
+			d.deliver(ctx, sub, event.Type, body)
+		}(sub)
+	}
+	wg.Wait()
+}
+
+// deliver POSTs body to one subscription, retrying with backoff until it
+// is accepted, maxAttempts is reached or ctx is cancelled.
+func (d *Dispatcher) deliver(ctx context.Context, sub types.WebhookSubscription, eventType string, body []byte) {
+	delay := d.retryDelay
+	for attempt := 1; ; attempt++ {
+		err := d.post(ctx, sub, eventType, body)
+		if err == nil {
+			return
+		}
+		if attempt == maxAttempts {
+			slog.Error("webhook delivery failed, giving up",
+				slog.Int64("webhook_id", sub.ID),
+				slog.String("type", eventType),
+				slog.Int("attempts", attempt),
+				slog.String("error", err.Error()))
+			return
+		}
+		slog.Warn("webhook delivery failed, retrying",
+			slog.Int64("webhook_id", sub.ID),
+			slog.String("type", eventType),
+			slog.Int("attempt", attempt),
+			slog.Duration("retry_in", delay),
+			slog.String("error", err.Error()))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// post makes one delivery attempt. Any 2xx response accepts the event.
+func (d *Dispatcher) post(ctx context.Context, sub types.WebhookSubscription, eventType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, eventType)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(sub.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	// Read the body to the end so the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("subscriber answered %s", resp.Status)
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of body keyed with secret: the value
+// of the X-Webhook-Signature header, after its "sha256=" prefix.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// errForbiddenAddress is returned for a delivery to an address that
+// isn't public.
+var errForbiddenAddress = errors.New("webhook: deliveries to loopback, private and link-local addresses are not allowed")
+
+// CheckURL accepts absolute http and https URLs whose host could be
+// delivered to: not "localhost", nor a loopback, private, link-local or
+// other internal IP address (see forbidden). Any other host name is
+// only checked once it is resolved, at delivery.
+func CheckURL(raw string) error {
+	if raw == "" {
+		return errors.New("url is required")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errForbiddenAddress
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && forbidden(addr) {
+		return errForbiddenAddress
+	}
+	return nil
+}
+
+// refuseForbidden is the delivery dialer's Control function: address is
+// the resolved "ip:port" about to be connected to.
+func refuseForbidden(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("webhook: unexpected address %q: %w", address, err)
+	}
+	if forbidden(addrPort.Addr()) {
+		return errForbiddenAddress
+	}
+	return nil
+}
+
+// sharedAddressSpace is 100.64.0.0/10, carrier-grade NAT (RFC 6598).
+// Some clouds put internal services there, e.g. a metadata service at
+// 100.100.100.200.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// forbidden reports whether addr is somewhere deliveries must not go:
+// loopback, private (RFC 1918, IPv6 ULA), link-local (which includes
+// 169.254.169.254), unspecified, multicast or shared address space.
+// IPv4-mapped IPv6 addresses are judged as the IPv4 address they are.
+func forbidden(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified() ||
+		sharedAddressSpace.Contains(addr)
+}
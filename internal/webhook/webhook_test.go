@@ -0,0 +1,292 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aanand-mishra/students-api/internal/storage"
+	"github.com/aanand-mishra/students-api/internal/types"
+)
+
+// memStore is a Store holding subscriptions in memory.
+type memStore struct {
+	mu   sync.Mutex
+	subs map[string][]types.WebhookSubscription
+}
+
+func (m *memStore) CreateWebhook(_ context.Context, tenantID string, sub types.WebhookSubscription) (types.WebhookSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.subs == nil {
+		m.subs = map[string][]types.WebhookSubscription{}
+	}
+	sub.ID = int64(len(m.subs[tenantID]) + 1)
+	m.subs[tenantID] = append(m.subs[tenantID], sub)
+	return sub, nil
+}
+
+func (m *memStore) GetWebhooks(_ context.Context, tenantID string) ([]types.WebhookSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]types.WebhookSubscription(nil), m.subs[tenantID]...), nil
+}
+
+func (m *memStore) DeleteWebhook(context.Context, string, int64) error {
+	return storage.ErrNotFound
+}
+
+// receiver is a subscriber: it records every delivery and answers each
+// with the next status in statuses (the last one repeats).
+type receiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	t.Helper()
+
+	r := &receiver{}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.requests = append(r.requests, req)
+		r.bodies = append(r.bodies, body)
+		n := len(r.requests)
+		r.mu.Unlock()
+
+		w.WriteHeader(statuses[min(n, len(statuses))-1])
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+// newTestDispatcher returns a Dispatcher whose retries come quickly, and
+// which may deliver to the loopback receivers of these tests.
+func newTestDispatcher(store Store) *Dispatcher {
+	d := New(store, 10, time.Second)
+	d.client = &http.Client{Timeout: time.Second}
+	d.retryDelay = time.Millisecond
+	return d
+}
+
+func TestSignature(t *testing.T) {
+	rec := newReceiver(t, http.StatusOK)
+	store := &memStore{}
+	store.CreateWebhook(context.Background(), types.DefaultTenant, types.WebhookSubscription{URL: rec.URL, Secret: "s3cret"})
+
+	student := types.Student{ID: 7, Name: "Rakesh", Email: "rakesh@test.com", TenantID: types.DefaultTenant}
+	d := newTestDispatcher(store)
+	d.OnCreate(context.Background(), student)
+	d.deliverAll(context.Background(), <-d.events)
+
+	if rec.count() != 1 {
+		t.Fatalf("deliveries = %d, want 1", rec.count())
+	}
+	req, body := rec.requests[0], rec.bodies[0]
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	if got, want := req.Header.Get(SignatureHeader), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Errorf("%s = %q, want %q", SignatureHeader, got, want)
+	}
+	if got := req.Header.Get(EventHeader); got != types.WebhookEventCreated {
+		t.Errorf("%s = %q, want %q", EventHeader, got, types.WebhookEventCreated)
+	}
+	if got := req.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+
+	var event types.WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatalf("decoding the body: %v", err)
+	}
+	if event.Type != types.WebhookEventCreated || event.StudentID != 7 || event.Student == nil || event.Student.Email != "rakesh@test.com" {
+		t.Errorf("event = %+v, want student 7 created", event)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		want     int
+	}{
+		{"accepted at once", []int{http.StatusOK}, 1},
+		{"accepted on the third attempt", []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusAccepted}, 3},
+		{"gives up after three retries", []int{http.StatusInternalServerError}, maxAttempts},
+		{"a 4xx is retried too", []int{http.StatusNotFound}, maxAttempts},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := newReceiver(t, tt.statuses...)
+			d := newTestDispatcher(&memStore{})
+			d.deliver(context.Background(), types.WebhookSubscription{ID: 1, URL: rec.URL}, types.WebhookEventDeleted, []byte(`{}`))
+
+			if got := rec.count(); got != tt.want {
+				t.Errorf("attempts = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	rec := newReceiver(t, http.StatusInternalServerError)
+	d := newTestDispatcher(&memStore{})
+	d.retryDelay = 20 * time.Millisecond
+
+	start := time.Now()
+	d.deliver(context.Background(), types.WebhookSubscription{ID: 1, URL: rec.URL}, types.WebhookEventDeleted, []byte(`{}`))
+
+	// 20ms, then 40ms, then 80ms between the four attempts.
+	if elapsed := time.Since(start); elapsed < 140*time.Millisecond {
+		t.Errorf("four attempts took %v, want at least 140ms of backoff", elapsed)
+	}
+}
+
+func TestRetriesStopWhenCancelled(t *testing.T) {
+	rec := newReceiver(t, http.StatusInternalServerError)
+	d := newTestDispatcher(&memStore{})
+	d.retryDelay = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	d.deliver(ctx, types.WebhookSubscription{ID: 1, URL: rec.URL}, types.WebhookEventDeleted, []byte(`{}`))
+
+	if got := rec.count(); got != 1 {
+		t.Errorf("attempts = %d, want 1 before the shutdown", got)
+	}
+}
+
+func TestOnlyTheTenantsWebhooks(t *testing.T) {
+	acme, other := newReceiver(t, http.StatusOK), newReceiver(t, http.StatusOK)
+	store := &memStore{}
+	store.CreateWebhook(context.Background(), "acme", types.WebhookSubscription{URL: acme.URL})
+	store.CreateWebhook(context.Background(), "other", types.WebhookSubscription{URL: other.URL})
+
+	d := newTestDispatcher(store)
+	d.OnDelete(context.Background(), "acme", 3)
+	d.deliverAll(context.Background(), <-d.events)
+
+	if acme.count() != 1 || other.count() != 0 {
+		t.Errorf("deliveries: acme %d, other %d; want 1 and 0", acme.count(), other.count())
+	}
+}
+
+func TestStartDelivers(t *testing.T) {
+	var got atomic.Int32
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got.Add(1) == 1 {
+			close(done)
+		}
+	}))
+	defer srv.Close()
+
+	store := &memStore{}
+	store.CreateWebhook(context.Background(), types.DefaultTenant, types.WebhookSubscription{URL: srv.URL})
+	d := newTestDispatcher(store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d.Start(ctx)
+	d.OnUpdate(context.Background(), types.Student{}, types.Student{ID: 1, TenantID: types.DefaultTenant})
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the event was never delivered")
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://lms.example.com/hooks", true},
+		{"http://203.0.113.10:8080/hook", true},
+		{"https://[2001:db8::1]/hook", true},
+		{"", false},
+		{"ftp://lms.example.com/hooks", false},
+		{"/hooks", false},
+		{"http://localhost:8080/hook", false},
+		{"http://LOCALHOST./hook", false},
+		{"http://api.localhost/hook", false},
+		{"http://127.0.0.1/hook", false},
+		{"http://[::1]/hook", false},
+		{"http://10.0.0.5/hook", false},
+		{"http://172.16.3.4/hook", false},
+		{"http://192.168.1.20/hook", false},
+		{"http://169.254.169.254/latest/meta-data/", false},
+		{"http://[fe80::1]/hook", false},
+		{"http://[fd00::1]/hook", false},
+		{"http://[::ffff:127.0.0.1]/hook", false},
+		{"http://0.0.0.0/hook", false},
+		{"http://100.100.100.200/hook", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if err := CheckURL(tt.url); (err == nil) != tt.ok {
+				t.Errorf("CheckURL(%q) = %v, want ok %v", tt.url, err, tt.ok)
+			}
+		})
+	}
+}
+
+// TestDeliveryRefusesInternalAddresses uses the real delivery client,
+// which must not connect to a loopback receiver even though the URL got
+// past registration (say, a host name that resolves to 127.0.0.1).
+func TestDeliveryRefusesInternalAddresses(t *testing.T) {
+	rec := newReceiver(t, http.StatusOK)
+	d := New(&memStore{}, 10, time.Second)
+	d.retryDelay = time.Millisecond
+
+	if err := d.post(context.Background(), types.WebhookSubscription{URL: rec.URL}, types.WebhookEventDeleted, []byte(`{}`)); err == nil {
+		t.Error("post to a loopback address = nil error")
+	}
+	d.deliver(context.Background(), types.WebhookSubscription{ID: 1, URL: rec.URL}, types.WebhookEventDeleted, []byte(`{}`))
+	if got := rec.count(); got != 0 {
+		t.Errorf("loopback receiver got %d deliveries, want 0", got)
+	}
+}
+
+func TestRefuseForbidden(t *testing.T) {
+	for addr, ok := range map[string]bool{
+		"93.184.216.34:443":      true,
+		"[2606:4700::1111]:443":  true,
+		"127.0.0.1:80":           false,
+		"169.254.169.254:80":     false,
+		"192.168.0.1:443":        false,
+		"[::1]:443":              false,
+		"[::ffff:10.0.0.1]:8080": false,
+	} {
+		if err := refuseForbidden("tcp", addr, nil); (err == nil) != ok {
+			t.Errorf("refuseForbidden(%s) = %v, want ok %v", addr, err, ok)
+		}
+	}
+	if !forbidden(netip.MustParseAddr("224.0.0.1")) {
+		t.Error("multicast 224.0.0.1 is not forbidden")
+	}
+}